@@ -0,0 +1,105 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/user/inventory-api/pkg/client"
+)
+
+var dhcpCmd = &cobra.Command{
+	Use:   "dhcp",
+	Short: "Generate DHCP host reservations from inventoried MAC addresses",
+	Long: `Generate DHCP host reservations for the MAC addresses of Nodes (or the
+--device-type Devices) and the NICs below them, for dnsmasq (dhcp-host lines),
+ISC dhcpd (host declarations) or Kea (a JSON list for a Dhcp4 "reservations"
+setting). Hostnames and IPv4 addresses come from hostname and ip labels,
+annotations or properties.
+
+With -f the file is replaced atomically, and left alone when its content
+wouldn't change, so the command can run from cron followed by a reload.
+
+Examples:
+  client dhcp
+  client dhcp --format dnsmasq -f /etc/dnsmasq.d/inventory.conf
+  client dhcp --format isc -l rack=r12 -f /etc/dhcp/inventory-hosts.conf
+  client dhcp --format kea --device-type Node,Switch -f reservations.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		format, _ := cmd.Flags().GetString("format")
+		file, _ := cmd.Flags().GetString("file")
+		deviceTypes, _ := cmd.Flags().GetStringSlice("device-type")
+		cmd.SilenceUsage = true
+		config, err := c.DHCPReservations(ctx, format, deviceTypes, listOptions(cmd))
+		if err != nil {
+			return fmt.Errorf("failed to get DHCP reservations: %w", err)
+		}
+
+		if file == "" || file == "-" {
+			_, err := os.Stdout.Write(config)
+			return err
+		}
+		if current, err := os.ReadFile(file); err == nil && bytes.Equal(current, config) {
+			fmt.Fprintf(os.Stderr, "%s is up to date\n", file)
+			return nil
+		}
+		if err := writeFileAtomic(file, config); err != nil {
+			return err
+		}
+		fmt.Fprintf(os.Stderr, "Wrote %s\n", file)
+		return nil
+	},
+}
+
+func init() {
+	dhcpCmd.Flags().String("format", client.DHCPDnsmasq, "Configuration format: dnsmasq, isc or kea")
+	dhcpCmd.Flags().StringP("file", "f", "", "Write the configuration to a file (atomically) instead of stdout")
+	dhcpCmd.Flags().StringSlice("device-type", nil, "Device types to make reservations for (default Node; * for all)")
+	dhcpCmd.Flags().StringP("selector", "l", "", "Label selector, e.g. 'rack=r12,role in (compute,login)'")
+	dhcpCmd.Flags().String("field-selector", "", "Field selector, e.g. status.phase=Ready")
+
+	rootCmd.AddCommand(dhcpCmd)
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so readers never see a partial file. An existing file's
+// permissions are kept.
+func writeFileAtomic(path string, data []byte) error {
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	if err := os.Chmod(tmp.Name(), mode); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,246 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/user/inventory-api/pkg/client"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+	"github.com/user/inventory-api/pkg/smd"
+)
+
+var exportCmd = &cobra.Command{
+	Use:   "export",
+	Short: "Export devices as NDJSON, CSV or SMD hardware inventory",
+	Long: `Export every device matching the selectors as NDJSON (one device per line,
+the format import takes), CSV (one row per device, for spreadsheets), or SMD
+hardware inventory by location (smd-location) or by FRU (smd-fru).
+
+Examples:
+  client export > devices.ndjson
+  client export --format csv -f devices.csv
+  client export -l rack=r12 --field-selector spec.deviceType=Node
+  client export --format smd-location -f hwinventory.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		format, _ := cmd.Flags().GetString("format")
+		file, _ := cmd.Flags().GetString("file")
+		cmd.SilenceUsage = true
+		body, err := c.ExportDevices(ctx, format, listOptions(cmd))
+		if err != nil {
+			return fmt.Errorf("failed to export devices: %w", err)
+		}
+		defer body.Close()
+
+		var out io.Writer = os.Stdout
+		if file != "" && file != "-" {
+			f, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		if _, err := io.Copy(out, body); err != nil {
+			return fmt.Errorf("failed to export devices: %w", err)
+		}
+		return nil
+	},
+}
+
+var importCmd = &cobra.Command{
+	Use:   "import -f <file>",
+	Short: "Import devices from NDJSON or an SMD hardware inventory dump",
+	Long: `Import devices from an NDJSON file (one device per line, as written by
+export). A device whose serial number is already in the inventory is updated,
+the others are created, and parents are linked by parentSerialNumber.
+
+Records with errors are listed and skipped; the rest are imported together.
+Use --dry-run to see what would change without changing anything.
+
+With --format smd the file is an SMD hardware inventory dump (the output of
+GET /hsm/v2/Inventory/Hardware or .../Hardware/Query/{xname}). Its populated
+locations are sent as a DiscoverySnapshot, which the server reconciles into
+devices like a collector's; xnames and SMD details are kept as properties.
+
+Examples:
+  client import -f devices.ndjson --dry-run
+  client import -f devices.ndjson
+  cat devices.ndjson | client import -f -
+  client import --format smd -f smd-hardware.json`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		file, _ := cmd.Flags().GetString("file")
+		dryRun, _ := cmd.Flags().GetBool("dry-run")
+		format, _ := cmd.Flags().GetString("format")
+		if format != "ndjson" && format != "smd" {
+			return fmt.Errorf("invalid format %q: must be ndjson or smd", format)
+		}
+		cmd.SilenceUsage = true
+		var in io.Reader = os.Stdin
+		if file != "-" {
+			f, err := os.Open(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			in = f
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if format == "smd" {
+			return importSMD(ctx, c, in, file, dryRun)
+		}
+		result, err := c.ImportDevices(ctx, in, dryRun)
+		if err != nil {
+			return fmt.Errorf("failed to import devices: %w", err)
+		}
+
+		if output != "table" {
+			if err := printOutput(result); err != nil {
+				return err
+			}
+		} else {
+			printImportResult(result)
+		}
+		if result.Failed > 0 {
+			return fmt.Errorf("%d records could not be imported", result.Failed)
+		}
+		return nil
+	},
+}
+
+func init() {
+	exportCmd.Flags().String("format", client.ExportNDJSON, "Export format: ndjson, csv, smd-location or smd-fru")
+	exportCmd.Flags().StringP("file", "f", "", "Write the export to a file instead of stdout")
+	exportCmd.Flags().StringP("selector", "l", "", "Label selector, e.g. 'rack=r12,role in (compute,login)'")
+	exportCmd.Flags().String("field-selector", "", "Field selector, e.g. spec.deviceType=DIMM,status.phase!=Ready")
+	exportCmd.Flags().String("sort-by", "", "Sort by uid, name, createdAt or updatedAt")
+	exportCmd.Flags().Bool("desc", false, "Sort in descending order")
+
+	importCmd.Flags().StringP("file", "f", "", "NDJSON file to import ('-' for stdin)")
+	importCmd.Flags().Bool("dry-run", false, "Show what would change without changing anything")
+	importCmd.Flags().String("format", "ndjson", "Input format: ndjson or smd")
+	importCmd.MarkFlagRequired("file")
+
+	rootCmd.AddCommand(exportCmd)
+	rootCmd.AddCommand(importCmd)
+}
+
+// printImportResult lists the records that change or fail, then the totals
+func printImportResult(result *client.ImportResult) {
+	tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "LINE\tSERIAL\tACTION\tUID\tDETAILS")
+	for _, rec := range result.Records {
+		var details string
+		switch rec.Action {
+		case client.ImportUnchanged:
+			continue
+		case client.ImportError:
+			details = rec.Error
+		case client.ImportUpdate:
+			fields := make([]string, 0, len(rec.Changes))
+			for _, change := range rec.Changes {
+				fields = append(fields, fmt.Sprintf("%s: %v -> %v", change.Field, orNone(change.From), orNone(change.To)))
+			}
+			details = strings.Join(fields, "; ")
+		}
+		fmt.Fprintf(tw, "%d\t%s\t%s\t%s\t%s\n", rec.Line, rec.SerialNumber, rec.Action, rec.UID, details)
+	}
+	tw.Flush()
+
+	verb := "Imported"
+	if result.DryRun {
+		verb = "Dry run, would import"
+	}
+	fmt.Printf("%s: %d created, %d updated, %d unchanged, %d failed\n", verb, result.Created, result.Updated, result.Unchanged, result.Failed)
+}
+
+// orNone shows an unset value in a change
+func orNone(v any) any {
+	if v == nil || v == "" {
+		return "(none)"
+	}
+	return v
+}
+
+// importSMD converts an SMD hardware inventory dump into a DiscoverySnapshot
+// and creates it (or, with dryRun, lists the devices it would hold)
+func importSMD(ctx context.Context, c *client.Client, in io.Reader, file string, dryRun bool) error {
+	data, err := io.ReadAll(in)
+	if err != nil {
+		return err
+	}
+	locations, err := smd.ParseDump(data)
+	if err != nil {
+		return err
+	}
+	specs, warnings := smd.DeviceSpecs(locations)
+	for _, warning := range warnings {
+		fmt.Fprintf(os.Stderr, "Warning: %s\n", warning)
+	}
+	if len(specs) == 0 {
+		return fmt.Errorf("no populated locations to import")
+	}
+
+	if dryRun {
+		if output != "table" {
+			return printOutput(specs)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "XNAME\tTYPE\tMANUFACTURER\tSERIAL\tPARENT SERIAL")
+		for _, spec := range specs {
+			var xname string
+			json.Unmarshal(spec.Properties[smd.PropertyXName], &xname)
+			fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\n", xname, spec.DeviceType, spec.Manufacturer, spec.SerialNumber, spec.ParentSerialNumber)
+		}
+		tw.Flush()
+		fmt.Printf("Dry run, would create a DiscoverySnapshot of %d devices\n", len(specs))
+		return nil
+	}
+
+	rawData, err := json.Marshal(specs)
+	if err != nil {
+		return err
+	}
+	name := fmt.Sprintf("smd-import-%d", time.Now().Unix())
+	if file != "-" {
+		name = fmt.Sprintf("smd-import-%s-%d", strings.TrimSuffix(filepath.Base(file), filepath.Ext(file)), time.Now().Unix())
+	}
+	snapshot, err := c.CreateDiscoverySnapshot(ctx, client.CreateDiscoverySnapshotRequest{
+		Name:                  name,
+		DiscoverySnapshotSpec: discoverysnapshot.DiscoverySnapshotSpec{RawData: rawData},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create DiscoverySnapshot: %w", err)
+	}
+	if output != "table" {
+		return printOutput(snapshot)
+	}
+	fmt.Printf("Created DiscoverySnapshot %s with %d devices; the server reconciles it into devices\n", snapshot.GetUID(), len(specs))
+	return nil
+}
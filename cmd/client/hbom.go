@@ -0,0 +1,64 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/spf13/cobra"
+)
+
+var deviceHBOMCmd = &cobra.Command{
+	Use:   "hbom [uid]",
+	Short: "Export a CycloneDX hardware BOM of a Device, or of every Device",
+	Long: `Export a CycloneDX hardware bill of materials (JSON) of a Device and
+everything below it. Without a uid the BOM covers every Device in the inventory.
+
+Examples:
+  client device hbom dev-1a2b3c4d -o node1.cdx.json
+  client device hbom -o cluster.cdx.json
+  client device hbom dev-1a2b3c4d | jq '.components[].name'`,
+	Args: cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		var uid string
+		if len(args) > 0 {
+			uid = args[0]
+		}
+		file, _ := cmd.Flags().GetString("output")
+		cmd.SilenceUsage = true
+		body, err := c.GetDeviceHBOM(ctx, uid)
+		if err != nil {
+			return fmt.Errorf("failed to get HBOM: %w", err)
+		}
+		defer body.Close()
+
+		var out io.Writer = os.Stdout
+		if file != "" && file != "-" {
+			f, err := os.Create(file)
+			if err != nil {
+				return err
+			}
+			defer f.Close()
+			out = f
+		}
+		if _, err := io.Copy(out, body); err != nil {
+			return fmt.Errorf("failed to get HBOM: %w", err)
+		}
+		return nil
+	},
+}
+
+func init() {
+	deviceCmd.AddCommand(deviceHBOMCmd)
+	// Shadows the global output format flag: an HBOM is always CycloneDX JSON
+	deviceHBOMCmd.Flags().StringP("output", "o", "", "Write the HBOM to a file instead of stdout")
+}
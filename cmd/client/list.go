@@ -0,0 +1,32 @@
+package main
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/user/inventory-api/pkg/client"
+)
+
+func init() {
+	for _, cmd := range []*cobra.Command{deviceListCmd, discoverysnapshotListCmd} {
+		cmd.Flags().StringP("selector", "l", "", "Label selector, e.g. 'rack=r12,role in (compute,login)'")
+		cmd.Flags().String("field-selector", "", "Field selector, e.g. spec.deviceType=DIMM,status.phase!=Ready")
+		cmd.Flags().String("sort-by", "", "Sort by uid, name, createdAt or updatedAt")
+		cmd.Flags().Bool("desc", false, "Sort in descending order")
+		cmd.Flags().Int("page-size", 0, "Fetch the list in pages of this size (0: one request)")
+	}
+}
+
+func listOptions(cmd *cobra.Command) client.ListOptions {
+	labelSelector, _ := cmd.Flags().GetString("selector")
+	fieldSelector, _ := cmd.Flags().GetString("field-selector")
+	sortBy, _ := cmd.Flags().GetString("sort-by")
+	descending, _ := cmd.Flags().GetBool("desc")
+	pageSize, _ := cmd.Flags().GetInt("page-size")
+	return client.ListOptions{
+		LabelSelector: labelSelector,
+		FieldSelector: fieldSelector,
+		SortBy:        sortBy,
+		Descending:    descending,
+		Limit:         pageSize,
+	}
+}
@@ -14,6 +14,7 @@
 // Generated commands for each resource:
 //   - client device [list|get|create|update|patch|delete]
 //   - client discoverysnapshot [list|get|create|update|patch|delete]
+//   - client subscription [list|get|create|update|patch|delete]
 //
 // Global flags (available for all commands):
 //
@@ -109,12 +110,16 @@ func init() {
 	rootCmd.PersistentFlags().DurationVar(&timeout, "timeout", 30*time.Second, "request timeout")
 	rootCmd.PersistentFlags().StringVarP(&output, "output", "o", "table", "output format: table, json, yaml")
 	rootCmd.PersistentFlags().StringVarP(&apiVersion, "version", "v", "", "API version to request (e.g., v1, v2beta1)")
+	rootCmd.PersistentFlags().String("token", "", "JWT bearer token for a server that requires authentication (or $INVENTORY_API_TOKEN)")
+	rootCmd.PersistentFlags().String("token-file", "", "File holding the JWT bearer token (or $INVENTORY_API_TOKEN_FILE)")
 
 	// Bind flags to viper
 	viper.BindPFlag("server", rootCmd.PersistentFlags().Lookup("server"))
 	viper.BindPFlag("timeout", rootCmd.PersistentFlags().Lookup("timeout"))
 	viper.BindPFlag("output", rootCmd.PersistentFlags().Lookup("output"))
 	viper.BindPFlag("version", rootCmd.PersistentFlags().Lookup("version"))
+	viper.BindPFlag("token", rootCmd.PersistentFlags().Lookup("token"))
+	viper.BindPFlag("token_file", rootCmd.PersistentFlags().Lookup("token-file"))
 
 	// Environment variable support
 	viper.SetEnvPrefix("INVENTORY_API")
@@ -123,6 +128,7 @@ func init() {
 	// Add resource commands
 	rootCmd.AddCommand(deviceCmd)
 	rootCmd.AddCommand(discoverysnapshotCmd)
+	rootCmd.AddCommand(subscriptionCmd)
 
 }
 
@@ -159,6 +165,14 @@ func getClient() (*client.Client, error) {
 		c = c.WithVersion(version)
 	}
 
+	token, err := client.ResolveToken(viper.GetString("token"), viper.GetString("token_file"))
+	if err != nil {
+		return nil, err
+	}
+	if token != "" {
+		c = c.WithToken(token)
+	}
+
 	return c, nil
 }
 
@@ -228,7 +242,18 @@ var deviceCmd = &cobra.Command{
 
 var deviceListCmd = &cobra.Command{
 	Use:   "list",
-	Short: "List all devices",
+	Short: "List devices",
+	Long: `List devices, optionally filtered by label and field selectors.
+
+Examples:
+  # All DIMMs from Hynix
+  client device list --field-selector spec.deviceType=DIMM,spec.manufacturer=Hynix
+
+  # Everything directly under a node
+  client device list --field-selector spec.parentID=dev-1a2b3c4d
+
+  # By label
+  client device list --selector 'rack=r12,role in (compute,login)'`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		c, err := getClient()
 		if err != nil {
@@ -238,7 +263,7 @@ var deviceListCmd = &cobra.Command{
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
-		items, err := c.GetDevices(ctx)
+		items, err := c.ListDevices(ctx, listOptions(cmd))
 		if err != nil {
 			return fmt.Errorf("failed to list devices: %w", err)
 		}
@@ -249,9 +274,29 @@ var deviceListCmd = &cobra.Command{
 
 var deviceGetCmd = &cobra.Command{
 	Use:   "get [uid]",
-	Short: "Get a Device by UID",
-	Args:  cobra.ExactArgs(1),
+	Short: "Get a Device by UID, serial number, MAC address or Redfish URI",
+	Long: `Get a Device by UID, or look it up by serial number, MAC address or Redfish URI.
+
+Examples:
+  client device get dev-1a2b3c4d
+  client device get --serial SN12345
+  client device get --mac aa:bb:cc:dd:ee:ff
+  client device get --redfish-uri /Systems/1/Memory/DIMM0`,
+	Args: cobra.MaximumNArgs(1),
 	RunE: func(cmd *cobra.Command, args []string) error {
+		serial, _ := cmd.Flags().GetString("serial")
+		mac, _ := cmd.Flags().GetString("mac")
+		redfishURI, _ := cmd.Flags().GetString("redfish-uri")
+		lookups := 0
+		for _, v := range []string{serial, mac, redfishURI} {
+			if v != "" {
+				lookups++
+			}
+		}
+		if lookups+len(args) != 1 {
+			return fmt.Errorf("give exactly one of a UID, --serial, --mac or --redfish-uri")
+		}
+
 		c, err := getClient()
 		if err != nil {
 			return fmt.Errorf("failed to create client: %w", err)
@@ -260,7 +305,17 @@ var deviceGetCmd = &cobra.Command{
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
-		item, err := c.GetDevice(ctx, args[0])
+		var item interface{}
+		switch {
+		case serial != "":
+			item, err = c.GetDeviceBySerial(ctx, serial)
+		case mac != "":
+			item, err = c.GetDeviceByMAC(ctx, mac)
+		case redfishURI != "":
+			item, err = c.GetDevicesByRedfishURI(ctx, redfishURI)
+		default:
+			item, err = c.GetDevice(ctx, args[0])
+		}
 		if err != nil {
 			return fmt.Errorf("failed to get Device: %w", err)
 		}
@@ -550,6 +605,11 @@ func init() {
 	deviceCmd.AddCommand(devicePatchCmd)
 	deviceCmd.AddCommand(deviceDeleteCmd)
 
+	// Lookup flags for get
+	deviceGetCmd.Flags().String("serial", "", "Look up the Device by serial number")
+	deviceGetCmd.Flags().String("mac", "", "Look up the Device by MAC address")
+	deviceGetCmd.Flags().String("redfish-uri", "", "Look up the Devices with this Redfish URI")
+
 	// Add spec flag for create and update commands
 	deviceCreateCmd.Flags().String("spec", "", "Device specification in JSON format")
 	deviceUpdateCmd.Flags().String("spec", "", "Device specification in JSON format")
@@ -572,7 +632,11 @@ var discoverysnapshotCmd = &cobra.Command{
 
 var discoverysnapshotListCmd = &cobra.Command{
 	Use:   "list",
-	Short: "List all discoverysnapshots",
+	Short: "List discoverysnapshots",
+	Long: `List discoverysnapshots, optionally filtered by label and field selectors.
+
+Examples:
+  client discoverysnapshot list --field-selector status.phase=Error`,
 	RunE: func(cmd *cobra.Command, args []string) error {
 		c, err := getClient()
 		if err != nil {
@@ -582,7 +646,7 @@ var discoverysnapshotListCmd = &cobra.Command{
 		ctx, cancel := context.WithTimeout(context.Background(), timeout)
 		defer cancel()
 
-		items, err := c.GetDiscoverySnapshots(ctx)
+		items, err := c.ListDiscoverySnapshots(ctx, listOptions(cmd))
 		if err != nil {
 			return fmt.Errorf("failed to list discoverysnapshots: %w", err)
 		}
@@ -896,3 +960,347 @@ func init() {
 	discoverysnapshotPatchCmd.Flags().StringArray("add", nil, "Add value to array field (field=value)")
 	discoverysnapshotPatchCmd.Flags().StringArray("remove", nil, "Remove value from array field (field=value)")
 }
+
+// Subscription commands
+var subscriptionCmd = &cobra.Command{
+	Use:   "subscription",
+	Short: "Manage subscriptions",
+	Long:  `Create, read, update, patch, and delete subscriptions.`,
+}
+
+var subscriptionListCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List all subscriptions",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		items, err := c.GetSubscriptions(ctx)
+		if err != nil {
+			return fmt.Errorf("failed to list subscriptions: %w", err)
+		}
+
+		return printOutput(items)
+	},
+}
+
+var subscriptionGetCmd = &cobra.Command{
+	Use:   "get [uid]",
+	Short: "Get a Subscription by UID",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		item, err := c.GetSubscription(ctx, args[0])
+		if err != nil {
+			return fmt.Errorf("failed to get Subscription: %w", err)
+		}
+
+		return printOutput(item)
+	},
+}
+
+var subscriptionCreateCmd = &cobra.Command{
+	Use:   "create",
+	Short: "Create a new Subscription",
+	Long: `Create a new Subscription.
+
+Examples:
+  # Create from stdin
+  echo '{"resourceKind": "Device", "eventTypes": ["io.fabrica.device.created", "io.fabrica.device.deleted"], "labelSelector": {"site": "example-value"}, "url": "https://example.com/hooks/inventory", "secret": "example-value", "maxAttempts": 5}' | client subscription create
+
+  # Create with --spec flag
+  client subscription create --spec '{"resourceKind": "Device", "eventTypes": ["io.fabrica.device.created", "io.fabrica.device.deleted"], "labelSelector": {"site": "example-value"}, "url": "https://example.com/hooks/inventory", "secret": "example-value", "maxAttempts": 5}'
+
+Spec fields:
+  resourceKind (string)
+  eventTypes ([]string)
+  labelSelector (map[string]string)
+  url (string) [required]
+  secret (string)
+  maxAttempts (int)
+`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		// Read request from flags or stdin
+		reqJSON, _ := cmd.Flags().GetString("spec")
+		var req client.CreateSubscriptionRequest
+
+		if reqJSON == "" {
+			// Read from stdin if no spec provided
+			decoder := json.NewDecoder(os.Stdin)
+			if err := decoder.Decode(&req); err != nil {
+				return fmt.Errorf("failed to decode request from stdin: %w", err)
+			}
+		} else {
+			// Parse request from JSON string
+			if err := json.Unmarshal([]byte(reqJSON), &req); err != nil {
+				return fmt.Errorf("failed to parse request JSON: %w", err)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		item, err := c.CreateSubscription(ctx, req)
+		if err != nil {
+			return fmt.Errorf("failed to create Subscription: %w", err)
+		}
+
+		return printOutput(item)
+	},
+}
+
+var subscriptionUpdateCmd = &cobra.Command{
+	Use:   "update [uid]",
+	Short: "Update an existing Subscription",
+	Long: `Update an existing Subscription.
+
+Examples:
+  # Update from stdin
+  echo '{"resourceKind": "Device", "eventTypes": ["io.fabrica.device.created", "io.fabrica.device.deleted"], "labelSelector": {"site": "example-value"}, "url": "https://example.com/hooks/inventory", "secret": "example-value", "maxAttempts": 5}' | client subscription update <uid>
+
+  # Update with --spec flag
+  client subscription update <uid> --spec '{"resourceKind": "Device", "eventTypes": ["io.fabrica.device.created", "io.fabrica.device.deleted"], "labelSelector": {"site": "example-value"}, "url": "https://example.com/hooks/inventory", "secret": "example-value", "maxAttempts": 5}'
+
+Spec fields:
+  resourceKind (string)
+  eventTypes ([]string)
+  labelSelector (map[string]string)
+  url (string) [required]
+  secret (string)
+  maxAttempts (int)
+`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		// Read request from flags or stdin
+		reqJSON, _ := cmd.Flags().GetString("spec")
+		var req client.UpdateSubscriptionRequest
+
+		if reqJSON == "" {
+			// Read from stdin if no spec provided
+			decoder := json.NewDecoder(os.Stdin)
+			if err := decoder.Decode(&req); err != nil {
+				return fmt.Errorf("failed to decode request from stdin: %w", err)
+			}
+		} else {
+			// Parse request from JSON string
+			if err := json.Unmarshal([]byte(reqJSON), &req); err != nil {
+				return fmt.Errorf("failed to parse request JSON: %w", err)
+			}
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		item, err := c.UpdateSubscription(ctx, args[0], req)
+		if err != nil {
+			return fmt.Errorf("failed to update Subscription: %w", err)
+		}
+
+		return printOutput(item)
+	},
+}
+
+var subscriptionPatchCmd = &cobra.Command{
+	Use:   "patch [uid]",
+	Short: "Patch a Subscription",
+	Long: `Patch an existing Subscription spec using various patch formats.
+
+IMPORTANT: Only the spec portion of the resource can be patched.
+Metadata (name, labels, annotations) and status are managed by the API.
+
+Examples:
+  # JSON Merge Patch (simple merge) - patch spec fields
+  client subscription patch <uid> --spec '{"manufacturer":"Intel","model":"Updated Model"}'
+
+  # Shorthand patch (dot notation - most convenient)
+  client subscription patch <uid> --set manufacturer=Intel --set model="Updated Model" --unset customField
+
+  # JSON Patch (RFC 6902 - most powerful)
+  client subscription patch <uid> --json-patch '[
+    {"op":"replace","path":"/manufacturer","value":"Intel"},
+    {"op":"add","path":"/properties/newField","value":"newValue"}
+  ]'
+
+  # From stdin (JSON Merge Patch format)
+  echo '{"manufacturer":"AMD","partNumber":"RYZEN-9000"}' | client subscription patch <uid>
+
+Patch Formats:
+  --spec        JSON Merge Patch (RFC 7386) - simple object merge
+  --set/--unset Shorthand patch - dot notation for convenience
+  --json-patch  JSON Patch (RFC 6902) - operation-based patches
+  stdin         JSON Merge Patch format
+
+Shorthand Operations (spec fields only):
+  --set field=value     Set a spec field value (supports dot notation)
+  --unset field         Remove a spec field (supports dot notation)
+  --add field=value     Add to spec array field (field must end with '.-')
+  --remove field=value  Remove from spec array field
+
+Note: All patch operations target the resource spec only.
+Attempts to patch metadata or status fields will be ignored.`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		uid := args[0]
+
+		// Get patch flags
+		specPatch, _ := cmd.Flags().GetString("spec")
+		jsonPatch, _ := cmd.Flags().GetString("json-patch")
+		setPairs, _ := cmd.Flags().GetStringArray("set")
+		unsetFields, _ := cmd.Flags().GetStringArray("unset")
+		addPairs, _ := cmd.Flags().GetStringArray("add")
+		removePairs, _ := cmd.Flags().GetStringArray("remove")
+
+		var patchData []byte
+		var contentType string
+
+		// Determine patch format and build patch data
+		if jsonPatch != "" {
+			// JSON Patch (RFC 6902)
+			patchData = []byte(jsonPatch)
+			contentType = "application/json-patch+json"
+		} else if len(setPairs) > 0 || len(unsetFields) > 0 || len(addPairs) > 0 || len(removePairs) > 0 {
+			// Shorthand patch - convert to JSON Merge Patch
+			patch := make(map[string]interface{})
+
+			// Process --set flags
+			for _, setPair := range setPairs {
+				parts := strings.SplitN(setPair, "=", 2)
+				if len(parts) != 2 {
+					return fmt.Errorf("invalid --set format: %s (expected field=value)", setPair)
+				}
+				setNestedField(patch, parts[0], parts[1])
+			}
+
+			// Process --unset flags
+			for _, field := range unsetFields {
+				setNestedField(patch, field, nil)
+			}
+
+			// Process --add flags (add to arrays)
+			for _, addPair := range addPairs {
+				parts := strings.SplitN(addPair, "=", 2)
+				if len(parts) != 2 {
+					return fmt.Errorf("invalid --add format: %s (expected field=value)", addPair)
+				}
+				// For arrays, we'll use JSON Merge Patch append syntax if possible
+				// Otherwise convert to JSON Patch
+				setNestedField(patch, parts[0], parts[1])
+			}
+
+			// Process --remove flags
+			for _, removePair := range removePairs {
+				parts := strings.SplitN(removePair, "=", 2)
+				if len(parts) != 2 {
+					return fmt.Errorf("invalid --remove format: %s (expected field=value)", removePair)
+				}
+				// Remove operations are complex and might need JSON Patch
+				// For now, we'll handle simple cases
+				return fmt.Errorf("--remove operations require --json-patch format")
+			}
+
+			patchBytes, err := json.Marshal(patch)
+			if err != nil {
+				return fmt.Errorf("failed to marshal shorthand patch: %w", err)
+			}
+			patchData = patchBytes
+			contentType = "application/merge-patch+json"
+		} else if specPatch != "" {
+			// JSON Merge Patch from --spec
+			patchData = []byte(specPatch)
+			contentType = "application/merge-patch+json"
+		} else {
+			// Read from stdin (default to JSON Merge Patch)
+			decoder := json.NewDecoder(os.Stdin)
+			var patch interface{}
+			if err := decoder.Decode(&patch); err != nil {
+				return fmt.Errorf("failed to decode patch from stdin: %w", err)
+			}
+			patchBytes, err := json.Marshal(patch)
+			if err != nil {
+				return fmt.Errorf("failed to marshal patch: %w", err)
+			}
+			patchData = patchBytes
+			contentType = "application/merge-patch+json"
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		item, err := c.PatchSubscription(ctx, uid, patchData, contentType)
+		if err != nil {
+			return fmt.Errorf("failed to patch Subscription: %w", err)
+		}
+
+		return printOutput(item)
+	},
+}
+
+var subscriptionDeleteCmd = &cobra.Command{
+	Use:   "delete [uid]",
+	Short: "Delete a Subscription",
+	Args:  cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		if err := c.DeleteSubscription(ctx, args[0]); err != nil {
+			return fmt.Errorf("failed to delete Subscription: %w", err)
+		}
+
+		fmt.Printf("Subscription %s deleted successfully\n", args[0])
+		return nil
+	},
+}
+
+func init() {
+	subscriptionCmd.AddCommand(subscriptionListCmd)
+	subscriptionCmd.AddCommand(subscriptionGetCmd)
+	subscriptionCmd.AddCommand(subscriptionCreateCmd)
+	subscriptionCmd.AddCommand(subscriptionUpdateCmd)
+	subscriptionCmd.AddCommand(subscriptionPatchCmd)
+	subscriptionCmd.AddCommand(subscriptionDeleteCmd)
+
+	// Add spec flag for create and update commands
+	subscriptionCreateCmd.Flags().String("spec", "", "Subscription specification in JSON format")
+	subscriptionUpdateCmd.Flags().String("spec", "", "Subscription specification in JSON format")
+
+	// Add patch command flags
+	subscriptionPatchCmd.Flags().String("spec", "", "JSON Merge Patch specification")
+	subscriptionPatchCmd.Flags().String("json-patch", "", "JSON Patch operations (RFC 6902)")
+	subscriptionPatchCmd.Flags().StringArray("set", nil, "Set field value using dot notation (field=value)")
+	subscriptionPatchCmd.Flags().StringArray("unset", nil, "Unset field using dot notation")
+	subscriptionPatchCmd.Flags().StringArray("add", nil, "Add value to array field (field=value)")
+	subscriptionPatchCmd.Flags().StringArray("remove", nil, "Remove value from array field (field=value)")
+}
@@ -0,0 +1,66 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/user/inventory-api/pkg/client"
+)
+
+var deviceTreeCmd = &cobra.Command{
+	Use:   "tree [uid]",
+	Short: "Show a Device and everything below it as a tree",
+	Long: `Show a Device and everything below it as a tree, with type, manufacturer and serial number columns.
+
+Examples:
+  client device tree dev-1a2b3c4d
+  client device tree dev-1a2b3c4d --depth 1
+  client device tree dev-1a2b3c4d -o json`,
+	Args: cobra.ExactArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+
+		depth, _ := cmd.Flags().GetInt("depth")
+		tree, err := c.GetDeviceTree(ctx, args[0], depth)
+		if err != nil {
+			return fmt.Errorf("failed to get Device tree: %w", err)
+		}
+
+		if output != "table" {
+			return printOutput(tree)
+		}
+		tw := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(tw, "NAME\tTYPE\tMANUFACTURER\tSERIAL\tUID")
+		printTreeNode(tw, tree, "", "")
+		return tw.Flush()
+	},
+}
+
+func init() {
+	deviceCmd.AddCommand(deviceTreeCmd)
+	deviceTreeCmd.Flags().Int("depth", 0, "Levels below the device to show (0: all)")
+}
+
+// printTreeNode prints a node and its children like tree(1). prefix goes
+// before the node's own branch; childPrefix before its children's.
+func printTreeNode(tw *tabwriter.Writer, node *client.DeviceTreeNode, prefix, childPrefix string) {
+	d := node.Device
+	fmt.Fprintf(tw, "%s%s\t%s\t%s\t%s\t%s\n", prefix, d.GetName(), d.Spec.DeviceType, d.Spec.Manufacturer, d.Spec.SerialNumber, d.GetUID())
+	for i, child := range node.Children {
+		if i == len(node.Children)-1 {
+			printTreeNode(tw, child, childPrefix+"└── ", childPrefix+"    ")
+		} else {
+			printTreeNode(tw, child, childPrefix+"├── ", childPrefix+"│   ")
+		}
+	}
+}
@@ -0,0 +1,132 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/user/inventory-api/pkg/client"
+)
+
+var deviceWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Print device changes as they happen",
+	Long: `Print device changes (ADDED, MODIFIED, DELETED) as they happen, until interrupted.
+
+Examples:
+  # Watch from now on
+  client device watch
+
+  # Print every existing device first, then changes
+  client device watch --initial
+
+  # Resume after the last resourceVersion printed
+  client device watch --resource-version 42 -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		ctx, cancel := watchContext()
+		defer cancel()
+
+		w, err := c.WatchDevices(ctx, watchOptions(cmd))
+		if err != nil {
+			return fmt.Errorf("failed to watch devices: %w", err)
+		}
+		for event := range w.Events() {
+			if err := printWatchEvent(event.Type, event.ResourceVersion, event.Object.GetUID(), event.Object.GetName(), event); err != nil {
+				return err
+			}
+		}
+		return watchResult(ctx, w.Err())
+	},
+}
+
+var discoverysnapshotWatchCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Print discoverysnapshot changes as they happen",
+	Long: `Print discoverysnapshot changes (ADDED, MODIFIED, DELETED) as they happen, until interrupted.
+
+Examples:
+  client discoverysnapshot watch
+  client discoverysnapshot watch --initial -o json`,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		c, err := getClient()
+		if err != nil {
+			return fmt.Errorf("failed to create client: %w", err)
+		}
+		ctx, cancel := watchContext()
+		defer cancel()
+
+		w, err := c.WatchDiscoverySnapshots(ctx, watchOptions(cmd))
+		if err != nil {
+			return fmt.Errorf("failed to watch discoverysnapshots: %w", err)
+		}
+		for event := range w.Events() {
+			if err := printWatchEvent(event.Type, event.ResourceVersion, event.Object.GetUID(), event.Object.GetName(), event); err != nil {
+				return err
+			}
+		}
+		return watchResult(ctx, w.Err())
+	},
+}
+
+func init() {
+	deviceCmd.AddCommand(deviceWatchCmd)
+	discoverysnapshotCmd.AddCommand(discoverysnapshotWatchCmd)
+
+	for _, cmd := range []*cobra.Command{deviceWatchCmd, discoverysnapshotWatchCmd} {
+		cmd.Flags().String("resource-version", "", "Resume after this watch resourceVersion")
+		cmd.Flags().String("event-id", "", "Resume after this CloudEvent ID")
+		cmd.Flags().Bool("initial", false, "Print every existing resource (as ADDED) before changes")
+	}
+}
+
+// watchContext is cancelled on Ctrl-C; watches ignore the --timeout flag
+func watchContext() (context.Context, context.CancelFunc) {
+	return signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+}
+
+func watchOptions(cmd *cobra.Command) client.WatchOptions {
+	resourceVersion, _ := cmd.Flags().GetString("resource-version")
+	eventID, _ := cmd.Flags().GetString("event-id")
+	initial, _ := cmd.Flags().GetBool("initial")
+	return client.WatchOptions{
+		ResourceVersion:   resourceVersion,
+		EventID:           eventID,
+		SendInitialEvents: initial,
+	}
+}
+
+// printWatchEvent prints one event: a line per event for table output, or the full event as a JSON line
+func printWatchEvent(eventType client.WatchEventType, resourceVersion, uid, name string, event interface{}) error {
+	switch output {
+	case "json":
+		return json.NewEncoder(os.Stdout).Encode(event)
+	case "table":
+		if eventType == client.WatchBookmark {
+			return nil
+		}
+		fmt.Printf("%-9s %-8s %-16s %s\n", eventType, resourceVersion, uid, name)
+		return nil
+	default:
+		return fmt.Errorf("unknown output format: %s", output)
+	}
+}
+
+// watchResult turns how a watch ended into the command's result
+func watchResult(ctx context.Context, err error) error {
+	if ctx.Err() != nil {
+		return nil // interrupted
+	}
+	if err != nil {
+		return fmt.Errorf("watch ended: %w", err)
+	}
+	return nil
+}
@@ -6,6 +6,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/user/inventory-api/pkg/client"
 	"github.com/user/inventory-api/pkg/collector"
 )
 
@@ -15,12 +16,19 @@ var rootCmd = &cobra.Command{
 	Run:   executeGatherAndPost,
 }
 
-var bmcIP string
+var (
+	bmcIP     string
+	token     string
+	tokenFile string
+)
 
 func init() {
 	// Define the --ip flag for the BMC IP
 	rootCmd.Flags().StringVarP(&bmcIP, "ip", "i", "", "The IP address of the BMC to gather inventory from (required)")
 	rootCmd.MarkFlagRequired("ip")
+	// The inventory API may require a JWT; the token can also come from the environment
+	rootCmd.Flags().StringVar(&token, "token", "", "JWT bearer token for the inventory API (or $INVENTORY_API_TOKEN)")
+	rootCmd.Flags().StringVar(&tokenFile, "token-file", "", "File holding the JWT bearer token (or $INVENTORY_API_TOKEN_FILE)")
 }
 
 func main() {
@@ -34,8 +42,14 @@ func main() {
 func executeGatherAndPost(cmd *cobra.Command, args []string) {
 	fmt.Printf("Starting inventory collection for BMC IP: %s\n", bmcIP)
 
+	apiToken, err := client.ResolveToken(token, tokenFile)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Collection Failed: %v\n", err)
+		os.Exit(1)
+	}
+
 	// Pass the IP captured by cobra to the new collector package
-	err := collector.CollectAndPost(bmcIP)
+	err = collector.CollectAndPost(bmcIP, apiToken)
 	if err != nil {
 		fmt.Fprintf(os.Stderr, "Collection Failed: %v\n", err)
 		os.Exit(1)
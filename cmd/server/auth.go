@@ -0,0 +1,55 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/user/inventory-api/internal/auth"
+)
+
+// publicPaths are served without a token, for probes and API discovery
+var publicPaths = []string{"/health", "/openapi.json", "/docs"}
+
+// newAuthenticator returns the JWT bearer authenticator for the configured
+// JWKS. The issuer and audience are required: without them, a token the
+// same identity provider issued for any other service would be accepted.
+func newAuthenticator(ctx context.Context, config *Config) (*auth.Authenticator, error) {
+	if config.AuthIssuer == "" || config.AuthAudience == "" {
+		if !config.AuthAnyIssuerAudience {
+			return nil, fmt.Errorf("--auth-issuer and --auth-audience are required with --auth-jwks-file or --auth-jwks-url (--auth-any-issuer-audience accepts any token signed by the JWKS)")
+		}
+		log.Println("Warning: without --auth-issuer and --auth-audience, any unexpired token signed by a key in the JWKS is accepted.")
+	}
+	return auth.New(ctx, auth.Config{
+		JWKSFile:        config.AuthJWKSFile,
+		JWKSURL:         config.AuthJWKSURL,
+		RefreshInterval: time.Duration(config.AuthJWKSRefresh) * time.Second,
+		Issuer:          config.AuthIssuer,
+		Audience:        config.AuthAudience,
+		Leeway:          time.Minute,
+		PublicPaths:     publicPaths,
+	}, log.Printf)
+}
+
+// registerSecurityScheme documents bearer authentication, which applies
+// only when the server runs with a JWKS
+func registerSecurityScheme(spec *openapi3.T) {
+	spec.Components.SecuritySchemes = openapi3.SecuritySchemes{
+		"bearerAuth": &openapi3.SecuritySchemeRef{Value: openapi3.NewJWTSecurityScheme().
+			WithDescription("JWT bearer token, required when the server runs with --auth-jwks-file or --auth-jwks-url")},
+	}
+	spec.Security = openapi3.SecurityRequirements{
+		openapi3.NewSecurityRequirement().Authenticate("bearerAuth"),
+	}
+	for _, item := range spec.Paths.Map() {
+		for _, op := range item.Operations() {
+			if op.Responses != nil && op.Responses.Value("401") == nil {
+				op.Responses.Set("401", errorResponse())
+			}
+		}
+	}
+}
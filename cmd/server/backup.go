@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/spf13/cobra"
+
+	internal_storage "github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/client"
+)
+
+// BackupHandler handles POST /admin/backup: it returns a tar.gz archive of
+// every resource as of one point in time (see internal_storage.WriteBackup).
+// Subscription secrets are left out unless ?includeSecrets=true. The route
+// is only served with --admin-backup.
+func BackupHandler(w http.ResponseWriter, r *http.Request) {
+	var opts internal_storage.BackupOptions
+	if value := r.URL.Query().Get("includeSecrets"); value != "" {
+		include, err := strconv.ParseBool(value)
+		if err != nil {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("invalid includeSecrets %q", value))
+			return
+		}
+		opts.IncludeSecrets = include
+	}
+
+	var archive bytes.Buffer
+	manifest, err := internal_storage.WriteBackup(r.Context(), internal_storage.Backend, &archive, opts)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to create backup: %w", err))
+		return
+	}
+
+	name := fmt.Sprintf("inventory-backup-%s.tar.gz", manifest.CreatedAt.Format("20060102T150405Z"))
+	w.Header().Set("Content-Type", "application/gzip")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", name))
+	w.Header().Set("Content-Length", strconv.Itoa(archive.Len()))
+	w.WriteHeader(http.StatusOK)
+	w.Write(archive.Bytes())
+}
+
+// registerAdminPaths documents the admin endpoints
+func registerAdminPaths(spec *openapi3.T) {
+	backup := openapi3.NewOperation()
+	backup.OperationID = "createBackup"
+	backup.Summary = "Back up every resource"
+	backup.Description = "Returns a tar.gz archive of all resources as of one point in time: manifest.json (counts and SHA-256 checksums) followed by <kind>s/<uid>.json files. Load it with inventory-api restore. Subscription secrets are left out unless includeSecrets is true. Only served when the server runs with --admin-backup; with authentication on, the token needs the admin role."
+	backup.Tags = []string{"Admin"}
+	backup.AddParameter(openapi3.NewQueryParameter("includeSecrets").
+		WithDescription("Keep each Subscription's signing secret in the archive").
+		WithSchema(openapi3.NewBoolSchema()))
+	backup.Responses = openapi3.NewResponses()
+	backup.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Backup archive").
+			WithContent(openapi3.Content{
+				"application/gzip": openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema().WithFormat("binary")),
+			}),
+	})
+	backup.Responses.Set("400", errorResponse())
+	backup.Responses.Set("403", errorResponse())
+	backup.Responses.Set("500", errorResponse())
+
+	spec.Paths.Set("/admin/backup", &openapi3.PathItem{Post: backup})
+}
+
+var backupCmd = &cobra.Command{
+	Use:   "backup",
+	Short: "Write a point-in-time backup archive of all resources",
+	Long: `Write a tar.gz archive of every Device, DiscoverySnapshot and Subscription
+as of one point in time, with a manifest of counts and checksums.
+
+By default the backup is taken from the running server (POST /admin/backup),
+so the server doesn't need to be stopped. With --backend the storage is read
+directly instead, which is only consistent while the server is stopped.`,
+	Example: `  # Back up the local server
+  inventory-api backup -o inventory.tar.gz
+
+  # Back up a stopped server's data directory
+  inventory-api backup --backend file:./data -o inventory.tar.gz`,
+	Args: cobra.NoArgs,
+	RunE: runBackup,
+}
+
+var restoreCmd = &cobra.Command{
+	Use:   "restore <archive>",
+	Short: "Load a backup archive into an empty data directory or backend",
+	Long: `Load a backup archive written by "inventory-api backup" into a storage
+backend that holds no resources. Every file is checked against the manifest
+before anything is written, and resources are restored exactly as backed
+up, keeping their UIDs and resourceVersions.`,
+	Example: `  # Restore into the configured storage
+  inventory-api restore inventory.tar.gz
+
+  # Restore into a new SQLite database
+  inventory-api restore inventory.tar.gz --backend sqlite:./restored/inventory.db`,
+	Args: cobra.ExactArgs(1),
+	RunE: runRestore,
+}
+
+func init() {
+	backupCmd.Flags().StringP("output", "o", "", "Archive to write (default inventory-backup-<time>.tar.gz)")
+	backupCmd.Flags().String("server", "", "Server to back up (default http://localhost:<port>)")
+	backupCmd.Flags().String("token", "", "JWT bearer token for a server that requires authentication (or $INVENTORY_API_TOKEN)")
+	backupCmd.Flags().String("token-file", "", "File holding the JWT bearer token (or $INVENTORY_API_TOKEN_FILE)")
+	backupCmd.Flags().String("backend", "", "Read this backend directly instead of asking the server (file:<data-dir> or sqlite:<dsn>)")
+	backupCmd.Flags().Bool("include-secrets", false, "Keep Subscription signing secrets in the archive")
+	restoreCmd.Flags().String("backend", "", "Backend to restore into (file:<data-dir> or sqlite:<dsn>; default the configured storage)")
+	rootCmd.AddCommand(backupCmd)
+	rootCmd.AddCommand(restoreCmd)
+}
+
+func runBackup(cmd *cobra.Command, args []string) error {
+	output, _ := cmd.Flags().GetString("output")
+	server, _ := cmd.Flags().GetString("server")
+	spec, _ := cmd.Flags().GetString("backend")
+	tokenFlag, _ := cmd.Flags().GetString("token")
+	tokenFile, _ := cmd.Flags().GetString("token-file")
+	includeSecrets, _ := cmd.Flags().GetBool("include-secrets")
+	if output == "" {
+		output = fmt.Sprintf("inventory-backup-%s.tar.gz", time.Now().UTC().Format("20060102T150405Z"))
+	}
+	if server == "" {
+		server = fmt.Sprintf("http://localhost:%d", config.Port)
+	}
+
+	token, err := client.ResolveToken(tokenFlag, tokenFile)
+	if err != nil {
+		return err
+	}
+	cmd.SilenceUsage = true
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var archive bytes.Buffer
+	if spec != "" {
+		backend, err := internal_storage.OpenBackend(ctx, spec)
+		if err != nil {
+			return fmt.Errorf("failed to open storage: %w", err)
+		}
+		defer backend.Close()
+		if _, err := internal_storage.WriteBackup(ctx, backend, &archive, internal_storage.BackupOptions{IncludeSecrets: includeSecrets}); err != nil {
+			return err
+		}
+	} else if err := downloadBackup(ctx, server, token, includeSecrets, &archive); err != nil {
+		return err
+	}
+
+	// Check the archive before keeping it
+	manifest, _, err := internal_storage.ReadBackup(bytes.NewReader(archive.Bytes()))
+	if err != nil {
+		return fmt.Errorf("backup is not valid: %w", err)
+	}
+	if err := writeFileAtomic(output, archive.Bytes()); err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Wrote %s (%d bytes, resourceVersion %d)\n", output, archive.Len(), manifest.ResourceVersion)
+	printBackupCounts(out, manifest)
+	if manifest.SecretsRedacted {
+		fmt.Fprintln(out, "  Subscription secrets left out (use --include-secrets to keep them)")
+	}
+	for _, skipped := range manifest.Skipped {
+		fmt.Fprintf(out, "  skipped %s\n", skipped)
+	}
+	if len(manifest.Skipped) > 0 {
+		return fmt.Errorf("%d unreadable resources are not in the backup (see inventory-api fsck)", len(manifest.Skipped))
+	}
+	return nil
+}
+
+func runRestore(cmd *cobra.Command, args []string) error {
+	spec, _ := cmd.Flags().GetString("backend")
+	if spec == "" {
+		spec = storageSpec(config)
+	}
+
+	cmd.SilenceUsage = true
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	f, err := os.Open(args[0])
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	unlock, err := internal_storage.LockWriter(spec)
+	if err != nil {
+		return fmt.Errorf("failed to lock storage (stop the server before restoring): %w", err)
+	}
+	defer unlock()
+	backend, err := internal_storage.OpenBackend(ctx, spec)
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer backend.Close()
+
+	manifest, err := internal_storage.Restore(ctx, backend, f)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Restored backup of %s into %s\n", manifest.CreatedAt.Format(time.RFC3339), spec)
+	printBackupCounts(out, manifest)
+	if manifest.SecretsRedacted {
+		fmt.Fprintln(out, "  Subscription secrets were not backed up; webhook deliveries are unsigned until they are set again")
+	}
+	return nil
+}
+
+// downloadBackup asks the server at baseURL for a backup archive,
+// authenticating with token when it isn't empty
+func downloadBackup(ctx context.Context, baseURL, token string, includeSecrets bool, w io.Writer) error {
+	endpoint := strings.TrimRight(baseURL, "/") + "/admin/backup"
+	if includeSecrets {
+		endpoint += "?includeSecrets=true"
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, endpoint, nil)
+	if err != nil {
+		return err
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to reach server: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound || resp.StatusCode == http.StatusMethodNotAllowed {
+		return fmt.Errorf("server returned %s: start it with --admin-backup to allow backups", resp.Status)
+	}
+	if resp.StatusCode != http.StatusOK {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return fmt.Errorf("server returned %s: %s", resp.Status, strings.TrimSpace(string(body)))
+	}
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		return fmt.Errorf("failed to download backup: %w", err)
+	}
+	return nil
+}
+
+// printBackupCounts lists the number of resources of each kind in a backup
+func printBackupCounts(out io.Writer, manifest *internal_storage.BackupManifest) {
+	for _, kind := range internal_storage.ResourceKinds {
+		fmt.Fprintf(out, "  %-18s %d\n", kind, manifest.Counts[kind])
+	}
+}
+
+// writeFileAtomic writes data to a temporary file next to path and renames
+// it into place, so path is never left half written
+func writeFileAtomic(path string, data []byte) error {
+	f, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".*")
+	if err != nil {
+		return err
+	}
+	tmp := f.Name()
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Chmod(tmp, 0644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
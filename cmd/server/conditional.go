@@ -0,0 +1,82 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/openchami/fabrica/pkg/resource"
+	fabrica_storage "github.com/openchami/fabrica/pkg/storage"
+
+	apimw "github.com/user/inventory-api/internal/middleware"
+	"github.com/user/inventory-api/internal/storage"
+)
+
+// checkIfMatch validates the request's If-Match header against the current ETag of res.
+// On a mismatch it writes 412 Precondition Failed and returns false.
+func checkIfMatch(w http.ResponseWriter, r *http.Request, res interface{}) bool {
+	etag, err := apimw.GenerateETag(res)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to generate ETag: %w", err))
+		return false
+	}
+	return apimw.CheckIfMatch(w, r, etag)
+}
+
+// checkIfNoneMatch sets the ETag of res on the response and validates If-None-Match.
+// When the client's copy is current it writes 304 Not Modified and returns false.
+func checkIfNoneMatch(w http.ResponseWriter, r *http.Request, res interface{}) bool {
+	etag, err := apimw.GenerateETag(res)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to generate ETag: %w", err))
+		return false
+	}
+	apimw.SetETag(w, etag)
+	return apimw.CheckIfNoneMatch(w, r, etag)
+}
+
+// setETag sets the ETag of res on the response, ignoring hashing errors.
+func setETag(w http.ResponseWriter, res interface{}) {
+	if etag, err := apimw.GenerateETag(res); err == nil {
+		apimw.SetETag(w, etag)
+	}
+}
+
+// checkVersionAnnotation takes the resourceVersion annotation out of the
+// annotations of a create or update request: only storage sets it. On an
+// update (current is the stored resource's metadata) a client may send back
+// the version it read, and a different one is a 409 Conflict; on a create
+// (current is nil) it is a 400 Bad Request. On an error it writes the
+// response and returns false.
+func checkVersionAnnotation(w http.ResponseWriter, annotations map[string]string, current *resource.Metadata) bool {
+	version, ok := annotations[storage.ResourceVersionAnnotation]
+	if !ok {
+		return true
+	}
+	delete(annotations, storage.ResourceVersionAnnotation)
+	if current == nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("annotation %s is set by the server", storage.ResourceVersionAnnotation))
+		return false
+	}
+	if stored := storage.ResourceVersionOf(current); version != stored {
+		respondError(w, http.StatusConflict, fmt.Errorf("%s is at resourceVersion %s, not %s: %w", current.UID, stored, version, storage.ErrConflict))
+		return false
+	}
+	return true
+}
+
+// writeErrorStatus maps a storage write error to an HTTP status code.
+// A resourceVersion conflict (a concurrent writer won) is 409 Conflict; a
+// store refusing writes until its journal is replayed is 503 Service Unavailable.
+func writeErrorStatus(err error) int {
+	switch {
+	case errors.Is(err, storage.ErrConflict):
+		return http.StatusConflict
+	case errors.Is(err, storage.ErrJournalPending):
+		return http.StatusServiceUnavailable
+	case errors.Is(err, fabrica_storage.ErrNotFound):
+		return http.StatusNotFound
+	default:
+		return http.StatusInternalServerError
+	}
+}
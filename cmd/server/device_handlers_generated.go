@@ -39,6 +39,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -56,12 +57,36 @@ func GetDevices(w http.ResponseWriter, r *http.Request) {
 	// Authorization: Add custom middleware in routes.go or implement checks here
 	// Example: if !authorized(r) { respondError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized")); return }
 
-	devices, err := storage.LoadAllDevices(r.Context())
+	// Stream changes instead of listing (?watch=true)
+	if isWatchRequest(r) {
+		serveWatch(w, r, "Device")
+		return
+	}
+
+	// Label and field selectors (?labelSelector=...&fieldSelector=...)
+	filter, err := parseListFilter(r, isDeviceField)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	// Sorting and pagination (?sortBy=...&order=...&limit=...&continue=...)
+	page, err := parseListPage(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	items, total, next, err := listDevices(r.Context(), filter, page)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load devices: %w", err))
 		return
 	}
-	respondJSON(w, http.StatusOK, devices)
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	if !page.paginated() {
+		respondJSON(w, http.StatusOK, items)
+		return
+	}
+	respondJSON(w, http.StatusOK, DevicesResponse{Items: items, Continue: next, Total: total})
 }
 
 // GetDevice returns a specific Device resource by UID
@@ -85,6 +110,9 @@ func GetDevice(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusNotFound, fmt.Errorf("Device not found: %w", err))
 		return
 	}
+	if !checkIfNoneMatch(w, r, device) {
+		return
+	}
 	respondJSON(w, http.StatusOK, device)
 }
 
@@ -95,6 +123,9 @@ func CreateDevice(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
 		return
 	}
+	if !checkVersionAnnotation(w, req.Annotations, nil) {
+		return
+	}
 
 	// Get version context from request
 	versionCtx := versioning.GetVersionContext(r.Context())
@@ -139,8 +170,8 @@ func CreateDevice(w http.ResponseWriter, r *http.Request) {
 	// Set initial status
 
 	// Save (Layer 1: Ent validation happens automatically if using Ent storage)
-	if err := storage.SaveDevice(r.Context(), device); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to save Device: %w", err))
+	if err := storage.SaveDeviceVersioned(r.Context(), device); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save Device: %w", err))
 		return
 	}
 
@@ -150,6 +181,7 @@ func CreateDevice(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Warning: Failed to publish resource created event for Device %s: %v\n", device.GetUID(), err)
 	}
 
+	setETag(w, device)
 	respondJSON(w, http.StatusCreated, device)
 }
 
@@ -168,11 +200,19 @@ func UpdateDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, device) {
+		return
+	}
+
 	var req UpdateDeviceRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
 		return
 	}
+	if !checkVersionAnnotation(w, req.Annotations, &device.Metadata) {
+		return
+	}
 
 	// Apply updates
 	if req.Name != "" {
@@ -192,8 +232,8 @@ func UpdateDevice(w http.ResponseWriter, r *http.Request) {
 
 	device.Touch()
 
-	if err := storage.SaveDevice(r.Context(), device); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to save Device: %w", err))
+	if err := storage.SaveDeviceVersioned(r.Context(), device); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save Device: %w", err))
 		return
 	}
 
@@ -206,6 +246,7 @@ func UpdateDevice(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Warning: Failed to publish resource updated event for Device %s: %v\n", device.GetUID(), err)
 	}
 
+	setETag(w, device)
 	respondJSON(w, http.StatusOK, device)
 }
 
@@ -224,6 +265,11 @@ func PatchDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, device) {
+		return
+	}
+
 	// Read patch document
 	patchData, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -262,8 +308,8 @@ func PatchDevice(w http.ResponseWriter, r *http.Request) {
 	device.Touch()
 
 	// Save the patched resource
-	if err := storage.SaveDevice(r.Context(), device); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to save patched Device: %w", err))
+	if err := storage.SaveDeviceVersioned(r.Context(), device); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save patched Device: %w", err))
 		return
 	}
 
@@ -277,6 +323,7 @@ func PatchDevice(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Warning: Failed to publish resource patched event for Device %s: %v\n", device.GetUID(), err)
 	}
 
+	setETag(w, device)
 	respondJSON(w, http.StatusOK, device)
 }
 
@@ -302,6 +349,11 @@ func UpdateDeviceStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, res) {
+		return
+	}
+
 	var statusUpdate device.DeviceStatus
 	if err := json.NewDecoder(r.Body).Decode(&statusUpdate); err != nil {
 		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid status body: %w", err))
@@ -311,8 +363,8 @@ func UpdateDeviceStatus(w http.ResponseWriter, r *http.Request) {
 	// Preserve spec - only update status
 	res.Touch()
 
-	if err := storage.SaveDevice(r.Context(), res); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to save Device status: %w", err))
+	if err := storage.SaveDeviceVersioned(r.Context(), res); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save Device status: %w", err))
 		return
 	}
 
@@ -326,6 +378,7 @@ func UpdateDeviceStatus(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Warning: Failed to publish status update event for Device %s: %v\n", res.GetUID(), err)
 	}
 
+	setETag(w, res)
 	respondJSON(w, http.StatusOK, res)
 }
 
@@ -348,6 +401,11 @@ func PatchDeviceStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, res) {
+		return
+	}
+
 	patchData, err := io.ReadAll(r.Body)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, fmt.Errorf("failed to read patch data: %w", err))
@@ -381,8 +439,8 @@ func PatchDeviceStatus(w http.ResponseWriter, r *http.Request) {
 
 	res.Touch()
 
-	if err := storage.SaveDevice(r.Context(), res); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to save patched Device status: %w", err))
+	if err := storage.SaveDeviceVersioned(r.Context(), res); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save patched Device status: %w", err))
 		return
 	}
 
@@ -396,6 +454,7 @@ func PatchDeviceStatus(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Warning: Failed to publish status patch event for Device %s: %v\n", res.GetUID(), err)
 	}
 
+	setETag(w, res)
 	respondJSON(w, http.StatusOK, res)
 }
 
@@ -414,14 +473,22 @@ func DeleteDevice(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := storage.DeleteDevice(r.Context(), uid); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to delete Device: %w", err))
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, device) {
+		return
+	}
+
+	// Delete only the version we checked, so a concurrent write isn't silently lost
+	if err := storage.DeleteIfVersion(r.Context(), "Device", uid, storage.ResourceVersionOf(&device.Metadata)); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to delete Device: %w", err))
 		return
 	}
 
 	// Publish resource deleted event
+	// Labels let webhook subscriptions apply their label selector to deletes
 	deleteMetadata := map[string]interface{}{
 		"deletedAt": time.Now(),
+		"labels":    device.Metadata.Labels,
 	}
 	if err := events.PublishResourceDeleted(r.Context(), "Device", device.GetUID(), device.GetName(), deleteMetadata); err != nil {
 		// Log the error but don't fail the request - events are non-critical
@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"slices"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// DHCP configuration formats
+const (
+	dhcpDnsmasq = "dnsmasq"
+	dhcpISC     = "isc"
+	dhcpKea     = "kea"
+)
+
+var (
+	// defaultDHCPTypes are the Device types reservations are made for by default
+	defaultDHCPTypes = []string{"Node"}
+
+	// bmcTypes are Device types whose MAC addresses belong to a BMC, not the
+	// host they're below
+	bmcTypes = []string{"BMC", "NodeBMC"}
+
+	// dhcpHostnameKeys and dhcpAddressKeys are the labels, annotations and
+	// properties a host's name and IPv4 address are taken from, in order
+	dhcpHostnameKeys = []string{"hostname"}
+	dhcpAddressKeys  = []string{"ip", "ip_address", "ipv4_address"}
+)
+
+// dhcpReservation is a fixed address (or just a name) for MAC addresses
+type dhcpReservation struct {
+	Hostname string
+	IP       string
+	MACs     []string
+}
+
+// kea reservation, as in a Dhcp4 "reservations" list
+type keaReservation struct {
+	HWAddress string `json:"hw-address"`
+	IPAddress string `json:"ip-address,omitempty"`
+	Hostname  string `json:"hostname,omitempty"`
+}
+
+// GetDHCPReservations handles GET /integrations/dhcp: DHCP host
+// reservations for the MAC addresses of the selected Devices, rendered for
+// dnsmasq, ISC dhcpd or Kea.
+//
+// A host's MAC addresses are those of its MAC-valued properties (except
+// ones namespaced under bmc, which are its BMC's) and of the Devices below
+// it (e.g. NICs) that aren't BMCs. Its hostname and IPv4 address come from
+// its labels, annotations or properties (hostname; ip or ip_address), or
+// for a NIC with its own ip, from the NIC. A host whose hostname isn't a
+// valid RFC 1123 hostname is left out, with a warning in the server log.
+func GetDHCPReservations(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = dhcpDnsmasq
+	}
+	if format != dhcpDnsmasq && format != dhcpISC && format != dhcpKea {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid format %q: must be dnsmasq, isc or kea", format))
+		return
+	}
+	hosts, ok := integrationDevices(w, r, defaultDHCPTypes)
+	if !ok {
+		return
+	}
+	all, err := storage.LoadAllDevices(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load devices: %w", err))
+		return
+	}
+
+	reservations, skipped := dhcpReservations(hosts, all)
+	for _, reason := range skipped {
+		log.Printf("Warning: DHCP reservations: %s", reason)
+	}
+	var body []byte
+	switch format {
+	case dhcpDnsmasq:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		body = renderDnsmasq(reservations)
+	case dhcpISC:
+		w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+		body = renderISC(reservations)
+	case dhcpKea:
+		w.Header().Set("Content-Type", "application/json")
+		body = renderKea(reservations)
+	}
+	w.WriteHeader(http.StatusOK)
+	w.Write(body)
+}
+
+// dhcpReservations returns the reservations of hosts, in the order of
+// hosts. A MAC address already reserved for an earlier host is skipped.
+//
+// Hostnames are written into DHCP server configuration, so they must be
+// valid hostnames (see validHostname). A host with an invalid hostname
+// attribute is left out, and the reason returned in skipped; one named
+// after a Device name that isn't a valid hostname is reserved without a
+// hostname.
+func dhcpReservations(hosts, all []*device.Device) (reservations []dhcpReservation, skipped []string) {
+	children := make(map[string][]*device.Device)
+	for _, dev := range all {
+		if dev.Spec.ParentID != "" {
+			children[dev.Spec.ParentID] = append(children[dev.Spec.ParentID], dev)
+		}
+	}
+	isHost := make(map[string]bool, len(hosts))
+	for _, host := range hosts {
+		isHost[host.GetUID()] = true
+	}
+
+	reserved := make(map[string]bool)
+	for _, host := range hosts {
+		hostname := deviceAttribute(host, dhcpHostnameKeys)
+		if hostname != "" && !validHostname(hostname) {
+			skipped = append(skipped, fmt.Sprintf("left out Device %s: hostname %q is not a valid hostname", host.GetUID(), hostname))
+			continue
+		}
+		if hostname == "" && validHostname(host.GetName()) {
+			hostname = host.GetName()
+		}
+		hostIP := deviceIPv4(host)
+
+		// MAC addresses by the IP they get: the host's, or a NIC's own
+		macsByIP := make(map[string][]string)
+		var ips []string
+		add := func(ip string, macs []string) {
+			for _, mac := range macs {
+				if reserved[mac] {
+					continue
+				}
+				reserved[mac] = true
+				if _, ok := macsByIP[ip]; !ok {
+					ips = append(ips, ip)
+				}
+				macsByIP[ip] = append(macsByIP[ip], mac)
+			}
+		}
+		add(hostIP, hostMACs(host))
+
+		visited := map[string]bool{host.GetUID(): true}
+		queue := slices.Clone(children[host.GetUID()])
+		for len(queue) > 0 {
+			dev := queue[0]
+			queue = queue[1:]
+			if visited[dev.GetUID()] || isHost[dev.GetUID()] || slices.Contains(bmcTypes, dev.Spec.DeviceType) {
+				continue
+			}
+			visited[dev.GetUID()] = true
+			ip := deviceIPv4(dev)
+			if ip == "" {
+				ip = hostIP
+			}
+			add(ip, hostMACs(dev))
+			queue = append(queue, children[dev.GetUID()]...)
+		}
+
+		for _, ip := range ips {
+			reservations = append(reservations, dhcpReservation{Hostname: hostname, IP: ip, MACs: macsByIP[ip]})
+		}
+	}
+	return reservations, skipped
+}
+
+// hostMACs returns the MAC addresses of a Device's MAC-valued properties,
+// leaving out those namespaced under bmc (e.g. bmc.mac_address)
+func hostMACs(dev *device.Device) []string {
+	props := make(map[string]json.RawMessage, len(dev.Spec.Properties))
+	for key, raw := range dev.Spec.Properties {
+		namespace := strings.ToLower(key[:max(strings.LastIndex(key, "."), 0)])
+		if !slices.Contains(strings.FieldsFunc(namespace, func(r rune) bool { return r == '.' || r == '_' }), "bmc") {
+			props[key] = raw
+		}
+	}
+	return storage.PropertyMACs(props)
+}
+
+// deviceAttribute returns the first of keys set as a label, annotation or
+// string property of a Device, looking at labels first, or ""
+func deviceAttribute(dev *device.Device, keys []string) string {
+	for _, key := range keys {
+		if value := dev.Metadata.Labels[key]; value != "" {
+			return value
+		}
+		if value := dev.Metadata.Annotations[key]; value != "" {
+			return value
+		}
+		if value := stringProperty(dev, key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// deviceIPv4 returns a Device's IPv4 address, or "" if it has none (or it
+// isn't a valid IPv4 address)
+func deviceIPv4(dev *device.Device) string {
+	ip := net.ParseIP(deviceAttribute(dev, dhcpAddressKeys))
+	if ip == nil || ip.To4() == nil {
+		return ""
+	}
+	return ip.To4().String()
+}
+
+const dhcpHeader = "Generated by inventory-api from the device inventory; changes will be overwritten"
+
+// renderDnsmasq renders reservations as dnsmasq dhcp-host lines
+func renderDnsmasq(reservations []dhcpReservation) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n", dhcpHeader)
+	for _, res := range reservations {
+		fields := slices.Clone(res.MACs)
+		if res.IP != "" {
+			fields = append(fields, res.IP)
+		}
+		if res.Hostname != "" {
+			fields = append(fields, res.Hostname)
+		}
+		fmt.Fprintf(&b, "dhcp-host=%s\n", strings.Join(fields, ","))
+	}
+	return b.Bytes()
+}
+
+// renderISC renders reservations as ISC dhcpd host declarations, one per
+// MAC address
+func renderISC(reservations []dhcpReservation) []byte {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "# %s\n", dhcpHeader)
+	names := make(map[string]int)
+	for _, res := range reservations {
+		for _, mac := range res.MACs {
+			base := res.Hostname
+			if base == "" {
+				base = strings.ReplaceAll(mac, ":", "")
+			}
+			name := base
+			if n := names[base]; n > 0 {
+				name = fmt.Sprintf("%s-%d", base, n)
+			}
+			names[base]++
+
+			fmt.Fprintf(&b, "\nhost %s {\n", name)
+			fmt.Fprintf(&b, "  hardware ethernet %s;\n", mac)
+			if res.IP != "" {
+				fmt.Fprintf(&b, "  fixed-address %s;\n", res.IP)
+			}
+			if res.Hostname != "" {
+				fmt.Fprintf(&b, "  option host-name %q;\n", res.Hostname)
+			}
+			b.WriteString("}\n")
+		}
+	}
+	return b.Bytes()
+}
+
+// renderKea renders reservations as a Kea Dhcp4 "reservations" list, one
+// per MAC address. Kea reserves an address only once, so a host's other MAC
+// addresses get just its hostname.
+func renderKea(reservations []dhcpReservation) []byte {
+	list := []keaReservation{}
+	usedIPs := make(map[string]bool)
+	for _, res := range reservations {
+		for _, mac := range res.MACs {
+			entry := keaReservation{HWAddress: mac, Hostname: res.Hostname}
+			if res.IP != "" && !usedIPs[res.IP] {
+				entry.IPAddress = res.IP
+				usedIPs[res.IP] = true
+			}
+			list = append(list, entry)
+		}
+	}
+	body, _ := json.MarshalIndent(list, "", "  ")
+	return append(body, '\n')
+}
+
+// registerDHCPPaths documents the DHCP integration endpoint
+func registerDHCPPaths(spec *openapi3.T) {
+	op := openapi3.NewOperation()
+	op.OperationID = "getDHCPReservations"
+	op.Summary = "Render DHCP host reservations for inventoried MAC addresses"
+	op.Description = "Reservations for the MAC addresses of the selected Devices (Nodes by default): their MAC-valued properties, except bmc.* ones, and those of the non-BMC Devices below them such as NICs. The hostname and IPv4 address come from a hostname and an ip (or ip_address) label, annotation or property; a NIC with its own ip gets that address. A Device whose hostname isn't a valid RFC 1123 hostname is left out (and logged); one named after a Device name that isn't a valid hostname gets no hostname. dnsmasq renders dhcp-host lines, isc host declarations, and kea a JSON list for a Dhcp4 reservations setting."
+	op.Tags = []string{"Integrations"}
+	op.Parameters = openapi3.Parameters{
+		{Value: openapi3.NewQueryParameter("format").WithSchema(openapi3.NewStringSchema().WithEnum(dhcpDnsmasq, dhcpISC, dhcpKea))},
+		{Value: openapi3.NewQueryParameter("deviceType").
+			WithDescription("Device types to make reservations for, comma-separated (default Node; * for all)").
+			WithSchema(openapi3.NewStringSchema())},
+	}
+	for _, param := range listParameters("spec.deviceType, spec.manufacturer, spec.partNumber, spec.serialNumber, spec.parentID, spec.parentSerialNumber, status.phase, metadata.name, metadata.uid and spec.properties.<key>") {
+		if name := param.Value.Name; name == "labelSelector" || name == "fieldSelector" {
+			op.Parameters = append(op.Parameters, param)
+		}
+	}
+	op.Responses = openapi3.NewResponses()
+	op.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("DHCP configuration").
+			WithContent(openapi3.Content{
+				"text/plain":       openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema()),
+				"application/json": openapi3.NewMediaType().WithSchema(openapi3.NewArraySchema().WithItems(openapi3.NewObjectSchema())),
+			}),
+	})
+	op.Responses.Set("400", errorResponse())
+
+	spec.Paths.Set("/integrations/dhcp", &openapi3.PathItem{Get: op})
+}
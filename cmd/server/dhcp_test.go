@@ -0,0 +1,73 @@
+package main
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// dhcpTestDevice returns a Node with a MAC address, an IP and the given hostname property
+func dhcpTestDevice(uid, name, mac, ip, hostname string) *device.Device {
+	dev := &device.Device{Spec: device.DeviceSpec{DeviceType: "Node", Properties: map[string]json.RawMessage{}}}
+	dev.Metadata.UID = uid
+	dev.Metadata.Name = name
+	for key, value := range map[string]string{"mac_address": mac, "ip": ip, "hostname": hostname} {
+		if value != "" {
+			raw, _ := json.Marshal(value)
+			dev.Spec.Properties[key] = raw
+		}
+	}
+	return dev
+}
+
+// TestDHCPHostnames checks that hostnames that could inject DHCP server
+// configuration are never rendered.
+func TestDHCPHostnames(t *testing.T) {
+	hosts := []*device.Device{
+		dhcpTestDevice("dev-1", "node-1", "aa:bb:cc:00:00:01", "10.0.0.1", "node1.example.com"),
+		dhcpTestDevice("dev-2", "node-2", "aa:bb:cc:00:00:02", "10.0.0.2", "node2\ndhcp-host=aa:bb:cc:ff:ff:ff,10.9.9.9"),
+		dhcpTestDevice("dev-3", "node-3", "aa:bb:cc:00:00:03", "10.0.0.3", `node3"; filename "evil`),
+		dhcpTestDevice("dev-4", "node 4;", "aa:bb:cc:00:00:04", "10.0.0.4", ""),
+	}
+	reservations, skipped := dhcpReservations(hosts, hosts)
+
+	if len(skipped) != 2 || !strings.Contains(skipped[0], "dev-2") || !strings.Contains(skipped[1], "dev-3") {
+		t.Errorf("skipped = %q, want dev-2 and dev-3", skipped)
+	}
+	want := []dhcpReservation{
+		{Hostname: "node1.example.com", IP: "10.0.0.1", MACs: []string{"aa:bb:cc:00:00:01"}},
+		{IP: "10.0.0.4", MACs: []string{"aa:bb:cc:00:00:04"}}, // device name isn't a hostname
+	}
+	if len(reservations) != len(want) {
+		t.Fatalf("reservations = %+v, want %+v", reservations, want)
+	}
+	for i := range want {
+		got := reservations[i]
+		if got.Hostname != want[i].Hostname || got.IP != want[i].IP || strings.Join(got.MACs, ",") != strings.Join(want[i].MACs, ",") {
+			t.Errorf("reservation %d = %+v, want %+v", i, got, want[i])
+		}
+	}
+
+	dnsmasq := string(renderDnsmasq(reservations))
+	wantDnsmasq := "dhcp-host=aa:bb:cc:00:00:01,10.0.0.1,node1.example.com\ndhcp-host=aa:bb:cc:00:00:04,10.0.0.4\n"
+	if !strings.HasSuffix(dnsmasq, wantDnsmasq) || strings.Count(dnsmasq, "\n") != 3 {
+		t.Errorf("dnsmasq config:\n%s\nwant the header and\n%s", dnsmasq, wantDnsmasq)
+	}
+	isc := string(renderISC(reservations))
+	for _, line := range []string{
+		"host node1.example.com {",
+		`  option host-name "node1.example.com";`,
+		"host aabbcc000004 {",
+	} {
+		if !strings.Contains(isc, line+"\n") {
+			t.Errorf("ISC config has no line %q:\n%s", line, isc)
+		}
+	}
+	for _, config := range []string{dnsmasq, isc, string(renderKea(reservations))} {
+		if strings.Contains(config, "evil") || strings.Contains(config, "10.9.9.9") {
+			t.Errorf("config has an injected hostname:\n%s", config)
+		}
+	}
+}
@@ -39,6 +39,7 @@ import (
 	"fmt"
 	"io"
 	"net/http"
+	"strconv"
 	"time"
 
 	"github.com/go-chi/chi/v5"
@@ -56,12 +57,36 @@ func GetDiscoverySnapshots(w http.ResponseWriter, r *http.Request) {
 	// Authorization: Add custom middleware in routes.go or implement checks here
 	// Example: if !authorized(r) { respondError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized")); return }
 
-	discoverysnapshots, err := storage.LoadAllDiscoverySnapshots(r.Context())
+	// Stream changes instead of listing (?watch=true)
+	if isWatchRequest(r) {
+		serveWatch(w, r, "DiscoverySnapshot")
+		return
+	}
+
+	// Label and field selectors (?labelSelector=...&fieldSelector=...)
+	filter, err := parseListFilter(r, isDiscoverySnapshotField)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	// Sorting and pagination (?sortBy=...&order=...&limit=...&continue=...)
+	page, err := parseListPage(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	items, total, next, err := listDiscoverySnapshots(r.Context(), filter, page)
 	if err != nil {
 		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load discoverysnapshots: %w", err))
 		return
 	}
-	respondJSON(w, http.StatusOK, discoverysnapshots)
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	if !page.paginated() {
+		respondJSON(w, http.StatusOK, items)
+		return
+	}
+	respondJSON(w, http.StatusOK, DiscoverySnapshotsResponse{Items: items, Continue: next, Total: total})
 }
 
 // GetDiscoverySnapshot returns a specific DiscoverySnapshot resource by UID
@@ -85,6 +110,9 @@ func GetDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusNotFound, fmt.Errorf("DiscoverySnapshot not found: %w", err))
 		return
 	}
+	if !checkIfNoneMatch(w, r, discoverySnapshot) {
+		return
+	}
 	respondJSON(w, http.StatusOK, discoverySnapshot)
 }
 
@@ -95,6 +123,9 @@ func CreateDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
 		return
 	}
+	if !checkVersionAnnotation(w, req.Annotations, nil) {
+		return
+	}
 
 	// Get version context from request
 	versionCtx := versioning.GetVersionContext(r.Context())
@@ -139,8 +170,8 @@ func CreateDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 	// Set initial status
 
 	// Save (Layer 1: Ent validation happens automatically if using Ent storage)
-	if err := storage.SaveDiscoverySnapshot(r.Context(), discoverySnapshot); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to save DiscoverySnapshot: %w", err))
+	if err := storage.SaveDiscoverySnapshotVersioned(r.Context(), discoverySnapshot); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save DiscoverySnapshot: %w", err))
 		return
 	}
 
@@ -150,6 +181,7 @@ func CreateDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Warning: Failed to publish resource created event for DiscoverySnapshot %s: %v\n", discoverySnapshot.GetUID(), err)
 	}
 
+	setETag(w, discoverySnapshot)
 	respondJSON(w, http.StatusCreated, discoverySnapshot)
 }
 
@@ -168,11 +200,19 @@ func UpdateDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, discoverySnapshot) {
+		return
+	}
+
 	var req UpdateDiscoverySnapshotRequest
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
 		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
 		return
 	}
+	if !checkVersionAnnotation(w, req.Annotations, &discoverySnapshot.Metadata) {
+		return
+	}
 
 	// Apply updates
 	if req.Name != "" {
@@ -192,8 +232,8 @@ func UpdateDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 
 	discoverySnapshot.Touch()
 
-	if err := storage.SaveDiscoverySnapshot(r.Context(), discoverySnapshot); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to save DiscoverySnapshot: %w", err))
+	if err := storage.SaveDiscoverySnapshotVersioned(r.Context(), discoverySnapshot); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save DiscoverySnapshot: %w", err))
 		return
 	}
 
@@ -206,6 +246,7 @@ func UpdateDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Warning: Failed to publish resource updated event for DiscoverySnapshot %s: %v\n", discoverySnapshot.GetUID(), err)
 	}
 
+	setETag(w, discoverySnapshot)
 	respondJSON(w, http.StatusOK, discoverySnapshot)
 }
 
@@ -224,6 +265,11 @@ func PatchDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, discoverySnapshot) {
+		return
+	}
+
 	// Read patch document
 	patchData, err := io.ReadAll(r.Body)
 	if err != nil {
@@ -262,8 +308,8 @@ func PatchDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 	discoverySnapshot.Touch()
 
 	// Save the patched resource
-	if err := storage.SaveDiscoverySnapshot(r.Context(), discoverySnapshot); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to save patched DiscoverySnapshot: %w", err))
+	if err := storage.SaveDiscoverySnapshotVersioned(r.Context(), discoverySnapshot); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save patched DiscoverySnapshot: %w", err))
 		return
 	}
 
@@ -277,6 +323,7 @@ func PatchDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Warning: Failed to publish resource patched event for DiscoverySnapshot %s: %v\n", discoverySnapshot.GetUID(), err)
 	}
 
+	setETag(w, discoverySnapshot)
 	respondJSON(w, http.StatusOK, discoverySnapshot)
 }
 
@@ -302,6 +349,11 @@ func UpdateDiscoverySnapshotStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, res) {
+		return
+	}
+
 	var statusUpdate discoverysnapshot.DiscoverySnapshotStatus
 	if err := json.NewDecoder(r.Body).Decode(&statusUpdate); err != nil {
 		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid status body: %w", err))
@@ -311,8 +363,8 @@ func UpdateDiscoverySnapshotStatus(w http.ResponseWriter, r *http.Request) {
 	// Preserve spec - only update status
 	res.Touch()
 
-	if err := storage.SaveDiscoverySnapshot(r.Context(), res); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to save DiscoverySnapshot status: %w", err))
+	if err := storage.SaveDiscoverySnapshotVersioned(r.Context(), res); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save DiscoverySnapshot status: %w", err))
 		return
 	}
 
@@ -326,6 +378,7 @@ func UpdateDiscoverySnapshotStatus(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Warning: Failed to publish status update event for DiscoverySnapshot %s: %v\n", res.GetUID(), err)
 	}
 
+	setETag(w, res)
 	respondJSON(w, http.StatusOK, res)
 }
 
@@ -348,6 +401,11 @@ func PatchDiscoverySnapshotStatus(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, res) {
+		return
+	}
+
 	patchData, err := io.ReadAll(r.Body)
 	if err != nil {
 		respondError(w, http.StatusBadRequest, fmt.Errorf("failed to read patch data: %w", err))
@@ -381,8 +439,8 @@ func PatchDiscoverySnapshotStatus(w http.ResponseWriter, r *http.Request) {
 
 	res.Touch()
 
-	if err := storage.SaveDiscoverySnapshot(r.Context(), res); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to save patched DiscoverySnapshot status: %w", err))
+	if err := storage.SaveDiscoverySnapshotVersioned(r.Context(), res); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save patched DiscoverySnapshot status: %w", err))
 		return
 	}
 
@@ -396,6 +454,7 @@ func PatchDiscoverySnapshotStatus(w http.ResponseWriter, r *http.Request) {
 		fmt.Printf("Warning: Failed to publish status patch event for DiscoverySnapshot %s: %v\n", res.GetUID(), err)
 	}
 
+	setETag(w, res)
 	respondJSON(w, http.StatusOK, res)
 }
 
@@ -414,14 +473,22 @@ func DeleteDiscoverySnapshot(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if err := storage.DeleteDiscoverySnapshot(r.Context(), uid); err != nil {
-		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to delete DiscoverySnapshot: %w", err))
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, discoverySnapshot) {
+		return
+	}
+
+	// Delete only the version we checked, so a concurrent write isn't silently lost
+	if err := storage.DeleteIfVersion(r.Context(), "DiscoverySnapshot", uid, storage.ResourceVersionOf(&discoverySnapshot.Metadata)); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to delete DiscoverySnapshot: %w", err))
 		return
 	}
 
 	// Publish resource deleted event
+	// Labels let webhook subscriptions apply their label selector to deletes
 	deleteMetadata := map[string]interface{}{
 		"deletedAt": time.Now(),
+		"labels":    discoverySnapshot.Metadata.Labels,
 	}
 	if err := events.PublishResourceDeleted(r.Context(), "DiscoverySnapshot", discoverySnapshot.GetUID(), discoverySnapshot.GetName(), deleteMetadata); err != nil {
 		// Log the error but don't fail the request - events are non-critical
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"time"
+
+	"github.com/openchami/fabrica/pkg/events"
+
+	"github.com/user/inventory-api/internal/eventlog"
+	"github.com/user/inventory-api/internal/natsbus"
+)
+
+// newEventBus builds the event bus selected by config.EventBus.
+//
+// It returns the bus to publish on and a function giving the bus a named
+// component (the reconcile controller, the webhook dispatcher) should subscribe
+// through. For the file and NATS buses that is a durable consumer, so events
+// published while the server was down are delivered on restart. Only one
+// server may use a store (see storage.LockWriter), so the NATS consumers have
+// a single subscriber. Replicas sharing reconcile work are not supported:
+// the resourceVersion counter, index and serial-number locks are per
+// process. NATS is for sharing events with other services.
+func newEventBus(config *Config) (bus events.EventBus, consumer func(name string) events.EventBus, err error) {
+	switch config.EventBus {
+	case "", "memory":
+		memBus := events.NewInMemoryEventBus(1000, 10)
+		memBus.Start()
+		return memBus, func(string) events.EventBus { return memBus }, nil
+
+	case "file":
+		fileBus, err := eventlog.NewEventBus(filepath.Join(config.DataDir, "events"), eventlog.Options{
+			RetentionBytes: config.EventLogMaxBytes,
+			RetentionAge:   time.Duration(config.EventLogMaxAge) * time.Hour,
+			NoSync:         config.EventLogNoSync,
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to open event log: %w", err)
+		}
+		fileBus.Start()
+		return fileBus, fileBus.Consumer, nil
+
+	case "nats":
+		natsBus, err := natsbus.NewEventBus(context.Background(), natsbus.Options{
+			URL:      config.NATSURL,
+			Stream:   config.NATSStream,
+			MaxBytes: config.EventLogMaxBytes,
+			MaxAge:   time.Duration(config.EventLogMaxAge) * time.Hour,
+		})
+		if err != nil {
+			return nil, nil, err
+		}
+		return natsBus, natsBus.Consumer, nil
+
+	default:
+		return nil, nil, fmt.Errorf("unknown event bus %q (expected \"memory\", \"file\" or \"nats\")", config.EventBus)
+	}
+}
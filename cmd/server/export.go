@@ -0,0 +1,606 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"maps"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/openchami/fabrica/pkg/events"
+	"github.com/openchami/fabrica/pkg/resource"
+	fabrica_storage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/internal/reconciliation"
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+const (
+	// maxImportBytes bounds the body of an import request
+	maxImportBytes = 64 << 20
+
+	// maxImportLine bounds one NDJSON record of an import request
+	maxImportLine = 1 << 20
+)
+
+// exportColumns are the columns of a CSV export
+var exportColumns = []string{
+	"uid", "name", "deviceType", "manufacturer", "partNumber", "serialNumber",
+	"parentID", "parentSerialNumber", "phase", "ready", "labels", "properties",
+	"createdAt", "updatedAt",
+}
+
+// Import actions
+const (
+	importCreate    = "create"
+	importUpdate    = "update"
+	importUnchanged = "unchanged"
+	importError     = "error"
+)
+
+// ImportChange is one field an import changes on an existing Device.
+type ImportChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from,omitempty"`
+	To    any    `json:"to,omitempty"`
+}
+
+// ImportRecordResult is what an import did (or, in a dry run, would do) with one record.
+type ImportRecordResult struct {
+	Line         int            `json:"line"`
+	SerialNumber string         `json:"serialNumber,omitempty"`
+	Action       string         `json:"action"` // create, update, unchanged or error
+	UID          string         `json:"uid,omitempty"`
+	Changes      []ImportChange `json:"changes,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// ImportResponse is the result of POST /import.
+type ImportResponse struct {
+	DryRun    bool                 `json:"dryRun"`
+	Created   int                  `json:"created"`
+	Updated   int                  `json:"updated"`
+	Unchanged int                  `json:"unchanged"`
+	Failed    int                  `json:"failed"`
+	Records   []ImportRecordResult `json:"records"`
+}
+
+// importRecord is one record of an import request as it's planned
+type importRecord struct {
+	result   *ImportRecordResult
+	record   *device.Device // as sent
+	existing *device.Device // the stored Device with the same serial number, if any
+	target   *device.Device // as it will be stored
+}
+
+// ExportDevices handles GET /export: every Device matching the list
+// selectors, as NDJSON (one Device per line), CSV, or SMD hardware inventory
+// by location or by FRU.
+func ExportDevices(w http.ResponseWriter, r *http.Request) {
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = "ndjson"
+	}
+	if format != "ndjson" && format != "csv" && format != exportSMDLocation && format != exportSMDFRU {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid format %q: must be ndjson, csv, %s or %s", format, exportSMDLocation, exportSMDFRU))
+		return
+	}
+	filter, err := parseListFilter(r, isDeviceField)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	page, err := parseListPage(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	if page.paginated() {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("export returns every matching Device and doesn't take limit or continue; narrow it with labelSelector or fieldSelector"))
+		return
+	}
+
+	devices, total, _, err := listDevices(r.Context(), filter, page)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load devices: %w", err))
+		return
+	}
+
+	// Fill in parent serial numbers, so an export can be imported elsewhere
+	// (where the parent has another UID)
+	serials := make(map[string]string, len(devices))
+	for _, dev := range devices {
+		serials[dev.GetUID()] = dev.Spec.SerialNumber
+	}
+	for _, dev := range devices {
+		parentID := dev.Spec.ParentID
+		if parentID == "" || dev.Spec.ParentSerialNumber != "" {
+			continue
+		}
+		serial, ok := serials[parentID]
+		if !ok {
+			if parent, err := storage.LoadDevice(r.Context(), parentID); err == nil {
+				serial = parent.Spec.SerialNumber
+			}
+			serials[parentID] = serial
+		}
+		dev.Spec.ParentSerialNumber = serial
+	}
+
+	w.Header().Set(totalCountHeader, strconv.Itoa(total))
+	if format == exportSMDLocation || format == exportSMDFRU {
+		writeSMDExport(w, r, format, devices)
+		return
+	}
+	if format == "csv" {
+		w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		w.Header().Set("Content-Disposition", `attachment; filename="devices.csv"`)
+		writeDevicesCSV(w, devices)
+		return
+	}
+	w.Header().Set("Content-Type", "application/x-ndjson")
+	w.Header().Set("Content-Disposition", `attachment; filename="devices.ndjson"`)
+	enc := json.NewEncoder(w)
+	for _, dev := range devices {
+		if err := enc.Encode(dev); err != nil {
+			return
+		}
+	}
+}
+
+// writeDevicesCSV writes devices as CSV with a header row. Labels are
+// key=value pairs separated by commas, properties a JSON object.
+func writeDevicesCSV(w io.Writer, devices []*device.Device) error {
+	cw := csv.NewWriter(w)
+	if err := cw.Write(exportColumns); err != nil {
+		return err
+	}
+	for _, dev := range devices {
+		labels := make([]string, 0, len(dev.Metadata.Labels))
+		for _, key := range slices.Sorted(maps.Keys(dev.Metadata.Labels)) {
+			labels = append(labels, key+"="+dev.Metadata.Labels[key])
+		}
+		properties := ""
+		if len(dev.Spec.Properties) > 0 {
+			data, err := json.Marshal(dev.Spec.Properties)
+			if err != nil {
+				return err
+			}
+			properties = string(data)
+		}
+		err := cw.Write([]string{
+			dev.GetUID(),
+			dev.GetName(),
+			dev.Spec.DeviceType,
+			dev.Spec.Manufacturer,
+			dev.Spec.PartNumber,
+			dev.Spec.SerialNumber,
+			dev.Spec.ParentID,
+			dev.Spec.ParentSerialNumber,
+			dev.Status.Phase,
+			strconv.FormatBool(dev.Status.Ready),
+			strings.Join(labels, ","),
+			properties,
+			dev.Metadata.CreatedAt.UTC().Format(time.RFC3339),
+			dev.Metadata.UpdatedAt.UTC().Format(time.RFC3339),
+		})
+		if err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	return cw.Error()
+}
+
+// ImportDevices handles POST /import: NDJSON Devices (as written by
+// GET /export) are upserted by serial number.
+//
+// A record whose serial number matches a stored Device updates its name,
+// labels (when the record has any) and spec; other records create Devices.
+// parentSerialNumber is resolved to a parentID against the other records
+// first and then storage, as the snapshot reconciler does; a record whose
+// parent chain would lead back to itself is an error. Status, UIDs and
+// parentIDs in the records are ignored. The records' serial numbers are
+// locked against snapshot reconciles until the import is committed.
+//
+// Records with errors are reported and skipped; the rest are committed as
+// one transaction. With ?dryRun=true nothing is written and the response
+// shows what would change.
+func ImportDevices(w http.ResponseWriter, r *http.Request) {
+	dryRun, _ := strconv.ParseBool(r.URL.Query().Get("dryRun"))
+	ctx := r.Context()
+
+	// Parse and validate each record
+	var records []*importRecord
+	lineBySerial := make(map[string]int)
+	scanner := bufio.NewScanner(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	scanner.Buffer(make([]byte, 64*1024), maxImportLine)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" {
+			continue
+		}
+		rec := &importRecord{result: &ImportRecordResult{Line: line}}
+		records = append(records, rec)
+
+		var dev device.Device
+		if err := json.Unmarshal([]byte(text), &dev); err != nil {
+			rec.fail(fmt.Errorf("invalid JSON: %w", err))
+			continue
+		}
+		rec.record = &dev
+		rec.result.SerialNumber = dev.Spec.SerialNumber
+		switch {
+		case dev.Spec.SerialNumber == "":
+			rec.fail(fmt.Errorf("spec.serialNumber is required"))
+			continue
+		case dev.Spec.DeviceType == "":
+			rec.fail(fmt.Errorf("spec.deviceType is required"))
+			continue
+		case dev.Kind != "" && dev.Kind != "Device":
+			rec.fail(fmt.Errorf("kind is %q, not Device", dev.Kind))
+			continue
+		}
+		if first, ok := lineBySerial[dev.Spec.SerialNumber]; ok {
+			rec.fail(fmt.Errorf("serial number %s is also on line %d", dev.Spec.SerialNumber, first))
+			continue
+		}
+		lineBySerial[dev.Spec.SerialNumber] = line
+	}
+	if err := scanner.Err(); err != nil {
+		var tooLarge *http.MaxBytesError
+		if errors.As(err, &tooLarge) {
+			respondError(w, http.StatusRequestEntityTooLarge, fmt.Errorf("import is larger than %d bytes", maxImportBytes))
+			return
+		}
+		respondError(w, http.StatusBadRequest, fmt.Errorf("failed to read import: %w", err))
+		return
+	}
+
+	// Lock the serials (and parent serials) of the records against snapshot
+	// reconciles, so neither creates a Device the other is about to create
+	serials := make([]string, 0, 2*len(records))
+	for _, rec := range records {
+		if rec.record != nil && rec.result.Action != importError {
+			serials = append(serials, rec.record.Spec.SerialNumber, rec.record.Spec.ParentSerialNumber)
+		}
+	}
+	unlock := reconciliation.LockSerials(serials)
+	defer unlock()
+
+	// Plan each valid record as a create or an update
+	bySerial := make(map[string]*importRecord)
+	for _, rec := range records {
+		if rec.record == nil || rec.result.Action == importError {
+			continue
+		}
+		existing, err := storage.LoadDeviceBySerial(ctx, rec.record.Spec.SerialNumber)
+		if err != nil && !errors.Is(err, fabrica_storage.ErrNotFound) {
+			respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to look up Device %s: %w", rec.record.Spec.SerialNumber, err))
+			return
+		}
+		if err := rec.plan(existing); err != nil {
+			rec.fail(err)
+			continue
+		}
+		bySerial[rec.record.Spec.SerialNumber] = rec
+	}
+
+	// Resolve parents from the import, then storage. Dropping a record can
+	// leave its children without a parent, so repeat until nothing changes.
+	storedParents := make(map[string]string)         // serial -> UID ("" if not stored)
+	storedDevices := make(map[string]*device.Device) // UID -> Device (nil if not stored)
+	for changed := true; changed; {
+		changed = false
+		for _, rec := range records {
+			if rec.target == nil || rec.result.Action == importError {
+				continue
+			}
+			parentSerial := rec.target.Spec.ParentSerialNumber
+			if parentSerial == "" {
+				continue
+			}
+			parentUID := ""
+			if parent, ok := bySerial[parentSerial]; ok {
+				parentUID = parent.target.GetUID()
+			} else {
+				uid, ok := storedParents[parentSerial]
+				if !ok {
+					parent, err := storage.LoadDeviceBySerial(ctx, parentSerial)
+					if err != nil && !errors.Is(err, fabrica_storage.ErrNotFound) {
+						respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to look up Device %s: %w", parentSerial, err))
+						return
+					}
+					if parent != nil {
+						uid = parent.GetUID()
+					}
+					storedParents[parentSerial] = uid
+				}
+				parentUID = uid
+			}
+			switch {
+			case parentUID == "":
+				rec.fail(fmt.Errorf("parent serial number %s matches no Device", parentSerial))
+			case parentUID == rec.target.GetUID():
+				rec.fail(fmt.Errorf("device can't be its own parent"))
+			default:
+				rec.target.Spec.ParentID = parentUID
+				continue
+			}
+			delete(bySerial, rec.record.Spec.SerialNumber)
+			changed = true
+		}
+		if !changed {
+			cyclic, err := failParentCycles(ctx, bySerial, storedDevices)
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, err)
+				return
+			}
+			changed = cyclic
+		}
+	}
+
+	// Work out what changes and stage it
+	response := ImportResponse{DryRun: dryRun, Records: make([]ImportRecordResult, 0, len(records))}
+	var created, updated []*device.Device
+	tx := storage.NewStorageClient().Begin()
+	for _, rec := range records {
+		if rec.result.Action != importError {
+			rec.diff()
+		}
+		switch rec.result.Action {
+		case importCreate:
+			response.Created++
+			created = append(created, rec.target)
+			if err := tx.Create(rec.target); err != nil {
+				respondError(w, http.StatusInternalServerError, err)
+				return
+			}
+		case importUpdate:
+			response.Updated++
+			updated = append(updated, rec.target)
+			rec.target.Metadata.UpdatedAt = time.Now()
+			if err := tx.Update(rec.target); err != nil {
+				respondError(w, http.StatusInternalServerError, err)
+				return
+			}
+		case importUnchanged:
+			response.Unchanged++
+		case importError:
+			response.Failed++
+		}
+		if dryRun && rec.result.Action == importCreate {
+			rec.result.UID = "" // not assigned until the import is committed
+		}
+		response.Records = append(response.Records, *rec.result)
+	}
+
+	if dryRun || tx.Len() == 0 {
+		respondJSON(w, http.StatusOK, response)
+		return
+	}
+	if err := tx.Commit(ctx); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to import devices (nothing was written): %w", err))
+		return
+	}
+	for _, dev := range created {
+		if err := events.PublishResourceCreated(ctx, "Device", dev.GetUID(), dev.GetName(), dev); err != nil {
+			fmt.Printf("Warning: Failed to publish resource created event for Device %s: %v\n", dev.GetUID(), err)
+		}
+	}
+	for _, dev := range updated {
+		if err := events.PublishResourceUpdated(ctx, "Device", dev.GetUID(), dev.GetName(), dev, nil); err != nil {
+			fmt.Printf("Warning: Failed to publish resource updated event for Device %s: %v\n", dev.GetUID(), err)
+		}
+	}
+	respondJSON(w, http.StatusOK, response)
+}
+
+// failParentCycles fails every planned record whose parent chain, followed
+// through the other records and then storage, leads back to itself, and
+// reports whether any did. stored caches the Devices loaded on the way.
+func failParentCycles(ctx context.Context, bySerial map[string]*importRecord, stored map[string]*device.Device) (bool, error) {
+	byUID := make(map[string]*importRecord, len(bySerial))
+	for _, rec := range bySerial {
+		byUID[rec.target.GetUID()] = rec
+	}
+
+	var cyclic []*importRecord
+	for _, rec := range bySerial {
+		uid := rec.target.GetUID()
+		seen := map[string]bool{uid: true}
+		for parentID := rec.target.Spec.ParentID; parentID != ""; {
+			if parentID == uid {
+				cyclic = append(cyclic, rec)
+				break
+			}
+			if seen[parentID] {
+				break // a cycle further up, without this record
+			}
+			seen[parentID] = true
+			if parent, ok := byUID[parentID]; ok {
+				parentID = parent.target.Spec.ParentID
+				continue
+			}
+			dev, ok := stored[parentID]
+			if !ok {
+				var err error
+				dev, err = storage.LoadDevice(ctx, parentID)
+				if err != nil && !errors.Is(err, fabrica_storage.ErrNotFound) {
+					return false, fmt.Errorf("failed to look up Device %s: %w", parentID, err)
+				}
+				stored[parentID] = dev
+			}
+			if dev == nil {
+				break
+			}
+			parentID = dev.Spec.ParentID
+		}
+	}
+
+	for _, rec := range cyclic {
+		rec.fail(fmt.Errorf("parent serial number %s would make the device its own ancestor", rec.target.Spec.ParentSerialNumber))
+		delete(bySerial, rec.record.Spec.SerialNumber)
+	}
+	return len(cyclic) > 0, nil
+}
+
+// fail marks the record as not importable
+func (rec *importRecord) fail(err error) {
+	rec.result.Action = importError
+	rec.result.UID = ""
+	rec.result.Error = err.Error()
+}
+
+// plan builds the Device the record will be stored as
+func (rec *importRecord) plan(existing *device.Device) error {
+	spec := rec.record.Spec
+	spec.ParentID = ""
+	annotations := make(map[string]string)
+	for k, v := range rec.record.Metadata.Annotations {
+		if k != storage.ResourceVersionAnnotation {
+			annotations[k] = v
+		}
+	}
+
+	if existing == nil {
+		uid, err := resource.GenerateUIDForResource("Device")
+		if err != nil {
+			return fmt.Errorf("failed to generate UID: %w", err)
+		}
+		target := &device.Device{
+			Resource: resource.Resource{
+				APIVersion:    "v1",
+				Kind:          "Device",
+				SchemaVersion: "v1",
+			},
+			Spec: spec,
+		}
+		name := rec.record.GetName()
+		if name == "" {
+			name = spec.SerialNumber
+		}
+		target.Metadata.Initialize(name, uid)
+		for k, v := range rec.record.Metadata.Labels {
+			target.SetLabel(k, v)
+		}
+		for k, v := range annotations {
+			target.SetAnnotation(k, v)
+		}
+		rec.target = target
+		rec.result.UID = uid
+		return nil
+	}
+
+	target := *existing
+	target.Spec = spec
+	target.Spec.ParentID = existing.Spec.ParentID // kept unless parentSerialNumber links elsewhere
+	if name := rec.record.GetName(); name != "" {
+		target.Metadata.Name = name
+	}
+	if rec.record.Metadata.Labels != nil {
+		target.Metadata.Labels = maps.Clone(rec.record.Metadata.Labels)
+	}
+	rec.existing = existing
+	rec.target = &target
+	rec.result.UID = existing.GetUID()
+	return nil
+}
+
+// diff sets the record's action, and for updates the fields that change
+func (rec *importRecord) diff() {
+	if rec.existing == nil {
+		rec.result.Action = importCreate
+		return
+	}
+	before, after := rec.existing, rec.target
+	var changes []ImportChange
+	change := func(field string, from, to any, equal bool) {
+		if !equal {
+			changes = append(changes, ImportChange{Field: field, From: from, To: to})
+		}
+	}
+	change("metadata.name", before.Metadata.Name, after.Metadata.Name, before.Metadata.Name == after.Metadata.Name)
+	change("metadata.labels", before.Metadata.Labels, after.Metadata.Labels, maps.Equal(before.Metadata.Labels, after.Metadata.Labels))
+	change("spec.deviceType", before.Spec.DeviceType, after.Spec.DeviceType, before.Spec.DeviceType == after.Spec.DeviceType)
+	change("spec.manufacturer", before.Spec.Manufacturer, after.Spec.Manufacturer, before.Spec.Manufacturer == after.Spec.Manufacturer)
+	change("spec.partNumber", before.Spec.PartNumber, after.Spec.PartNumber, before.Spec.PartNumber == after.Spec.PartNumber)
+	change("spec.parentID", before.Spec.ParentID, after.Spec.ParentID, before.Spec.ParentID == after.Spec.ParentID)
+	change("spec.parentSerialNumber", before.Spec.ParentSerialNumber, after.Spec.ParentSerialNumber, before.Spec.ParentSerialNumber == after.Spec.ParentSerialNumber)
+	beforeProps, _ := json.Marshal(before.Spec.Properties)
+	afterProps, _ := json.Marshal(after.Spec.Properties)
+	change("spec.properties", before.Spec.Properties, after.Spec.Properties, string(beforeProps) == string(afterProps))
+
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].Field < changes[j].Field })
+	rec.result.Changes = changes
+	if len(changes) == 0 {
+		rec.result.Action = importUnchanged
+	} else {
+		rec.result.Action = importUpdate
+	}
+}
+
+// registerExportPaths documents the export and import endpoints
+func registerExportPaths(spec *openapi3.T) {
+	export := openapi3.NewOperation()
+	export.OperationID = "exportDevices"
+	export.Summary = "Export Devices as NDJSON, CSV or SMD hardware inventory"
+	export.Description = "Every Device matching the selectors, one JSON object per line (ndjson), one row per Device (csv), or a JSON array of SMD HWInvByLoc (smd-location) or HWInvByFRU (smd-fru). parentSerialNumber is filled in from the parent when only parentID is set. SMD locations are the Devices with an xname property, and those below them placed by type."
+	export.Tags = []string{"Device"}
+	export.Parameters = openapi3.Parameters{
+		{Value: openapi3.NewQueryParameter("format").WithSchema(openapi3.NewStringSchema().WithEnum("ndjson", "csv", exportSMDLocation, exportSMDFRU))},
+	}
+	for _, param := range listParameters("spec.deviceType, spec.manufacturer, spec.partNumber, spec.serialNumber, spec.parentID, spec.parentSerialNumber, status.phase, metadata.name, metadata.uid and spec.properties.<key>") {
+		if name := param.Value.Name; name != "limit" && name != "continue" {
+			export.Parameters = append(export.Parameters, param)
+		}
+	}
+	export.Responses = openapi3.NewResponses()
+	export.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Exported Devices").
+			WithContent(openapi3.Content{
+				"application/x-ndjson": openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema()),
+				"text/csv":             openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema()),
+				"application/json":     openapi3.NewMediaType().WithSchema(openapi3.NewArraySchema().WithItems(openapi3.NewObjectSchema())),
+			}),
+	})
+	export.Responses.Set("400", errorResponse())
+
+	importOp := openapi3.NewOperation()
+	importOp.OperationID = "importDevices"
+	importOp.Summary = "Import Devices from NDJSON, upserting by serial number"
+	importOp.Description = "One Device per line, as exported. Records matching a stored serial number update it; the rest create Devices. Records with errors are reported and skipped, the rest are committed together. dryRun reports the changes without writing them."
+	importOp.Tags = []string{"Device"}
+	importOp.Parameters = openapi3.Parameters{
+		{Value: openapi3.NewQueryParameter("dryRun").WithSchema(openapi3.NewBoolSchema())},
+	}
+	importOp.RequestBody = &openapi3.RequestBodyRef{
+		Value: openapi3.NewRequestBody().WithRequired(true).WithContent(openapi3.Content{
+			"application/x-ndjson": openapi3.NewMediaType().WithSchema(openapi3.NewStringSchema()),
+		}),
+	}
+	importOp.Responses = openapi3.NewResponses()
+	importOp.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("What was (or would be) created, updated or rejected, per record").
+			WithJSONSchema(openapi3.NewObjectSchema()),
+	})
+	importOp.Responses.Set("400", errorResponse())
+	importOp.Responses.Set("409", errorResponse())
+	importOp.Responses.Set("413", errorResponse())
+
+	spec.Paths.Set("/export", &openapi3.PathItem{Get: export})
+	spec.Paths.Set("/import", &openapi3.PathItem{Post: importOp})
+}
@@ -0,0 +1,187 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// postImport sends NDJSON records to the import handler
+func postImport(t *testing.T, records []string, dryRun bool) ImportResponse {
+	t.Helper()
+	target := "/import"
+	if dryRun {
+		target += "?dryRun=true"
+	}
+	w := httptest.NewRecorder()
+	ImportDevices(w, httptest.NewRequest(http.MethodPost, target, strings.NewReader(strings.Join(records, "\n"))))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var response ImportResponse
+	if err := json.Unmarshal(w.Body.Bytes(), &response); err != nil {
+		t.Fatal(err)
+	}
+	return response
+}
+
+// importedDevices returns the stored Devices by serial number
+func importedDevices(t *testing.T) map[string]*device.Device {
+	t.Helper()
+	devices, err := storage.LoadAllDevices(context.Background())
+	if err != nil {
+		t.Fatal(err)
+	}
+	bySerial := make(map[string]*device.Device, len(devices))
+	for _, d := range devices {
+		bySerial[d.Spec.SerialNumber] = d
+	}
+	return bySerial
+}
+
+// checkImport compares a response's totals and per-line actions
+func checkImport(t *testing.T, response ImportResponse, created, updated, unchanged, failed int, actions map[int]string) {
+	t.Helper()
+	if response.Created != created || response.Updated != updated || response.Unchanged != unchanged || response.Failed != failed {
+		t.Errorf("created %d, updated %d, unchanged %d, failed %d; want %d, %d, %d, %d",
+			response.Created, response.Updated, response.Unchanged, response.Failed, created, updated, unchanged, failed)
+	}
+	byLine := make(map[int]ImportRecordResult)
+	for _, rec := range response.Records {
+		byLine[rec.Line] = rec
+	}
+	for line, action := range actions {
+		if rec := byLine[line]; rec.Action != action {
+			t.Errorf("line %d: %s (%s), want %s", line, rec.Action, rec.Error, action)
+		}
+	}
+}
+
+// TestImportDryRunAndCommit checks that a dry run reports what a commit then
+// does, without writing, and that records with errors are reported and
+// skipped while the rest are imported.
+func TestImportDryRunAndCommit(t *testing.T) {
+	initTestStorage(t)
+	saveTestDevice(t, "node-1", "Node", "N1", nil)
+	saveTestDevice(t, "chassis-1", "Chassis", "CH1", nil)
+
+	records := []string{
+		/* 1 */ `{"metadata":{"name":"node-1"},"spec":{"deviceType":"Node","serialNumber":"N1","manufacturer":"Acme"}}`,
+		/* 2 */ `{"metadata":{"name":"chassis-1"},"spec":{"deviceType":"Chassis","serialNumber":"CH1"}}`,
+		/* 3 */ `{"spec":{"deviceType":"DIMM","serialNumber":"D1","parentSerialNumber":"N1"}}`,
+		/* 4 */ `{"spec":{"deviceType":"DIMM","serialNumber":"D2","parentSerialNumber":"N2"}}`,
+		/* 5 */ `{"kind":"Device","metadata":{"name":"node-2","uid":"dev-ignored"},"spec":{"deviceType":"Node","serialNumber":"N2","parentID":"dev-ignored"}}`,
+		/* 6 */ ``,
+		/* 7 */ `{"spec":{"deviceType":`,
+		/* 8 */ `{"spec":{"deviceType":"Node"}}`,
+		/* 9 */ `{"spec":{"serialNumber":"X9"}}`,
+		/* 10 */ `{"kind":"DiscoverySnapshot","spec":{"deviceType":"Node","serialNumber":"X10"}}`,
+		/* 11 */ `{"spec":{"deviceType":"Node","serialNumber":"N2"}}`,
+		/* 12 */ `{"spec":{"deviceType":"DIMM","serialNumber":"X12","parentSerialNumber":"NOWHERE"}}`,
+		/* 13 */ `{"spec":{"deviceType":"DIMM","serialNumber":"X13","parentSerialNumber":"X13"}}`,
+	}
+	actions := map[int]string{
+		1: importUpdate, 2: importUnchanged, 3: importCreate, 4: importCreate, 5: importCreate,
+		7: importError, 8: importError, 9: importError, 10: importError, 11: importError, 12: importError, 13: importError,
+	}
+
+	before := importedDevices(t)
+	dry := postImport(t, records, true)
+	if !dry.DryRun {
+		t.Error("dry run not reported as one")
+	}
+	checkImport(t, dry, 3, 1, 1, 7, actions)
+	for _, rec := range dry.Records {
+		if rec.Action == importCreate && rec.UID != "" {
+			t.Errorf("line %d: dry run reports UID %s for a Device it didn't create", rec.Line, rec.UID)
+		}
+		if rec.Action == importError && rec.Error == "" {
+			t.Errorf("line %d: error without a message", rec.Line)
+		}
+		if rec.Line == 1 && (len(rec.Changes) != 1 || rec.Changes[0].Field != "spec.manufacturer") {
+			t.Errorf("line 1 changes: %+v", rec.Changes)
+		}
+	}
+	after := importedDevices(t)
+	if len(after) != len(before) || after["N1"].Spec.Manufacturer != "" || storage.ResourceVersionOf(&after["N1"].Metadata) != storage.ResourceVersionOf(&before["N1"].Metadata) {
+		t.Fatal("dry run wrote to storage")
+	}
+
+	committed := postImport(t, records, false)
+	if committed.DryRun {
+		t.Error("commit reported as a dry run")
+	}
+	checkImport(t, committed, 3, 1, 1, 7, actions)
+	for i, rec := range committed.Records {
+		if rec.Action != dry.Records[i].Action || rec.Error != dry.Records[i].Error {
+			t.Errorf("line %d: committed %s %q, dry run said %s %q", rec.Line, rec.Action, rec.Error, dry.Records[i].Action, dry.Records[i].Error)
+		}
+	}
+
+	stored := importedDevices(t)
+	if len(stored) != 5 {
+		t.Errorf("%d devices stored, want 5", len(stored))
+	}
+	for _, serial := range []string{"X9", "X10", "X12", "X13"} {
+		if stored[serial] != nil {
+			t.Errorf("record with serial %s was imported despite its error", serial)
+		}
+	}
+	if stored["N1"].Spec.Manufacturer != "Acme" || stored["N1"].GetUID() != before["N1"].GetUID() {
+		t.Errorf("N1 updated as %+v", stored["N1"])
+	}
+	if stored["D1"].Spec.ParentID != stored["N1"].GetUID() || stored["D2"].Spec.ParentID != stored["N2"].GetUID() {
+		t.Error("parents not linked by serial number")
+	}
+	if stored["N2"].GetUID() == "dev-ignored" || stored["N2"].Spec.ParentID != "" {
+		t.Errorf("UID or parentID taken from the record: %+v", stored["N2"].Metadata)
+	}
+
+	// Importing the same records again changes nothing
+	checkImport(t, postImport(t, records, false), 0, 0, 5, 7, nil)
+}
+
+// TestImportParentCycles checks that records whose parent serial numbers,
+// followed through the import and then storage, loop back are rejected, and
+// so are the records left without a parent by that.
+func TestImportParentCycles(t *testing.T) {
+	initTestStorage(t)
+	ctx := context.Background()
+	// Stored: rack R1 holds chassis C1
+	rack := saveParentedDevice(t, "R1", "")
+	saveParentedDevice(t, "C1", rack)
+
+	records := []string{
+		/* 1 */ `{"spec":{"deviceType":"Rack","serialNumber":"R1","parentSerialNumber":"C1"}}`, // through storage
+		/* 2 */ `{"spec":{"deviceType":"Node","serialNumber":"A","parentSerialNumber":"B"}}`, // within the import
+		/* 3 */ `{"spec":{"deviceType":"Node","serialNumber":"B","parentSerialNumber":"A"}}`,
+		/* 4 */ `{"spec":{"deviceType":"DIMM","serialNumber":"B1","parentSerialNumber":"B"}}`, // parent rejected
+		/* 5 */ `{"spec":{"deviceType":"Node","serialNumber":"N1","parentSerialNumber":"C1"}}`,
+	}
+	response := postImport(t, records, false)
+	checkImport(t, response, 1, 0, 0, 4, map[int]string{
+		1: importError, 2: importError, 3: importError, 4: importError, 5: importCreate,
+	})
+	for _, rec := range response.Records[:3] {
+		if !strings.Contains(rec.Error, "own ancestor") {
+			t.Errorf("line %d: error %q doesn't name the cycle", rec.Line, rec.Error)
+		}
+	}
+
+	stored := importedDevices(t)
+	if stored["R1"].Spec.ParentID != "" {
+		t.Errorf("R1 was given parent %s", stored["R1"].Spec.ParentID)
+	}
+	if stored["N1"] == nil || stored["N1"].Spec.ParentID != stored["C1"].GetUID() {
+		t.Error("the record without a cycle wasn't imported under C1")
+	}
+	if _, err := storage.LoadDeviceBySerial(ctx, "A"); err == nil {
+		t.Error("a record in a cycle was imported")
+	}
+}
@@ -0,0 +1,122 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	internal_storage "github.com/user/inventory-api/internal/storage"
+)
+
+var fsckCmd = &cobra.Command{
+	Use:   "fsck",
+	Short: "Check stored resources for corruption and inconsistencies",
+	Long: `Check every stored Device, DiscoverySnapshot and Subscription for:
+
+  unparseable       files or rows that aren't valid resources
+  empty-uid         resources stored without a UID, or with an empty metadata.uid
+  uid-mismatch      metadata.uid differs from the UID the resource is stored under
+  kind-mismatch     the resource's kind is empty or not the kind it's stored as
+  prefix-mismatch   UIDs without their kind's prefix (dev-, dis-, sub-)
+  dangling-parent   devices whose parentID names a device that doesn't exist
+  parent-cycle      devices that are (indirectly) their own parent
+  duplicate-serial  serial numbers shared by several devices
+  stale-topology    childrenDeviceIds, depth or rootDeviceId out of date
+
+Nothing is changed unless --fix is given. Then resources that can't be
+trusted are quarantined (moved to <data-dir>/.quarantine, or the quarantine
+table for SQLite) rather than deleted, metadata is corrected to match where
+the resource is stored, dangling parent links and cycles are cleared, and
+topology fields are recomputed. Duplicate serial numbers are only reported.
+
+Stop the server before running with --fix. The command exits with an error
+while any issue remains unrepaired.`,
+	Example: `  # Check the configured storage
+  inventory-api fsck
+
+  # Repair what can be repaired
+  inventory-api fsck --fix
+
+  # Check another backend
+  inventory-api fsck --backend sqlite:./data/inventory.db`,
+	Args: cobra.NoArgs,
+	RunE: runFsck,
+}
+
+func init() {
+	fsckCmd.Flags().Bool("fix", false, "Repair the issues that can be repaired safely")
+	fsckCmd.Flags().String("backend", "", "Backend to check (file:<data-dir> or sqlite:<dsn>; default the configured storage)")
+	rootCmd.AddCommand(fsckCmd)
+}
+
+func runFsck(cmd *cobra.Command, args []string) error {
+	fix, _ := cmd.Flags().GetBool("fix")
+	spec, _ := cmd.Flags().GetString("backend")
+	if spec == "" {
+		spec = storageSpec(config)
+	}
+
+	cmd.SilenceUsage = true
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if fix {
+		unlock, err := internal_storage.LockWriter(spec)
+		if err != nil {
+			return fmt.Errorf("failed to lock storage (stop the server before repairing): %w", err)
+		}
+		defer unlock()
+	}
+	backend, err := internal_storage.OpenBackend(ctx, spec)
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	defer backend.Close()
+
+	out := cmd.OutOrStdout()
+	fmt.Fprintf(out, "Checking %s\n", spec)
+	issues, err := internal_storage.Fsck(ctx, backend, fix)
+	if err != nil {
+		return err
+	}
+
+	remaining, fixable := 0, 0
+	for _, issue := range issues {
+		uid := issue.UID
+		if uid == "" {
+			uid = `""`
+		}
+		var action string
+		switch {
+		case issue.Fixed:
+			action = "fixed: " + issue.Repair
+		case issue.Repair != "":
+			action = "fix: " + issue.Repair
+			fixable++
+		default:
+			action = "needs manual repair"
+		}
+		if !issue.Fixed {
+			remaining++
+		}
+		fmt.Fprintf(out, "  %-16s %s %s: %s (%s)\n", issue.Check, issue.Kind, uid, issue.Message, action)
+	}
+
+	switch {
+	case len(issues) == 0:
+		fmt.Fprintln(out, "No issues found.")
+		return nil
+	case remaining == 0:
+		fmt.Fprintf(out, "%d issues, all fixed.\n", len(issues))
+		return nil
+	case fixable > 0:
+		return fmt.Errorf("%d issues found, %d can be repaired with --fix", remaining, fixable)
+	default:
+		return fmt.Errorf("%d issues need manual repair", remaining)
+	}
+}
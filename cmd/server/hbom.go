@@ -0,0 +1,327 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/google/uuid"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+const (
+	// hbomSpecVersion is the CycloneDX version of HBOM documents
+	hbomSpecVersion = "1.6"
+
+	// hbomMediaType is the content type of HBOM documents
+	hbomMediaType = "application/vnd.cyclonedx+json; version=" + hbomSpecVersion
+
+	// hbomPropertyPrefix namespaces the Device fields CycloneDX has no field for
+	hbomPropertyPrefix = "openchami:inventory:"
+)
+
+// hbomDocument is a CycloneDX BOM of Devices
+type hbomDocument struct {
+	BOMFormat    string           `json:"bomFormat"`
+	SpecVersion  string           `json:"specVersion"`
+	SerialNumber string           `json:"serialNumber"`
+	Version      int              `json:"version"`
+	Metadata     hbomMetadata     `json:"metadata"`
+	Components   []*hbomComponent `json:"components"`
+}
+
+type hbomMetadata struct {
+	Timestamp time.Time      `json:"timestamp"`
+	Tools     hbomTools      `json:"tools"`
+	Component *hbomComponent `json:"component,omitempty"`
+}
+
+type hbomTools struct {
+	Components []*hbomComponent `json:"components"`
+}
+
+// hbomComponent is a CycloneDX component: a Device, or the firmware on one
+type hbomComponent struct {
+	Type         string           `json:"type"`
+	BOMRef       string           `json:"bom-ref,omitempty"`
+	Manufacturer *hbomEntity      `json:"manufacturer,omitempty"`
+	Name         string           `json:"name"`
+	Version      string           `json:"version,omitempty"`
+	Description  string           `json:"description,omitempty"`
+	Properties   []hbomProperty   `json:"properties,omitempty"`
+	Components   []*hbomComponent `json:"components,omitempty"`
+}
+
+type hbomEntity struct {
+	Name string `json:"name"`
+}
+
+type hbomProperty struct {
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+// firmwareKeyWord matches the words of a property key that mark it as a firmware version
+var firmwareKeyWord = regexp.MustCompile(`^(firmware|fw|bios|uefi)$`)
+
+// GetDeviceHBOM handles GET /devices/{uid}/hbom: a CycloneDX hardware BOM
+// of the Device, with everything below it nested as its parts.
+func GetDeviceHBOM(w http.ResponseWriter, r *http.Request) {
+	root, ok := loadHierarchyRoot(w, r)
+	if !ok {
+		return
+	}
+
+	visited := map[string]bool{root.GetUID(): true}
+	subject, err := deviceComponentTree(r.Context(), root, loadChildren, visited)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load children: %w", err))
+		return
+	}
+
+	// The Device is the subject of the BOM, keeping its firmware; the
+	// Devices below it are the BOM's components
+	doc := newHBOM()
+	doc.Metadata.Component = subject
+	var firmware []*hbomComponent
+	for _, component := range subject.Components {
+		if component.Type == "firmware" {
+			firmware = append(firmware, component)
+		} else {
+			doc.Components = append(doc.Components, component)
+		}
+	}
+	subject.Components = firmware
+	respondHBOM(w, doc, root.GetName())
+}
+
+// GetHBOM handles GET /hbom: a CycloneDX hardware BOM of every Device, with
+// each root Device's descendants nested as its parts.
+func GetHBOM(w http.ResponseWriter, r *http.Request) {
+	devices, err := storage.LoadAllDevices(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load devices: %w", err))
+		return
+	}
+	sort.SliceStable(devices, func(i, j int) bool {
+		if devices[i].GetName() != devices[j].GetName() {
+			return devices[i].GetName() < devices[j].GetName()
+		}
+		return devices[i].GetUID() < devices[j].GetUID()
+	})
+
+	byUID := make(map[string]*device.Device, len(devices))
+	for _, dev := range devices {
+		byUID[dev.GetUID()] = dev
+	}
+	childrenOf := make(map[string][]*device.Device)
+	for _, dev := range devices {
+		if _, ok := byUID[dev.Spec.ParentID]; ok {
+			childrenOf[dev.Spec.ParentID] = append(childrenOf[dev.Spec.ParentID], dev)
+		}
+	}
+	children := func(_ context.Context, uid string) ([]*device.Device, error) {
+		return childrenOf[uid], nil
+	}
+
+	doc := newHBOM()
+	visited := make(map[string]bool, len(devices))
+	add := func(dev *device.Device) {
+		visited[dev.GetUID()] = true
+		component, _ := deviceComponentTree(r.Context(), dev, children, visited)
+		doc.Components = append(doc.Components, component)
+	}
+	for _, dev := range devices {
+		if _, ok := byUID[dev.Spec.ParentID]; !ok {
+			add(dev)
+		}
+	}
+	// Devices in a parent cycle have no root; list them at the top rather than drop them
+	for _, dev := range devices {
+		if !visited[dev.GetUID()] {
+			add(dev)
+		}
+	}
+	respondHBOM(w, doc, "cluster")
+}
+
+// newHBOM returns an HBOM document with no components
+func newHBOM() *hbomDocument {
+	return &hbomDocument{
+		BOMFormat:    "CycloneDX",
+		SpecVersion:  hbomSpecVersion,
+		SerialNumber: "urn:uuid:" + uuid.NewString(),
+		Version:      1,
+		Metadata: hbomMetadata{
+			Timestamp: time.Now().UTC().Truncate(time.Second),
+			Tools: hbomTools{Components: []*hbomComponent{
+				{Type: "application", Name: "inventory-api"},
+			}},
+		},
+		Components: []*hbomComponent{},
+	}
+}
+
+// respondHBOM writes an HBOM document as a download named after name
+func respondHBOM(w http.ResponseWriter, doc *hbomDocument, name string) {
+	w.Header().Set("Content-Type", hbomMediaType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "hbom-"+name+".cdx.json"))
+	w.WriteHeader(http.StatusOK)
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.Encode(doc)
+}
+
+// deviceComponentTree returns the component of dev with the components of
+// the Devices below it nested inside, skipping any already visited
+func deviceComponentTree(
+	ctx context.Context,
+	dev *device.Device,
+	children func(ctx context.Context, uid string) ([]*device.Device, error),
+	visited map[string]bool,
+) (*hbomComponent, error) {
+	component := newDeviceComponent(dev)
+	kids, err := children(ctx, dev.GetUID())
+	if err != nil {
+		return nil, err
+	}
+	for _, child := range kids {
+		if visited[child.GetUID()] {
+			continue // a parent cycle; don't loop forever
+		}
+		visited[child.GetUID()] = true
+		childComponent, err := deviceComponentTree(ctx, child, children, visited)
+		if err != nil {
+			return nil, err
+		}
+		component.Components = append(component.Components, childComponent)
+	}
+	return component, nil
+}
+
+// newDeviceComponent returns the device component of dev, with its firmware
+// versions as nested firmware components
+func newDeviceComponent(dev *device.Device) *hbomComponent {
+	component := &hbomComponent{
+		Type:        "device",
+		BOMRef:      dev.GetUID(),
+		Name:        dev.GetName(),
+		Description: dev.Spec.DeviceType,
+	}
+	if component.Name == "" {
+		component.Name = dev.Spec.SerialNumber
+	}
+	if dev.Spec.Manufacturer != "" {
+		component.Manufacturer = &hbomEntity{Name: dev.Spec.Manufacturer}
+	}
+	for _, field := range []struct{ name, value string }{
+		{"uid", dev.GetUID()},
+		{"deviceType", dev.Spec.DeviceType},
+		{"partNumber", dev.Spec.PartNumber},
+		{"serialNumber", dev.Spec.SerialNumber},
+	} {
+		if field.value != "" {
+			component.Properties = append(component.Properties, hbomProperty{Name: hbomPropertyPrefix + field.name, Value: field.value})
+		}
+	}
+	for _, fw := range deviceFirmware(dev.Spec.Properties) {
+		fw.BOMRef = dev.GetUID() + "/firmware/" + fw.Name
+		component.Components = append(component.Components, fw)
+	}
+	return component
+}
+
+// deviceFirmware returns a firmware component for each string property
+// whose key has a firmware, fw, bios or uefi word, e.g. firmware_version,
+// bios.version or microcode_fw. Object values are looked into, so
+// bmc: {"firmware_version": "2.70"} is bmc.firmware_version.
+func deviceFirmware(properties map[string]json.RawMessage) []*hbomComponent {
+	var firmware []*hbomComponent
+	var walk func(key string, raw json.RawMessage)
+	walk = func(key string, raw json.RawMessage) {
+		var version string
+		if err := json.Unmarshal(raw, &version); err == nil {
+			if version != "" && isFirmwareKey(key) {
+				firmware = append(firmware, &hbomComponent{Type: "firmware", Name: firmwareName(key), Version: version})
+			}
+			return
+		}
+		var object map[string]json.RawMessage
+		if err := json.Unmarshal(raw, &object); err == nil {
+			for sub, raw := range object {
+				walk(key+"."+sub, raw)
+			}
+		}
+	}
+	for key, raw := range properties {
+		walk(key, raw)
+	}
+	sort.Slice(firmware, func(i, j int) bool { return firmware[i].Name < firmware[j].Name })
+	return firmware
+}
+
+// isFirmwareKey reports whether a property key names a firmware version
+func isFirmwareKey(key string) bool {
+	for _, word := range strings.FieldsFunc(strings.ToLower(key), isKeySeparator) {
+		if firmwareKeyWord.MatchString(word) {
+			return true
+		}
+	}
+	return false
+}
+
+// firmwareName is a firmware property key without a trailing version word,
+// e.g. bios for bios.version and bmc.firmware for bmc.firmware_version
+func firmwareName(key string) string {
+	trimmed := strings.TrimRightFunc(strings.TrimSuffix(strings.ToLower(key), "version"), isKeySeparator)
+	if trimmed == "" {
+		return key
+	}
+	return key[:len(trimmed)]
+}
+
+func isKeySeparator(r rune) bool {
+	return r == '.' || r == '_' || r == '-'
+}
+
+// registerHBOMPaths documents the HBOM endpoints
+func registerHBOMPaths(spec *openapi3.T) {
+	response := &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("CycloneDX " + hbomSpecVersion + " JSON document").
+			WithContent(openapi3.Content{
+				hbomMediaType: openapi3.NewMediaType().WithSchema(openapi3.NewObjectSchema()),
+			}),
+	}
+	description := "Devices are device components (bom-ref is the UID) with manufacturer, and part number, serial number and type as openchami:inventory:* properties. Properties holding firmware versions (keys with a firmware, fw, bios or uefi word) become nested firmware components. Child Devices are nested in their parent's components."
+
+	deviceOp := openapi3.NewOperation()
+	deviceOp.OperationID = "getDeviceHBOM"
+	deviceOp.Summary = "Get a CycloneDX hardware BOM of a Device and everything below it"
+	deviceOp.Description = "The Device is metadata.component; the Devices below it are components. " + description
+	deviceOp.Tags = []string{"Device"}
+	deviceOp.Parameters = openapi3.Parameters{{Value: openapi3.NewPathParameter("uid").WithSchema(openapi3.NewStringSchema())}}
+	deviceOp.Responses = openapi3.NewResponses()
+	deviceOp.Responses.Set("200", response)
+	deviceOp.Responses.Set("404", errorResponse())
+
+	cluster := openapi3.NewOperation()
+	cluster.OperationID = "getHBOM"
+	cluster.Summary = "Get a CycloneDX hardware BOM of every Device"
+	cluster.Description = "Each Device without a parent is a top-level component. " + description
+	cluster.Tags = []string{"Device"}
+	cluster.Responses = openapi3.NewResponses()
+	cluster.Responses.Set("200", response)
+	cluster.Responses.Set("500", errorResponse())
+
+	spec.Paths.Set("/devices/{uid}/hbom", &openapi3.PathItem{Get: deviceOp})
+	spec.Paths.Set("/hbom", &openapi3.PathItem{Get: cluster})
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/openchami/fabrica/pkg/resource"
+	"github.com/santhosh-tekuri/jsonschema/v5"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// hbomSchema compiles the CycloneDX 1.6 schema subset in testdata
+func hbomSchema(t *testing.T) *jsonschema.Schema {
+	t.Helper()
+	compiler := jsonschema.NewCompiler()
+	compiler.Draft = jsonschema.Draft7
+	compiler.AssertFormat = true
+	schema, err := compiler.Compile("testdata/cyclonedx-1.6-hbom.schema.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return schema
+}
+
+// checkHBOM validates an HBOM response against the schema and checks the
+// bom-refs are unique, which the schema can't express
+func checkHBOM(t *testing.T, schema *jsonschema.Schema, w *httptest.ResponseRecorder) map[string]any {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	if ct := w.Header().Get("Content-Type"); ct != "application/vnd.cyclonedx+json; version=1.6" {
+		t.Errorf("Content-Type %q", ct)
+	}
+	var doc map[string]any
+	if err := json.Unmarshal(w.Body.Bytes(), &doc); err != nil {
+		t.Fatal(err)
+	}
+	if err := schema.Validate(doc); err != nil {
+		t.Errorf("HBOM doesn't match the CycloneDX 1.6 schema: %#v\n%s", err, w.Body)
+	}
+
+	refs := make(map[string]bool)
+	var walk func(components any)
+	walk = func(components any) {
+		list, _ := components.([]any)
+		for _, c := range list {
+			component := c.(map[string]any)
+			if ref, ok := component["bom-ref"].(string); ok {
+				if refs[ref] {
+					t.Errorf("bom-ref %s is used twice", ref)
+				}
+				refs[ref] = true
+			}
+			walk(component["components"])
+		}
+	}
+	metadata := doc["metadata"].(map[string]any)
+	if subject, ok := metadata["component"]; ok {
+		walk([]any{subject})
+	}
+	walk(doc["components"])
+	return doc
+}
+
+// saveHBOMDevice stores a Device below parentID and returns its UID
+func saveHBOMDevice(t *testing.T, name, deviceType, serial, parentID string, properties string) string {
+	t.Helper()
+	uid, err := resource.GenerateUIDForResource("Device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := &device.Device{
+		Resource: resource.Resource{APIVersion: "v1", Kind: "Device", SchemaVersion: "v1"},
+		Spec: device.DeviceSpec{
+			DeviceType:   deviceType,
+			Manufacturer: "Acme",
+			PartNumber:   "P-" + deviceType,
+			SerialNumber: serial,
+			ParentID:     parentID,
+		},
+	}
+	if properties != "" {
+		if err := json.Unmarshal([]byte(properties), &dev.Spec.Properties); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dev.Metadata.Initialize(name, uid)
+	if err := storage.SaveDevice(context.Background(), dev); err != nil {
+		t.Fatal(err)
+	}
+	return uid
+}
+
+// TestHBOMSchema checks that device and cluster HBOMs are valid CycloneDX 1.6
+// documents, with Devices nested by parent and firmware as components.
+func TestHBOMSchema(t *testing.T) {
+	schema := hbomSchema(t)
+	initTestStorage(t)
+
+	rack := saveHBOMDevice(t, "rack-1", "Rack", "R1", "", "")
+	node := saveHBOMDevice(t, "node-1", "Node", "N1", rack,
+		`{"bios_version":"2.1.0","bmc":{"firmware_version":"1.70","ip":"10.0.0.2"},"speedMHz":3200}`)
+	saveHBOMDevice(t, "dimm-1", "DIMM", "D1", node, "")
+	saveHBOMDevice(t, "dimm-2", "DIMM", "", node, `{"fw":"A1"}`)
+	// A parent cycle, which has no root
+	loopA := saveHBOMDevice(t, "loop-a", "Node", "LA", "", "")
+	loopB := saveHBOMDevice(t, "loop-b", "Node", "LB", loopA, "")
+	dev, err := storage.LoadDevice(context.Background(), loopA)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev.Spec.ParentID = loopB
+	if err := storage.SaveDevice(context.Background(), dev); err != nil {
+		t.Fatal(err)
+	}
+
+	w := httptest.NewRecorder()
+	GetHBOM(w, httptest.NewRequest(http.MethodGet, "/hbom", nil))
+	cluster := checkHBOM(t, schema, w)
+	body := w.Body.String()
+	for _, want := range []string{`"name": "bios"`, `"version": "2.1.0"`, `"name": "bmc.firmware"`, `"name": "fw"`, `"loop-a"`, `"loop-b"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("cluster HBOM has no %s", want)
+		}
+	}
+	if top, _ := cluster["components"].([]any); len(top) != 2 {
+		t.Errorf("%d top-level components, want rack-1 and the cycle", len(top))
+	}
+
+	r := httptest.NewRequest(http.MethodGet, "/devices/"+node+"/hbom", nil)
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("uid", node)
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx))
+	w = httptest.NewRecorder()
+	GetDeviceHBOM(w, r)
+	doc := checkHBOM(t, schema, w)
+	subject := doc["metadata"].(map[string]any)["component"].(map[string]any)
+	if subject["bom-ref"] != node || subject["type"] != "device" {
+		t.Errorf("subject component %v", subject)
+	}
+	if parts, _ := doc["components"].([]any); len(parts) != 2 {
+		t.Errorf("%d components below node-1, want its two DIMMs", len(parts))
+	}
+
+	// The schema does catch what CycloneDX doesn't allow
+	for valid, invalid := range map[string]string{
+		`"type": "device"`:         `"type": "server"`,
+		`"bomFormat": "CycloneDX"`: `"bomFormat": "SPDX"`,
+		`"name": "bios"`:           `"name": "bios", "sha": "x"`,
+	} {
+		var doc any
+		if err := json.Unmarshal([]byte(strings.Replace(body, valid, invalid, 1)), &doc); err != nil {
+			t.Fatal(err)
+		}
+		if schema.Validate(doc) == nil {
+			t.Errorf("schema accepts %s", invalid)
+		}
+	}
+}
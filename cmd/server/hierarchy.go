@@ -0,0 +1,225 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// DeviceTreeNode is a Device with its descendants nested below it
+type DeviceTreeNode struct {
+	Device   *device.Device    `json:"device"`
+	Children []*DeviceTreeNode `json:"children,omitempty"`
+}
+
+// GetDeviceChildren returns the Devices whose parent is the Device
+func GetDeviceChildren(w http.ResponseWriter, r *http.Request) {
+	root, ok := loadHierarchyRoot(w, r)
+	if !ok {
+		return
+	}
+	children, err := loadChildren(r.Context(), root.GetUID())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load children: %w", err))
+		return
+	}
+	respondJSON(w, http.StatusOK, children)
+}
+
+// GetDeviceDescendants returns every Device below the Device, breadth first,
+// down to ?depth= levels (all levels if unset)
+func GetDeviceDescendants(w http.ResponseWriter, r *http.Request) {
+	depth, err := parseDepth(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	root, ok := loadHierarchyRoot(w, r)
+	if !ok {
+		return
+	}
+
+	var descendants []*device.Device
+	visited := map[string]bool{root.GetUID(): true}
+	level := []*device.Device{root}
+	for d := 1; len(level) > 0 && (depth == 0 || d <= depth); d++ {
+		var next []*device.Device
+		for _, parent := range level {
+			children, err := loadChildren(r.Context(), parent.GetUID())
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load children: %w", err))
+				return
+			}
+			for _, child := range children {
+				if visited[child.GetUID()] {
+					continue // a parent cycle; don't loop forever
+				}
+				visited[child.GetUID()] = true
+				next = append(next, child)
+			}
+		}
+		descendants = append(descendants, next...)
+		level = next
+	}
+	if descendants == nil {
+		descendants = []*device.Device{}
+	}
+	respondJSON(w, http.StatusOK, descendants)
+}
+
+// GetDeviceAncestors returns the Device's parent, its parent's parent and so
+// on up to the root
+func GetDeviceAncestors(w http.ResponseWriter, r *http.Request) {
+	dev, ok := loadHierarchyRoot(w, r)
+	if !ok {
+		return
+	}
+
+	ancestors := []*device.Device{}
+	visited := map[string]bool{dev.GetUID(): true}
+	for parentID := dev.Spec.ParentID; parentID != "" && !visited[parentID]; {
+		visited[parentID] = true
+		parent, err := storage.LoadDevice(r.Context(), parentID)
+		if err != nil {
+			break // a dangling parent link ends the chain
+		}
+		ancestors = append(ancestors, parent)
+		parentID = parent.Spec.ParentID
+	}
+	respondJSON(w, http.StatusOK, ancestors)
+}
+
+// GetDeviceTree returns the Device with its descendants nested below it,
+// down to ?depth= levels (all levels if unset)
+func GetDeviceTree(w http.ResponseWriter, r *http.Request) {
+	depth, err := parseDepth(r)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	root, ok := loadHierarchyRoot(w, r)
+	if !ok {
+		return
+	}
+
+	tree := &DeviceTreeNode{Device: root}
+	visited := map[string]bool{root.GetUID(): true}
+	level := []*DeviceTreeNode{tree}
+	for d := 1; len(level) > 0 && (depth == 0 || d <= depth); d++ {
+		var next []*DeviceTreeNode
+		for _, node := range level {
+			children, err := loadChildren(r.Context(), node.Device.GetUID())
+			if err != nil {
+				respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load children: %w", err))
+				return
+			}
+			for _, child := range children {
+				if visited[child.GetUID()] {
+					continue
+				}
+				visited[child.GetUID()] = true
+				childNode := &DeviceTreeNode{Device: child}
+				node.Children = append(node.Children, childNode)
+				next = append(next, childNode)
+			}
+		}
+		level = next
+	}
+	respondJSON(w, http.StatusOK, tree)
+}
+
+// loadHierarchyRoot loads the Device named in the URL, writing a 404 if it doesn't exist
+func loadHierarchyRoot(w http.ResponseWriter, r *http.Request) (*device.Device, bool) {
+	uid := chi.URLParam(r, "uid")
+	dev, err := storage.LoadDevice(r.Context(), uid)
+	if err != nil {
+		respondError(w, http.StatusNotFound, fmt.Errorf("Device not found: %w", err))
+		return nil, false
+	}
+	return dev, true
+}
+
+// loadChildren loads a Device's children from the index, ordered by name
+func loadChildren(ctx context.Context, uid string) ([]*device.Device, error) {
+	children, err := storage.LoadDeviceChildren(ctx, uid)
+	if err != nil {
+		return nil, err
+	}
+	sort.SliceStable(children, func(i, j int) bool {
+		return children[i].GetName() < children[j].GetName()
+	})
+	return children, nil
+}
+
+// parseDepth reads ?depth= (0 or unset: unlimited)
+func parseDepth(r *http.Request) (int, error) {
+	value := r.URL.Query().Get("depth")
+	if value == "" {
+		return 0, nil
+	}
+	depth, err := strconv.Atoi(value)
+	if err != nil || depth < 0 {
+		return 0, fmt.Errorf("invalid depth %q: must be a non-negative integer", value)
+	}
+	return depth, nil
+}
+
+// registerDeviceHierarchyPaths documents the Device hierarchy endpoints
+func registerDeviceHierarchyPaths(spec *openapi3.T) {
+	uidParam := openapi3.NewPathParameter("uid").WithSchema(openapi3.NewStringSchema())
+	depthParam := openapi3.NewQueryParameter("depth").
+		WithDescription("Levels below the device to include (0 or unset: all)").
+		WithSchema(openapi3.NewIntegerSchema().WithMin(0))
+
+	arraySchema := openapi3.NewArraySchema()
+	arraySchema.Items = &openapi3.SchemaRef{Ref: "#/components/schemas/Device"}
+	listResponse := &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Successful response").
+			WithJSONSchemaRef(&openapi3.SchemaRef{Value: arraySchema}),
+	}
+
+	childrenSchema := openapi3.NewArraySchema()
+	childrenSchema.Items = &openapi3.SchemaRef{Ref: "#/components/schemas/DeviceTreeNode"}
+	treeSchema := openapi3.NewObjectSchema().
+		WithPropertyRef("device", &openapi3.SchemaRef{Ref: "#/components/schemas/Device"}).
+		WithProperty("children", childrenSchema)
+	spec.Components.Schemas["DeviceTreeNode"] = &openapi3.SchemaRef{Value: treeSchema}
+
+	operation := func(id, summary string, params openapi3.Parameters, response *openapi3.ResponseRef) *openapi3.PathItem {
+		op := openapi3.NewOperation()
+		op.OperationID = id
+		op.Summary = summary
+		op.Tags = []string{"Device"}
+		op.Parameters = append(openapi3.Parameters{{Value: uidParam}}, params...)
+		op.Responses = openapi3.NewResponses()
+		op.Responses.Set("200", response)
+		op.Responses.Set("404", errorResponse())
+		if len(params) > 0 {
+			op.Responses.Set("400", errorResponse())
+		}
+		return &openapi3.PathItem{Get: op}
+	}
+
+	spec.Paths.Set("/devices/{uid}/children", operation("getDeviceChildren",
+		"List the Devices directly below a Device", nil, listResponse))
+	spec.Paths.Set("/devices/{uid}/descendants", operation("getDeviceDescendants",
+		"List every Device below a Device, breadth first", openapi3.Parameters{{Value: depthParam}}, listResponse))
+	spec.Paths.Set("/devices/{uid}/ancestors", operation("getDeviceAncestors",
+		"List a Device's parent, grandparent and so on up to the root", nil, listResponse))
+	spec.Paths.Set("/devices/{uid}/tree", operation("getDeviceTree",
+		"Get a Device with its descendants as a nested tree", openapi3.Parameters{{Value: depthParam}},
+		&openapi3.ResponseRef{
+			Value: openapi3.NewResponse().
+				WithDescription("Successful response").
+				WithJSONSchemaRef(&openapi3.SchemaRef{Ref: "#/components/schemas/DeviceTreeNode"}),
+		}))
+}
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"slices"
+	"testing"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/openchami/fabrica/pkg/resource"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// saveParentedDevice stores a Device with a serial number and parent, returning its UID
+func saveParentedDevice(t *testing.T, serial, parentID string) string {
+	t.Helper()
+	uid, err := resource.GenerateUIDForResource("Device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := &device.Device{
+		Resource: resource.Resource{APIVersion: "v1", Kind: "Device", SchemaVersion: "v1"},
+		Spec:     device.DeviceSpec{DeviceType: "Rack", SerialNumber: serial, ParentID: parentID},
+	}
+	dev.Metadata.Initialize(serial, uid)
+	if err := storage.SaveDevice(context.Background(), dev); err != nil {
+		t.Fatal(err)
+	}
+	return uid
+}
+
+// getHierarchy calls a hierarchy handler for the Device uid
+func getHierarchy(t *testing.T, handler http.HandlerFunc, uid, query string) *httptest.ResponseRecorder {
+	t.Helper()
+	r := httptest.NewRequest(http.MethodGet, "/devices/"+uid+"?"+query, nil)
+	routeCtx := chi.NewRouteContext()
+	routeCtx.URLParams.Add("uid", uid)
+	r = r.WithContext(context.WithValue(r.Context(), chi.RouteCtxKey, routeCtx))
+	w := httptest.NewRecorder()
+	handler(w, r)
+	return w
+}
+
+// hierarchyNames returns the names of the Devices in a list response
+func hierarchyNames(t *testing.T, w *httptest.ResponseRecorder) []string {
+	t.Helper()
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var devices []*device.Device
+	if err := json.Unmarshal(w.Body.Bytes(), &devices); err != nil {
+		t.Fatal(err)
+	}
+	names := []string{}
+	for _, dev := range devices {
+		names = append(names, dev.GetName())
+	}
+	return names
+}
+
+// TestDeviceHierarchy walks a rack with two nodes and a DIMM through the
+// children, descendants, ancestors and tree endpoints.
+func TestDeviceHierarchy(t *testing.T) {
+	initTestStorage(t)
+	rack := saveParentedDevice(t, "rack", "")
+	nodeB := saveParentedDevice(t, "node-b", rack)
+	nodeA := saveParentedDevice(t, "node-a", rack)
+	dimm := saveParentedDevice(t, "dimm", nodeA)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		uid     string
+		query   string
+		want    []string
+	}{
+		{"children", GetDeviceChildren, rack, "", []string{"node-a", "node-b"}},
+		{"children of a leaf", GetDeviceChildren, dimm, "", []string{}},
+		{"descendants", GetDeviceDescendants, rack, "", []string{"node-a", "node-b", "dimm"}},
+		{"descendants to depth 1", GetDeviceDescendants, rack, "depth=1", []string{"node-a", "node-b"}},
+		{"ancestors", GetDeviceAncestors, dimm, "", []string{"node-a", "rack"}},
+		{"ancestors of a root", GetDeviceAncestors, rack, "", []string{}},
+	}
+	for _, tt := range tests {
+		if got := hierarchyNames(t, getHierarchy(t, tt.handler, tt.uid, tt.query)); !slices.Equal(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	w := getHierarchy(t, GetDeviceTree, rack, "")
+	if w.Code != http.StatusOK {
+		t.Fatalf("tree: status %d: %s", w.Code, w.Body)
+	}
+	var tree DeviceTreeNode
+	if err := json.Unmarshal(w.Body.Bytes(), &tree); err != nil {
+		t.Fatal(err)
+	}
+	if tree.Device.GetUID() != rack || len(tree.Children) != 2 ||
+		tree.Children[0].Device.GetUID() != nodeA || tree.Children[1].Device.GetUID() != nodeB ||
+		len(tree.Children[0].Children) != 1 || tree.Children[0].Children[0].Device.GetUID() != dimm ||
+		len(tree.Children[1].Children) != 0 {
+		t.Errorf("tree: %s", w.Body)
+	}
+
+	if w := getHierarchy(t, GetDeviceTree, rack, "depth=-1"); w.Code != http.StatusBadRequest {
+		t.Errorf("negative depth: status %d, want 400", w.Code)
+	}
+	if w := getHierarchy(t, GetDeviceAncestors, "dev-missing", ""); w.Code != http.StatusNotFound {
+		t.Errorf("unknown device: status %d, want 404", w.Code)
+	}
+}
@@ -0,0 +1,374 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+var (
+	// defaultAnsibleTypes are the Device types Ansible hosts are by default
+	defaultAnsibleTypes = []string{"Node"}
+
+	// defaultPrometheusTypes are the Device types Prometheus targets are by default
+	defaultPrometheusTypes = []string{"Node", "BMC", "NodeBMC"}
+
+	// hostnameProperties and addressProperties are where a Device's hostname
+	// and IP address are looked for, in order
+	hostnameProperties = []string{"hostname", "fqdn"}
+	addressProperties  = []string{"ip_address", "ip", "ipv4_address"}
+)
+
+// invalidNameChars are the characters not allowed in Ansible group and
+// variable names and Prometheus label names
+var invalidNameChars = regexp.MustCompile(`[^A-Za-z0-9_]+`)
+
+// ansibleGroup is a group of an Ansible dynamic inventory
+type ansibleGroup struct {
+	Hosts    []string `json:"hosts,omitempty"`
+	Children []string `json:"children,omitempty"`
+}
+
+// prometheusTargetGroup is one entry of a Prometheus http_sd_config response
+type prometheusTargetGroup struct {
+	Targets []string          `json:"targets"`
+	Labels  map[string]string `json:"labels"`
+}
+
+// GetAnsibleInventory handles GET /integrations/ansible: the Devices as an
+// Ansible dynamic inventory (the JSON an inventory script prints for
+// --list). Hosts are grouped by type (type_<type>), by each label
+// (label_<key>_<value>) and by the properties named in ?groupBy=
+// (property_<key>_<value>), and get their properties in the
+// inventory_properties host variable. Properties never set ansible_*
+// variables (which control how Ansible connects); the only one set is
+// ansible_host, from a property holding a valid address or hostname.
+func GetAnsibleInventory(w http.ResponseWriter, r *http.Request) {
+	devices, ok := integrationDevices(w, r, defaultAnsibleTypes)
+	if !ok {
+		return
+	}
+	groupBy := queryList(r, "groupBy")
+
+	hostvars := make(map[string]map[string]any, len(devices))
+	groups := make(map[string]*ansibleGroup)
+	addHost := func(group, host string) {
+		g, ok := groups[group]
+		if !ok {
+			g = &ansibleGroup{}
+			groups[group] = g
+		}
+		g.Hosts = append(g.Hosts, host)
+	}
+
+	for _, dev := range devices {
+		host := ansibleHostname(dev)
+		if _, taken := hostvars[host]; taken {
+			host = dev.GetUID()
+		}
+
+		vars := map[string]any{
+			"inventory_uid":           dev.GetUID(),
+			"inventory_name":          dev.GetName(),
+			"inventory_device_type":   dev.Spec.DeviceType,
+			"inventory_serial_number": dev.Spec.SerialNumber,
+		}
+		if dev.Spec.Manufacturer != "" {
+			vars["inventory_manufacturer"] = dev.Spec.Manufacturer
+		}
+		if dev.Spec.PartNumber != "" {
+			vars["inventory_part_number"] = dev.Spec.PartNumber
+		}
+		if dev.Spec.ParentID != "" {
+			vars["inventory_parent_uid"] = dev.Spec.ParentID
+		}
+		if len(dev.Metadata.Labels) > 0 {
+			vars["inventory_labels"] = dev.Metadata.Labels
+		}
+		properties := make(map[string]any, len(dev.Spec.Properties))
+		for key, raw := range dev.Spec.Properties {
+			var value any
+			if err := json.Unmarshal(raw, &value); err == nil {
+				properties[key] = value
+			}
+		}
+		if len(properties) > 0 {
+			vars["inventory_properties"] = properties
+		}
+		if address := ansibleHost(dev); address != "" {
+			vars["ansible_host"] = address
+		}
+		hostvars[host] = vars
+
+		addHost("type_"+ansibleName(dev.Spec.DeviceType), host)
+		for key, value := range dev.Metadata.Labels {
+			addHost("label_"+ansibleName(key)+"_"+ansibleName(value), host)
+		}
+		for _, key := range groupBy {
+			if value := stringProperty(dev, key); value != "" {
+				addHost("property_"+ansibleName(key)+"_"+ansibleName(value), host)
+			}
+		}
+	}
+
+	inventory := map[string]any{
+		"_meta": map[string]any{"hostvars": hostvars},
+	}
+	all := &ansibleGroup{Children: []string{"ungrouped"}}
+	for name, group := range groups {
+		sort.Strings(group.Hosts)
+		inventory[name] = group
+		all.Children = append(all.Children, name)
+	}
+	sort.Strings(all.Children[1:])
+	inventory["all"] = all
+	respondJSON(w, http.StatusOK, inventory)
+}
+
+// GetPrometheusTargets handles GET /integrations/prometheus/sd: a target
+// group per Device with a hostname or IP address, for Prometheus'
+// http_sd_config. ?port= is appended to targets without one. Devices are
+// described in __meta_inventory_* labels, available for relabeling.
+func GetPrometheusTargets(w http.ResponseWriter, r *http.Request) {
+	port := r.URL.Query().Get("port")
+	if port != "" {
+		if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+			respondError(w, http.StatusBadRequest, fmt.Errorf("invalid port %q: must be 1-65535", port))
+			return
+		}
+	}
+	devices, ok := integrationDevices(w, r, defaultPrometheusTypes)
+	if !ok {
+		return
+	}
+
+	groups := []prometheusTargetGroup{}
+	for _, dev := range devices {
+		target := firstStringProperty(dev, hostnameProperties)
+		if target == "" {
+			target = firstStringProperty(dev, addressProperties)
+		}
+		if target == "" {
+			continue // nothing to scrape
+		}
+		if port != "" {
+			if _, _, err := net.SplitHostPort(target); err != nil {
+				target = net.JoinHostPort(target, port)
+			}
+		}
+
+		labels := map[string]string{
+			"__meta_inventory_uid":           dev.GetUID(),
+			"__meta_inventory_name":          dev.GetName(),
+			"__meta_inventory_device_type":   dev.Spec.DeviceType,
+			"__meta_inventory_serial_number": dev.Spec.SerialNumber,
+		}
+		if dev.Spec.Manufacturer != "" {
+			labels["__meta_inventory_manufacturer"] = dev.Spec.Manufacturer
+		}
+		if dev.Spec.PartNumber != "" {
+			labels["__meta_inventory_part_number"] = dev.Spec.PartNumber
+		}
+		if dev.Spec.ParentID != "" {
+			labels["__meta_inventory_parent_uid"] = dev.Spec.ParentID
+		}
+		for key, value := range dev.Metadata.Labels {
+			labels["__meta_inventory_label_"+ansibleName(key)] = value
+		}
+		for key := range dev.Spec.Properties {
+			if value := stringProperty(dev, key); value != "" {
+				labels["__meta_inventory_property_"+ansibleName(key)] = value
+			}
+		}
+		groups = append(groups, prometheusTargetGroup{Targets: []string{target}, Labels: labels})
+	}
+	respondJSON(w, http.StatusOK, groups)
+}
+
+// integrationDevices lists the Devices matching the request's selectors and
+// ?deviceType= (comma-separated; defaultTypes if unset, "*" for any),
+// ordered by name. It writes the error response if it fails.
+func integrationDevices(w http.ResponseWriter, r *http.Request, defaultTypes []string) ([]*device.Device, bool) {
+	filter, err := parseListFilter(r, isDeviceField)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return nil, false
+	}
+	devices, _, _, err := listDevices(r.Context(), filter, &listPage{sortBy: sortByName})
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load devices: %w", err))
+		return nil, false
+	}
+
+	types := queryList(r, "deviceType")
+	if len(types) == 0 {
+		types = defaultTypes
+	}
+	if slices.Contains(types, "*") {
+		return devices, true
+	}
+	matching := devices[:0]
+	for _, dev := range devices {
+		if slices.Contains(types, dev.Spec.DeviceType) {
+			matching = append(matching, dev)
+		}
+	}
+	return matching, true
+}
+
+// queryList returns the values of a query parameter given as a
+// comma-separated list, repeated, or both
+func queryList(r *http.Request, name string) []string {
+	var list []string
+	for _, value := range r.URL.Query()[name] {
+		for _, item := range strings.Split(value, ",") {
+			if item = strings.TrimSpace(item); item != "" {
+				list = append(list, item)
+			}
+		}
+	}
+	return list
+}
+
+// ansibleHostname is the inventory hostname of a Device: its hostname
+// property (if it is a valid hostname), or its name
+func ansibleHostname(dev *device.Device) string {
+	if hostname := firstStringProperty(dev, hostnameProperties); validHostname(hostname) {
+		return hostname
+	}
+	if dev.GetName() != "" {
+		return dev.GetName()
+	}
+	return dev.GetUID()
+}
+
+// ansibleHost is the ansible_host of a Device: the first of its
+// ansible_host, ip_address, ip and ipv4_address properties that is an IP
+// address or a valid hostname, or ""
+func ansibleHost(dev *device.Device) string {
+	for _, key := range append([]string{"ansible_host"}, addressProperties...) {
+		if value := stringProperty(dev, key); net.ParseIP(value) != nil || validHostname(value) {
+			return value
+		}
+	}
+	return ""
+}
+
+// validHostname reports whether s is a hostname or FQDN as RFC 1123 allows:
+// dot-separated labels of 1 to 63 letters, digits and hyphens, not starting
+// or ending with a hyphen, 253 characters at most
+func validHostname(s string) bool {
+	if s == "" || len(s) > 253 {
+		return false
+	}
+	for _, label := range strings.Split(s, ".") {
+		if len(label) == 0 || len(label) > 63 || label[0] == '-' || label[len(label)-1] == '-' {
+			return false
+		}
+		for _, c := range label {
+			if !(c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' || c == '-') {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// ansibleName makes s a valid Ansible group or variable name (and
+// Prometheus label name): runs of other characters become _
+func ansibleName(s string) string {
+	name := strings.Trim(invalidNameChars.ReplaceAllString(s, "_"), "_")
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		name = "_" + name
+	}
+	return name
+}
+
+// firstStringProperty returns the first of keys that is a non-empty string
+// property of a Device, or ""
+func firstStringProperty(dev *device.Device, keys []string) string {
+	for _, key := range keys {
+		if value := stringProperty(dev, key); value != "" {
+			return value
+		}
+	}
+	return ""
+}
+
+// stringProperty returns a string property of a Device, or ""
+func stringProperty(dev *device.Device, key string) string {
+	var value string
+	if raw, ok := dev.Spec.Properties[key]; ok {
+		json.Unmarshal(raw, &value)
+	}
+	return value
+}
+
+// registerIntegrationPaths documents the integration endpoints
+func registerIntegrationPaths(spec *openapi3.T) {
+	filterParams := func(defaultTypes []string) openapi3.Parameters {
+		params := openapi3.Parameters{
+			{Value: openapi3.NewQueryParameter("deviceType").
+				WithDescription("Device types to include, comma-separated (default " + strings.Join(defaultTypes, ",") + "; * for all)").
+				WithSchema(openapi3.NewStringSchema())},
+		}
+		for _, param := range listParameters("spec.deviceType, spec.manufacturer, spec.partNumber, spec.serialNumber, spec.parentID, spec.parentSerialNumber, status.phase, metadata.name, metadata.uid and spec.properties.<key>") {
+			if name := param.Value.Name; name == "labelSelector" || name == "fieldSelector" {
+				params = append(params, param)
+			}
+		}
+		return params
+	}
+
+	ansible := openapi3.NewOperation()
+	ansible.OperationID = "getAnsibleInventory"
+	ansible.Summary = "Get the Devices as an Ansible dynamic inventory"
+	ansible.Description = "The JSON an inventory script prints for --list. Hosts are named by their hostname property (or name) and grouped as type_<deviceType>, label_<key>_<value> and, for the properties in groupBy, property_<key>_<value>. Host variables are inventory_* fields, with the Device's properties in inventory_properties; ansible_host is taken from an ansible_host, ip_address, ip or ipv4_address property holding an IP address or valid hostname, and no other ansible_* variable is set."
+	ansible.Tags = []string{"Integrations"}
+	ansible.Parameters = append(filterParams(defaultAnsibleTypes), &openapi3.ParameterRef{
+		Value: openapi3.NewQueryParameter("groupBy").
+			WithDescription("Properties to group hosts by, comma-separated").
+			WithSchema(openapi3.NewStringSchema()),
+	})
+	ansible.Responses = openapi3.NewResponses()
+	ansible.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Ansible inventory").
+			WithJSONSchema(openapi3.NewObjectSchema()),
+	})
+	ansible.Responses.Set("400", errorResponse())
+
+	targetGroup := openapi3.NewObjectSchema().
+		WithProperty("targets", openapi3.NewArraySchema().WithItems(openapi3.NewStringSchema())).
+		WithProperty("labels", openapi3.NewObjectSchema().WithAdditionalProperties(openapi3.NewStringSchema()))
+	prometheus := openapi3.NewOperation()
+	prometheus.OperationID = "getPrometheusTargets"
+	prometheus.Summary = "Get scrape targets for Prometheus HTTP service discovery"
+	prometheus.Description = "A target group per Device with a hostname, fqdn, ip_address or ip property, for http_sd_config. Labels are __meta_inventory_* (uid, name, device_type, serial_number, manufacturer, part_number, parent_uid, label_<key>, property_<key>) for relabeling."
+	prometheus.Tags = []string{"Integrations"}
+	prometheus.Parameters = append(filterParams(defaultPrometheusTypes), &openapi3.ParameterRef{
+		Value: openapi3.NewQueryParameter("port").
+			WithDescription("Port added to targets that have none").
+			WithSchema(openapi3.NewIntegerSchema().WithMin(1).WithMax(65535)),
+	})
+	prometheus.Responses = openapi3.NewResponses()
+	prometheus.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Target groups").
+			WithJSONSchema(openapi3.NewArraySchema().WithItems(targetGroup)),
+	})
+	prometheus.Responses.Set("400", errorResponse())
+
+	spec.Paths.Set("/integrations/ansible", &openapi3.PathItem{Get: ansible})
+	spec.Paths.Set("/integrations/prometheus/sd", &openapi3.PathItem{Get: prometheus})
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+// TestAnsibleInventoryHostvars checks that properties stay in
+// inventory_properties and can't set ansible_* connection variables.
+func TestAnsibleInventoryHostvars(t *testing.T) {
+	initTestStorage(t)
+	saveTestDevice(t, "node-1", "Node", "SN1", map[string]string{
+		"hostname":     "node1.example.com",
+		"ansible_user": "root",
+		"ansible_host": "10.0.0.5 -o ProxyCommand=sh",
+		"ip_address":   "10.0.0.7",
+		"rack":         "r12",
+	})
+	saveTestDevice(t, "node-2", "Node", "SN2", map[string]string{
+		"hostname": "bad host;name",
+	})
+
+	w := httptest.NewRecorder()
+	GetAnsibleInventory(w, httptest.NewRequest(http.MethodGet, "/integrations/ansible", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("status %d: %s", w.Code, w.Body)
+	}
+	var inventory struct {
+		Meta struct {
+			Hostvars map[string]map[string]any `json:"hostvars"`
+		} `json:"_meta"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &inventory); err != nil {
+		t.Fatal(err)
+	}
+
+	vars, ok := inventory.Meta.Hostvars["node1.example.com"]
+	if !ok {
+		t.Fatalf("no host node1.example.com in %v", inventory.Meta.Hostvars)
+	}
+	for key := range vars {
+		if key != "ansible_host" && strings.HasPrefix(key, "ansible_") {
+			t.Errorf("host variable %s set from a property", key)
+		}
+	}
+	if vars["ansible_host"] != "10.0.0.7" {
+		t.Errorf("ansible_host = %v, want 10.0.0.7", vars["ansible_host"])
+	}
+	properties, _ := vars["inventory_properties"].(map[string]any)
+	if properties["ansible_user"] != "root" || properties["rack"] != "r12" {
+		t.Errorf("inventory_properties = %v", vars["inventory_properties"])
+	}
+
+	// An invalid hostname property isn't used as the inventory hostname
+	if _, ok := inventory.Meta.Hostvars["node-2"]; !ok {
+		t.Errorf("node-2 is not named by its device name: %v", inventory.Meta.Hostvars)
+	}
+}
+
+func TestValidHostname(t *testing.T) {
+	for _, tt := range []struct {
+		hostname string
+		want     bool
+	}{
+		{"node1", true},
+		{"node-1.rack12.example.com", true},
+		{"1node", true},
+		{"", false},
+		{"-node", false},
+		{"node-", false},
+		{"node..example", false},
+		{"node_1", false},
+		{"node 1", false},
+		{"node1\nhost-record=evil", false},
+		{"node\"1", false},
+		{"a23456789012345678901234567890123456789012345678901234567890123", true},
+		{"a234567890123456789012345678901234567890123456789012345678901234", false},
+	} {
+		if got := validHostname(tt.hostname); got != tt.want {
+			t.Errorf("validHostname(%q) = %v, want %v", tt.hostname, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"errors"
+
+	"github.com/openchami/fabrica/pkg/resource"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+)
+
+// listDevices returns the page of devices a list request asks for, with the
+// number of matching devices and the continue token for the next page
+func listDevices(ctx context.Context, filter *listFilter, page *listPage) ([]*device.Device, int, string, error) {
+	return listResources(ctx, filter, page, storage.QueryDevices, func(ctx context.Context) ([]*device.Device, error) {
+		devices, err := storage.LoadAllDevices(ctx)
+		return filterDevices(devices, filter), err
+	}, func(obj *device.Device) *resource.Metadata { return &obj.Metadata })
+}
+
+// listDiscoverySnapshots is listDevices for discoverysnapshots
+func listDiscoverySnapshots(ctx context.Context, filter *listFilter, page *listPage) ([]*discoverysnapshot.DiscoverySnapshot, int, string, error) {
+	return listResources(ctx, filter, page, storage.QueryDiscoverySnapshots, func(ctx context.Context) ([]*discoverysnapshot.DiscoverySnapshot, error) {
+		snapshots, err := storage.LoadAllDiscoverySnapshots(ctx)
+		return filterDiscoverySnapshots(snapshots, filter), err
+	}, func(obj *discoverysnapshot.DiscoverySnapshot) *resource.Metadata { return &obj.Metadata })
+}
+
+// listResources runs a list request in the storage backend when it supports
+// queries (e.g. SQLite), and otherwise loads every resource and filters,
+// sorts and pages them in memory. Both give the same results and tokens.
+func listResources[T any](
+	ctx context.Context,
+	filter *listFilter,
+	page *listPage,
+	query func(ctx context.Context, q storage.ListQuery) ([]*T, *storage.ListResult, error),
+	loadFiltered func(ctx context.Context) ([]*T, error),
+	metadata func(obj *T) *resource.Metadata,
+) ([]*T, int, string, error) {
+	q := storage.ListQuery{
+		Labels:     filter.labels,
+		Fields:     filter.fields,
+		SortBy:     page.sortBy,
+		Descending: page.descending,
+		Limit:      page.limit,
+	}
+	if page.after != nil {
+		q.After = &storage.ListCursor{Name: page.after.Name, Time: page.after.Time, UID: page.after.UID}
+	}
+
+	items, result, err := query(ctx, q)
+	switch {
+	case err == nil:
+		next := ""
+		if result.More && len(items) > 0 {
+			last := page.key(metadata(items[len(items)-1]))
+			last.Query = page.query
+			next = encodeContinueToken(&last)
+		}
+		return items, result.Total, next, nil
+	case !errors.Is(err, storage.ErrUnsupportedQuery):
+		return nil, 0, "", err
+	}
+
+	all, err := loadFiltered(ctx)
+	if err != nil {
+		return nil, 0, "", err
+	}
+	items, next := paginate(all, page, metadata)
+	return items, len(all), next, nil
+}
@@ -0,0 +1,229 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/openchami/fabrica/pkg/resource"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// initSQLiteTestStorage is initTestStorage with an in-memory SQLite database,
+// whose lists are filtered, sorted and paged in SQL
+func initSQLiteTestStorage(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	if err := storage.InitSQLiteBackend(ctx, ":memory:"); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.InitVersioning(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.InitIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { storage.Backend.Close() })
+}
+
+// saveListTestDevices stores the same twelve Devices in every backend, with
+// repeated names and timestamps so sorting has ties to break
+func saveListTestDevices(t *testing.T) {
+	t.Helper()
+	base := time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i := 1; i <= 12; i++ {
+		dev := &device.Device{
+			Resource: resource.Resource{APIVersion: "v1", Kind: "Device", SchemaVersion: "v1"},
+			Spec: device.DeviceSpec{
+				DeviceType:   []string{"Node", "DIMM", "CPU"}[i%3],
+				SerialNumber: fmt.Sprintf("SN%02d", i),
+				Properties:   map[string]json.RawMessage{},
+			},
+		}
+		dev.Metadata.UID = fmt.Sprintf("dev-%02d", 13-i) // UID order differs from creation order
+		dev.Metadata.Name = fmt.Sprintf("node-%d", i%4)
+		dev.Metadata.CreatedAt = base.Add(time.Duration(i/3) * time.Minute)
+		dev.Metadata.UpdatedAt = base.Add(time.Duration(12-i) * time.Second)
+		if i%4 != 0 {
+			dev.Metadata.Labels = map[string]string{"rack": fmt.Sprintf("r%d", i%3)}
+			if i%2 == 0 {
+				dev.Metadata.Labels["env"] = "prod"
+			}
+		}
+		if i%2 == 1 {
+			dev.Spec.ParentID = "dev-01"
+			dev.Spec.Properties["speedMHz"] = json.RawMessage(`3200`)
+		}
+		if i%3 == 0 {
+			dev.Spec.Properties["hostname"] = json.RawMessage(fmt.Sprintf(`"node%d"`, i))
+		}
+		if i == 5 {
+			dev.Spec.Properties["speedMHz"] = json.RawMessage(` 4800 `)
+			dev.Spec.Properties["hostname"] = json.RawMessage(`null`)
+		}
+		if err := storage.SaveDevice(context.Background(), dev); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// listResult is what a list request returned: every page's UIDs, the
+// continue tokens that led to the next page and the total count
+type listResult struct {
+	Pages  [][]string
+	Tokens []string
+	Total  int
+}
+
+// listAllPages lists devices for query, following continue tokens when limit is set
+func listAllPages(t *testing.T, query url.Values, limit int) listResult {
+	t.Helper()
+	var result listResult
+	token := ""
+	for {
+		q := url.Values{}
+		for key, values := range query {
+			q[key] = values
+		}
+		if limit > 0 {
+			q.Set("limit", strconv.Itoa(limit))
+		}
+		if token != "" {
+			q.Set("continue", token)
+		}
+		w := httptest.NewRecorder()
+		GetDevices(w, httptest.NewRequest(http.MethodGet, "/devices?"+q.Encode(), nil))
+		if w.Code != http.StatusOK {
+			t.Fatalf("%s: status %d: %s", q.Encode(), w.Code, w.Body)
+		}
+
+		var page DevicesResponse
+		if limit > 0 {
+			if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+				t.Fatal(err)
+			}
+		} else if err := json.Unmarshal(w.Body.Bytes(), &page.Items); err != nil {
+			t.Fatal(err)
+		}
+		uids := []string{}
+		for _, d := range page.Items {
+			uids = append(uids, d.Metadata.UID)
+		}
+		result.Pages = append(result.Pages, uids)
+		result.Total, _ = strconv.Atoi(w.Header().Get(totalCountHeader))
+		if page.Continue == "" || len(result.Pages) > 20 { // Twelve devices never take more pages
+			return result
+		}
+		result.Tokens = append(result.Tokens, page.Continue)
+		token = page.Continue
+	}
+}
+
+// TestListQueryParity checks that the SQL path (SQLite) and the in-memory
+// path (file backend) select, sort and page lists the same way, and hand out
+// the same continue tokens.
+func TestListQueryParity(t *testing.T) {
+	filters := []url.Values{
+		{},
+		{"labelSelector": {"rack=r1"}},
+		{"labelSelector": {"rack!=r1"}},
+		{"labelSelector": {"rack in (r0,r2)"}},
+		{"labelSelector": {"rack notin (r2)"}},
+		{"labelSelector": {"env"}},
+		{"labelSelector": {"!env,rack"}},
+		{"fieldSelector": {"spec.deviceType=DIMM"}},
+		{"fieldSelector": {"spec.deviceType!=DIMM,metadata.name=node-1"}},
+		{"fieldSelector": {"spec.parentID="}},
+		{"fieldSelector": {"spec.properties.speedMHz=3200"}},
+		{"fieldSelector": {"spec.properties.speedMHz!=3200"}},
+		{"fieldSelector": {"spec.properties.speedMHz=4800"}},
+		{"fieldSelector": {"spec.properties.hostname=node6"}},
+		{"fieldSelector": {"spec.properties.hostname="}},
+		{"fieldSelector": {"spec.properties.hostname!=node6"}},
+		{"spec.deviceType": {"Node"}, "labelSelector": {"rack"}},
+	}
+	sorts := []url.Values{
+		{},
+		{"sortBy": {"name"}},
+		{"sortBy": {"createdAt"}, "order": {"desc"}},
+		{"sortBy": {"updatedAt"}},
+		{"sortBy": {"uid"}, "order": {"desc"}},
+	}
+
+	type request struct {
+		query url.Values
+		limit int
+	}
+	var requests []request
+	for _, filter := range filters {
+		for _, sort := range sorts {
+			query := url.Values{}
+			for key, values := range filter {
+				query[key] = values
+			}
+			for key, values := range sort {
+				query[key] = values
+			}
+			for _, limit := range []int{0, 1, 5} {
+				requests = append(requests, request{query, limit})
+			}
+		}
+	}
+
+	run := func(t *testing.T, init func(t *testing.T)) []listResult {
+		init(t)
+		saveListTestDevices(t)
+		results := make([]listResult, len(requests))
+		for i, req := range requests {
+			results[i] = listAllPages(t, req.query, req.limit)
+		}
+		return results
+	}
+	var inMemory, inSQL []listResult
+	t.Run("file", func(t *testing.T) { inMemory = run(t, initTestStorage) })
+	t.Run("sqlite", func(t *testing.T) { inSQL = run(t, initSQLiteTestStorage) })
+	if len(inMemory) != len(requests) || len(inSQL) != len(requests) {
+		t.Fatal("a backend didn't run every request")
+	}
+
+	for i, req := range requests {
+		name := fmt.Sprintf("%s&limit=%d", req.query.Encode(), req.limit)
+		if !reflect.DeepEqual(inMemory[i], inSQL[i]) {
+			t.Errorf("%s:\n  in memory: %+v\n  in SQL:    %+v", name, inMemory[i], inSQL[i])
+		}
+		if req.limit == 0 {
+			continue
+		}
+		// Paging returns the unpaged list, whichever way it runs
+		var paged []string
+		for _, page := range inSQL[i].Pages {
+			paged = append(paged, page...)
+		}
+		whole := inSQL[i-i%3].Pages[0] // The unpaged request of the same query
+		if fmt.Sprint(paged) != fmt.Sprint(whole) {
+			t.Errorf("%s: pages %v, unpaged %v", name, paged, whole)
+		}
+		if inSQL[i].Total != len(whole) {
+			t.Errorf("%s: total %d, want %d", name, inSQL[i].Total, len(whole))
+		}
+	}
+	if t.Failed() {
+		return
+	}
+
+	// Every selector case selects something
+	for i, req := range requests {
+		if req.limit == 0 && len(inSQL[i].Pages[0]) == 0 {
+			t.Errorf("%s selects nothing; the case tests little", req.query.Encode())
+		}
+	}
+}
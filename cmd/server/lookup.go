@@ -0,0 +1,149 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+	fabrica_storage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// GetDeviceBySerial returns the Device with a serial number
+func GetDeviceBySerial(w http.ResponseWriter, r *http.Request) {
+	serial, err := url.PathUnescape(chi.URLParam(r, "serial"))
+	if err != nil || serial == "" {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("serial number is required"))
+		return
+	}
+
+	dev, err := storage.LoadDeviceBySerial(r.Context(), serial)
+	if err != nil {
+		if errors.Is(err, fabrica_storage.ErrNotFound) {
+			respondError(w, http.StatusNotFound, fmt.Errorf("no Device has serial number %q", serial))
+			return
+		}
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to look up Device: %w", err))
+		return
+	}
+	if !checkIfNoneMatch(w, r, dev) {
+		return
+	}
+	respondJSON(w, http.StatusOK, dev)
+}
+
+// GetDeviceByMAC returns the Device with a MAC-valued property (e.g.
+// mac_address) equal to the MAC address, in any common notation
+func GetDeviceByMAC(w http.ResponseWriter, r *http.Request) {
+	mac := chi.URLParam(r, "mac")
+	if _, ok := storage.NormalizeMAC(mac); !ok {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid MAC address %q", mac))
+		return
+	}
+
+	devices, err := storage.LoadDevicesByMAC(r.Context(), mac)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to look up Device: %w", err))
+		return
+	}
+	switch len(devices) {
+	case 0:
+		respondError(w, http.StatusNotFound, fmt.Errorf("no Device has MAC address %q", mac))
+		return
+	case 1:
+	default:
+		respondError(w, http.StatusConflict, fmt.Errorf("MAC address %q is on several Devices: %s", mac, deviceUIDs(devices)))
+		return
+	}
+	if !checkIfNoneMatch(w, r, devices[0]) {
+		return
+	}
+	respondJSON(w, http.StatusOK, devices[0])
+}
+
+// GetDevicesByRedfishURI returns the Devices whose redfish_uri property is
+// ?uri=. Redfish URIs are only unique per BMC, so the result is a list.
+func GetDevicesByRedfishURI(w http.ResponseWriter, r *http.Request) {
+	uri := r.URL.Query().Get("uri")
+	if uri == "" {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("uri query parameter is required"))
+		return
+	}
+
+	devices, err := storage.LoadDevicesByRedfishURI(r.Context(), uri)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to look up Devices: %w", err))
+		return
+	}
+	respondJSON(w, http.StatusOK, devices)
+}
+
+func deviceUIDs(devices []*device.Device) string {
+	uids := make([]string, len(devices))
+	for i, d := range devices {
+		uids[i] = d.GetUID()
+	}
+	return strings.Join(uids, ", ")
+}
+
+// registerDeviceLookupPaths documents the Device lookup endpoints
+func registerDeviceLookupPaths(spec *openapi3.T) {
+	deviceResponse := &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Successful response").
+			WithJSONSchemaRef(&openapi3.SchemaRef{Ref: "#/components/schemas/Device"}),
+	}
+
+	bySerial := openapi3.NewOperation()
+	bySerial.OperationID = "getDeviceBySerial"
+	bySerial.Summary = "Get a Device by serial number"
+	bySerial.Tags = []string{"Device"}
+	bySerial.Parameters = openapi3.Parameters{
+		{Value: openapi3.NewPathParameter("serial").WithSchema(openapi3.NewStringSchema())},
+	}
+	bySerial.Responses = openapi3.NewResponses()
+	bySerial.Responses.Set("200", deviceResponse)
+	bySerial.Responses.Set("404", errorResponse())
+
+	byMAC := openapi3.NewOperation()
+	byMAC.OperationID = "getDeviceByMAC"
+	byMAC.Summary = "Get a Device by MAC address"
+	byMAC.Description = "Matches MAC-valued properties such as mac_address. 409 if several Devices have the MAC address."
+	byMAC.Tags = []string{"Device"}
+	byMAC.Parameters = openapi3.Parameters{
+		{Value: openapi3.NewPathParameter("mac").WithSchema(openapi3.NewStringSchema())},
+	}
+	byMAC.Responses = openapi3.NewResponses()
+	byMAC.Responses.Set("200", deviceResponse)
+	byMAC.Responses.Set("400", errorResponse())
+	byMAC.Responses.Set("404", errorResponse())
+	byMAC.Responses.Set("409", errorResponse())
+
+	arraySchema := openapi3.NewArraySchema()
+	arraySchema.Items = &openapi3.SchemaRef{Ref: "#/components/schemas/Device"}
+	byRedfishURI := openapi3.NewOperation()
+	byRedfishURI.OperationID = "getDevicesByRedfishURI"
+	byRedfishURI.Summary = "List the Devices with a Redfish URI"
+	byRedfishURI.Description = "Matches the redfish_uri property. The URI may include the /redfish/v1 prefix or be a full URL."
+	byRedfishURI.Tags = []string{"Device"}
+	byRedfishURI.Parameters = openapi3.Parameters{
+		{Value: openapi3.NewQueryParameter("uri").WithRequired(true).WithSchema(openapi3.NewStringSchema())},
+	}
+	byRedfishURI.Responses = openapi3.NewResponses()
+	byRedfishURI.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Successful response").
+			WithJSONSchemaRef(&openapi3.SchemaRef{Value: arraySchema}),
+	})
+	byRedfishURI.Responses.Set("400", errorResponse())
+
+	spec.Paths.Set("/devices/by-serial/{serial}", &openapi3.PathItem{Get: bySerial})
+	spec.Paths.Set("/devices/by-mac/{mac}", &openapi3.PathItem{Get: byMAC})
+	spec.Paths.Set("/devices/by-redfish-uri", &openapi3.PathItem{Get: byRedfishURI})
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"log"
 	"net/http"
+	"net/netip"
 	"os"
 	"os/signal"
 	"syscall"
@@ -24,7 +25,11 @@ import (
 
 	// --- Your existing storage and NEW reconciler import ---
 	internal_storage "github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/internal/auth"
 	"github.com/user/inventory-api/internal/reconciliation"
+	"github.com/user/inventory-api/internal/watch"
+	"github.com/user/inventory-api/internal/webhook"
+	"github.com/user/inventory-api/pkg/resources/subscription"
 	
 	// --- Blank imports to register resources ---
 	_ "github.com/user/inventory-api/pkg/resources/device"
@@ -60,6 +65,52 @@ type Config struct {
 	IdleTimeout  int    `mapstructure:"idle_timeout"`
 	DataDir      string `mapstructure:"data_dir"`
 	Debug        bool   `mapstructure:"debug"`
+
+	// Storage selects the storage backend: "file" (default, JSON files under DataDir) or "sqlite"
+	Storage string `mapstructure:"storage"`
+	// StorageDSN is the SQLite database for the "sqlite" backend (default DataDir/inventory.db)
+	StorageDSN string `mapstructure:"storage_dsn"`
+
+	// ResyncInterval is how often (in seconds) unfinished snapshots are re-enqueued; 0 disables it
+	ResyncInterval int `mapstructure:"resync_interval"`
+
+	// EventBus selects the event bus: "memory" (default), "file" (durable log under DataDir/events) or "nats" (JetStream)
+	EventBus string `mapstructure:"event_bus"`
+	// EventLogMaxBytes caps the size of the file event log or NATS stream (0 = unlimited)
+	EventLogMaxBytes int64 `mapstructure:"event_log_max_bytes"`
+	// EventLogMaxAge drops events older than this many hours from the file event log or NATS stream (0 = unlimited)
+	EventLogMaxAge int `mapstructure:"event_log_max_age"`
+	// EventLogNoSync skips syncing the file event log to disk after every event
+	EventLogNoSync bool `mapstructure:"event_log_no_sync"`
+	// NATSURL and NATSStream select the JetStream server and stream for the "nats" event bus
+	NATSURL    string `mapstructure:"nats_url"`
+	NATSStream string `mapstructure:"nats_stream"`
+
+	// WatchHistory is how many recent events ?watch=true requests can resume from
+	WatchHistory int `mapstructure:"watch_history"`
+
+	// SMDCompat serves read-only SMD hardware inventory endpoints under /hsm/v2
+	SMDCompat bool `mapstructure:"smd_compat"`
+
+	// AuthJWKSFile or AuthJWKSURL turns on JWT bearer authentication, verifying tokens against this key set
+	AuthJWKSFile string `mapstructure:"auth_jwks_file"`
+	AuthJWKSURL  string `mapstructure:"auth_jwks_url"`
+	// AuthJWKSRefresh is how often (in seconds) the key set is reloaded; 0 reloads only for unknown key IDs
+	AuthJWKSRefresh int `mapstructure:"auth_jwks_refresh"`
+	// AuthIssuer and AuthAudience must match the token's iss and aud claims
+	AuthIssuer   string `mapstructure:"auth_issuer"`
+	AuthAudience string `mapstructure:"auth_audience"`
+	// AuthAnyIssuerAudience allows authentication without AuthIssuer or AuthAudience
+	AuthAnyIssuerAudience bool `mapstructure:"auth_any_issuer_audience"`
+	// AuthAdminRole is the role a token needs for the /admin endpoints
+	AuthAdminRole string `mapstructure:"auth_admin_role"`
+
+	// AdminBackup serves POST /admin/backup, which is off by default
+	AdminBackup bool `mapstructure:"admin_backup"`
+
+	// WebhookAllowNetworks are networks (CIDRs or addresses) webhooks may be
+	// delivered to even though they are loopback, link-local or metadata addresses
+	WebhookAllowNetworks []string `mapstructure:"webhook_allow_networks"`
 }
 
 // DefaultConfig returns the default configuration
@@ -73,6 +124,21 @@ func DefaultConfig() *Config {
 		IdleTimeout:  60,
 		DataDir:      "./data",
 		Debug:        false,
+
+		Storage: "file",
+
+		ResyncInterval: 300,
+
+		EventBus:         "memory",
+		EventLogMaxBytes: 1 << 30,
+		EventLogMaxAge:   7 * 24,
+		NATSURL:          "nats://127.0.0.1:4222",
+		NATSStream:       "INVENTORY_EVENTS",
+
+		WatchHistory: 4096,
+
+		AuthJWKSRefresh: 3600,
+		AuthAdminRole:   "admin",
 	}
 }
 
@@ -112,7 +178,48 @@ func init() {
 	serveCmd.Flags().Int("write-timeout", 15, "Write timeout in seconds")
 	serveCmd.Flags().Int("idle-timeout", 60, "Idle timeout in seconds")
 	serveCmd.Flags().String("data-dir", "./data", "Directory for file storage")
+	serveCmd.Flags().String("storage", "file", "Storage backend: file (JSON files under data-dir) or sqlite")
+	serveCmd.Flags().String("storage-dsn", "", "SQLite database file or DSN for the sqlite storage backend (default data-dir/inventory.db)")
+	serveCmd.Flags().Int("resync-interval", 300, "Seconds between resyncs of unfinished snapshots (0 to disable)")
+	serveCmd.Flags().String("event-bus", "memory", "Event bus: memory, file (durable log under data-dir) or nats (JetStream)")
+	serveCmd.Flags().Int64("event-log-max-bytes", 1<<30, "Maximum size of the file event log or NATS stream in bytes (0 for unlimited)")
+	serveCmd.Flags().Int("event-log-max-age", 7*24, "Maximum age of events in the file event log or NATS stream in hours (0 for unlimited)")
+	serveCmd.Flags().Bool("event-log-no-sync", false, "Don't sync the file event log to disk after every event (faster; an OS crash may lose the latest events)")
+	serveCmd.Flags().String("nats-url", "nats://127.0.0.1:4222", "NATS server URL for the nats event bus")
+	serveCmd.Flags().String("nats-stream", "INVENTORY_EVENTS", "JetStream stream name for the nats event bus")
+	serveCmd.Flags().Int("watch-history", 4096, "Number of recent events watch streams can resume from")
+	serveCmd.Flags().Bool("smd-compat", false, "Serve read-only SMD hardware inventory endpoints under /hsm/v2/Inventory")
+	serveCmd.Flags().String("auth-jwks-file", "", "JWKS file to verify JWT bearer tokens with (enables authentication)")
+	serveCmd.Flags().String("auth-jwks-url", "", "JWKS URL to verify JWT bearer tokens with (enables authentication)")
+	serveCmd.Flags().Int("auth-jwks-refresh", 3600, "Seconds between reloads of the JWKS (0 to reload only for unknown key IDs)")
+	serveCmd.Flags().String("auth-issuer", "", "Required JWT issuer (iss)")
+	serveCmd.Flags().String("auth-audience", "", "Required JWT audience (aud)")
+	serveCmd.Flags().Bool("auth-any-issuer-audience", false, "Allow authentication without --auth-issuer or --auth-audience, accepting tokens for any issuer or audience")
+	serveCmd.Flags().String("auth-admin-role", "admin", "Role (in the roles or scope claim) a token needs for /admin endpoints")
+	serveCmd.Flags().Bool("admin-backup", false, "Serve POST /admin/backup (requires the admin role when authentication is on)")
+	serveCmd.Flags().StringSlice("webhook-allow-networks", nil, "Loopback, link-local or metadata networks (CIDRs or addresses) webhooks may still be delivered to")
 	viper.BindPFlags(serveCmd.Flags())
+	viper.BindPFlag("data_dir", serveCmd.Flags().Lookup("data-dir"))
+	viper.BindPFlag("storage", serveCmd.Flags().Lookup("storage"))
+	viper.BindPFlag("storage_dsn", serveCmd.Flags().Lookup("storage-dsn"))
+	viper.BindPFlag("resync_interval", serveCmd.Flags().Lookup("resync-interval"))
+	viper.BindPFlag("event_bus", serveCmd.Flags().Lookup("event-bus"))
+	viper.BindPFlag("event_log_max_bytes", serveCmd.Flags().Lookup("event-log-max-bytes"))
+	viper.BindPFlag("event_log_max_age", serveCmd.Flags().Lookup("event-log-max-age"))
+	viper.BindPFlag("event_log_no_sync", serveCmd.Flags().Lookup("event-log-no-sync"))
+	viper.BindPFlag("nats_url", serveCmd.Flags().Lookup("nats-url"))
+	viper.BindPFlag("nats_stream", serveCmd.Flags().Lookup("nats-stream"))
+	viper.BindPFlag("watch_history", serveCmd.Flags().Lookup("watch-history"))
+	viper.BindPFlag("smd_compat", serveCmd.Flags().Lookup("smd-compat"))
+	viper.BindPFlag("auth_jwks_file", serveCmd.Flags().Lookup("auth-jwks-file"))
+	viper.BindPFlag("auth_jwks_url", serveCmd.Flags().Lookup("auth-jwks-url"))
+	viper.BindPFlag("auth_jwks_refresh", serveCmd.Flags().Lookup("auth-jwks-refresh"))
+	viper.BindPFlag("auth_issuer", serveCmd.Flags().Lookup("auth-issuer"))
+	viper.BindPFlag("auth_audience", serveCmd.Flags().Lookup("auth-audience"))
+	viper.BindPFlag("auth_any_issuer_audience", serveCmd.Flags().Lookup("auth-any-issuer-audience"))
+	viper.BindPFlag("auth_admin_role", serveCmd.Flags().Lookup("auth-admin-role"))
+	viper.BindPFlag("admin_backup", serveCmd.Flags().Lookup("admin-backup"))
+	viper.BindPFlag("webhook_allow_networks", serveCmd.Flags().Lookup("webhook-allow-networks"))
 	viper.BindPFlags(rootCmd.PersistentFlags())
 	rootCmd.AddCommand(serveCmd)
 	rootCmd.AddCommand(versionCmd)
@@ -151,16 +258,40 @@ func runServer(cmd *cobra.Command, args []string) error {
 	// Create a logger for the reconciliation system
 	reconLogger := reconcile.NewDefaultLogger() // <<< ADDED
 
+	// Webhooks may not reach loopback, link-local or metadata addresses unless allowed
+	allowNetworks, err := parseNetworks(config.WebhookAllowNetworks)
+	if err != nil {
+		return fmt.Errorf("invalid --webhook-allow-networks: %w", err)
+	}
+	subscription.SetAllowedNetworks(allowNetworks)
+
 	// --- 1. Initialize Storage Backend ---
-	if err := internal_storage.InitFileBackend(config.DataDir); err != nil {
-		return fmt.Errorf("failed to initialize file storage: %w", err)
+	// Versions, the device index and the reconciler's locks are in memory,
+	// so this must be the only process writing to the store
+	unlockStorage, err := internal_storage.LockWriter(storageSpec(config))
+	if err != nil {
+		return fmt.Errorf("failed to lock storage (is another server using it?): %w", err)
+	}
+	defer unlockStorage()
+	storageDescription, err := initStorageBackend(config)
+	if err != nil {
+		return err
+	}
+	// Give every write a resourceVersion so concurrent updates can't silently overwrite each other
+	if err := internal_storage.InitVersioning(context.Background()); err != nil {
+		return fmt.Errorf("failed to initialize resource versioning: %w", err)
+	}
+	// Wrap the backend with the serial/parent index so the reconciler can do point lookups
+	if err := internal_storage.InitIndex(context.Background()); err != nil {
+		return fmt.Errorf("failed to initialize device index: %w", err)
 	}
 	storageBackend := internal_storage.Backend
 	if storageBackend == nil {
 		return fmt.Errorf("storage backend is nil after initialization")
 	}
 	SetStorageBackend(storageBackend) // This sets globalStorage
-	log.Printf("File storage initialized in %s", config.DataDir)
+	defer storageBackend.Close()
+	log.Printf("Storage initialized: %s", storageDescription)
 
 	eventConf := &events.EventConfig{
 		Enabled:                true,
@@ -173,21 +304,34 @@ func runServer(cmd *cobra.Command, args []string) error {
 	log.Println("Event system configured and enabled.")
 
 	// --- 2. Initialize Event Bus --- (ADDED BACK)
-	eventBus := events.NewInMemoryEventBus(1000, 10)
-	eventBus.Start()
+	eventBus, consumerBus, err := newEventBus(config)
+	if err != nil {
+		return err
+	}
 	defer eventBus.Close()
 	SetEventBus(eventBus) // Set the global for handlers
-	log.Println("Event bus started.")
+	// The generated handlers and the reconcilers publish through fabrica's global bus
+	events.SetGlobalEventBus(eventBus)
+	log.Printf("Event bus started (%s).", config.EventBus)
 
 	// --- 4. Register Reconcilers --- (ADDED BACK)
 	// The reconciler needs the *typed client* from your storage.go
-	apiStorageClient := internal_storage.NewStorageClient()
-	controller := reconcile.NewController(eventBus, storageBackend)
+	apiStorageClient := internal_storage.NewVersionedClient()
+	// Reconcilers announce their status writes; reconciling again because of them would loop
+	controller := reconcile.NewController(reconciliation.WithoutStatusEvents(consumerBus("reconcile-controller")), storageBackend)
 	log.Println("Reconciliation controller initialized.")
 	snapshotReconciler := reconciliation.NewSnapshotReconciler(eventBus, apiStorageClient, reconLogger)
 	if err := controller.RegisterReconciler(snapshotReconciler); err != nil {
 		log.Fatalf("Failed to register reconciler: %v", err)
 	}
+	// The topology reconciler keeps Device.Status children/depth/root current
+	topologyReconciler := reconciliation.NewDeviceTopologyReconciler(eventBus, apiStorageClient, reconLogger)
+	if err := topologyReconciler.Start(context.Background()); err != nil {
+		return fmt.Errorf("failed to start topology reconciler: %w", err)
+	}
+	if err := controller.RegisterReconciler(topologyReconciler); err != nil {
+		log.Fatalf("Failed to register reconciler: %v", err)
+	}
 
 	// --- 5. Start Controller --- (ADDED BACK)
 	ctx, cancel := context.WithCancel(context.Background())
@@ -200,18 +344,68 @@ func runServer(cmd *cobra.Command, args []string) error {
 		}
 	}()
 
+	// Re-enqueue snapshots the in-memory event bus lost (restart, crash mid-reconcile, dropped events)
+	resyncer := reconciliation.NewSnapshotResyncer(controller, apiStorageClient, reconLogger, time.Duration(config.ResyncInterval)*time.Second)
+	if err := resyncer.Start(ctx); err != nil {
+		log.Printf("Snapshot resync failed: %v", err)
+	}
+
+	// Deliver events to the webhook endpoints of Subscription resources
+	dispatcher := webhook.NewDispatcher(consumerBus("webhook-dispatcher"), reconLogger)
+	if err := dispatcher.Start(); err != nil {
+		return fmt.Errorf("failed to start webhook dispatcher: %w", err)
+	}
+	defer dispatcher.Stop()
+
+	// Bridge resource events to ?watch=true list requests
+	broadcaster := watch.NewBroadcaster(eventBus, config.WatchHistory)
+	if err := broadcaster.Start(); err != nil {
+		return fmt.Errorf("failed to start watch broadcaster: %w", err)
+	}
+	defer broadcaster.Stop()
+	SetWatchBroadcaster(broadcaster)
+
 	// --- 6. Setup Router ---
 	r := chi.NewRouter()
 	r.Use(middleware.Logger)
 	r.Use(middleware.Recoverer)
 	r.Use(middleware.RequestID)
 	r.Use(middleware.RealIP)
+	authEnabled := config.AuthJWKSFile != "" || config.AuthJWKSURL != ""
+	if authEnabled {
+		authenticator, err := newAuthenticator(ctx, config)
+		if err != nil {
+			return fmt.Errorf("failed to initialize authentication: %w", err)
+		}
+		r.Use(authenticator.Middleware)
+		log.Printf("JWT bearer authentication enabled (keys from %s).", authenticator.KeySource())
+	}
 	if config.Debug {
 		r.Mount("/debug", middleware.Profiler())
 	}
 
 	RegisterGeneratedRoutes(r) // This is the Fabrica "server"
 	r.Get("/health", healthHandler)
+	if config.AdminBackup {
+		r.Group(func(r chi.Router) {
+			if authEnabled {
+				r.Use(auth.RequireRole(config.AuthAdminRole))
+			} else {
+				log.Println("Warning: POST /admin/backup is enabled without authentication; anyone who can reach the server can download the inventory.")
+			}
+			r.Post("/admin/backup", BackupHandler)
+		})
+	}
+	r.Get("/hbom", GetHBOM)
+	r.Get("/export", ExportDevices)
+	r.Post("/import", ImportDevices)
+	r.Get("/integrations/ansible", GetAnsibleInventory)
+	r.Get("/integrations/prometheus/sd", GetPrometheusTargets)
+	r.Get("/integrations/dhcp", GetDHCPReservations)
+	if config.SMDCompat {
+		registerSMDCompatRoutes(r)
+		log.Println("Serving SMD hardware inventory under /hsm/v2/Inventory.")
+	}
 
 	r.Post("/discoverysnapshots", manualCreateSnapshotHandler)
 	log.Println("Overriding POST /discoverysnapshots with manual handler.")
@@ -299,7 +493,7 @@ func manualCreateSnapshotHandler(w http.ResponseWriter, r *http.Request) {
 	// --- END FIX ---
 
 	// 5. Save it to storage
-	if err := internal_storage.SaveDiscoverySnapshot(context.Background(), snapshot); err != nil {
+	if err := internal_storage.SaveDiscoverySnapshotVersioned(context.Background(), snapshot); err != nil {
 		http.Error(w, "Failed to save snapshot: "+err.Error(), 500)
 		return
 	}
@@ -344,7 +538,7 @@ func debugEventHandler(w http.ResponseWriter, r *http.Request) {
 
 	// 2. Save it to storage
 	// (The real handler does this)
-	if err := internal_storage.SaveDiscoverySnapshot(context.Background(), testSnapshot); err != nil {
+	if err := internal_storage.SaveDiscoverySnapshotVersioned(context.Background(), testSnapshot); err != nil {
 		log.Printf("--- DEBUG: Failed to save snapshot: %v ---", err)
 		http.Error(w, "Failed to save", 500)
 		return
@@ -399,4 +593,20 @@ var versionCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("inventory-api v1.0.0")
 	},
-}
\ No newline at end of file
+}
+// parseNetworks parses CIDRs, taking a bare address as a network of one
+func parseNetworks(values []string) ([]netip.Prefix, error) {
+	networks := make([]netip.Prefix, 0, len(values))
+	for _, value := range values {
+		if addr, err := netip.ParseAddr(value); err == nil {
+			networks = append(networks, netip.PrefixFrom(addr, addr.BitLen()))
+			continue
+		}
+		network, err := netip.ParsePrefix(value)
+		if err != nil {
+			return nil, err
+		}
+		networks = append(networks, network.Masked())
+	}
+	return networks, nil
+}
@@ -0,0 +1,164 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"strings"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	internal_storage "github.com/user/inventory-api/internal/storage"
+)
+
+var migrateCmd = &cobra.Command{
+	Use:   "migrate --from <backend> --to <backend>",
+	Short: "Copy all resources from one storage backend to another",
+	Long: `Copy every Device, DiscoverySnapshot and Subscription from one storage
+backend to another, keeping UIDs, timestamps, labels, parent links and
+resourceVersions exactly as stored, then verify that both hold the same
+resources (counts and checksums).
+
+Backends are given as file:<data-dir> or sqlite:<dsn>. Resources already in
+the destination with the same content are skipped, so an interrupted
+migration is resumed by running the same command again. Stop the server
+before migrating, so no writes are missed.`,
+	Example: `  # Move from the file backend to SQLite
+  inventory-api migrate --from file:./data --to sqlite:./data/inventory.db
+
+  # Only check that a finished migration matches its source
+  inventory-api migrate --from file:./data --to sqlite:./data/inventory.db --verify-only`,
+	Args: cobra.NoArgs,
+	RunE: runMigrate,
+}
+
+func init() {
+	migrateCmd.Flags().String("from", "", "Source backend (file:<data-dir> or sqlite:<dsn>)")
+	migrateCmd.Flags().String("to", "", "Destination backend (file:<data-dir> or sqlite:<dsn>)")
+	migrateCmd.Flags().Int("batch-size", 500, "Resources written per atomic batch")
+	migrateCmd.Flags().Bool("verify-only", false, "Compare the backends without copying anything")
+	migrateCmd.MarkFlagRequired("from")
+	migrateCmd.MarkFlagRequired("to")
+	rootCmd.AddCommand(migrateCmd)
+}
+
+func runMigrate(cmd *cobra.Command, args []string) error {
+	from, _ := cmd.Flags().GetString("from")
+	to, _ := cmd.Flags().GetString("to")
+	batchSize, _ := cmd.Flags().GetInt("batch-size")
+	verifyOnly, _ := cmd.Flags().GetBool("verify-only")
+
+	cmd.SilenceUsage = true
+
+	if sameBackend(from, to) {
+		return fmt.Errorf("--from and --to are the same backend")
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	if !verifyOnly {
+		// A server writing to either backend would make the copy incomplete
+		for _, spec := range []string{from, to} {
+			unlock, err := internal_storage.LockWriter(spec)
+			if err != nil {
+				return fmt.Errorf("failed to lock %s (stop the server before migrating): %w", spec, err)
+			}
+			defer unlock()
+		}
+	}
+	src, err := internal_storage.OpenBackend(ctx, from)
+	if err != nil {
+		return fmt.Errorf("failed to open source: %w", err)
+	}
+	defer src.Close()
+	dst, err := internal_storage.OpenBackend(ctx, to)
+	if err != nil {
+		return fmt.Errorf("failed to open destination: %w", err)
+	}
+	defer dst.Close()
+
+	out := cmd.OutOrStdout()
+	skipped := 0
+	if !verifyOnly {
+		fmt.Fprintf(out, "Migrating %s -> %s\n", from, to)
+		stats, err := internal_storage.Migrate(ctx, src, dst, internal_storage.MigrateOptions{
+			BatchSize: batchSize,
+			Progress: func(kind string, s *internal_storage.MigrateStats) {
+				fmt.Fprintf(out, "  %-18s %d copied...\n", kind, s.Copied)
+			},
+		})
+		if err != nil {
+			return fmt.Errorf("migration stopped (run the command again to resume): %w", err)
+		}
+		for _, kind := range internal_storage.ResourceKinds {
+			s := stats[kind]
+			fmt.Fprintf(out, "  %-18s %d copied, %d unchanged, %d skipped\n", kind, s.Copied, s.Unchanged, s.Skipped)
+			for _, problem := range s.Problems {
+				fmt.Fprintf(out, "  skipped %s\n", problem)
+			}
+			skipped += s.Skipped
+		}
+	}
+
+	fmt.Fprintln(out, "Verifying...")
+	results, err := internal_storage.Verify(ctx, src, dst, nil)
+	if err != nil {
+		return err
+	}
+	ok := true
+	for _, kind := range internal_storage.ResourceKinds {
+		v := results[kind]
+		status := "ok"
+		if !v.OK() {
+			status, ok = "MISMATCH", false
+		}
+		fmt.Fprintf(out, "  %-18s source %d, destination %d, checksum %s  %s\n", kind, v.Source, v.Destination, v.SourceChecksum[:12], status)
+		printUIDs(out, "missing", v.Missing)
+		printUIDs(out, "different", v.Different)
+		printUIDs(out, "extra", v.Extra)
+	}
+	if !ok {
+		return fmt.Errorf("destination does not match source")
+	}
+	if skipped > 0 {
+		return fmt.Errorf("%d unreadable resources were not migrated (see inventory-api fsck)", skipped)
+	}
+	fmt.Fprintln(out, "Migration verified.")
+	return nil
+}
+
+// printUIDs lists up to 10 UIDs of a verification problem
+func printUIDs(out io.Writer, what string, uids []string) {
+	if len(uids) == 0 {
+		return
+	}
+	shown := uids
+	if len(shown) > 10 {
+		shown = shown[:10]
+	}
+	fmt.Fprintf(out, "    %d %s: %s", len(uids), what, strings.Join(shown, ", "))
+	if len(uids) > len(shown) {
+		fmt.Fprint(out, ", ...")
+	}
+	fmt.Fprintln(out)
+}
+
+// sameBackend reports whether two backend specs name the same storage
+func sameBackend(a, b string) bool {
+	normalize := func(spec string) string {
+		scheme, location, ok := strings.Cut(spec, ":")
+		if !ok {
+			scheme, location = "file", spec
+		}
+		if abs, err := filepath.Abs(location); err == nil {
+			location = abs
+		}
+		return scheme + ":" + location
+	}
+	return normalize(a) == normalize(b)
+}
@@ -40,11 +40,20 @@ import (
 	"github.com/user/inventory-api/pkg/resources/device"
 
 	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+
+	"github.com/user/inventory-api/pkg/resources/subscription"
 )
 
 // DeviceResponse represents the response for Device operations
 type DeviceResponse = device.Device
 
+// DevicesResponse is one page of a paginated Device list
+type DevicesResponse struct {
+	Items    []*device.Device `json:"items"`
+	Continue string           `json:"continue,omitempty"`
+	Total    int              `json:"total"`
+}
+
 // CreateDeviceRequest represents a request to create a Device
 type CreateDeviceRequest struct {
 	device.DeviceSpec `json:",inline"`
@@ -64,6 +73,13 @@ type UpdateDeviceRequest struct {
 // DiscoverySnapshotResponse represents the response for DiscoverySnapshot operations
 type DiscoverySnapshotResponse = discoverysnapshot.DiscoverySnapshot
 
+// DiscoverySnapshotsResponse is one page of a paginated DiscoverySnapshot list
+type DiscoverySnapshotsResponse struct {
+	Items    []*discoverysnapshot.DiscoverySnapshot `json:"items"`
+	Continue string                                 `json:"continue,omitempty"`
+	Total    int                                    `json:"total"`
+}
+
 // CreateDiscoverySnapshotRequest represents a request to create a DiscoverySnapshot
 type CreateDiscoverySnapshotRequest struct {
 	discoverysnapshot.DiscoverySnapshotSpec `json:",inline"`
@@ -80,6 +96,25 @@ type UpdateDiscoverySnapshotRequest struct {
 	Annotations                             map[string]string `json:"annotations,omitempty"`
 }
 
+// SubscriptionResponse represents the response for Subscription operations
+type SubscriptionResponse = subscription.Subscription
+
+// CreateSubscriptionRequest represents a request to create a Subscription
+type CreateSubscriptionRequest struct {
+	subscription.SubscriptionSpec `json:",inline"`
+	Name                          string            `json:"name" validate:"required"`
+	Labels                        map[string]string `json:"labels,omitempty"`
+	Annotations                   map[string]string `json:"annotations,omitempty"`
+}
+
+// UpdateSubscriptionRequest represents a request to update a Subscription
+type UpdateSubscriptionRequest struct {
+	subscription.SubscriptionSpec `json:",inline,omitempty"`
+	Name                          string            `json:"name,omitempty"`
+	Labels                        map[string]string `json:"labels,omitempty"`
+	Annotations                   map[string]string `json:"annotations,omitempty"`
+}
+
 // ErrorResponse represents an error response
 type ErrorResponse struct {
 	Error   string `json:"error"`
@@ -27,6 +27,7 @@ import (
 	"github.com/getkin/kin-openapi/openapi3gen"
 	"github.com/user/inventory-api/pkg/resources/device"
 	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+	"github.com/user/inventory-api/pkg/resources/subscription"
 )
 
 // ServeOpenAPISpec returns the OpenAPI 3.0 specification
@@ -107,10 +108,57 @@ func GenerateOpenAPISpec() *openapi3.T {
 	// Register all resource paths
 	registerDevicePaths(spec)
 	registerDiscoverySnapshotPaths(spec)
+	registerSubscriptionPaths(spec)
+	registerDeviceLookupPaths(spec)
+	registerDeviceHierarchyPaths(spec)
+	registerHBOMPaths(spec)
+	registerExportPaths(spec)
+	registerSMDPaths(spec)
+	registerIntegrationPaths(spec)
+	registerDHCPPaths(spec)
+	registerAdminPaths(spec)
+	registerSecurityScheme(spec)
 
 	return spec
 }
 
+// listParameters documents the selector, sorting and pagination query parameters of a list operation
+func listParameters(fields string) openapi3.Parameters {
+	labelSelector := openapi3.NewQueryParameter("labelSelector").
+		WithDescription("Label selector, e.g. rack=r12,role in (compute,login),!decommissioned").
+		WithSchema(openapi3.NewStringSchema())
+	fieldSelector := openapi3.NewQueryParameter("fieldSelector").
+		WithDescription("Field selector of field=value and field!=value terms. Fields: " + fields + ". Each field may also be passed as its own query parameter.").
+		WithSchema(openapi3.NewStringSchema())
+	sortBy := openapi3.NewQueryParameter("sortBy").
+		WithDescription("Sort order (ties are broken by uid)").
+		WithSchema(openapi3.NewStringSchema().WithEnum("uid", "name", "createdAt", "updatedAt"))
+	order := openapi3.NewQueryParameter("order").
+		WithSchema(openapi3.NewStringSchema().WithEnum("asc", "desc"))
+	limit := openapi3.NewQueryParameter("limit").
+		WithDescription("Page size (at most 1000). With limit or continue the response is a page object instead of an array.").
+		WithSchema(openapi3.NewIntegerSchema().WithMin(1))
+	continueToken := openapi3.NewQueryParameter("continue").
+		WithDescription("Token from the previous page; the other query parameters must be the same").
+		WithSchema(openapi3.NewStringSchema())
+	return openapi3.Parameters{
+		{Value: labelSelector}, {Value: fieldSelector},
+		{Value: sortBy}, {Value: order}, {Value: limit}, {Value: continueToken},
+	}
+}
+
+// listResponseSchema is a bare array of a resource, or a page of them when paginating
+func listResponseSchema(kind string) *openapi3.SchemaRef {
+	arraySchema := openapi3.NewArraySchema()
+	arraySchema.Items = &openapi3.SchemaRef{Ref: "#/components/schemas/" + kind}
+	pageSchema := openapi3.NewObjectSchema().
+		WithProperty("items", arraySchema).
+		WithProperty("continue", openapi3.NewStringSchema()).
+		WithProperty("total", openapi3.NewIntegerSchema()).
+		WithRequired([]string{"items", "total"})
+	return &openapi3.SchemaRef{Value: openapi3.NewOneOfSchema(arraySchema, pageSchema)}
+}
+
 // registerDevicePaths registers OpenAPI paths for Device resources
 func registerDevicePaths(spec *openapi3.T) {
 	// Generate schemas from Go types - NO ANNOTATIONS NEEDED
@@ -144,13 +192,13 @@ func registerDevicePaths(spec *openapi3.T) {
 	listOp.Description = "Returns a list of all Device resources in the inventory"
 	listOp.Tags = []string{"Device"}
 	listOp.Responses = openapi3.NewResponses()
-	arraySchema := openapi3.NewArraySchema()
-	arraySchema.Items = &openapi3.SchemaRef{Ref: "#/components/schemas/Device"}
 	listOp.Responses.Set("200", &openapi3.ResponseRef{
 		Value: openapi3.NewResponse().
 			WithDescription("Successful response").
-			WithJSONSchemaRef(&openapi3.SchemaRef{Value: arraySchema}),
+			WithJSONSchemaRef(listResponseSchema("Device")),
 	})
+	listOp.Parameters = listParameters("spec.deviceType, spec.manufacturer, spec.partNumber, spec.serialNumber, spec.parentID, spec.parentSerialNumber, status.phase, metadata.name, metadata.uid and spec.properties.<key>")
+	listOp.Responses.Set("400", errorResponse())
 	listOp.Responses.Set("500", errorResponse())
 
 	// Create Device operation
@@ -295,13 +343,13 @@ func registerDiscoverySnapshotPaths(spec *openapi3.T) {
 	listOp.Description = "Returns a list of all DiscoverySnapshot resources in the inventory"
 	listOp.Tags = []string{"DiscoverySnapshot"}
 	listOp.Responses = openapi3.NewResponses()
-	arraySchema := openapi3.NewArraySchema()
-	arraySchema.Items = &openapi3.SchemaRef{Ref: "#/components/schemas/DiscoverySnapshot"}
 	listOp.Responses.Set("200", &openapi3.ResponseRef{
 		Value: openapi3.NewResponse().
 			WithDescription("Successful response").
-			WithJSONSchemaRef(&openapi3.SchemaRef{Value: arraySchema}),
+			WithJSONSchemaRef(listResponseSchema("DiscoverySnapshot")),
 	})
+	listOp.Parameters = listParameters("status.phase, metadata.name and metadata.uid")
+	listOp.Responses.Set("400", errorResponse())
 	listOp.Responses.Set("500", errorResponse())
 
 	// Create DiscoverySnapshot operation
@@ -413,6 +461,157 @@ func registerDiscoverySnapshotPaths(spec *openapi3.T) {
 	spec.Paths.Set("/discoverysnapshots/{uid}", itemPath)
 }
 
+// registerSubscriptionPaths registers OpenAPI paths for Subscription resources
+func registerSubscriptionPaths(spec *openapi3.T) {
+	// Generate schemas from Go types - NO ANNOTATIONS NEEDED
+	resourceSchema, _ := openapi3gen.NewSchemaRefForValue(&subscription.Subscription{}, spec.Components.Schemas)
+	spec.Components.Schemas["Subscription"] = resourceSchema
+
+	createReqSchema, _ := openapi3gen.NewSchemaRefForValue(&CreateSubscriptionRequest{}, spec.Components.Schemas)
+	spec.Components.Schemas["CreateSubscriptionRequest"] = createReqSchema
+
+	updateReqSchema, _ := openapi3gen.NewSchemaRefForValue(&UpdateSubscriptionRequest{}, spec.Components.Schemas)
+	spec.Components.Schemas["UpdateSubscriptionRequest"] = updateReqSchema
+
+	// Error response schema
+	if _, exists := spec.Components.Schemas["ErrorResponse"]; !exists {
+		errorSchema := openapi3.NewObjectSchema().
+			WithProperty("error", openapi3.NewStringSchema()).
+			WithRequired([]string{"error"})
+		spec.Components.Schemas["ErrorResponse"] = &openapi3.SchemaRef{Value: errorSchema}
+	}
+
+	// DELETE response schema
+	if _, exists := spec.Components.Schemas["DeleteResponse"]; !exists {
+		deleteSchema, _ := openapi3gen.NewSchemaRefForValue(&DeleteResponse{}, spec.Components.Schemas)
+		spec.Components.Schemas["DeleteResponse"] = deleteSchema
+	}
+
+	// List Subscriptions operation
+	listOp := openapi3.NewOperation()
+	listOp.OperationID = "listSubscriptions"
+	listOp.Summary = "List all Subscription resources"
+	listOp.Description = "Returns a list of all Subscription resources in the inventory"
+	listOp.Tags = []string{"Subscription"}
+	listOp.Responses = openapi3.NewResponses()
+	arraySchema := openapi3.NewArraySchema()
+	arraySchema.Items = &openapi3.SchemaRef{Ref: "#/components/schemas/Subscription"}
+	listOp.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Successful response").
+			WithJSONSchemaRef(&openapi3.SchemaRef{Value: arraySchema}),
+	})
+	listOp.Responses.Set("500", errorResponse())
+
+	// Create Subscription operation
+	createOp := openapi3.NewOperation()
+	createOp.OperationID = "createSubscription"
+	createOp.Summary = "Create a new Subscription resource"
+	createOp.Description = "Creates a new Subscription resource with the provided specification"
+	createOp.Tags = []string{"Subscription"}
+	createOp.RequestBody = &openapi3.RequestBodyRef{
+		Value: openapi3.NewRequestBody().
+			WithRequired(true).
+			WithJSONSchemaRef(&openapi3.SchemaRef{
+				Ref: "#/components/schemas/CreateSubscriptionRequest",
+			}),
+	}
+	createOp.Responses = openapi3.NewResponses()
+	createOp.Responses.Set("201", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Resource created successfully").
+			WithJSONSchemaRef(&openapi3.SchemaRef{
+				Ref: "#/components/schemas/Subscription",
+			}),
+	})
+	createOp.Responses.Set("400", errorResponse())
+	createOp.Responses.Set("500", errorResponse())
+
+	// Get Subscription operation
+	getOp := openapi3.NewOperation()
+	getOp.OperationID = "getSubscription"
+	getOp.Summary = "Get a specific Subscription resource"
+	getOp.Description = "Returns details of a specific Subscription resource by UID"
+	getOp.Tags = []string{"Subscription"}
+	getOp.Responses = openapi3.NewResponses()
+	getOp.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Successful response").
+			WithJSONSchemaRef(&openapi3.SchemaRef{
+				Ref: "#/components/schemas/Subscription",
+			}),
+	})
+	getOp.Responses.Set("404", errorResponse())
+	getOp.Responses.Set("500", errorResponse())
+
+	// Update Subscription operation
+	updateOp := openapi3.NewOperation()
+	updateOp.OperationID = "updateSubscription"
+	updateOp.Summary = "Update a Subscription resource"
+	updateOp.Description = "Updates an existing Subscription resource with new values"
+	updateOp.Tags = []string{"Subscription"}
+	updateOp.RequestBody = &openapi3.RequestBodyRef{
+		Value: openapi3.NewRequestBody().
+			WithRequired(true).
+			WithJSONSchemaRef(&openapi3.SchemaRef{
+				Ref: "#/components/schemas/UpdateSubscriptionRequest",
+			}),
+	}
+	updateOp.Responses = openapi3.NewResponses()
+	updateOp.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Resource updated successfully").
+			WithJSONSchemaRef(&openapi3.SchemaRef{
+				Ref: "#/components/schemas/Subscription",
+			}),
+	})
+	updateOp.Responses.Set("400", errorResponse())
+	updateOp.Responses.Set("404", errorResponse())
+	updateOp.Responses.Set("500", errorResponse())
+
+	// Delete Subscription operation
+	deleteOp := openapi3.NewOperation()
+	deleteOp.OperationID = "deleteSubscription"
+	deleteOp.Summary = "Delete a Subscription resource"
+	deleteOp.Description = "Removes a Subscription resource from the inventory"
+	deleteOp.Tags = []string{"Subscription"}
+	deleteOp.Responses = openapi3.NewResponses()
+	deleteOp.Responses.Set("200", &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Resource deleted successfully").
+			WithJSONSchemaRef(&openapi3.SchemaRef{
+				Ref: "#/components/schemas/DeleteResponse",
+			}),
+	})
+	deleteOp.Responses.Set("400", errorResponse())
+	deleteOp.Responses.Set("404", errorResponse())
+	deleteOp.Responses.Set("500", errorResponse())
+
+	// Create path items
+	collectionPath := &openapi3.PathItem{
+		Get:  listOp,
+		Post: createOp,
+	}
+
+	uidParam := openapi3.NewPathParameter("uid").
+		WithDescription("Unique identifier of the Subscription resource").
+		WithRequired(true).
+		WithSchema(openapi3.NewStringSchema())
+
+	itemPath := &openapi3.PathItem{
+		Get:    getOp,
+		Put:    updateOp,
+		Delete: deleteOp,
+		Parameters: []*openapi3.ParameterRef{
+			{Value: uidParam},
+		},
+	}
+
+	// Add paths to spec
+	spec.Paths.Set("/subscriptions", collectionPath)
+	spec.Paths.Set("/subscriptions/{uid}", itemPath)
+}
+
 // Helper function for error responses
 func errorResponse() *openapi3.ResponseRef {
 	return &openapi3.ResponseRef{
@@ -0,0 +1,199 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/openchami/fabrica/pkg/resource"
+)
+
+const (
+	// maxListLimit caps the page size a client can ask for
+	maxListLimit = 1000
+
+	// totalCountHeader carries the number of matching resources on every list response
+	totalCountHeader = "X-Total-Count"
+)
+
+// Sort orders for list requests (?sortBy=)
+const (
+	sortByUID       = "uid"
+	sortByName      = "name"
+	sortByCreatedAt = "createdAt"
+	sortByUpdatedAt = "updatedAt"
+)
+
+// listPage is the paging and sorting of a list request
+type listPage struct {
+	sortBy     string
+	descending bool
+
+	// limit is the page size (0: everything, as a bare array)
+	limit int
+
+	// after is where the previous page ended
+	after *continueToken
+
+	// query identifies the rest of the request, which a continue token must not outlive
+	query string
+}
+
+// continueToken records the sort key of the last item of a page. The next
+// page starts after it, so items created or deleted in between don't shift
+// the pages: nothing is skipped or repeated unless an item's own sort key
+// changes (e.g. its updatedAt when sorting by updatedAt).
+type continueToken struct {
+	Name  string `json:"n,omitempty"`
+	Time  int64  `json:"t,omitempty"`
+	UID   string `json:"u"`
+	Query string `json:"q"`
+}
+
+// parseListPage reads limit, continue, sortBy and order from a list request.
+// Without limit or continue a list is returned whole, as before.
+func parseListPage(r *http.Request) (*listPage, error) {
+	query := r.URL.Query()
+	page := &listPage{sortBy: sortByUID}
+
+	switch sortBy := query.Get("sortBy"); sortBy {
+	case "", sortByUID:
+	case sortByName, sortByCreatedAt, sortByUpdatedAt:
+		page.sortBy = sortBy
+	default:
+		return nil, fmt.Errorf("invalid sortBy %q: must be one of uid, name, createdAt, updatedAt", sortBy)
+	}
+	switch order := query.Get("order"); order {
+	case "", "asc":
+	case "desc":
+		page.descending = true
+	default:
+		return nil, fmt.Errorf("invalid order %q: must be asc or desc", order)
+	}
+
+	if limit := query.Get("limit"); limit != "" {
+		n, err := strconv.Atoi(limit)
+		if err != nil || n < 1 {
+			return nil, fmt.Errorf("invalid limit %q: must be a positive integer", limit)
+		}
+		page.limit = min(n, maxListLimit)
+	}
+
+	page.query = queryFingerprint(query)
+	if token := query.Get("continue"); token != "" {
+		after, err := decodeContinueToken(token)
+		if err != nil || after.Query != page.query {
+			return nil, fmt.Errorf("invalid continue token: it must come from the previous page of the same list request")
+		}
+		page.after = after
+		if page.limit == 0 {
+			page.limit = maxListLimit
+		}
+	}
+	return page, nil
+}
+
+// paginated reports whether the response is a page (rather than a bare array)
+func (p *listPage) paginated() bool {
+	return p.limit > 0
+}
+
+// paginate sorts items and returns the requested page, with the token for the next one
+func paginate[T any](items []*T, page *listPage, metadata func(obj *T) *resource.Metadata) ([]*T, string) {
+	sort.Slice(items, func(i, j int) bool {
+		a, b := page.key(metadata(items[i])), page.key(metadata(items[j]))
+		return page.before(a, b)
+	})
+	if !page.paginated() {
+		return items, ""
+	}
+
+	start := 0
+	if page.after != nil {
+		start = sort.Search(len(items), func(i int) bool {
+			return page.before(*page.after, page.key(metadata(items[i])))
+		})
+	}
+	end := min(start+page.limit, len(items))
+
+	next := ""
+	if end < len(items) {
+		last := page.key(metadata(items[end-1]))
+		last.Query = page.query
+		next = encodeContinueToken(&last)
+	}
+	return items[start:end], next
+}
+
+// key returns the sort key of an item
+func (p *listPage) key(meta *resource.Metadata) continueToken {
+	key := continueToken{UID: meta.UID}
+	switch p.sortBy {
+	case sortByName:
+		key.Name = meta.Name
+	case sortByCreatedAt:
+		key.Time = meta.CreatedAt.UnixNano()
+	case sortByUpdatedAt:
+		key.Time = meta.UpdatedAt.UnixNano()
+	}
+	return key
+}
+
+// before reports whether sort key a comes before b. The UID breaks ties, so the order is total.
+func (p *listPage) before(a, b continueToken) bool {
+	var cmp int
+	switch p.sortBy {
+	case sortByName:
+		cmp = strings.Compare(a.Name, b.Name)
+	case sortByCreatedAt, sortByUpdatedAt:
+		switch {
+		case a.Time < b.Time:
+			cmp = -1
+		case a.Time > b.Time:
+			cmp = 1
+		}
+	}
+	if cmp == 0 {
+		cmp = strings.Compare(a.UID, b.UID)
+	}
+	if p.descending {
+		return cmp > 0
+	}
+	return cmp < 0
+}
+
+// queryFingerprint hashes the query parameters other than limit and continue
+func queryFingerprint(query url.Values) string {
+	rest := url.Values{}
+	for key, values := range query {
+		if key != "limit" && key != "continue" {
+			rest[key] = values
+		}
+	}
+	sum := sha256.Sum256([]byte(rest.Encode()))
+	return hex.EncodeToString(sum[:8])
+}
+
+func encodeContinueToken(token *continueToken) string {
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeContinueToken(s string) (*continueToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	var token continueToken
+	if err := json.Unmarshal(data, &token); err != nil {
+		return nil, err
+	}
+	return &token, nil
+}
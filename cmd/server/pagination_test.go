@@ -0,0 +1,162 @@
+package main
+
+import (
+	"context"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/user/inventory-api/internal/storage"
+)
+
+// getDevicePage runs a list request and returns its page, failing unless it has status want
+func getDevicePage(t *testing.T, query url.Values, want int) DevicesResponse {
+	t.Helper()
+	w := httptest.NewRecorder()
+	GetDevices(w, httptest.NewRequest(http.MethodGet, "/devices?"+query.Encode(), nil))
+	if w.Code != want {
+		t.Fatalf("%s: status %d, want %d: %s", query.Encode(), w.Code, want, w.Body)
+	}
+	var page DevicesResponse
+	if want == http.StatusOK {
+		if err := json.Unmarshal(w.Body.Bytes(), &page); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return page
+}
+
+func TestContinueTokenEncoding(t *testing.T) {
+	token := &continueToken{Name: "node-1", Time: 1735689600000000000, UID: "dev-01", Query: "0123456789abcdef"}
+	encoded := encodeContinueToken(token)
+	if strings.ContainsAny(encoded, "+/=") {
+		t.Errorf("token %q isn't URL-safe", encoded)
+	}
+	decoded, err := decodeContinueToken(encoded)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if *decoded != *token {
+		t.Errorf("decoded %+v, want %+v", decoded, token)
+	}
+
+	// The fingerprint covers everything but limit and continue
+	a := queryFingerprint(url.Values{"sortBy": {"name"}, "limit": {"5"}})
+	b := queryFingerprint(url.Values{"sortBy": {"name"}, "limit": {"50"}, "continue": {encoded}})
+	if a != b {
+		t.Error("limit or continue changed the query fingerprint")
+	}
+	for _, other := range []url.Values{
+		{"sortBy": {"name"}, "order": {"desc"}},
+		{"sortBy": {"createdAt"}},
+		{"sortBy": {"name"}, "labelSelector": {"rack=r1"}},
+	} {
+		if queryFingerprint(other) == a {
+			t.Errorf("%s has the same fingerprint as sortBy=name", other.Encode())
+		}
+	}
+}
+
+// TestContinueTokenRejected checks that tokens that were tampered with, or
+// come from a request with other sorting or filtering, fail with 400.
+func TestContinueTokenRejected(t *testing.T) {
+	initTestStorage(t)
+	for i := 1; i <= 5; i++ {
+		saveTestDevice(t, fmt.Sprintf("node-%d", i), "Node", fmt.Sprintf("SN%d", i), nil)
+	}
+	first := getDevicePage(t, url.Values{"sortBy": {"name"}, "limit": {"2"}}, http.StatusOK)
+	if first.Continue == "" {
+		t.Fatal("no continue token on the first page")
+	}
+	getDevicePage(t, url.Values{"sortBy": {"name"}, "limit": {"2"}, "continue": {first.Continue}}, http.StatusOK)
+
+	// A token for another query, made by editing a real one
+	edited, _ := decodeContinueToken(first.Continue)
+	edited.Query = queryFingerprint(url.Values{"sortBy": {"uid"}})
+	forged := encodeContinueToken(edited)
+
+	tests := map[string]url.Values{
+		"not base64":         {"sortBy": {"name"}, "continue": {"not a token!"}},
+		"not JSON":           {"sortBy": {"name"}, "continue": {base64.RawURLEncoding.EncodeToString([]byte("{"))}},
+		"truncated":          {"sortBy": {"name"}, "continue": {first.Continue[:len(first.Continue)-4]}},
+		"padded":             {"sortBy": {"name"}, "continue": {first.Continue + "=="}},
+		"other query":        {"sortBy": {"name"}, "continue": {forged}},
+		"sort order changed": {"sortBy": {"createdAt"}, "limit": {"2"}, "continue": {first.Continue}},
+		"direction changed":  {"sortBy": {"name"}, "order": {"desc"}, "limit": {"2"}, "continue": {first.Continue}},
+		"selector added":     {"sortBy": {"name"}, "labelSelector": {"rack=r1"}, "limit": {"2"}, "continue": {first.Continue}},
+	}
+	for name, query := range tests {
+		t.Run(name, func(t *testing.T) {
+			getDevicePage(t, query, http.StatusBadRequest)
+		})
+	}
+}
+
+// TestPagesStableUnderChanges checks that devices created or deleted while a
+// client pages through a list don't make it skip or repeat the others.
+func TestPagesStableUnderChanges(t *testing.T) {
+	for _, backend := range []struct {
+		name string
+		init func(t *testing.T)
+	}{
+		{"file", initTestStorage},
+		{"sqlite", initSQLiteTestStorage},
+	} {
+		t.Run(backend.name, func(t *testing.T) {
+			backend.init(t)
+			for _, name := range []string{"b", "d", "f", "h", "j", "l", "n"} {
+				saveTestDevice(t, name, "Node", "SN-"+name, nil)
+			}
+			query := url.Values{"sortBy": {"name"}, "limit": {"3"}}
+
+			var seen []string
+			uids := map[string]string{}
+			page := getDevicePage(t, query, http.StatusOK)
+			for _, d := range page.Items {
+				seen = append(seen, d.Metadata.Name)
+			}
+			if fmt.Sprint(seen) != "[b d f]" || page.Total != 7 {
+				t.Fatalf("first page %v of %d", seen, page.Total)
+			}
+			for _, d := range page.Items {
+				uids[d.Metadata.Name] = d.Metadata.UID
+			}
+
+			// Between pages: one device before the token and one after it are
+			// created, and one already seen and one not yet seen are deleted
+			saveTestDevice(t, "a", "Node", "SN-a", nil)
+			saveTestDevice(t, "g", "Node", "SN-g", nil)
+			if err := storage.DeleteDevice(context.Background(), uids["d"]); err != nil {
+				t.Fatal(err)
+			}
+			all, err := storage.LoadAllDevices(context.Background())
+			if err != nil {
+				t.Fatal(err)
+			}
+			for _, d := range all {
+				if d.Metadata.Name == "j" {
+					if err := storage.DeleteDevice(context.Background(), d.Metadata.UID); err != nil {
+						t.Fatal(err)
+					}
+				}
+			}
+
+			for page.Continue != "" {
+				query.Set("continue", page.Continue)
+				page = getDevicePage(t, query, http.StatusOK)
+				for _, d := range page.Items {
+					seen = append(seen, d.Metadata.Name)
+				}
+			}
+			// "a" sorts before where the client is, so it isn't seen; nothing repeats
+			if got := strings.Join(seen, " "); got != "b d f g h l n" {
+				t.Errorf("paged through %q, want %q", got, "b d f g h l n")
+			}
+		})
+	}
+}
@@ -14,6 +14,7 @@
 // This file registers routes for all resource types:
 //   - /devices (Device operations)
 //   - /discoverysnapshots (DiscoverySnapshot operations)
+//   - /subscriptions (Subscription operations)
 //
 // Route patterns:
 //   - GET    /resource              -> List all resources
@@ -46,12 +47,25 @@ func RegisterGeneratedRoutes(r chi.Router) {
 	r.Route("/devices", func(r chi.Router) {
 		r.Get("/", GetDevices)
 		r.Post("/", CreateDevice)
+
+		// Lookups by natural keys
+		r.Get("/by-serial/{serial}", GetDeviceBySerial)
+		r.Get("/by-mac/{mac}", GetDeviceByMAC)
+		r.Get("/by-redfish-uri", GetDevicesByRedfishURI)
+
 		r.Route("/{uid}", func(r chi.Router) {
 			r.Get("/", GetDevice)
 			r.Put("/", UpdateDevice)
 			r.Patch("/", PatchDevice)
 			r.Delete("/", DeleteDevice)
 
+			// Hierarchy navigation
+			r.Get("/children", GetDeviceChildren)
+			r.Get("/descendants", GetDeviceDescendants)
+			r.Get("/ancestors", GetDeviceAncestors)
+			r.Get("/tree", GetDeviceTree)
+			r.Get("/hbom", GetDeviceHBOM)
+
 			// Status subresource
 			r.Route("/status", func(r chi.Router) {
 				r.Put("/", UpdateDeviceStatus)
@@ -78,6 +92,24 @@ func RegisterGeneratedRoutes(r chi.Router) {
 		})
 	})
 
+	// Subscription routes
+	r.Route("/subscriptions", func(r chi.Router) {
+		r.Get("/", GetSubscriptions)
+		r.Post("/", CreateSubscription)
+		r.Route("/{uid}", func(r chi.Router) {
+			r.Get("/", GetSubscription)
+			r.Put("/", UpdateSubscription)
+			r.Patch("/", PatchSubscription)
+			r.Delete("/", DeleteSubscription)
+
+			// Status subresource
+			r.Route("/status", func(r chi.Router) {
+				r.Put("/", UpdateSubscriptionStatus)
+				r.Patch("/", PatchSubscriptionStatus)
+			})
+		})
+	})
+
 	// OpenAPI documentation routes
 	r.Get("/openapi.json", ServeOpenAPISpec)
 	r.Get("/docs", ServeSwaggerUI)
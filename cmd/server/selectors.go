@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/user/inventory-api/internal/selector"
+	"github.com/user/inventory-api/pkg/resources/device"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+)
+
+// propertyFieldPrefix selects on a device property, e.g. spec.properties.speedMHz=3200
+const propertyFieldPrefix = "spec.properties."
+
+// deviceFields are the device fields lists can be filtered on
+var deviceFields = map[string]func(d *device.Device) string{
+	"metadata.name":           func(d *device.Device) string { return d.Metadata.Name },
+	"metadata.uid":            func(d *device.Device) string { return d.Metadata.UID },
+	"spec.deviceType":         func(d *device.Device) string { return d.Spec.DeviceType },
+	"spec.manufacturer":       func(d *device.Device) string { return d.Spec.Manufacturer },
+	"spec.partNumber":         func(d *device.Device) string { return d.Spec.PartNumber },
+	"spec.serialNumber":       func(d *device.Device) string { return d.Spec.SerialNumber },
+	"spec.parentID":           func(d *device.Device) string { return d.Spec.ParentID },
+	"spec.parentSerialNumber": func(d *device.Device) string { return d.Spec.ParentSerialNumber },
+	"status.phase":            func(d *device.Device) string { return d.Status.Phase },
+}
+
+// discoverySnapshotFields are the discoverysnapshot fields lists can be filtered on
+var discoverySnapshotFields = map[string]func(s *discoverysnapshot.DiscoverySnapshot) string{
+	"metadata.name": func(s *discoverysnapshot.DiscoverySnapshot) string { return s.Metadata.Name },
+	"metadata.uid":  func(s *discoverysnapshot.DiscoverySnapshot) string { return s.Metadata.UID },
+	"status.phase":  func(s *discoverysnapshot.DiscoverySnapshot) string { return s.Status.Phase },
+}
+
+func isDeviceField(field string) bool {
+	if key, ok := strings.CutPrefix(field, propertyFieldPrefix); ok {
+		return key != ""
+	}
+	_, ok := deviceFields[field]
+	return ok
+}
+
+func isDiscoverySnapshotField(field string) bool {
+	_, ok := discoverySnapshotFields[field]
+	return ok
+}
+
+// deviceFieldValue returns a device field. A property that isn't set is
+// reported as missing; a string property compares by its string value and
+// any other JSON value by its compact JSON text (e.g. 16, true).
+func deviceFieldValue(d *device.Device, field string) (string, bool) {
+	if key, ok := strings.CutPrefix(field, propertyFieldPrefix); ok {
+		raw, ok := d.Spec.Properties[key]
+		if !ok {
+			return "", false
+		}
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			return s, true
+		}
+		var compact bytes.Buffer
+		if err := json.Compact(&compact, raw); err != nil {
+			return string(raw), true
+		}
+		return compact.String(), true
+	}
+	get, ok := deviceFields[field]
+	if !ok {
+		return "", false
+	}
+	return get(d), true
+}
+
+func discoverySnapshotFieldValue(s *discoverysnapshot.DiscoverySnapshot, field string) (string, bool) {
+	get, ok := discoverySnapshotFields[field]
+	if !ok {
+		return "", false
+	}
+	return get(s), true
+}
+
+// listFilter is the label and field selection of a list request
+type listFilter struct {
+	labels selector.Selector
+	fields selector.Selector
+}
+
+// parseListFilter reads labelSelector and fieldSelector from a list request.
+// A known field may also be given as its own query parameter
+// (?spec.deviceType=DIMM), which is the same as field=value in fieldSelector.
+func parseListFilter(r *http.Request, isField func(field string) bool) (*listFilter, error) {
+	query := r.URL.Query()
+
+	labels, err := selector.ParseLabels(query.Get("labelSelector"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid labelSelector: %w", err)
+	}
+	fields, err := selector.ParseFields(query.Get("fieldSelector"))
+	if err != nil {
+		return nil, fmt.Errorf("invalid fieldSelector: %w", err)
+	}
+	for _, req := range fields {
+		if !isField(req.Key) {
+			return nil, fmt.Errorf("invalid fieldSelector: unsupported field %q", req.Key)
+		}
+	}
+	for key, values := range query {
+		if !isField(key) {
+			continue
+		}
+		for _, value := range values {
+			fields = append(fields, selector.Requirement{Key: key, Operator: selector.Equals, Values: []string{value}})
+		}
+	}
+
+	return &listFilter{labels: labels, fields: fields}, nil
+}
+
+// matches reports whether a resource's labels and fields satisfy the filter
+func (f *listFilter) matches(labels map[string]string, field func(field string) (string, bool)) bool {
+	return f.labels.MatchesLabels(labels) && f.fields.Matches(field)
+}
+
+// filterDevices keeps the devices that match a list filter
+func filterDevices(devices []*device.Device, filter *listFilter) []*device.Device {
+	result := make([]*device.Device, 0, len(devices))
+	for _, d := range devices {
+		field := func(name string) (string, bool) { return deviceFieldValue(d, name) }
+		if filter.matches(d.Metadata.Labels, field) {
+			result = append(result, d)
+		}
+	}
+	return result
+}
+
+// filterDiscoverySnapshots keeps the discoverysnapshots that match a list filter
+func filterDiscoverySnapshots(snapshots []*discoverysnapshot.DiscoverySnapshot, filter *listFilter) []*discoverysnapshot.DiscoverySnapshot {
+	result := make([]*discoverysnapshot.DiscoverySnapshot, 0, len(snapshots))
+	for _, s := range snapshots {
+		field := func(name string) (string, bool) { return discoverySnapshotFieldValue(s, name) }
+		if filter.matches(s.Metadata.Labels, field) {
+			result = append(result, s)
+		}
+	}
+	return result
+}
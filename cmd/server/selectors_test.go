@@ -0,0 +1,103 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/openchami/fabrica/pkg/resource"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// initTestStorage points storage at a fresh data directory, set up as the server does
+func initTestStorage(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	if err := storage.InitJournaledFileBackend(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.InitVersioning(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.InitIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { storage.Backend.Close() })
+}
+
+// saveTestDevice stores a Device with the given string properties
+func saveTestDevice(t *testing.T, name, deviceType, serial string, properties map[string]string) {
+	t.Helper()
+	uid, err := resource.GenerateUIDForResource("Device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := &device.Device{
+		Resource: resource.Resource{APIVersion: "v1", Kind: "Device", SchemaVersion: "v1"},
+		Spec:     device.DeviceSpec{DeviceType: deviceType, SerialNumber: serial, Properties: map[string]json.RawMessage{}},
+	}
+	dev.Metadata.UID = uid
+	dev.Metadata.Name = name
+	for key, value := range properties {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dev.Spec.Properties[key] = raw
+	}
+	if err := storage.SaveDevice(context.Background(), dev); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestInvalidSelectorsRejected checks that list requests with malformed
+// selectors or unknown fields fail with 400 instead of listing everything.
+func TestInvalidSelectorsRejected(t *testing.T) {
+	initTestStorage(t)
+	saveTestDevice(t, "node-1", "Node", "SN1", nil)
+
+	tests := []struct {
+		name    string
+		handler http.HandlerFunc
+		query   url.Values
+		want    int
+	}{
+		{"valid selectors", GetDevices, url.Values{"labelSelector": {"rack in (r1,r2)"}, "fieldSelector": {"spec.deviceType=Node"}}, http.StatusOK},
+		{"field parameter", GetDevices, url.Values{"spec.deviceType": {"Node"}}, http.StatusOK},
+		{"property field", GetDevices, url.Values{"fieldSelector": {"spec.properties.speedMHz=3200"}}, http.StatusOK},
+		{"malformed label selector", GetDevices, url.Values{"labelSelector": {"rack in (r1"}}, http.StatusBadRequest},
+		{"label value with spaces", GetDevices, url.Values{"labelSelector": {"rack=r1 r2"}}, http.StatusBadRequest},
+		{"malformed field selector", GetDevices, url.Values{"fieldSelector": {"spec.deviceType"}}, http.StatusBadRequest},
+		{"set-based field selector", GetDevices, url.Values{"fieldSelector": {"spec.deviceType in (Node)"}}, http.StatusBadRequest},
+		{"unknown field", GetDevices, url.Values{"fieldSelector": {"spec.colour=red"}}, http.StatusBadRequest},
+		{"empty property name", GetDevices, url.Values{"fieldSelector": {"spec.properties.=x"}}, http.StatusBadRequest},
+		{"snapshot field", GetDiscoverySnapshots, url.Values{"fieldSelector": {"status.phase=Completed"}}, http.StatusOK},
+		{"device field on snapshots", GetDiscoverySnapshots, url.Values{"fieldSelector": {"spec.deviceType=Node"}}, http.StatusBadRequest},
+		{"malformed snapshot label selector", GetDiscoverySnapshots, url.Values{"labelSelector": {"=x"}}, http.StatusBadRequest},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			w := httptest.NewRecorder()
+			tt.handler(w, httptest.NewRequest(http.MethodGet, "/?"+tt.query.Encode(), nil))
+			if w.Code != tt.want {
+				t.Fatalf("status %d, want %d: %s", w.Code, tt.want, w.Body)
+			}
+			if tt.want != http.StatusBadRequest {
+				return
+			}
+			var body map[string]any
+			if err := json.Unmarshal(w.Body.Bytes(), &body); err != nil {
+				t.Fatalf("error response isn't JSON: %s", w.Body)
+			}
+			if !strings.Contains(w.Body.String(), "Selector") {
+				t.Errorf("error doesn't name the selector: %s", w.Body)
+			}
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/go-chi/chi/v5"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+	"github.com/user/inventory-api/pkg/smd"
+)
+
+// Export formats for SMD hardware inventory
+const (
+	exportSMDLocation = "smd-location"
+	exportSMDFRU      = "smd-fru"
+)
+
+// writeSMDExport writes devices as a JSON array of SMD locations or FRUs.
+// Xnames are derived from the whole inventory, so a selected Device gets
+// the same xname as in an unfiltered export.
+func writeSMDExport(w http.ResponseWriter, r *http.Request, format string, devices []*device.Device) {
+	var body any
+	if format == exportSMDFRU {
+		body = smd.ByFRU(devices)
+	} else {
+		all, err := storage.LoadAllDevices(r.Context())
+		if err != nil {
+			respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load devices: %w", err))
+			return
+		}
+		body = smd.ByLocation(devices, smd.XNames(all))
+	}
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "hwinventory-"+strings.TrimPrefix(format, "smd-")+".json"))
+	respondJSON(w, http.StatusOK, body)
+}
+
+// registerSMDCompatRoutes adds read-only SMD hardware inventory endpoints,
+// so services that read hardware from SMD can read it from here instead
+func registerSMDCompatRoutes(r chi.Router) {
+	r.Route("/hsm/v2/Inventory", func(r chi.Router) {
+		r.Get("/Hardware", GetSMDHardware)
+		r.Get("/Hardware/{xname}", GetSMDHardwareByXName)
+		r.Get("/HardwareByFRU", GetSMDHardwareByFRU)
+		r.Get("/HardwareByFRU/{fruid}", GetSMDHardwareByFRUID)
+	})
+}
+
+// GetSMDHardware handles GET /hsm/v2/Inventory/Hardware: every location,
+// optionally only those with ?id= xnames or ?type= SMD types
+func GetSMDHardware(w http.ResponseWriter, r *http.Request) {
+	devices, err := storage.LoadAllDevices(r.Context())
+	if err != nil {
+		respondSMDProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	ids, types := smdQuerySet(r, "id"), smdQuerySet(r, "type")
+	locations := []smd.HWInvByLoc{}
+	for _, loc := range smd.ByLocation(devices, smd.XNames(devices)) {
+		if (ids == nil || ids[strings.ToLower(loc.ID)]) && (types == nil || types[strings.ToLower(loc.Type)]) {
+			locations = append(locations, loc)
+		}
+	}
+	respondJSON(w, http.StatusOK, locations)
+}
+
+// GetSMDHardwareByXName handles GET /hsm/v2/Inventory/Hardware/{xname}
+func GetSMDHardwareByXName(w http.ResponseWriter, r *http.Request) {
+	xname := strings.ToLower(chi.URLParam(r, "xname"))
+	devices, err := storage.LoadAllDevices(r.Context())
+	if err != nil {
+		respondSMDProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, loc := range smd.ByLocation(devices, smd.XNames(devices)) {
+		if strings.ToLower(loc.ID) == xname {
+			respondJSON(w, http.StatusOK, loc)
+			return
+		}
+	}
+	respondSMDProblem(w, http.StatusNotFound, "no such xname.")
+}
+
+// GetSMDHardwareByFRU handles GET /hsm/v2/Inventory/HardwareByFRU: every
+// FRU, optionally only those with ?fruid= IDs or ?type= SMD types
+func GetSMDHardwareByFRU(w http.ResponseWriter, r *http.Request) {
+	devices, err := storage.LoadAllDevices(r.Context())
+	if err != nil {
+		respondSMDProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	fruids, types := smdQuerySet(r, "fruid"), smdQuerySet(r, "type")
+	frus := []smd.HWInvByFRU{}
+	for _, fru := range smd.ByFRU(devices) {
+		if (fruids == nil || fruids[strings.ToLower(fru.FRUID)]) && (types == nil || types[strings.ToLower(fru.Type)]) {
+			frus = append(frus, fru)
+		}
+	}
+	respondJSON(w, http.StatusOK, frus)
+}
+
+// GetSMDHardwareByFRUID handles GET /hsm/v2/Inventory/HardwareByFRU/{fruid}
+func GetSMDHardwareByFRUID(w http.ResponseWriter, r *http.Request) {
+	fruid := chi.URLParam(r, "fruid")
+	devices, err := storage.LoadAllDevices(r.Context())
+	if err != nil {
+		respondSMDProblem(w, http.StatusInternalServerError, err.Error())
+		return
+	}
+	for _, fru := range smd.ByFRU(devices) {
+		if strings.EqualFold(fru.FRUID, fruid) {
+			respondJSON(w, http.StatusOK, fru)
+			return
+		}
+	}
+	respondSMDProblem(w, http.StatusNotFound, "no such fru.")
+}
+
+// smdQuerySet returns the lowercased values of a repeatable query
+// parameter, or nil if it isn't given
+func smdQuerySet(r *http.Request, name string) map[string]bool {
+	values := r.URL.Query()[name]
+	if len(values) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(values))
+	for _, value := range values {
+		for _, v := range strings.Split(value, ",") {
+			set[strings.ToLower(strings.TrimSpace(v))] = true
+		}
+	}
+	return set
+}
+
+// respondSMDProblem writes an error as SMD does, as an RFC 7807 problem
+func respondSMDProblem(w http.ResponseWriter, status int, detail string) {
+	w.Header().Set("Content-Type", "application/problem+json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(map[string]any{
+		"type":   "about:blank",
+		"title":  http.StatusText(status),
+		"detail": detail,
+		"status": status,
+	})
+}
+
+// registerSMDPaths documents the SMD compatibility endpoints
+func registerSMDPaths(spec *openapi3.T) {
+	arrayResponse := &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Successful response").
+			WithJSONSchema(openapi3.NewArraySchema().WithItems(openapi3.NewObjectSchema())),
+	}
+	objectResponse := &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Successful response").
+			WithJSONSchema(openapi3.NewObjectSchema()),
+	}
+	problemResponse := &openapi3.ResponseRef{
+		Value: openapi3.NewResponse().
+			WithDescription("Not found").
+			WithContent(openapi3.Content{
+				"application/problem+json": openapi3.NewMediaType().WithSchema(openapi3.NewObjectSchema()),
+			}),
+	}
+	const served = " Only served when the server runs with --smd-compat."
+
+	operation := func(id, summary string, params openapi3.Parameters, response *openapi3.ResponseRef) *openapi3.PathItem {
+		op := openapi3.NewOperation()
+		op.OperationID = id
+		op.Summary = summary
+		op.Description = "Read-only SMD (State Management Database) compatible hardware inventory, converted from Devices." + served
+		op.Tags = []string{"SMD"}
+		op.Parameters = params
+		op.Responses = openapi3.NewResponses()
+		op.Responses.Set("200", response)
+		if response == objectResponse {
+			op.Responses.Set("404", problemResponse)
+		}
+		return &openapi3.PathItem{Get: op}
+	}
+	typeParam := &openapi3.ParameterRef{Value: openapi3.NewQueryParameter("type").
+		WithDescription("Only these SMD types (repeatable or comma-separated)").
+		WithSchema(openapi3.NewStringSchema())}
+
+	spec.Paths.Set("/hsm/v2/Inventory/Hardware", operation("getSMDHardware",
+		"List SMD hardware inventory by location", openapi3.Parameters{
+			{Value: openapi3.NewQueryParameter("id").WithDescription("Only these xnames (repeatable or comma-separated)").WithSchema(openapi3.NewStringSchema())},
+			typeParam,
+		}, arrayResponse))
+	spec.Paths.Set("/hsm/v2/Inventory/Hardware/{xname}", operation("getSMDHardwareByXName",
+		"Get the SMD hardware inventory location at an xname", openapi3.Parameters{
+			{Value: openapi3.NewPathParameter("xname").WithSchema(openapi3.NewStringSchema())},
+		}, objectResponse))
+	spec.Paths.Set("/hsm/v2/Inventory/HardwareByFRU", operation("getSMDHardwareByFRU",
+		"List SMD hardware inventory by FRU", openapi3.Parameters{
+			{Value: openapi3.NewQueryParameter("fruid").WithDescription("Only these FRU IDs (repeatable or comma-separated)").WithSchema(openapi3.NewStringSchema())},
+			typeParam,
+		}, arrayResponse))
+	spec.Paths.Set("/hsm/v2/Inventory/HardwareByFRU/{fruid}", operation("getSMDHardwareByFRUID",
+		"Get an SMD FRU", openapi3.Parameters{
+			{Value: openapi3.NewPathParameter("fruid").WithSchema(openapi3.NewStringSchema())},
+		}, objectResponse))
+}
@@ -0,0 +1,54 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	internal_storage "github.com/user/inventory-api/internal/storage"
+)
+
+// initStorageBackend opens the storage backend selected by config.Storage
+// and returns a description of it for the log.
+func initStorageBackend(config *Config) (string, error) {
+	switch config.Storage {
+	case "", "file":
+		if err := internal_storage.InitJournaledFileBackend(config.DataDir); err != nil {
+			return "", fmt.Errorf("failed to initialize file storage: %w", err)
+		}
+		return fmt.Sprintf("file backend in %s", config.DataDir), nil
+
+	case "sqlite":
+		if config.StorageDSN == "" {
+			if err := os.MkdirAll(config.DataDir, 0755); err != nil {
+				return "", fmt.Errorf("failed to create data directory: %w", err)
+			}
+		}
+		dsn := sqliteDSN(config)
+		if err := internal_storage.InitSQLiteBackend(context.Background(), dsn); err != nil {
+			return "", fmt.Errorf("failed to initialize sqlite storage: %w", err)
+		}
+		return fmt.Sprintf("sqlite backend at %s", dsn), nil
+
+	default:
+		return "", fmt.Errorf("unknown storage backend %q (expected \"file\" or \"sqlite\")", config.Storage)
+	}
+}
+
+// sqliteDSN returns the database of the sqlite backend
+func sqliteDSN(config *Config) string {
+	if config.StorageDSN != "" {
+		return config.StorageDSN
+	}
+	return filepath.Join(config.DataDir, "inventory.db")
+}
+
+// storageSpec returns the backend spec (as taken by migrate and fsck) of the
+// configured storage
+func storageSpec(config *Config) string {
+	if config.Storage == "sqlite" {
+		return "sqlite:" + sqliteDSN(config)
+	}
+	return "file:" + config.DataDir
+}
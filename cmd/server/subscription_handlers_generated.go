@@ -0,0 +1,486 @@
+// Code generated by Fabrica dev. DO NOT EDIT.
+// Template: server/handlers.go.tmpl
+// Generated: 2025-11-08T10:29:29-08:00
+//
+// # Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+//
+// This file contains REST API handlers for Subscription resources.
+//
+// To modify this code:
+//  1. Edit the template file: pkg/codegen/templates/handlers.go.tmpl
+//  2. Run 'make dev' to regenerate
+//  3. Do NOT edit this file directly - changes will be lost
+//
+// Generated handlers provide:
+//   - GET /subscriptions (list all subscriptions)
+//   - GET /subscriptions/{uid} (get specific Subscription)
+//   - POST /subscriptions (create new Subscription)
+//   - PUT /subscriptions/{uid} (update Subscription spec)
+//   - PATCH /subscriptions/{uid} (patch Subscription spec)
+//   - DELETE /subscriptions/{uid} (delete Subscription)
+//   - PUT /subscriptions/{uid}/status (update Subscription status)
+//   - PATCH /subscriptions/{uid}/status (patch Subscription status)
+//
+// Authorization: Add custom middleware for authentication/authorization
+// Storage: Uses storage.LoadSubscription*/SaveSubscription*/DeleteSubscription*
+// Version Support: Available (see version context in handlers)
+//
+// To enable full version conversion for this resource:
+//  1. Create v2beta1 package: pkg/resources/subscription/v2beta1/
+//  2. Implement converter: v2beta1/converter.go
+//  3. Add version-aware storage: storage.LoadSubscriptionWithVersion()
+//  4. Register versions in cmd/server/main.go
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/openchami/fabrica/pkg/events"
+	"github.com/openchami/fabrica/pkg/patch"
+	"github.com/openchami/fabrica/pkg/resource"
+	"github.com/openchami/fabrica/pkg/validation"
+	"github.com/openchami/fabrica/pkg/versioning"
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/subscription"
+)
+
+// GetSubscriptions returns all Subscription resources
+func GetSubscriptions(w http.ResponseWriter, r *http.Request) {
+	// Authorization: Add custom middleware in routes.go or implement checks here
+	// Example: if !authorized(r) { respondError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized")); return }
+
+	subscriptions, err := storage.LoadAllSubscriptions(r.Context())
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to load subscriptions: %w", err))
+		return
+	}
+	// Secrets are write-only
+	redacted := make([]*subscription.Subscription, 0, len(subscriptions))
+	for _, sub := range subscriptions {
+		redacted = append(redacted, sub.Redacted())
+	}
+	respondJSON(w, http.StatusOK, redacted)
+}
+
+// GetSubscription returns a specific Subscription resource by UID
+func GetSubscription(w http.ResponseWriter, r *http.Request) {
+	uid := chi.URLParam(r, "uid")
+	if uid == "" {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("Subscription UID is required"))
+		return
+	}
+
+	// Version context available here for version-aware operations
+	// versionCtx := versioning.GetVersionContext(r.Context())
+	// Requested version: versionCtx.ServeVersion
+	// To enable: replace storage.LoadSubscription() with version-aware function
+
+	// Authorization: Add custom middleware in routes.go or implement checks here
+	// Example: if !authorized(r) { respondError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized")); return }
+
+	subscription, err := storage.LoadSubscription(r.Context(), uid)
+	if err != nil {
+		respondError(w, http.StatusNotFound, fmt.Errorf("Subscription not found: %w", err))
+		return
+	}
+	if !checkIfNoneMatch(w, r, subscription.Redacted()) {
+		return
+	}
+	respondJSON(w, http.StatusOK, subscription.Redacted())
+}
+
+// CreateSubscription creates a new Subscription resource
+func CreateSubscription(w http.ResponseWriter, r *http.Request) {
+	var req CreateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if !checkVersionAnnotation(w, req.Annotations, nil) {
+		return
+	}
+
+	// Get version context from request
+	versionCtx := versioning.GetVersionContext(r.Context())
+
+	uid, err := resource.GenerateUIDForResource("Subscription")
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to generate UID: %w", err))
+		return
+	}
+
+	subscription := &subscription.Subscription{
+		Resource: resource.Resource{
+			APIVersion:    versionCtx.GroupVersion,
+			Kind:          "Subscription",
+			SchemaVersion: versionCtx.ServeVersion,
+		},
+		Spec: req.SubscriptionSpec,
+	}
+
+	subscription.Metadata.Initialize(req.Name, uid)
+
+	// Set labels and annotations
+	for k, v := range req.Labels {
+		subscription.SetLabel(k, v)
+	}
+	for k, v := range req.Annotations {
+		subscription.SetAnnotation(k, v)
+	}
+
+	// Layer 2: Fabrica struct tag validation
+	if err := validation.ValidateResource(subscription); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("validation failed: %w", err))
+		return
+	}
+
+	// Layer 3: Custom business logic validation
+	if err := validation.ValidateWithContext(r.Context(), subscription); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("validation failed: %w", err))
+		return
+	}
+
+	// Set initial status
+
+	// Save (Layer 1: Ent validation happens automatically if using Ent storage)
+	if err := storage.SaveSubscription(r.Context(), subscription); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save Subscription: %w", err))
+		return
+	}
+
+	// Publish resource created event
+	if err := events.PublishResourceCreated(r.Context(), "Subscription", subscription.GetUID(), subscription.GetName(), subscription.Redacted()); err != nil {
+		// Log the error but don't fail the request - events are non-critical
+		fmt.Printf("Warning: Failed to publish resource created event for Subscription %s: %v\n", subscription.GetUID(), err)
+	}
+
+	setETag(w, subscription.Redacted())
+	respondJSON(w, http.StatusCreated, subscription.Redacted())
+}
+
+// UpdateSubscription updates the spec of an existing Subscription resource
+// NOTE: This endpoint ONLY updates the spec. Use PUT //subscriptions/{uid}/status to update status.
+func UpdateSubscription(w http.ResponseWriter, r *http.Request) {
+	uid := chi.URLParam(r, "uid")
+	if uid == "" {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("Subscription UID is required"))
+		return
+	}
+
+	subscription, err := storage.LoadSubscription(r.Context(), uid)
+	if err != nil {
+		respondError(w, http.StatusNotFound, fmt.Errorf("Subscription not found: %w", err))
+		return
+	}
+
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, subscription.Redacted()) {
+		return
+	}
+
+	var req UpdateSubscriptionRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid request body: %w", err))
+		return
+	}
+	if !checkVersionAnnotation(w, req.Annotations, &subscription.Metadata) {
+		return
+	}
+
+	// Apply updates
+	if req.Name != "" {
+		subscription.SetName(req.Name)
+	}
+
+	// Update spec fields ONLY - status should use /status subresource
+	// The secret is write-only, so an update without one keeps the current secret
+	if req.Secret == "" {
+		req.Secret = subscription.Spec.Secret
+	}
+	subscription.Spec = req.SubscriptionSpec
+
+	// Update labels and annotations
+	for k, v := range req.Labels {
+		subscription.SetLabel(k, v)
+	}
+	for k, v := range req.Annotations {
+		subscription.SetAnnotation(k, v)
+	}
+
+	subscription.Touch()
+
+	if err := storage.SaveSubscription(r.Context(), subscription); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save Subscription: %w", err))
+		return
+	}
+
+	// Publish resource updated event
+	updateMetadata := map[string]interface{}{
+		"updatedAt": subscription.Metadata.UpdatedAt,
+	}
+	if err := events.PublishResourceUpdated(r.Context(), "Subscription", subscription.GetUID(), subscription.GetName(), subscription.Redacted(), updateMetadata); err != nil {
+		// Log the error but don't fail the request - events are non-critical
+		fmt.Printf("Warning: Failed to publish resource updated event for Subscription %s: %v\n", subscription.GetUID(), err)
+	}
+
+	setETag(w, subscription.Redacted())
+	respondJSON(w, http.StatusOK, subscription.Redacted())
+}
+
+// PatchSubscription patches an existing Subscription resource spec using JSON Merge Patch, JSON Patch, or Shorthand Patch
+// Only the spec portion of the resource can be patched - metadata and status are API-managed
+func PatchSubscription(w http.ResponseWriter, r *http.Request) {
+	uid := chi.URLParam(r, "uid")
+	if uid == "" {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("Subscription UID is required"))
+		return
+	}
+
+	subscription, err := storage.LoadSubscription(r.Context(), uid)
+	if err != nil {
+		respondError(w, http.StatusNotFound, fmt.Errorf("Subscription not found: %w", err))
+		return
+	}
+
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, subscription.Redacted()) {
+		return
+	}
+
+	// Read patch document
+	patchData, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("failed to read patch data: %w", err))
+		return
+	}
+
+	// Marshal current spec to JSON for patching (only allow spec modifications)
+	currentSpecJSON, err := json.Marshal(subscription.Spec)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to marshal current spec: %w", err))
+		return
+	}
+
+	// Detect patch type from Content-Type header
+	contentType := r.Header.Get("Content-Type")
+	patchType := patch.DetectPatchType(contentType)
+
+	// Apply patch to spec only
+	patchResult, err := patch.ApplyPatchWithOptions(currentSpecJSON, patchData, patchType, patch.PatchOptions{
+		AllowAddFields:    true,
+		AllowRemoveFields: true,
+	})
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, fmt.Errorf("failed to apply patch to spec: %w", err))
+		return
+	}
+
+	// Unmarshal the patched result back to the spec
+	if err := json.Unmarshal(patchResult.Updated, &subscription.Spec); err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to unmarshal patched spec: %w", err))
+		return
+	}
+
+	// Touch to update metadata
+	subscription.Touch()
+
+	// Save the patched resource
+	if err := storage.SaveSubscription(r.Context(), subscription); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save patched Subscription: %w", err))
+		return
+	}
+
+	// Publish resource patched event
+	patchMetadata := map[string]interface{}{
+		"patchType": patchType,
+		"updatedAt": subscription.Metadata.UpdatedAt,
+	}
+	if err := events.PublishResourcePatched(r.Context(), "Subscription", subscription.GetUID(), subscription.GetName(), subscription.Redacted(), patchMetadata); err != nil {
+		// Log the error but don't fail the request - events are non-critical
+		fmt.Printf("Warning: Failed to publish resource patched event for Subscription %s: %v\n", subscription.GetUID(), err)
+	}
+
+	setETag(w, subscription.Redacted())
+	respondJSON(w, http.StatusOK, subscription.Redacted())
+}
+
+// UpdateSubscriptionStatus updates only the status of a Subscription resource
+// This endpoint is intended for controllers, reconcilers, and monitoring systems.
+// It does not modify the spec or metadata (except updatedAt timestamp).
+//
+// Authorization: Requires 'update_status' permission (separate from 'update' permission)
+// Events: Publishes resource updated event with updateType: "status"
+func UpdateSubscriptionStatus(w http.ResponseWriter, r *http.Request) {
+	uid := chi.URLParam(r, "uid")
+	if uid == "" {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("Subscription UID is required"))
+		return
+	}
+
+	// Authorization: Add custom middleware for status update authorization
+	// Status updates can have different permissions than spec updates
+
+	res, err := storage.LoadSubscription(r.Context(), uid)
+	if err != nil {
+		respondError(w, http.StatusNotFound, fmt.Errorf("Subscription not found: %w", err))
+		return
+	}
+
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, res.Redacted()) {
+		return
+	}
+
+	var statusUpdate subscription.SubscriptionStatus
+	if err := json.NewDecoder(r.Body).Decode(&statusUpdate); err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("invalid status body: %w", err))
+		return
+	}
+
+	// Preserve spec - only update status
+	res.Touch()
+
+	if err := storage.SaveSubscription(r.Context(), res); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save Subscription status: %w", err))
+		return
+	}
+
+	// Publish status update event
+	statusMetadata := map[string]interface{}{
+		"updatedAt":  res.Metadata.UpdatedAt,
+		"updateType": "status",
+	}
+	if err := events.PublishResourceUpdated(r.Context(), "Subscription", res.GetUID(), res.GetName(), res.Redacted(), statusMetadata); err != nil {
+		// Log but don't fail - events are non-critical
+		fmt.Printf("Warning: Failed to publish status update event for Subscription %s: %v\n", res.GetUID(), err)
+	}
+
+	setETag(w, res.Redacted())
+	respondJSON(w, http.StatusOK, res.Redacted())
+}
+
+// PatchSubscriptionStatus patches only the status of a Subscription resource
+// Supports JSON Merge Patch, JSON Patch, and Shorthand Patch formats.
+// Only modifies status fields - spec and metadata are preserved.
+func PatchSubscriptionStatus(w http.ResponseWriter, r *http.Request) {
+	uid := chi.URLParam(r, "uid")
+	if uid == "" {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("Subscription UID is required"))
+		return
+	}
+
+	// Authorization: Add custom middleware for status patch authorization
+	// Status patches can have different permissions than spec patches
+
+	res, err := storage.LoadSubscription(r.Context(), uid)
+	if err != nil {
+		respondError(w, http.StatusNotFound, fmt.Errorf("Subscription not found: %w", err))
+		return
+	}
+
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, res.Redacted()) {
+		return
+	}
+
+	patchData, err := io.ReadAll(r.Body)
+	if err != nil {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("failed to read patch data: %w", err))
+		return
+	}
+
+	// Marshal current status for patching
+	currentStatusJSON, err := json.Marshal(res.Status)
+	if err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to marshal current status: %w", err))
+		return
+	}
+
+	contentType := r.Header.Get("Content-Type")
+	patchType := patch.DetectPatchType(contentType)
+
+	patchResult, err := patch.ApplyPatchWithOptions(currentStatusJSON, patchData, patchType, patch.PatchOptions{
+		AllowAddFields:    true,
+		AllowRemoveFields: false, // Don't allow removing status fields
+	})
+	if err != nil {
+		respondError(w, http.StatusUnprocessableEntity, fmt.Errorf("failed to apply patch to status: %w", err))
+		return
+	}
+
+	// Unmarshal patched status back
+	if err := json.Unmarshal(patchResult.Updated, &res.Status); err != nil {
+		respondError(w, http.StatusInternalServerError, fmt.Errorf("failed to unmarshal patched status: %w", err))
+		return
+	}
+
+	res.Touch()
+
+	if err := storage.SaveSubscription(r.Context(), res); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to save patched Subscription status: %w", err))
+		return
+	}
+
+	// Publish status patch event
+	patchMetadata := map[string]interface{}{
+		"patchType":  patchType,
+		"updatedAt":  res.Metadata.UpdatedAt,
+		"updateType": "status",
+	}
+	if err := events.PublishResourcePatched(r.Context(), "Subscription", res.GetUID(), res.GetName(), res.Redacted(), patchMetadata); err != nil {
+		fmt.Printf("Warning: Failed to publish status patch event for Subscription %s: %v\n", res.GetUID(), err)
+	}
+
+	setETag(w, res.Redacted())
+	respondJSON(w, http.StatusOK, res.Redacted())
+}
+
+// DeleteSubscription deletes a Subscription resource
+func DeleteSubscription(w http.ResponseWriter, r *http.Request) {
+	uid := chi.URLParam(r, "uid")
+	if uid == "" {
+		respondError(w, http.StatusBadRequest, fmt.Errorf("Subscription UID is required"))
+		return
+	}
+
+	// Load resource before deletion for event publishing
+	subscription, err := storage.LoadSubscription(r.Context(), uid)
+	if err != nil {
+		respondError(w, http.StatusNotFound, fmt.Errorf("Subscription not found: %w", err))
+		return
+	}
+
+	// Reject the write if the client's copy is stale (If-Match)
+	if !checkIfMatch(w, r, subscription.Redacted()) {
+		return
+	}
+
+	// Delete only the version we checked, so a concurrent write isn't silently lost
+	if err := storage.DeleteIfVersion(r.Context(), "Subscription", uid, storage.ResourceVersionOf(&subscription.Metadata)); err != nil {
+		respondError(w, writeErrorStatus(err), fmt.Errorf("failed to delete Subscription: %w", err))
+		return
+	}
+
+	// Publish resource deleted event
+	// Labels let webhook subscriptions apply their label selector to deletes
+	deleteMetadata := map[string]interface{}{
+		"deletedAt": time.Now(),
+		"labels":    subscription.Metadata.Labels,
+	}
+	if err := events.PublishResourceDeleted(r.Context(), "Subscription", subscription.GetUID(), subscription.GetName(), deleteMetadata); err != nil {
+		// Log the error but don't fail the request - events are non-critical
+		fmt.Printf("Warning: Failed to publish resource deleted event for Subscription %s: %v\n", subscription.GetUID(), err)
+	}
+
+	respondJSON(w, http.StatusOK, &DeleteResponse{
+		Message: "Subscription deleted successfully",
+		UID:     uid,
+	})
+}
@@ -0,0 +1,162 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	internal_storage "github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/internal/watch"
+)
+
+// How often an idle watch stream gets a BOOKMARK (also keeps proxies from closing it)
+const watchBookmarkInterval = 30 * time.Second
+
+var watchBroadcaster *watch.Broadcaster
+
+// SetWatchBroadcaster sets the broadcaster ?watch=true list requests stream from
+func SetWatchBroadcaster(b *watch.Broadcaster) {
+	watchBroadcaster = b
+}
+
+// isWatchRequest reports whether a list request asks for a watch stream (?watch=true)
+func isWatchRequest(r *http.Request) bool {
+	return r.URL.Query().Get("watch") == "true"
+}
+
+// serveWatch streams changes to resources of one kind.
+//
+// The stream is Server-Sent Events, or NDJSON when the client accepts
+// application/x-ndjson (or passes format=ndjson). Query parameters:
+//   - resourceVersion: resume after this watch resourceVersion (SSE clients
+//     can send Last-Event-ID instead)
+//   - eventId: resume after this CloudEvent ID
+//   - sendInitialEvents=true: start with an ADDED event for every existing
+//     resource, followed by a BOOKMARK
+//
+// A position that is no longer in the history gets 410 Gone; list again and
+// start a new watch.
+func serveWatch(w http.ResponseWriter, r *http.Request, kind string) {
+	if watchBroadcaster == nil {
+		respondError(w, http.StatusServiceUnavailable, fmt.Errorf("watch is not available"))
+		return
+	}
+
+	query := r.URL.Query()
+	opts := watch.Options{
+		Kind:            kind,
+		ResourceVersion: query.Get("resourceVersion"),
+		EventID:         query.Get("eventId"),
+	}
+	if opts.ResourceVersion == "" && opts.EventID == "" {
+		opts.ResourceVersion = r.Header.Get("Last-Event-ID")
+	}
+
+	watcher, startVersion, err := watchBroadcaster.Watch(opts)
+	if errors.Is(err, watch.ErrExpired) {
+		respondError(w, http.StatusGone, err)
+		return
+	}
+	if err != nil {
+		respondError(w, http.StatusBadRequest, err)
+		return
+	}
+	defer watcher.Stop()
+
+	// Watches outlive the server's write timeout
+	rc := http.NewResponseController(w)
+	rc.SetWriteDeadline(time.Time{})
+
+	stream := newWatchStream(w, rc, query.Get("format") == "ndjson" || strings.Contains(r.Header.Get("Accept"), "application/x-ndjson"))
+	w.WriteHeader(http.StatusOK)
+	rc.Flush()
+
+	if query.Get("sendInitialEvents") == "true" {
+		// The watch is already registered, so nothing changed after this list is missed
+		items, err := internal_storage.Backend.LoadAll(r.Context(), kind)
+		if err != nil {
+			stream.writeError(fmt.Errorf("failed to list %s resources: %w", kind, err))
+			return
+		}
+		for _, item := range items {
+			if err := stream.write(watch.Event{Type: watch.Added, ResourceVersion: startVersion, Object: item}); err != nil {
+				return
+			}
+		}
+		if err := stream.write(watch.Event{Type: watch.Bookmark, ResourceVersion: startVersion}); err != nil {
+			return
+		}
+	}
+
+	ticker := time.NewTicker(watchBookmarkInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-r.Context().Done():
+			return
+		case ev, ok := <-watcher.Events():
+			if !ok {
+				if err := watcher.Err(); err != nil {
+					stream.writeError(err)
+				}
+				return
+			}
+			if err := stream.write(ev); err != nil {
+				return
+			}
+		case <-ticker.C:
+			if version, ok := watcher.Bookmark(); ok {
+				if err := stream.write(watch.Event{Type: watch.Bookmark, ResourceVersion: version}); err != nil {
+					return
+				}
+			}
+		}
+	}
+}
+
+// watchStream writes watch events as SSE or NDJSON
+type watchStream struct {
+	w      http.ResponseWriter
+	rc     *http.ResponseController
+	ndjson bool
+}
+
+func newWatchStream(w http.ResponseWriter, rc *http.ResponseController, ndjson bool) *watchStream {
+	if ndjson {
+		w.Header().Set("Content-Type", "application/x-ndjson")
+	} else {
+		w.Header().Set("Content-Type", "text/event-stream")
+	}
+	w.Header().Set("Cache-Control", "no-cache")
+	w.Header().Set("X-Accel-Buffering", "no")
+	return &watchStream{w: w, rc: rc, ndjson: ndjson}
+}
+
+func (s *watchStream) write(ev watch.Event) error {
+	data, err := json.Marshal(ev)
+	if err != nil {
+		return err
+	}
+	if s.ndjson {
+		_, err = fmt.Fprintf(s.w, "%s\n", data)
+	} else {
+		// The SSE id is the resourceVersion, so a reconnecting EventSource resumes via Last-Event-ID
+		if ev.ResourceVersion != "" {
+			fmt.Fprintf(s.w, "id: %s\n", ev.ResourceVersion)
+		}
+		_, err = fmt.Fprintf(s.w, "event: %s\ndata: %s\n\n", ev.Type, data)
+	}
+	if err != nil {
+		return err
+	}
+	return s.rc.Flush()
+}
+
+func (s *watchStream) writeError(err error) {
+	object, _ := json.Marshal(map[string]string{"message": err.Error()})
+	s.write(watch.Event{Type: watch.Error, Object: object})
+}
@@ -0,0 +1,295 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// minRefetchInterval limits how often a token with an unknown key ID makes
+// the key set reload, so bad tokens can't hammer the JWKS endpoint
+const minRefetchInterval = time.Minute
+
+// publicKey is a signature verification key of a JWKS
+type publicKey struct {
+	kid string
+	alg string // empty when the JWK doesn't restrict its algorithm
+	key crypto.PublicKey
+}
+
+// KeySet is a JSON Web Key Set loaded from a file or URL. Tokens are
+// verified against the keys in memory; the set is reloaded periodically and
+// when a token names a key ID it doesn't have, at most once per
+// refetchInterval whether or not the reloads succeed.
+type KeySet struct {
+	file, url       string
+	client          *http.Client
+	refetchInterval time.Duration
+
+	mu   sync.RWMutex
+	keys []publicKey
+
+	loading   sync.Mutex // held while reloading
+	attempted time.Time  // start of the last reload, guarded by loading
+}
+
+// NewKeySet loads the key set in file or, if file is empty, at url.
+func NewKeySet(ctx context.Context, file, url string) (*KeySet, error) {
+	if (file == "") == (url == "") {
+		return nil, fmt.Errorf("exactly one of a JWKS file or URL is required")
+	}
+	ks := &KeySet{
+		file:            file,
+		url:             url,
+		client:          &http.Client{Timeout: 10 * time.Second},
+		refetchInterval: minRefetchInterval,
+	}
+	if err := ks.Reload(ctx); err != nil {
+		return nil, err
+	}
+	return ks, nil
+}
+
+// Source returns the file or URL the key set is loaded from
+func (ks *KeySet) Source() string {
+	if ks.file != "" {
+		return ks.file
+	}
+	return ks.url
+}
+
+// Reload reads the key set again. On error the keys already loaded are kept.
+func (ks *KeySet) Reload(ctx context.Context) error {
+	ks.loading.Lock()
+	defer ks.loading.Unlock()
+	return ks.reload(ctx)
+}
+
+// reloadIfStale reloads the key set unless a reload was attempted within
+// refetchInterval, successful or not, so an unreachable or broken JWKS
+// endpoint isn't fetched for every token.
+func (ks *KeySet) reloadIfStale(ctx context.Context) {
+	ks.loading.Lock()
+	defer ks.loading.Unlock()
+	if time.Since(ks.attempted) < ks.refetchInterval {
+		return
+	}
+	ks.reload(ctx)
+}
+
+// reload reads the key set; the caller holds loading
+func (ks *KeySet) reload(ctx context.Context) error {
+	ks.attempted = time.Now()
+	data, err := ks.read(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load JWKS from %s: %w", ks.Source(), err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return fmt.Errorf("invalid JWKS from %s: %w", ks.Source(), err)
+	}
+	ks.mu.Lock()
+	ks.keys = keys
+	ks.mu.Unlock()
+	return nil
+}
+
+// Run reloads the key set every interval until ctx is done
+func (ks *KeySet) Run(ctx context.Context, interval time.Duration, logf func(format string, args ...any)) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := ks.Reload(ctx); err != nil {
+				logf("%v", err)
+			}
+		}
+	}
+}
+
+func (ks *KeySet) read(ctx context.Context) ([]byte, error) {
+	if ks.file != "" {
+		return os.ReadFile(ks.file)
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, ks.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	resp, err := ks.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unexpected status %s", resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
+
+// lookup returns the key a token with kid and alg is verified with. A token
+// without a key ID is accepted only when the set has a single key.
+func (ks *KeySet) lookup(ctx context.Context, kid, alg string) (crypto.PublicKey, error) {
+	key, found := ks.find(kid)
+	if !found && kid != "" {
+		// The issuer may have rotated in a new key since the last load
+		ks.reloadIfStale(ctx)
+		key, found = ks.find(kid)
+	}
+	if !found {
+		if kid == "" {
+			return nil, fmt.Errorf("token has no key ID")
+		}
+		return nil, fmt.Errorf("unknown key ID %q", kid)
+	}
+	if key.alg != "" && key.alg != alg {
+		return nil, fmt.Errorf("key %q is for %s, not %s", kid, key.alg, alg)
+	}
+	return key.key, nil
+}
+
+func (ks *KeySet) find(kid string) (publicKey, bool) {
+	ks.mu.RLock()
+	defer ks.mu.RUnlock()
+	if kid == "" {
+		if len(ks.keys) == 1 {
+			return ks.keys[0], true
+		}
+		return publicKey{}, false
+	}
+	for _, key := range ks.keys {
+		if key.kid == kid {
+			return key, true
+		}
+	}
+	return publicKey{}, false
+}
+
+// jwk is a JSON Web Key (RFC 7517) of the types tokens are signed with
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	// RSA
+	N string `json:"n"`
+	E string `json:"e"`
+	// EC and OKP
+	Crv string `json:"crv"`
+	X   string `json:"x"`
+	Y   string `json:"y"`
+}
+
+// parseJWKS returns the signature keys of a JWKS document. Keys of other
+// types or uses (e.g. encryption keys) are skipped.
+func parseJWKS(data []byte) ([]publicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, err
+	}
+	var keys []publicKey
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, err := k.publicKey()
+		if err != nil {
+			return nil, fmt.Errorf("key %d (%q): %w", i, k.Kid, err)
+		}
+		if key != nil {
+			keys = append(keys, publicKey{kid: k.Kid, alg: k.Alg, key: key})
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no signature keys")
+	}
+	return keys, nil
+}
+
+// publicKey returns the key, or nil for a key type tokens can't be verified with
+func (k jwk) publicKey() (crypto.PublicKey, error) {
+	switch k.Kty {
+	case "RSA":
+		n, err := decodeBigInt(k.N)
+		if err != nil {
+			return nil, fmt.Errorf("invalid n: %w", err)
+		}
+		e, err := decodeBigInt(k.E)
+		if err != nil {
+			return nil, fmt.Errorf("invalid e: %w", err)
+		}
+		if !e.IsInt64() || e.Int64() < 2 || e.Int64() > 1<<31-1 {
+			return nil, fmt.Errorf("invalid e")
+		}
+		return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+	case "EC":
+		var curve elliptic.Curve
+		switch k.Crv {
+		case "P-256":
+			curve = elliptic.P256()
+		case "P-384":
+			curve = elliptic.P384()
+		case "P-521":
+			curve = elliptic.P521()
+		default:
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := decodeBigInt(k.X)
+		if err != nil {
+			return nil, fmt.Errorf("invalid x: %w", err)
+		}
+		y, err := decodeBigInt(k.Y)
+		if err != nil {
+			return nil, fmt.Errorf("invalid y: %w", err)
+		}
+		if !curve.IsOnCurve(x, y) {
+			return nil, fmt.Errorf("point is not on %s", k.Crv)
+		}
+		return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+	case "OKP":
+		if k.Crv != "Ed25519" {
+			return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+		}
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return nil, fmt.Errorf("invalid x")
+		}
+		return ed25519.PublicKey(x), nil
+	}
+	return nil, nil
+}
+
+func decodeBigInt(s string) (*big.Int, error) {
+	b, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return nil, err
+	}
+	if len(b) == 0 {
+		return nil, fmt.Errorf("empty value")
+	}
+	return new(big.Int).SetBytes(b), nil
+}
@@ -0,0 +1,189 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingKey is an RSA key with its key ID
+type signingKey struct {
+	kid string
+	key *rsa.PrivateKey
+}
+
+func newSigningKey(t *testing.T, kid string) signingKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signingKey{kid: kid, key: key}
+}
+
+// sign returns a token for claims, signed with k
+func (k signingKey) sign(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = k.kid
+	signed, err := token.SignedString(k.key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return signed
+}
+
+// jwksServer serves the public halves of its current keys, or fails with
+// 500 while failing is set, and counts the requests it gets
+type jwksServer struct {
+	*httptest.Server
+	requests atomic.Int32
+
+	mu      sync.Mutex
+	keys    []signingKey
+	failing bool
+}
+
+func newJWKSServer(t *testing.T, keys ...signingKey) *jwksServer {
+	s := &jwksServer{keys: keys}
+	s.Server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		s.requests.Add(1)
+		s.mu.Lock()
+		defer s.mu.Unlock()
+		if s.failing {
+			http.Error(w, "unavailable", http.StatusInternalServerError)
+			return
+		}
+		jwks := struct {
+			Keys []map[string]string `json:"keys"`
+		}{}
+		for _, k := range s.keys {
+			jwks.Keys = append(jwks.Keys, map[string]string{
+				"kty": "RSA",
+				"kid": k.kid,
+				"alg": "RS256",
+				"n":   base64.RawURLEncoding.EncodeToString(k.key.N.Bytes()),
+				"e":   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(k.key.E)).Bytes()),
+			})
+		}
+		json.NewEncoder(w).Encode(jwks)
+	}))
+	t.Cleanup(s.Close)
+	return s
+}
+
+func (s *jwksServer) set(failing bool, keys ...signingKey) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.failing = failing
+	if keys != nil {
+		s.keys = keys
+	}
+}
+
+func validClaims() jwt.MapClaims {
+	now := time.Now()
+	return jwt.MapClaims{
+		"iss": "https://issuer.example.com",
+		"aud": "inventory-api",
+		"sub": "alice",
+		"iat": now.Unix(),
+		"exp": now.Add(time.Hour).Unix(),
+	}
+}
+
+func newTestAuthenticator(t *testing.T, url string) *Authenticator {
+	t.Helper()
+	a, err := New(context.Background(), Config{
+		JWKSURL:  url,
+		Issuer:   "https://issuer.example.com",
+		Audience: "inventory-api",
+	}, t.Logf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return a
+}
+
+// TestKeyRotation checks that a token signed with a key added to the JWKS
+// after it was loaded is accepted, and one signed with a removed key isn't.
+func TestKeyRotation(t *testing.T) {
+	ctx := context.Background()
+	oldKey, newKey := newSigningKey(t, "old"), newSigningKey(t, "new")
+	server := newJWKSServer(t, oldKey)
+	a := newTestAuthenticator(t, server.URL)
+	a.keys.refetchInterval = 0 // every unknown key ID reloads
+
+	if _, err := a.Authenticate(ctx, oldKey.sign(t, validClaims())); err != nil {
+		t.Fatalf("token from the loaded key: %v", err)
+	}
+
+	server.set(false, newKey)
+	if _, err := a.Authenticate(ctx, newKey.sign(t, validClaims())); err != nil {
+		t.Fatalf("token from the rotated-in key: %v", err)
+	}
+	if _, err := a.Authenticate(ctx, oldKey.sign(t, validClaims())); err == nil {
+		t.Fatal("token from the rotated-out key was accepted")
+	}
+
+	claims := validClaims()
+	claims["aud"] = "other-service"
+	if _, err := a.Authenticate(ctx, newKey.sign(t, claims)); err == nil {
+		t.Fatal("token for another audience was accepted")
+	}
+}
+
+// TestFailingJWKSRateLimited checks that tokens with unknown key IDs don't
+// make a failing JWKS endpoint be fetched more than once per interval, and
+// that the keys already loaded keep working meanwhile.
+func TestFailingJWKSRateLimited(t *testing.T) {
+	ctx := context.Background()
+	key := newSigningKey(t, "current")
+	server := newJWKSServer(t, key)
+	a := newTestAuthenticator(t, server.URL)
+	if n := server.requests.Load(); n != 1 {
+		t.Fatalf("%d requests to load the JWKS, want 1", n)
+	}
+
+	// The initial load counts, so unknown key IDs don't reload right away
+	unknown := newSigningKey(t, "unknown")
+	if _, err := a.Authenticate(ctx, unknown.sign(t, validClaims())); err == nil {
+		t.Fatal("token from an unknown key was accepted")
+	}
+	if n := server.requests.Load(); n != 1 {
+		t.Fatalf("%d requests after an unknown key ID right after loading, want 1", n)
+	}
+
+	// Once the interval has passed, one failing reload is made, and no more
+	server.set(true)
+	a.keys.loading.Lock()
+	a.keys.attempted = time.Now().Add(-2 * minRefetchInterval)
+	a.keys.loading.Unlock()
+	for i := 0; i < 20; i++ {
+		if _, err := a.Authenticate(ctx, unknown.sign(t, validClaims())); err == nil {
+			t.Fatal("token from an unknown key was accepted")
+		}
+	}
+	if n := server.requests.Load(); n != 2 {
+		t.Errorf("%d requests with a failing JWKS, want 2", n)
+	}
+
+	if _, err := a.Authenticate(ctx, key.sign(t, validClaims())); err != nil {
+		t.Errorf("token from a loaded key after failed reloads: %v", err)
+	}
+}
@@ -0,0 +1,212 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+// Package auth authenticates API requests by JWT bearer token.
+//
+// Tokens are verified offline against a JSON Web Key Set (JWKS) loaded from a
+// file or URL, as issued by OpenCHAMI's token service or any OIDC provider.
+// A token must be signed with an asymmetric algorithm by a key in the set,
+// must not be expired, and must carry the configured issuer and audience.
+// The claims of an authenticated request are in its context (see
+// ClaimsFromContext).
+package auth
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// signingMethods are the algorithms tokens may be signed with. HMAC
+// algorithms are left out: a JWKS is public, so its keys must not verify
+// symmetric signatures.
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+// Config configures an Authenticator.
+type Config struct {
+	// JWKSFile or JWKSURL is where the token signing keys are loaded from
+	JWKSFile string
+	JWKSURL  string
+	// RefreshInterval is how often the keys are reloaded (0 = only when a
+	// token names an unknown key)
+	RefreshInterval time.Duration
+
+	// Issuer and Audience, when set, must be the token's iss and one of its aud
+	Issuer   string
+	Audience string
+	// Leeway allows for clock skew when checking exp, nbf and iat
+	Leeway time.Duration
+
+	// PublicPaths are request paths served without a token (e.g. /health)
+	PublicPaths []string
+}
+
+// Authenticator verifies bearer tokens.
+type Authenticator struct {
+	keys   *KeySet
+	parser *jwt.Parser
+	public map[string]bool
+}
+
+// New loads the key set and returns an Authenticator. With a
+// RefreshInterval, the keys are reloaded in the background until ctx is done.
+func New(ctx context.Context, cfg Config, logf func(format string, args ...any)) (*Authenticator, error) {
+	keys, err := NewKeySet(ctx, cfg.JWKSFile, cfg.JWKSURL)
+	if err != nil {
+		return nil, err
+	}
+	go keys.Run(ctx, cfg.RefreshInterval, logf)
+
+	options := []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+		jwt.WithLeeway(cfg.Leeway),
+	}
+	if cfg.Issuer != "" {
+		options = append(options, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		options = append(options, jwt.WithAudience(cfg.Audience))
+	}
+
+	public := make(map[string]bool, len(cfg.PublicPaths))
+	for _, p := range cfg.PublicPaths {
+		public[p] = true
+	}
+	return &Authenticator{keys: keys, parser: jwt.NewParser(options...), public: public}, nil
+}
+
+// KeySource returns the file or URL the signing keys are loaded from
+func (a *Authenticator) KeySource() string {
+	return a.keys.Source()
+}
+
+// Authenticate verifies a token and returns its claims.
+func (a *Authenticator) Authenticate(ctx context.Context, token string) (jwt.MapClaims, error) {
+	claims := jwt.MapClaims{}
+	_, err := a.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		kid, _ := t.Header["kid"].(string)
+		return a.keys.lookup(ctx, kid, t.Method.Alg())
+	})
+	if err != nil {
+		return nil, err
+	}
+	return claims, nil
+}
+
+// Middleware rejects requests without a valid bearer token with 401
+// Unauthorized, except to PublicPaths, and puts the claims of the others in
+// their context.
+func (a *Authenticator) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if a.public[r.URL.Path] {
+			next.ServeHTTP(w, r)
+			return
+		}
+		scheme, token, _ := strings.Cut(r.Header.Get("Authorization"), " ")
+		if !strings.EqualFold(scheme, "Bearer") || strings.TrimSpace(token) == "" {
+			unauthorized(w, "", errors.New("bearer token required"))
+			return
+		}
+		claims, err := a.Authenticate(r.Context(), strings.TrimSpace(token))
+		if err != nil {
+			unauthorized(w, "invalid_token", fmt.Errorf("invalid token: %w", err))
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(WithClaims(r.Context(), claims)))
+	})
+}
+
+// RequireRole rejects requests whose token doesn't grant role (see HasRole)
+// with 403 Forbidden. Use it after Middleware.
+func RequireRole(role string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			claims, ok := ClaimsFromContext(r.Context())
+			if !ok || !HasRole(claims, role) {
+				forbidden(w, fmt.Errorf("token does not have the %s role", role))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// HasRole reports whether claims grant role, in a "roles" claim (a string
+// or a list) or as one of the space-separated values of the "scope" claim.
+func HasRole(claims jwt.MapClaims, role string) bool {
+	switch roles := claims["roles"].(type) {
+	case string:
+		if roles == role {
+			return true
+		}
+	case []any:
+		for _, r := range roles {
+			if r == role {
+				return true
+			}
+		}
+	}
+	if scope, ok := claims["scope"].(string); ok {
+		for _, s := range strings.Fields(scope) {
+			if s == role {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// unauthorized writes a 401 with an RFC 6750 challenge and the API's JSON
+// error body
+func unauthorized(w http.ResponseWriter, code string, err error) {
+	challenge := `Bearer realm="inventory-api"`
+	if code != "" {
+		challenge += fmt.Sprintf(`, error=%q, error_description=%q`, code, strings.ReplaceAll(err.Error(), `"`, `'`))
+	}
+	w.Header().Set("WWW-Authenticate", challenge)
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusUnauthorized)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+		Code  int    `json:"code"`
+	}{err.Error(), http.StatusUnauthorized})
+}
+
+// forbidden writes a 403 with the API's JSON error body
+func forbidden(w http.ResponseWriter, err error) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusForbidden)
+	json.NewEncoder(w).Encode(struct {
+		Error string `json:"error"`
+		Code  int    `json:"code"`
+	}{err.Error(), http.StatusForbidden})
+}
+
+type claimsKey struct{}
+
+// WithClaims returns a context carrying the claims of an authenticated token
+func WithClaims(ctx context.Context, claims jwt.MapClaims) context.Context {
+	return context.WithValue(ctx, claimsKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the request's token, or false if
+// the request wasn't authenticated (authentication is off, or the path is
+// public)
+func ClaimsFromContext(ctx context.Context) (jwt.MapClaims, bool) {
+	claims, ok := ctx.Value(claimsKey{}).(jwt.MapClaims)
+	return claims, ok
+}
@@ -0,0 +1,46 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package auth
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+func TestRequireRole(t *testing.T) {
+	handler := RequireRole("admin")(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	tests := []struct {
+		name   string
+		claims jwt.MapClaims // nil: unauthenticated request
+		want   int
+	}{
+		{"no claims", nil, http.StatusForbidden},
+		{"no role", jwt.MapClaims{"sub": "alice"}, http.StatusForbidden},
+		{"other roles", jwt.MapClaims{"roles": []any{"reader", "writer"}}, http.StatusForbidden},
+		{"role prefix in scope", jwt.MapClaims{"scope": "read administrator"}, http.StatusForbidden},
+		{"roles list", jwt.MapClaims{"roles": []any{"reader", "admin"}}, http.StatusNoContent},
+		{"roles string", jwt.MapClaims{"roles": "admin"}, http.StatusNoContent},
+		{"scope", jwt.MapClaims{"scope": "read admin"}, http.StatusNoContent},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest(http.MethodPost, "/admin/backup", nil)
+			if tt.claims != nil {
+				r = r.WithContext(WithClaims(r.Context(), tt.claims))
+			}
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, r)
+			if w.Code != tt.want {
+				t.Errorf("status %d, want %d", w.Code, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,355 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"sync"
+	"time"
+
+	"github.com/openchami/fabrica/pkg/events"
+
+	"github.com/user/inventory-api/internal/eventmatch"
+)
+
+const (
+	offsetsFile = "offsets.json"
+
+	// How often committed offsets are flushed and retention is applied
+	flushInterval     = time.Second
+	retentionInterval = time.Minute
+)
+
+// StartPosition says where a new subscription starts reading.
+type StartPosition struct {
+	kind   startKind
+	offset uint64
+	time   time.Time
+}
+
+type startKind int
+
+const (
+	startLatest startKind = iota
+	startEarliest
+	startOffset
+	startTime
+)
+
+// FromLatest starts at the next event published (the in-memory bus behaviour).
+func FromLatest() StartPosition { return StartPosition{kind: startLatest} }
+
+// FromEarliest replays every retained event.
+func FromEarliest() StartPosition { return StartPosition{kind: startEarliest} }
+
+// FromOffset replays from a log offset.
+func FromOffset(offset uint64) StartPosition { return StartPosition{kind: startOffset, offset: offset} }
+
+// FromTime replays every event published at or after t.
+func FromTime(t time.Time) StartPosition { return StartPosition{kind: startTime, time: t} }
+
+// SubscribeOptions configures a subscription.
+type SubscribeOptions struct {
+	// Consumer names a durable consumer. Its offset is committed as events are
+	// handled, and a later subscription with the same name resumes from it
+	// (Start is only used the first time). Empty means an ephemeral subscription.
+	Consumer string
+
+	// Start is where the subscription starts reading
+	Start StartPosition
+}
+
+// EventBus implements events.EventBus on top of a durable Log.
+//
+// Publish returns once the event is appended to the log. Each subscription
+// reads the log in order in its own goroutine, so a slow handler delays only
+// its own subscription.
+type EventBus struct {
+	log     *Log
+	offsets *offsetStore
+
+	mu        sync.Mutex
+	subs      map[events.SubscriptionID]*subscriber
+	nextSubID int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+	wg     sync.WaitGroup
+}
+
+// Compile-time check that EventBus implements events.EventBus
+var _ events.EventBus = (*EventBus)(nil)
+
+// subscriber is one running subscription
+type subscriber struct {
+	id       events.SubscriptionID
+	pattern  string
+	handler  events.EventHandler
+	consumer string
+	start    uint64
+	done     chan struct{}
+}
+
+// NewEventBus opens the event log in dir.
+//
+// Returns:
+//   - *EventBus: Initialized event bus (must call Start())
+func NewEventBus(dir string, opts Options) (*EventBus, error) {
+	log, err := OpenLog(dir, opts)
+	if err != nil {
+		return nil, err
+	}
+	offsets, err := loadOffsetStore(filepath.Join(dir, offsetsFile))
+	if err != nil {
+		log.Close()
+		return nil, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	return &EventBus{
+		log:       log,
+		offsets:   offsets,
+		subs:      make(map[events.SubscriptionID]*subscriber),
+		nextSubID: 1,
+		ctx:       ctx,
+		cancel:    cancel,
+	}, nil
+}
+
+// Start begins flushing consumer offsets and applying retention in the background.
+func (b *EventBus) Start() {
+	b.wg.Add(1)
+	go func() {
+		defer b.wg.Done()
+
+		flush := time.NewTicker(flushInterval)
+		defer flush.Stop()
+		retention := time.NewTicker(retentionInterval)
+		defer retention.Stop()
+
+		for {
+			select {
+			case <-b.ctx.Done():
+				return
+			case <-flush.C:
+				if err := b.offsets.Flush(); err != nil {
+					fmt.Printf("Error flushing event consumer offsets: %v\n", err)
+				}
+			case <-retention.C:
+				if _, err := b.log.Prune(); err != nil {
+					fmt.Printf("Error applying event log retention: %v\n", err)
+				}
+			}
+		}
+	}()
+}
+
+// Log returns the underlying event log.
+func (b *EventBus) Log() *Log {
+	return b.log
+}
+
+// Publish appends an event to the log.
+func (b *EventBus) Publish(ctx context.Context, event events.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+	if _, err := b.log.Append(data); err != nil {
+		return err
+	}
+	return nil
+}
+
+// Subscribe subscribes to events published from now on, like the in-memory bus.
+func (b *EventBus) Subscribe(eventType string, handler events.EventHandler) (events.SubscriptionID, error) {
+	return b.SubscribeWithOptions(eventType, handler, SubscribeOptions{Start: FromLatest()})
+}
+
+// SubscribeWithOptions subscribes to events matching a pattern, starting at
+// opts.Start or, for a durable consumer, at its committed offset.
+func (b *EventBus) SubscribeWithOptions(eventType string, handler events.EventHandler, opts SubscribeOptions) (events.SubscriptionID, error) {
+	start, err := b.resolveStart(opts)
+	if err != nil {
+		return "", err
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.ctx.Err() != nil {
+		return "", fmt.Errorf("event bus is closed")
+	}
+	if opts.Consumer != "" {
+		for _, sub := range b.subs {
+			if sub.consumer == opts.Consumer {
+				return "", fmt.Errorf("consumer %q is already subscribed", opts.Consumer)
+			}
+		}
+	}
+
+	sub := &subscriber{
+		id:       events.SubscriptionID(fmt.Sprintf("sub-%d", b.nextSubID)),
+		pattern:  eventType,
+		handler:  handler,
+		consumer: opts.Consumer,
+		start:    start,
+		done:     make(chan struct{}),
+	}
+	b.nextSubID++
+	b.subs[sub.id] = sub
+
+	b.wg.Add(1)
+	go b.run(sub)
+	return sub.id, nil
+}
+
+// resolveStart turns subscribe options into a log offset
+func (b *EventBus) resolveStart(opts SubscribeOptions) (uint64, error) {
+	if opts.Consumer != "" {
+		if offset, ok := b.offsets.Get(opts.Consumer); ok {
+			return offset, nil
+		}
+	}
+	switch opts.Start.kind {
+	case startEarliest:
+		return b.log.FirstOffset(), nil
+	case startOffset:
+		return opts.Start.offset, nil
+	case startTime:
+		offset, err := b.log.OffsetAt(opts.Start.time)
+		if err != nil {
+			return 0, fmt.Errorf("failed to find offset for %s: %w", opts.Start.time, err)
+		}
+		return offset, nil
+	default:
+		return b.log.NextOffset(), nil
+	}
+}
+
+// run feeds a subscriber from the log until it is unsubscribed or the bus closes
+func (b *EventBus) run(sub *subscriber) {
+	defer b.wg.Done()
+
+	cur := b.log.newCursor(sub.start)
+	defer cur.Close()
+
+	for {
+		// Grab the change channel before reading, so an append between the
+		// read and the wait still wakes us
+		changed := b.log.Changed()
+
+		rec, ok, err := cur.Next()
+		if err != nil {
+			fmt.Printf("Error reading event log for %s: %v\n", sub.id, err)
+			select {
+			case <-sub.done:
+				return
+			case <-b.ctx.Done():
+				return
+			case <-time.After(time.Second):
+			}
+			continue
+		}
+		if !ok {
+			select {
+			case <-sub.done:
+				return
+			case <-b.ctx.Done():
+				return
+			case <-changed:
+			}
+			continue
+		}
+
+		b.deliver(sub, rec)
+		if sub.consumer != "" {
+			b.offsets.Set(sub.consumer, rec.Offset+1)
+		}
+	}
+}
+
+// deliver decodes a record and calls the handler if the event matches
+func (b *EventBus) deliver(sub *subscriber, rec Record) {
+	var event events.Event
+	if err := json.Unmarshal(rec.Event, &event); err != nil {
+		fmt.Printf("Error decoding event at offset %d: %v\n", rec.Offset, err)
+		return
+	}
+	if !eventmatch.Matches(event.Type(), sub.pattern) {
+		return
+	}
+	if err := sub.handler(b.ctx, event); err != nil {
+		// Log error but don't stop processing (same as the in-memory bus)
+		fmt.Printf("Error handling event %s: %v\n", event.ID(), err)
+	}
+}
+
+// Unsubscribe stops a subscription. A durable consumer keeps its committed offset.
+func (b *EventBus) Unsubscribe(id events.SubscriptionID) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	sub, ok := b.subs[id]
+	if !ok {
+		return fmt.Errorf("subscription not found: %s", id)
+	}
+	close(sub.done)
+	delete(b.subs, id)
+	return nil
+}
+
+// Close stops all subscriptions, flushes consumer offsets and closes the log.
+func (b *EventBus) Close() error {
+	b.mu.Lock()
+	b.cancel()
+	b.mu.Unlock()
+	b.wg.Wait()
+
+	flushErr := b.offsets.Flush()
+	if err := b.log.Close(); err != nil {
+		return err
+	}
+	return flushErr
+}
+
+// Consumer returns a view of the bus whose subscriptions are durable consumers
+// named after name and the subscribed pattern. Pass it to components such as
+// reconcile.Controller so they resume where they left off after a restart.
+func (b *EventBus) Consumer(name string) events.EventBus {
+	return &consumerBus{bus: b, name: name}
+}
+
+// consumerBus is the events.EventBus returned by EventBus.Consumer
+type consumerBus struct {
+	bus  *EventBus
+	name string
+}
+
+func (c *consumerBus) Publish(ctx context.Context, event events.Event) error {
+	return c.bus.Publish(ctx, event)
+}
+
+func (c *consumerBus) Subscribe(eventType string, handler events.EventHandler) (events.SubscriptionID, error) {
+	return c.bus.SubscribeWithOptions(eventType, handler, SubscribeOptions{
+		Consumer: c.name + "/" + eventType,
+		Start:    FromLatest(),
+	})
+}
+
+func (c *consumerBus) Unsubscribe(id events.SubscriptionID) error {
+	return c.bus.Unsubscribe(id)
+}
+
+// Close is a no-op; the owner of the underlying bus closes it.
+func (c *consumerBus) Close() error {
+	return nil
+}
@@ -0,0 +1,442 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+// Package eventlog provides a durable, file-backed event bus.
+//
+// Events are appended to a segmented log on disk. Each segment is a file of
+// newline-delimited JSON records named after the offset of its first record
+// (e.g. 00000000000000000042.log). Subscribers read the log sequentially, so
+// they can start from any retained offset or timestamp, and durable consumers
+// resume from their last committed offset after a restart.
+package eventlog
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	segmentExt = ".log"
+
+	// DefaultSegmentBytes is the size at which the active segment is rolled over
+	DefaultSegmentBytes = 64 << 20
+)
+
+// Options configures a Log.
+type Options struct {
+	// SegmentBytes is the size at which a new segment is started (default DefaultSegmentBytes)
+	SegmentBytes int64
+
+	// RetentionBytes caps the total size of the log; 0 means unlimited.
+	// The oldest segments are deleted first; the active segment is never deleted.
+	RetentionBytes int64
+
+	// RetentionAge deletes segments whose last write is older than this; 0 means unlimited.
+	RetentionAge time.Duration
+
+	// NoSync skips syncing the active segment to disk after every append.
+	// Appends are then much faster, and a crash of the process still loses
+	// nothing, but records appended shortly before an operating system crash
+	// or power loss may be lost. By default Append returns only once its
+	// record is on disk.
+	NoSync bool
+}
+
+// Record is one entry in the log.
+type Record struct {
+	Offset uint64          `json:"offset"`
+	Time   time.Time       `json:"time"`
+	Event  json.RawMessage `json:"event"`
+}
+
+// segment is one file of the log
+type segment struct {
+	base uint64 // offset of the first record
+	path string
+}
+
+// Log is an append-only, segmented record log.
+type Log struct {
+	dir  string
+	opts Options
+
+	mu         sync.Mutex
+	segments   []segment // sorted by base; the last one is active
+	active     *os.File
+	activeSize int64
+	next       uint64        // offset of the next record to append
+	appended   chan struct{} // closed (and replaced) on every append
+	closed     bool
+}
+
+// OpenLog opens (or creates) the log in dir, recovering the next offset from
+// the active segment. A partially written trailing record is truncated.
+func OpenLog(dir string, opts Options) (*Log, error) {
+	if opts.SegmentBytes <= 0 {
+		opts.SegmentBytes = DefaultSegmentBytes
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create event log directory: %w", err)
+	}
+
+	l := &Log{dir: dir, opts: opts, appended: make(chan struct{})}
+
+	segments, err := listSegments(dir)
+	if err != nil {
+		return nil, err
+	}
+	l.segments = segments
+
+	if len(l.segments) == 0 {
+		if err := l.openSegment(0); err != nil {
+			return nil, err
+		}
+		return l, nil
+	}
+
+	last := l.segments[len(l.segments)-1]
+	next, size, err := recoverSegment(last)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.OpenFile(last.path, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open segment %s: %w", last.path, err)
+	}
+	l.active = file
+	l.activeSize = size
+	l.next = next
+	return l, nil
+}
+
+// listSegments returns the segments in dir, oldest first
+func listSegments(dir string) ([]segment, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read event log directory: %w", err)
+	}
+	var segments []segment
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, segmentExt) {
+			continue
+		}
+		base, err := strconv.ParseUint(strings.TrimSuffix(name, segmentExt), 10, 64)
+		if err != nil {
+			continue // Not a segment
+		}
+		segments = append(segments, segment{base: base, path: filepath.Join(dir, name)})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].base < segments[j].base })
+	return segments, nil
+}
+
+// recoverSegment finds the next offset after a segment's last complete record,
+// truncating any partial record left by a crash. It returns the next offset and the segment size.
+func recoverSegment(seg segment) (uint64, int64, error) {
+	file, err := os.OpenFile(seg.path, os.O_RDWR, 0644)
+	if err != nil {
+		return 0, 0, fmt.Errorf("failed to open segment %s: %w", seg.path, err)
+	}
+	defer file.Close()
+
+	next := seg.base
+	var good int64
+	reader := bufio.NewReader(file)
+	for {
+		line, err := reader.ReadBytes('\n')
+		if err == io.EOF {
+			break // Anything after the last newline is a torn write
+		}
+		if err != nil {
+			return 0, 0, fmt.Errorf("failed to read segment %s: %w", seg.path, err)
+		}
+		var rec Record
+		if json.Unmarshal(line, &rec) != nil {
+			break
+		}
+		good += int64(len(line))
+		next = rec.Offset + 1
+	}
+
+	if err := file.Truncate(good); err != nil {
+		return 0, 0, fmt.Errorf("failed to truncate segment %s: %w", seg.path, err)
+	}
+	return next, good, nil
+}
+
+// openSegment starts a new active segment at base. Caller holds l.mu (or is OpenLog).
+func (l *Log) openSegment(base uint64) error {
+	path := filepath.Join(l.dir, fmt.Sprintf("%020d%s", base, segmentExt))
+	file, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0644)
+	if err != nil {
+		return fmt.Errorf("failed to create segment %s: %w", path, err)
+	}
+	l.segments = append(l.segments, segment{base: base, path: path})
+	l.active = file
+	l.activeSize = 0
+	l.next = base
+	return nil
+}
+
+// Append writes data as the next record and returns its offset. Unless
+// Options.NoSync is set, the record is synced to disk before it returns.
+func (l *Log) Append(data json.RawMessage) (uint64, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return 0, errors.New("event log is closed")
+	}
+
+	line, err := json.Marshal(Record{Offset: l.next, Time: time.Now().UTC(), Event: data})
+	if err != nil {
+		return 0, fmt.Errorf("failed to encode record: %w", err)
+	}
+	line = append(line, '\n')
+
+	if l.activeSize > 0 && l.activeSize+int64(len(line)) > l.opts.SegmentBytes {
+		if err := l.roll(); err != nil {
+			return 0, err
+		}
+	}
+
+	if _, err := l.active.Write(line); err != nil {
+		return 0, fmt.Errorf("failed to append record: %w", err)
+	}
+	if !l.opts.NoSync {
+		if err := l.active.Sync(); err != nil {
+			return 0, fmt.Errorf("failed to sync segment: %w", err)
+		}
+	}
+	offset := l.next
+	l.next++
+	l.activeSize += int64(len(line))
+
+	close(l.appended)
+	l.appended = make(chan struct{})
+	return offset, nil
+}
+
+// roll closes the active segment and starts a new one. Caller holds l.mu.
+func (l *Log) roll() error {
+	if err := l.active.Sync(); err != nil {
+		return fmt.Errorf("failed to sync segment: %w", err)
+	}
+	if err := l.active.Close(); err != nil {
+		return fmt.Errorf("failed to close segment: %w", err)
+	}
+	return l.openSegment(l.next)
+}
+
+// Changed returns a channel that is closed on the next append.
+func (l *Log) Changed() <-chan struct{} {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.appended
+}
+
+// NextOffset returns the offset the next appended record will get.
+func (l *Log) NextOffset() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.next
+}
+
+// FirstOffset returns the offset of the oldest retained record.
+func (l *Log) FirstOffset() uint64 {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	return l.segments[0].base
+}
+
+// OffsetAt returns the offset of the first record written at or after t,
+// or NextOffset if there is none.
+func (l *Log) OffsetAt(t time.Time) (uint64, error) {
+	cur := l.newCursor(l.FirstOffset())
+	defer cur.Close()
+	for {
+		rec, ok, err := cur.Next()
+		if err != nil {
+			return 0, err
+		}
+		if !ok {
+			return cur.next, nil
+		}
+		if !rec.Time.Before(t) {
+			return rec.Offset, nil
+		}
+	}
+}
+
+// Prune applies the retention limits and returns how many segments it deleted.
+func (l *Log) Prune() (int, error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	type sized struct {
+		segment
+		size    int64
+		modTime time.Time
+	}
+	// The active segment is never a candidate
+	candidates := make([]sized, 0, len(l.segments))
+	total := l.activeSize
+	for _, seg := range l.segments[:len(l.segments)-1] {
+		info, err := os.Stat(seg.path)
+		if err != nil {
+			return 0, fmt.Errorf("failed to stat segment %s: %w", seg.path, err)
+		}
+		candidates = append(candidates, sized{segment: seg, size: info.Size(), modTime: info.ModTime()})
+		total += info.Size()
+	}
+
+	removed := 0
+	now := time.Now()
+	for _, seg := range candidates {
+		tooOld := l.opts.RetentionAge > 0 && now.Sub(seg.modTime) > l.opts.RetentionAge
+		tooBig := l.opts.RetentionBytes > 0 && total > l.opts.RetentionBytes
+		if !tooOld && !tooBig {
+			break // Segments are oldest first, so the rest are within limits too
+		}
+		if err := os.Remove(seg.path); err != nil {
+			return removed, fmt.Errorf("failed to delete segment %s: %w", seg.path, err)
+		}
+		total -= seg.size
+		removed++
+	}
+	l.segments = l.segments[removed:]
+	return removed, nil
+}
+
+// Close syncs and closes the active segment.
+func (l *Log) Close() error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.closed {
+		return nil
+	}
+	l.closed = true
+	close(l.appended)
+	if err := l.active.Sync(); err != nil {
+		l.active.Close()
+		return fmt.Errorf("failed to sync segment: %w", err)
+	}
+	return l.active.Close()
+}
+
+// segmentFor returns the segment holding offset, or the oldest retained
+// segment if offset has already been pruned.
+func (l *Log) segmentFor(offset uint64) segment {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	i := sort.Search(len(l.segments), func(i int) bool { return l.segments[i].base > offset })
+	if i == 0 {
+		return l.segments[0]
+	}
+	return l.segments[i-1]
+}
+
+// hasSegmentAfter reports whether a segment newer than base exists and starts at or before offset
+func (l *Log) hasSegmentAfter(base, offset uint64) bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, seg := range l.segments {
+		if seg.base > base && seg.base <= offset {
+			return true
+		}
+	}
+	return false
+}
+
+// cursor reads records sequentially, following the log across segments.
+type cursor struct {
+	log     *Log
+	next    uint64 // offset of the next record to return
+	seg     segment
+	file    *os.File
+	reader  *bufio.Reader
+	pending []byte // partial record read at the end of the active segment
+}
+
+// newCursor returns a cursor positioned at offset. If offset has been pruned,
+// the cursor starts at the oldest retained record.
+func (l *Log) newCursor(offset uint64) *cursor {
+	return &cursor{log: l, next: offset}
+}
+
+// Next returns the next record. ok is false when the cursor has caught up with the log.
+func (c *cursor) Next() (rec Record, ok bool, err error) {
+	for {
+		if c.file == nil {
+			c.seg = c.log.segmentFor(c.next)
+			file, err := os.Open(c.seg.path)
+			if err != nil {
+				if errors.Is(err, os.ErrNotExist) {
+					continue // Pruned while we looked; segmentFor now returns a newer one
+				}
+				return Record{}, false, fmt.Errorf("failed to open segment %s: %w", c.seg.path, err)
+			}
+			c.file = file
+			c.reader = bufio.NewReader(file)
+			c.pending = nil
+			if c.seg.base > c.next {
+				c.next = c.seg.base // Older records were pruned
+			}
+		}
+
+		line, err := c.reader.ReadBytes('\n')
+		if err == io.EOF {
+			c.pending = append(c.pending, line...)
+			// The writer moved on to a newer segment: follow it
+			if c.log.hasSegmentAfter(c.seg.base, c.next) {
+				c.closeFile()
+				continue
+			}
+			return Record{}, false, nil
+		}
+		if err != nil {
+			return Record{}, false, fmt.Errorf("failed to read segment %s: %w", c.seg.path, err)
+		}
+		if len(c.pending) > 0 {
+			line = append(c.pending, line...)
+			c.pending = nil
+		}
+
+		if err := json.Unmarshal(line, &rec); err != nil {
+			return Record{}, false, fmt.Errorf("corrupt record in segment %s: %w", c.seg.path, err)
+		}
+		if rec.Offset < c.next {
+			continue // Seeking forward to the start offset
+		}
+		c.next = rec.Offset + 1
+		return rec, true, nil
+	}
+}
+
+func (c *cursor) closeFile() {
+	if c.file != nil {
+		c.file.Close()
+		c.file = nil
+		c.reader = nil
+	}
+}
+
+// Close releases the cursor's open segment.
+func (c *cursor) Close() {
+	c.closeFile()
+}
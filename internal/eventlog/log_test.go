@@ -0,0 +1,307 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package eventlog
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openchami/fabrica/pkg/events"
+)
+
+// appendN appends records {"n":from}..{"n":from+count-1} and checks their offsets
+func appendN(t *testing.T, l *Log, from, count int) {
+	t.Helper()
+	for i := from; i < from+count; i++ {
+		offset, err := l.Append(json.RawMessage(fmt.Sprintf(`{"n":%d}`, i)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if offset != uint64(i) {
+			t.Fatalf("record %d got offset %d", i, offset)
+		}
+	}
+}
+
+// readFrom returns the n field of every record from offset on
+func readFrom(t *testing.T, l *Log, offset uint64) []int {
+	t.Helper()
+	cur := l.newCursor(offset)
+	defer cur.Close()
+	var got []int
+	for {
+		rec, ok, err := cur.Next()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if !ok {
+			return got
+		}
+		var event struct{ N int }
+		if err := json.Unmarshal(rec.Event, &event); err != nil {
+			t.Fatal(err)
+		}
+		if rec.Offset != uint64(event.N) {
+			t.Fatalf("record {n:%d} read at offset %d", event.N, rec.Offset)
+		}
+		got = append(got, event.N)
+	}
+}
+
+func sequence(from, to int) []int {
+	var s []int
+	for i := from; i < to; i++ {
+		s = append(s, i)
+	}
+	return s
+}
+
+func equalInts(a, b []int) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// TestSegmentsAndRecovery checks that records roll over into new segments
+// with continuous offsets, and that reopening the log resumes after the last
+// complete record, dropping a torn one.
+func TestSegmentsAndRecovery(t *testing.T) {
+	dir := t.TempDir()
+	opts := Options{SegmentBytes: 200}
+	l, err := OpenLog(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	appendN(t, l, 0, 10)
+	if len(l.segments) < 3 {
+		t.Fatalf("%d segments for 10 records of 200-byte segments, want several", len(l.segments))
+	}
+	if got := readFrom(t, l, 0); !equalInts(got, sequence(0, 10)) {
+		t.Fatalf("read %v", got)
+	}
+	if err := l.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Leave half a record at the end of the active segment, as a crash would
+	segments, err := listSegments(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	active := segments[len(segments)-1].path
+	file, err := os.OpenFile(active, os.O_WRONLY|os.O_APPEND, 0644)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := file.WriteString(`{"offset":10,"time":"2025-01-01T00:00:00Z","ev`); err != nil {
+		t.Fatal(err)
+	}
+	file.Close()
+
+	l, err = OpenLog(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	if next := l.NextOffset(); next != 10 {
+		t.Fatalf("NextOffset after reopening = %d, want 10", next)
+	}
+	appendN(t, l, 10, 5)
+	if got := readFrom(t, l, 0); !equalInts(got, sequence(0, 15)) {
+		t.Errorf("read %v after recovery", got)
+	}
+}
+
+// TestReplay checks that reading starts at an offset or a time, across segments.
+func TestReplay(t *testing.T) {
+	l, err := OpenLog(t.TempDir(), Options{SegmentBytes: 200})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+
+	appendN(t, l, 0, 5)
+	time.Sleep(10 * time.Millisecond)
+	mid := time.Now()
+	appendN(t, l, 5, 5)
+
+	if got := readFrom(t, l, 3); !equalInts(got, sequence(3, 10)) {
+		t.Errorf("from offset 3: %v", got)
+	}
+	if got := readFrom(t, l, 10); len(got) != 0 {
+		t.Errorf("from the next offset: %v", got)
+	}
+
+	offset, err := l.OffsetAt(mid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if offset != 5 {
+		t.Errorf("OffsetAt(mid) = %d, want 5", offset)
+	}
+	if offset, _ := l.OffsetAt(time.Now().Add(time.Hour)); offset != 10 {
+		t.Errorf("OffsetAt(future) = %d, want NextOffset 10", offset)
+	}
+}
+
+// TestRetention checks that pruning deletes the oldest segments first, by
+// size and by age, and never the active one.
+func TestRetention(t *testing.T) {
+	dir := t.TempDir()
+	l, err := OpenLog(dir, Options{SegmentBytes: 200, RetentionBytes: 400})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer l.Close()
+	appendN(t, l, 0, 20)
+
+	before := len(l.segments)
+	removed, err := l.Prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if removed == 0 || removed >= before {
+		t.Fatalf("Prune removed %d of %d segments", removed, before)
+	}
+	first := l.FirstOffset()
+	if first == 0 {
+		t.Fatal("FirstOffset didn't advance")
+	}
+	var total int64
+	segments, _ := listSegments(dir)
+	for _, seg := range segments {
+		info, err := os.Stat(seg.path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		total += info.Size()
+	}
+	if total > 400 {
+		t.Errorf("%d bytes retained, want at most 400", total)
+	}
+	// Reading from a pruned offset starts at the oldest retained record
+	if got := readFrom(t, l, 0); !equalInts(got, sequence(int(first), 20)) {
+		t.Errorf("from offset 0 after pruning: %v", got)
+	}
+
+	// By age: every closed segment is old, the active one stays
+	l.opts = Options{RetentionAge: time.Hour}
+	old := time.Now().Add(-2 * time.Hour)
+	for _, seg := range l.segments {
+		if err := os.Chtimes(seg.path, old, old); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, err := l.Prune(); err != nil {
+		t.Fatal(err)
+	}
+	if len(l.segments) != 1 {
+		t.Fatalf("%d segments after pruning by age, want only the active one", len(l.segments))
+	}
+	if _, err := os.Stat(l.segments[0].path); err != nil {
+		t.Errorf("active segment: %v", err)
+	}
+	appendN(t, l, 20, 1)
+}
+
+// TestDurableConsumerResumes checks that a durable consumer picks up after a
+// restart where it left off, including events published while it was down.
+func TestDurableConsumerResumes(t *testing.T) {
+	events.SetEventConfig(&events.EventConfig{Enabled: true, LifecycleEventsEnabled: true, EventTypePrefix: "io.test", Source: "test"})
+	ctx := context.Background()
+	dir := t.TempDir()
+	opts := Options{SegmentBytes: 1024}
+
+	var mu sync.Mutex
+	var got []string
+	handler := func(ctx context.Context, event events.Event) error {
+		mu.Lock()
+		defer mu.Unlock()
+		got = append(got, event.ResourceUID())
+		return nil
+	}
+	publish := func(bus *EventBus, from, to int) {
+		for i := from; i < to; i++ {
+			event, err := events.NewResourceEvent("created", "Device", fmt.Sprintf("dev-%d", i), nil)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := bus.Publish(ctx, *event); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	waitFor := func(n int) []string {
+		t.Helper()
+		deadline := time.Now().Add(5 * time.Second)
+		for {
+			mu.Lock()
+			count := len(got)
+			mu.Unlock()
+			if count >= n || time.Now().After(deadline) {
+				break
+			}
+			time.Sleep(10 * time.Millisecond)
+		}
+		time.Sleep(50 * time.Millisecond) // Catch any extra deliveries
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]string(nil), got...)
+	}
+
+	bus, err := NewEventBus(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := bus.Consumer("reconciler").Subscribe("io.test.device.*", handler); err != nil {
+		t.Fatal(err)
+	}
+	publish(bus, 0, 3)
+	if delivered := waitFor(3); len(delivered) != 3 {
+		t.Fatalf("delivered %v before the restart", delivered)
+	}
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	// Published while the consumer isn't running
+	bus, err = NewEventBus(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publish(bus, 3, 5)
+	if err := bus.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	bus, err = NewEventBus(dir, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer bus.Close()
+	if _, err := bus.Consumer("reconciler").Subscribe("io.test.device.*", handler); err != nil {
+		t.Fatal(err)
+	}
+	delivered := waitFor(5)
+	want := []string{"dev-0", "dev-1", "dev-2", "dev-3", "dev-4"}
+	if fmt.Sprint(delivered) != fmt.Sprint(want) {
+		t.Errorf("delivered %v, want %v", delivered, want)
+	}
+	if _, err := os.Stat(filepath.Join(dir, offsetsFile)); err != nil {
+		t.Errorf("offsets file: %v", err)
+	}
+}
@@ -0,0 +1,101 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package eventlog
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// offsetStore keeps the committed offset of each durable consumer.
+//
+// Offsets are held in memory and flushed to a single JSON file, written to a
+// temporary file and renamed so a crash never leaves it half-written. An
+// offset is the next record the consumer should read.
+type offsetStore struct {
+	path string
+
+	mu      sync.Mutex
+	offsets map[string]uint64
+	dirty   bool
+}
+
+func loadOffsetStore(path string) (*offsetStore, error) {
+	s := &offsetStore{path: path, offsets: make(map[string]uint64)}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return s, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read consumer offsets: %w", err)
+	}
+	if err := json.Unmarshal(data, &s.offsets); err != nil {
+		return nil, fmt.Errorf("failed to parse consumer offsets %s: %w", path, err)
+	}
+	return s, nil
+}
+
+// Get returns the committed offset of a consumer.
+func (s *offsetStore) Get(consumer string) (uint64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offset, ok := s.offsets[consumer]
+	return offset, ok
+}
+
+// Set commits a consumer's offset in memory; Flush persists it.
+func (s *offsetStore) Set(consumer string, offset uint64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if current, ok := s.offsets[consumer]; ok && current == offset {
+		return
+	}
+	s.offsets[consumer] = offset
+	s.dirty = true
+}
+
+// Flush writes the offsets to disk if any changed since the last flush.
+func (s *offsetStore) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if !s.dirty {
+		return nil
+	}
+	data, err := json.MarshalIndent(s.offsets, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode consumer offsets: %w", err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(s.path), ".offsets-*")
+	if err != nil {
+		return fmt.Errorf("failed to write consumer offsets: %w", err)
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write consumer offsets: %w", err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write consumer offsets: %w", err)
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write consumer offsets: %w", err)
+	}
+	if err := os.Rename(tmp.Name(), s.path); err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("failed to write consumer offsets: %w", err)
+	}
+	s.dirty = false
+	return nil
+}
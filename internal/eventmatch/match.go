@@ -0,0 +1,31 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+// Package eventmatch matches event types against subscription patterns.
+package eventmatch
+
+import "strings"
+
+// Matches checks an event type against a subscription pattern, with the same
+// rules as fabrica's in-memory bus: "*" matches one segment, "**" the rest.
+func Matches(eventType, pattern string) bool {
+	if eventType == pattern {
+		return true
+	}
+
+	eventParts := strings.Split(eventType, ".")
+	patternParts := strings.Split(pattern, ".")
+	for i, p := range patternParts {
+		if p == "**" {
+			return true
+		}
+		if i >= len(eventParts) {
+			return false
+		}
+		if p != "*" && p != eventParts[i] {
+			return false
+		}
+	}
+	return len(eventParts) == len(patternParts)
+}
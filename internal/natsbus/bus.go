@@ -0,0 +1,360 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+// Package natsbus provides an events.EventBus backed by NATS JetStream.
+//
+// Events are published as structured-mode CloudEvents (JSON body) to a
+// subject equal to the event type, e.g. "io.fabrica.device.created", so
+// other services can subscribe with ordinary NATS subject filters such as
+// "io.fabrica.device.>". All subjects are captured by one JetStream stream.
+package natsbus
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/openchami/fabrica/pkg/events"
+)
+
+const (
+	// DefaultStream is the JetStream stream that captures inventory events
+	DefaultStream = "INVENTORY_EVENTS"
+
+	// CloudEvents structured-mode content type
+	contentTypeCloudEvents = "application/cloudevents+json"
+
+	// How long a durable consumer waits for an ack (or a progress report)
+	// before redelivering
+	defaultAckWait = 30 * time.Second
+)
+
+// Options configures an EventBus.
+type Options struct {
+	// URL of the NATS server (default nats.DefaultURL)
+	URL string
+
+	// Stream is the JetStream stream name (default DefaultStream)
+	Stream string
+
+	// Subjects captured by the stream (default: the configured event type prefix + ".>")
+	Subjects []string
+
+	// MaxBytes and MaxAge limit the stream; 0 means unlimited
+	MaxBytes int64
+	MaxAge   time.Duration
+
+	// MaxDeliver bounds redeliveries of a message a durable consumer's handler failed on; 0 means unlimited
+	MaxDeliver int
+
+	// AckWait is how long a durable consumer waits to hear from a handler
+	// before redelivering its message (default 30s). A handler that is still
+	// running reports progress every AckWait/2, so it may take longer.
+	AckWait time.Duration
+}
+
+// EventBus implements events.EventBus on NATS JetStream.
+type EventBus struct {
+	nc      *nats.Conn
+	ownConn bool
+	js      jetstream.JetStream
+	opts    Options
+
+	mu        sync.Mutex
+	subs      map[events.SubscriptionID]jetstream.ConsumeContext
+	nextSubID int
+
+	ctx    context.Context
+	cancel context.CancelFunc
+}
+
+// Compile-time check that EventBus implements events.EventBus
+var _ events.EventBus = (*EventBus)(nil)
+
+// NewEventBus connects to NATS at opts.URL and makes sure the stream exists.
+func NewEventBus(ctx context.Context, opts Options) (*EventBus, error) {
+	url := opts.URL
+	if url == "" {
+		url = nats.DefaultURL
+	}
+	nc, err := nats.Connect(url, nats.Name("inventory-api"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS at %s: %w", url, err)
+	}
+
+	bus, err := NewEventBusFromConn(ctx, nc, opts)
+	if err != nil {
+		nc.Close()
+		return nil, err
+	}
+	bus.ownConn = true
+	return bus, nil
+}
+
+// NewEventBusFromConn uses an existing connection (e.g. to an embedded
+// nats-server) and makes sure the stream exists. The caller keeps ownership of nc.
+func NewEventBusFromConn(ctx context.Context, nc *nats.Conn, opts Options) (*EventBus, error) {
+	if opts.Stream == "" {
+		opts.Stream = DefaultStream
+	}
+	if opts.AckWait <= 0 {
+		opts.AckWait = defaultAckWait
+	}
+	if len(opts.Subjects) == 0 {
+		opts.Subjects = []string{events.GetEventConfig().EventTypePrefix + ".>"}
+	}
+
+	js, err := jetstream.New(nc)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create JetStream context: %w", err)
+	}
+
+	streamConfig := jetstream.StreamConfig{
+		Name:      opts.Stream,
+		Subjects:  opts.Subjects,
+		Storage:   jetstream.FileStorage,
+		Retention: jetstream.LimitsPolicy,
+		MaxBytes:  -1,
+		MaxAge:    opts.MaxAge,
+	}
+	if opts.MaxBytes > 0 {
+		streamConfig.MaxBytes = opts.MaxBytes
+	}
+	if _, err := js.CreateOrUpdateStream(ctx, streamConfig); err != nil {
+		return nil, fmt.Errorf("failed to create stream %s: %w", opts.Stream, err)
+	}
+
+	busCtx, cancel := context.WithCancel(context.Background())
+	return &EventBus{
+		nc:        nc,
+		js:        js,
+		opts:      opts,
+		subs:      make(map[events.SubscriptionID]jetstream.ConsumeContext),
+		nextSubID: 1,
+		ctx:       busCtx,
+		cancel:    cancel,
+	}, nil
+}
+
+// Publish publishes an event to the subject derived from its type and waits
+// for JetStream to store it. The event ID is used for duplicate detection.
+func (b *EventBus) Publish(ctx context.Context, event events.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("failed to encode event: %w", err)
+	}
+
+	msg := nats.NewMsg(SubjectForType(event.Type()))
+	msg.Data = data
+	msg.Header.Set("Content-Type", contentTypeCloudEvents)
+	msg.Header.Set(jetstream.MsgIDHeader, event.ID())
+
+	if _, err := b.js.PublishMsg(ctx, msg); err != nil {
+		return fmt.Errorf("failed to publish event %s: %w", event.ID(), err)
+	}
+	return nil
+}
+
+// Subscribe delivers events published from now on to handler, in order.
+// Every subscriber gets every matching event (ephemeral ordered consumer).
+func (b *EventBus) Subscribe(eventType string, handler events.EventHandler) (events.SubscriptionID, error) {
+	consumer, err := b.js.OrderedConsumer(b.ctx, b.opts.Stream, jetstream.OrderedConsumerConfig{
+		FilterSubjects: []string{SubjectForPattern(eventType)},
+		DeliverPolicy:  jetstream.DeliverNewPolicy,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create consumer for %s: %w", eventType, err)
+	}
+
+	return b.consume(consumer, func(msg jetstream.Msg) {
+		// Ordered consumers don't ack; a failed handler is only logged (same as the in-memory bus)
+		b.handle(msg, handler)
+	})
+}
+
+// SubscribeDurable delivers events through a named durable consumer.
+//
+// The consumer lives on the server, so it resumes where it left off after a
+// restart. Events whose handler fails are redelivered; while the handler
+// runs the message is kept from being redelivered however long it takes
+// (e.g. webhook retries). Processes subscribing with the same name would
+// split the events between them, which the inventory server doesn't support:
+// only one server may use a store (see storage.LockWriter).
+func (b *EventBus) SubscribeDurable(name, eventType string, handler events.EventHandler) (events.SubscriptionID, error) {
+	consumer, err := b.js.CreateOrUpdateConsumer(b.ctx, b.opts.Stream, jetstream.ConsumerConfig{
+		Durable:       name,
+		FilterSubject: SubjectForPattern(eventType),
+		DeliverPolicy: jetstream.DeliverNewPolicy,
+		AckPolicy:     jetstream.AckExplicitPolicy,
+		AckWait:       b.opts.AckWait,
+		MaxDeliver:    b.opts.MaxDeliver,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to create durable consumer %s: %w", name, err)
+	}
+
+	return b.consume(consumer, func(msg jetstream.Msg) {
+		stop := b.keepInProgress(msg)
+		err := b.handle(msg, handler)
+		stop()
+		if err != nil {
+			msg.Nak()
+			return
+		}
+		msg.Ack()
+	})
+}
+
+// keepInProgress tells the server every AckWait/2 that msg is still being
+// handled, until the returned function is called
+func (b *EventBus) keepInProgress(msg jetstream.Msg) (stop func()) {
+	done := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		ticker := time.NewTicker(b.opts.AckWait / 2)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				msg.InProgress()
+			}
+		}
+	}()
+	return func() {
+		close(done)
+		<-stopped
+	}
+}
+
+// consume starts a consumer and registers it as a subscription
+func (b *EventBus) consume(consumer jetstream.Consumer, handler jetstream.MessageHandler) (events.SubscriptionID, error) {
+	consumeCtx, err := consumer.Consume(handler)
+	if err != nil {
+		return "", fmt.Errorf("failed to start consumer: %w", err)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	id := events.SubscriptionID(fmt.Sprintf("sub-%d", b.nextSubID))
+	b.nextSubID++
+	b.subs[id] = consumeCtx
+	return id, nil
+}
+
+// handle decodes a message and calls the handler
+func (b *EventBus) handle(msg jetstream.Msg, handler events.EventHandler) error {
+	var event events.Event
+	if err := json.Unmarshal(msg.Data(), &event); err != nil {
+		// Redelivery won't fix a malformed message, so treat it as handled
+		fmt.Printf("Error decoding event on %s: %v\n", msg.Subject(), err)
+		return nil
+	}
+	if err := handler(b.ctx, event); err != nil {
+		fmt.Printf("Error handling event %s: %v\n", event.ID(), err)
+		return err
+	}
+	return nil
+}
+
+// Unsubscribe stops a subscription. A durable consumer stays on the server.
+func (b *EventBus) Unsubscribe(id events.SubscriptionID) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	consumeCtx, ok := b.subs[id]
+	if !ok {
+		return fmt.Errorf("subscription not found: %s", id)
+	}
+	consumeCtx.Stop()
+	delete(b.subs, id)
+	return nil
+}
+
+// Close stops all subscriptions and, if the bus opened the connection, drains it.
+func (b *EventBus) Close() error {
+	b.mu.Lock()
+	for id, consumeCtx := range b.subs {
+		consumeCtx.Stop()
+		delete(b.subs, id)
+	}
+	b.mu.Unlock()
+	b.cancel()
+
+	if b.ownConn {
+		return b.nc.Drain()
+	}
+	return nil
+}
+
+// Consumer returns a view of the bus whose subscriptions are durable consumers
+// named after name, so events published while the server was down are
+// delivered when it comes back.
+func (b *EventBus) Consumer(name string) events.EventBus {
+	return &consumerBus{bus: b, name: name}
+}
+
+// consumerBus is the events.EventBus returned by EventBus.Consumer
+type consumerBus struct {
+	bus  *EventBus
+	name string
+}
+
+func (c *consumerBus) Publish(ctx context.Context, event events.Event) error {
+	return c.bus.Publish(ctx, event)
+}
+
+func (c *consumerBus) Subscribe(eventType string, handler events.EventHandler) (events.SubscriptionID, error) {
+	name := c.name
+	if eventType != "**" {
+		name = c.name + "_" + durableSuffix(eventType)
+	}
+	return c.bus.SubscribeDurable(name, eventType, handler)
+}
+
+func (c *consumerBus) Unsubscribe(id events.SubscriptionID) error {
+	return c.bus.Unsubscribe(id)
+}
+
+// Close is a no-op; the owner of the underlying bus closes it.
+func (c *consumerBus) Close() error {
+	return nil
+}
+
+// SubjectForType returns the NATS subject an event type is published on.
+// Event types are already dot-separated; characters NATS reserves are replaced.
+func SubjectForType(eventType string) string {
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case '*', '>', ' ', '\t', '\r', '\n':
+			return '_'
+		}
+		return r
+	}, eventType)
+}
+
+// SubjectForPattern converts a fabrica subscription pattern into a NATS subject filter.
+// "*" matches one token in both; fabrica's "**" (the rest) becomes ">".
+func SubjectForPattern(pattern string) string {
+	tokens := strings.Split(pattern, ".")
+	for i, token := range tokens {
+		if token == "**" {
+			return strings.Join(append(tokens[:i:i], ">"), ".")
+		}
+	}
+	return pattern
+}
+
+// durableSuffix turns a pattern into characters allowed in a durable name
+func durableSuffix(pattern string) string {
+	replacer := strings.NewReplacer(".", "_", "**", "all", "*", "any", ">", "all", " ", "_")
+	return replacer.Replace(pattern)
+}
@@ -0,0 +1,227 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package natsbus
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/openchami/fabrica/pkg/events"
+)
+
+// runServer starts an in-process nats-server with JetStream
+func runServer(t *testing.T) *server.Server {
+	t.Helper()
+	ns, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      server.RANDOM_PORT,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(10 * time.Second) {
+		t.Fatal("nats-server did not start")
+	}
+	t.Cleanup(ns.Shutdown)
+	return ns
+}
+
+// newTestBus connects a bus to ns
+func newTestBus(t *testing.T, ns *server.Server, opts Options) *EventBus {
+	t.Helper()
+	nc, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(nc.Close)
+	bus, err := NewEventBusFromConn(context.Background(), nc, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { bus.Close() })
+	return bus
+}
+
+func publish(t *testing.T, bus *EventBus, eventType string) *events.Event {
+	t.Helper()
+	event, err := events.NewEvent(eventType, "test", map[string]string{"uid": "dev-1"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.Publish(context.Background(), *event); err != nil {
+		t.Fatal(err)
+	}
+	return event
+}
+
+// deliveries records the IDs of the events a handler is called with
+type deliveries struct {
+	mu  sync.Mutex
+	ids []string
+	ch  chan string
+}
+
+func newDeliveries() *deliveries {
+	return &deliveries{ch: make(chan string, 100)}
+}
+
+func (d *deliveries) record(event events.Event) int {
+	d.mu.Lock()
+	d.ids = append(d.ids, event.ID())
+	n := len(d.ids)
+	d.mu.Unlock()
+	d.ch <- event.ID()
+	return n
+}
+
+func (d *deliveries) count() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return len(d.ids)
+}
+
+func (d *deliveries) wait(t *testing.T, want string) {
+	t.Helper()
+	select {
+	case id := <-d.ch:
+		if id != want {
+			t.Fatalf("delivered %s, want %s", id, want)
+		}
+	case <-time.After(10 * time.Second):
+		t.Fatalf("event %s was not delivered", want)
+	}
+}
+
+// waitAcked waits until the durable consumer has nothing pending or unacked
+func waitAcked(t *testing.T, bus *EventBus, name string) {
+	t.Helper()
+	ctx := context.Background()
+	deadline := time.Now().Add(10 * time.Second)
+	for {
+		consumer, err := bus.js.Consumer(ctx, bus.opts.Stream, name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		info, err := consumer.Info(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.NumAckPending == 0 && info.NumPending == 0 {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("consumer %s: %d unacked, %d pending", name, info.NumAckPending, info.NumPending)
+		}
+		time.Sleep(50 * time.Millisecond)
+	}
+}
+
+func TestDurableDeliveryAndAck(t *testing.T) {
+	ns := runServer(t)
+	bus := newTestBus(t, ns, Options{})
+
+	got := newDeliveries()
+	if _, err := bus.SubscribeDurable("test", "io.fabrica.device.*", func(ctx context.Context, event events.Event) error {
+		got.record(event)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	publish(t, bus, "io.fabrica.subscription.created") // not matched by the filter
+	event := publish(t, bus, "io.fabrica.device.created")
+	got.wait(t, event.ID())
+	waitAcked(t, bus, "test")
+	if n := got.count(); n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+}
+
+func TestDurableRedeliversFailedEvents(t *testing.T) {
+	ns := runServer(t)
+	bus := newTestBus(t, ns, Options{})
+
+	got := newDeliveries()
+	if _, err := bus.SubscribeDurable("test", "io.fabrica.device.*", func(ctx context.Context, event events.Event) error {
+		if got.record(event) == 1 {
+			return errors.New("transient failure")
+		}
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	event := publish(t, bus, "io.fabrica.device.updated")
+	got.wait(t, event.ID())
+	got.wait(t, event.ID()) // redelivered after the failure
+	waitAcked(t, bus, "test")
+	if n := got.count(); n != 2 {
+		t.Errorf("handler called %d times, want 2", n)
+	}
+}
+
+// TestDurableSlowHandlerNotRedelivered checks that a handler running longer
+// than AckWait keeps its message, instead of it being delivered again.
+func TestDurableSlowHandlerNotRedelivered(t *testing.T) {
+	ns := runServer(t)
+	const ackWait = time.Second
+	bus := newTestBus(t, ns, Options{AckWait: ackWait})
+
+	got := newDeliveries()
+	if _, err := bus.SubscribeDurable("slow", "io.fabrica.device.*", func(ctx context.Context, event events.Event) error {
+		got.record(event)
+		time.Sleep(3 * ackWait)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+
+	event := publish(t, bus, "io.fabrica.device.created")
+	got.wait(t, event.ID())
+	waitAcked(t, bus, "slow")
+	time.Sleep(2 * ackWait)
+	if n := got.count(); n != 1 {
+		t.Errorf("handler called %d times, want 1", n)
+	}
+}
+
+// TestDurableResumesAfterRestart checks that events published while no
+// subscriber was running are delivered when it subscribes again.
+func TestDurableResumesAfterRestart(t *testing.T) {
+	ns := runServer(t)
+
+	first := newTestBus(t, ns, Options{})
+	id, err := first.SubscribeDurable("resume", "io.fabrica.device.*", func(ctx context.Context, event events.Event) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := first.Unsubscribe(id); err != nil {
+		t.Fatal(err)
+	}
+	event := publish(t, first, "io.fabrica.device.deleted")
+
+	second := newTestBus(t, ns, Options{})
+	got := newDeliveries()
+	if _, err := second.SubscribeDurable("resume", "io.fabrica.device.*", func(ctx context.Context, event events.Event) error {
+		got.record(event)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	got.wait(t, event.ID())
+	waitAcked(t, second, "resume")
+}
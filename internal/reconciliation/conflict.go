@@ -0,0 +1,42 @@
+package reconciliation
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/user/inventory-api/internal/storage"
+)
+
+// maxConflictRetries bounds how often a write is retried after losing a
+// resourceVersion race to another writer (e.g. an API PATCH)
+const maxConflictRetries = 5
+
+// updateWithRetry applies mutate to obj and saves it.
+//
+// If another writer changed the resource since it was read, the save fails
+// with storage.ErrConflict; the latest copy is then loaded into obj (in place,
+// so pointers held by the caller stay valid) and mutate is applied again.
+// An error from mutate (e.g. because the latest copy needs no more work) is
+// returned without saving.
+func updateWithRetry[T any](ctx context.Context, client *storage.VersionedClient, kind, uid string, obj *T, mutate func(*T) error) error {
+	for attempt := 0; ; attempt++ {
+		if err := mutate(obj); err != nil {
+			return err
+		}
+		err := client.Update(ctx, obj)
+		if err == nil || !errors.Is(err, storage.ErrConflict) || attempt >= maxConflictRetries {
+			return err
+		}
+
+		latest, getErr := client.Get(ctx, kind, uid)
+		if getErr != nil {
+			return fmt.Errorf("failed to reload %s %s after conflict: %w", kind, uid, getErr)
+		}
+		fresh, ok := latest.(*T)
+		if !ok {
+			return fmt.Errorf("reloaded %s %s has unexpected type %T", kind, uid, latest)
+		}
+		*obj = *fresh
+	}
+}
@@ -0,0 +1,106 @@
+package reconciliation
+
+import (
+	"sort"
+	"sync"
+)
+
+// identityLocks hands out per-identity mutexes (e.g. one per serial number).
+//
+// A reconcile locks every identity it is going to touch, so two snapshots
+// with overlapping serial numbers run one after the other, while snapshots
+// for unrelated hardware still run in parallel.
+type identityLocks struct {
+	mu    sync.Mutex
+	locks map[string]*identityLock
+}
+
+// serialLocks guards Device serial numbers for everything that creates
+// Devices or links them by serial number: snapshot reconciles and device
+// imports alike (see LockSerials).
+var serialLocks = newIdentityLocks()
+
+// LockSerials locks the given Device serial numbers against snapshot
+// reconciles and other callers, and returns a function that unlocks them.
+// Hold the locks from looking the serials up until the writes based on
+// those lookups are committed.
+func LockSerials(serials []string) (unlock func()) {
+	return serialLocks.LockAll(serials)
+}
+
+// identityLock is a reference-counted mutex, dropped from the map when unused
+type identityLock struct {
+	mu   sync.Mutex
+	refs int
+}
+
+func newIdentityLocks() *identityLocks {
+	return &identityLocks{locks: make(map[string]*identityLock)}
+}
+
+// LockAll locks every key and returns a function that unlocks them.
+// Keys are de-duplicated and locked in sorted order so that callers with
+// overlapping key sets can't deadlock.
+func (l *identityLocks) LockAll(keys []string) (unlock func()) {
+	sorted := uniqueSorted(keys)
+
+	held := make([]*identityLock, 0, len(sorted))
+	for _, key := range sorted {
+		lock := l.acquire(key)
+		lock.mu.Lock()
+		held = append(held, lock)
+	}
+
+	return func() {
+		for i := len(held) - 1; i >= 0; i-- {
+			held[i].mu.Unlock()
+			l.release(sorted[i])
+		}
+	}
+}
+
+// acquire returns the lock for key, creating it if needed, and takes a reference
+func (l *identityLocks) acquire(key string) *identityLock {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, ok := l.locks[key]
+	if !ok {
+		lock = &identityLock{}
+		l.locks[key] = lock
+	}
+	lock.refs++
+	return lock
+}
+
+// release drops a reference and forgets the lock once nobody holds or waits on it
+func (l *identityLocks) release(key string) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	lock, ok := l.locks[key]
+	if !ok {
+		return
+	}
+	lock.refs--
+	if lock.refs == 0 {
+		delete(l.locks, key)
+	}
+}
+
+func uniqueSorted(keys []string) []string {
+	seen := make(map[string]struct{}, len(keys))
+	out := make([]string, 0, len(keys))
+	for _, key := range keys {
+		if key == "" {
+			continue
+		}
+		if _, dup := seen[key]; dup {
+			continue
+		}
+		seen[key] = struct{}{}
+		out = append(out, key)
+	}
+	sort.Strings(out)
+	return out
+}
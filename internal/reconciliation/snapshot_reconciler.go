@@ -3,12 +3,14 @@ package reconciliation
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
 
 	"github.com/openchami/fabrica/pkg/events"
 	"github.com/openchami/fabrica/pkg/reconcile"
 	fabResource "github.com/openchami/fabrica/pkg/resource"
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
 
 	"github.com/user/inventory-api/internal/storage"
 	"github.com/user/inventory-api/pkg/resources/device"
@@ -19,10 +21,10 @@ import (
 // ...
 type SnapshotReconciler struct {
 	reconcile.BaseReconciler
-	client *storage.StorageClient
+	client *storage.VersionedClient
 	logger reconcile.Logger
 }
-func NewSnapshotReconciler(eb events.EventBus, client *storage.StorageClient, logger reconcile.Logger) *SnapshotReconciler {
+func NewSnapshotReconciler(eb events.EventBus, client *storage.VersionedClient, logger reconcile.Logger) *SnapshotReconciler {
 	return &SnapshotReconciler{
 		BaseReconciler: reconcile.BaseReconciler{
 			EventBus: eb,
@@ -53,10 +55,17 @@ func (r *SnapshotReconciler) Reconcile(ctx context.Context, resource interface{}
 	r.logger.Infof("RECONCILER: Received request for DiscoverySnapshot %s", snapshot.GetName())
 
 	// 2. Set phase to "Processing"
-	snapshot.Status.Phase = "Processing"
-	snapshot.Status.Message = "Reconciler has started processing the snapshot."
-	snapshot.Status.Ready = false
-	if err := r.client.Update(ctx, &snapshot); err != nil {
+	if err := r.updateSnapshot(ctx, &snapshot, func(s *discoverysnapshot.DiscoverySnapshot) {
+		now := time.Now()
+		s.Status.Phase = "Processing"
+		s.Status.Message = "Reconciler has started processing the snapshot."
+		s.Status.Ready = false
+		s.Status.Retryable = false
+		s.Status.NextAttemptAt = nil
+		s.Status.ProcessingSince = &now
+	}); errors.Is(err, errSnapshotCompleted) {
+		return reconcile.Result{}, nil
+	} else if err != nil {
 		return reconcile.Result{}, fmt.Errorf("failed to update snapshot status to Processing: %w", err)
 	}
 
@@ -65,15 +74,75 @@ func (r *SnapshotReconciler) Reconcile(ctx context.Context, resource interface{}
 	// 3a. Unmarshal the payload
 	var payloadSpecs []device.DeviceSpec
 	if err := json.Unmarshal(snapshot.Spec.RawData, &payloadSpecs); err != nil {
-		return r.failSnapshot(ctx, &snapshot, "Failed to parse rawData", err)
+		return r.failSnapshot(ctx, &snapshot, "Failed to parse rawData", err, false)
 	}
 
-	// 3b. Load all existing devices from storage
-	deviceMapBySerial, err := r.buildDeviceMapBySerial(ctx)
+	// Lock every serial this snapshot touches (devices and their parents).
+	// Another snapshot (or an import) for the same hardware waits here until
+	// we're done, so concurrent writers can't both create the same Device.
+	unlock := LockSerials(snapshotSerials(payloadSpecs))
+	defer unlock()
+
+	// The devices and the Completed status are written in one transaction,
+	// so a failure (or a crash) leaves inventory as it was and the snapshot
+	// can simply be reconciled again. If another writer changed one of the
+	// resources meanwhile, the whole payload is applied again on fresh copies.
+	var applied *appliedPayload
+	var err error
+	for attempt := 0; ; attempt++ {
+		applied, err = r.applyPayload(ctx, &snapshot, payloadSpecs)
+		if err == nil || !errors.Is(err, storage.ErrConflict) || attempt >= maxConflictRetries {
+			break
+		}
+		r.logger.Warnf("RECONCILER: Conflict applying snapshot %s, retrying: %v", snapshot.GetName(), err)
+		latest, getErr := r.client.Get(ctx, "DiscoverySnapshot", snapshot.GetUID())
+		if getErr != nil {
+			return reconcile.Result{}, fmt.Errorf("failed to reload snapshot after conflict: %w", getErr)
+		}
+		snapshot = *latest.(*discoverysnapshot.DiscoverySnapshot)
+		if snapshot.Status.Phase == "Completed" {
+			// Another reconcile of this snapshot committed it meanwhile
+			r.logger.Infof("RECONCILER: Snapshot %s was completed meanwhile", snapshot.GetName())
+			return reconcile.Result{}, nil
+		}
+	}
 	if err != nil {
-		return r.failSnapshot(ctx, &snapshot, "Failed to build device map", err)
+		return r.failSnapshot(ctx, &snapshot, "Failed to apply snapshot", err, transient(err))
+	}
+
+	// Announce the device writes and the Completed status now that they are stored
+	for _, dev := range applied.created {
+		r.publishDeviceEvent(ctx, "created", dev)
+	}
+	for _, dev := range applied.updated {
+		r.publishDeviceEvent(ctx, "updated", dev)
+	}
+	publishStatusUpdated(ctx, r.logger, "DiscoverySnapshot", snapshot.GetUID(), snapshot.GetName(), &snapshot)
+
+	r.logger.Infof("RECONCILER: Successfully reconciled %s", snapshot.GetName())
+
+	return reconcile.Result{}, nil
+}
+
+// appliedPayload is the outcome of a committed snapshot payload
+type appliedPayload struct {
+	created []*device.Device
+	updated []*device.Device
+}
+
+// applyPayload stages the devices of a payload, their parent links and the
+// snapshot's Completed status in a transaction and commits it.
+func (r *SnapshotReconciler) applyPayload(ctx context.Context, snapshot *discoverysnapshot.DiscoverySnapshot, payloadSpecs []device.DeviceSpec) (*appliedPayload, error) {
+	tx := r.client.Begin()
+	applied := &appliedPayload{}
+	created := make(map[string]bool) // UIDs of new devices
+	updated := make(map[string]bool) // UIDs of existing devices already in applied.updated
+	markUpdated := func(dev *device.Device) {
+		if !created[dev.GetUID()] && !updated[dev.GetUID()] {
+			updated[dev.GetUID()] = true
+			applied.updated = append(applied.updated, dev)
+		}
 	}
-	r.logger.Infof("RECONCILER: Loaded %d existing devices into map", len(deviceMapBySerial))
 
 	// This map will hold all devices *from this snapshot* (new and updated)
 	// We need it for the second pass
@@ -90,32 +159,41 @@ func (r *SnapshotReconciler) Reconcile(ctx context.Context, resource interface{}
 			continue
 		}
 
-		existingDevice, found := deviceMapBySerial[spec.SerialNumber]
+		// 3b. Look up the existing device by serial number: one staged
+		// earlier in this payload, or else one in storage (index point lookup)
+		existingDevice, found := snapshotDeviceMap[spec.SerialNumber]
 		if !found {
+			var err error
+			existingDevice, err = r.client.GetDeviceBySerial(ctx, spec.SerialNumber)
+			if err != nil && !errors.Is(err, fabricaStorage.ErrNotFound) {
+				return nil, fmt.Errorf("failed to look up device %s: %w", spec.SerialNumber, err)
+			}
+		}
+		if existingDevice == nil {
 			// --- CREATE NEW DEVICE ---
 			r.logger.Infof("RECONCILER (Pass 1): Creating new device: %s", spec.SerialNumber)
-			newDevice, err := r.createNewDevice(ctx, spec)
+			newDevice, err := r.createNewDevice(tx, spec)
 			if err != nil {
-				r.logger.Errorf("RECONCILER (Pass 1): Failed to create device %s: %v", spec.SerialNumber, err)
-				continue
+				return nil, err
 			}
+			created[newDevice.GetUID()] = true
+			applied.created = append(applied.created, newDevice)
 			snapshotDeviceMap[newDevice.Spec.SerialNumber] = newDevice
-			deviceMapBySerial[newDevice.Spec.SerialNumber] = newDevice // Add to global map
 
 		} else {
 			// --- UPDATE EXISTING DEVICE ---
 			r.logger.Infof("RECONCILER (Pass 1): Updating existing device: %s (UID: %s)", spec.SerialNumber, existingDevice.GetUID())
 
 			// Preserve the ParentID from the database, in case the snapshot doesn't have it
-			// This is important for the 2-pass linking
-			spec.ParentID = existingDevice.Spec.ParentID
-			existingDevice.Spec = spec // Update the spec
+			// This is important for the 2-pass linking.
+			newSpec := spec
+			newSpec.ParentID = existingDevice.Spec.ParentID
+			existingDevice.Spec = newSpec // Update the spec
 			existingDevice.Metadata.UpdatedAt = time.Now()
-
-			if err := r.client.Update(ctx, existingDevice); err != nil {
-				r.logger.Errorf("RECONCILER (Pass 1): Failed to update device %s: %v", spec.SerialNumber, err)
-				continue
+			if err := tx.Update(existingDevice); err != nil {
+				return nil, fmt.Errorf("failed to stage device %s: %w", spec.SerialNumber, err)
 			}
+			markUpdated(existingDevice)
 			snapshotDeviceMap[existingDevice.Spec.SerialNumber] = existingDevice
 		}
 		processedCount++
@@ -123,8 +201,8 @@ func (r *SnapshotReconciler) Reconcile(ctx context.Context, resource interface{}
 
 	// --- PASS 2: LINK PARENT IDs ---
 	// Now we loop through the devices *we just processed* and link them.
-	// We use the *full* deviceMapBySerial so we can link to parents
-	// that might have existed before this snapshot.
+	// Parents are resolved from this snapshot first, then from storage,
+	// so we can link to parents that existed before this snapshot.
 
 	r.logger.Infof("RECONCILER (Pass 2): Linking parent relationships...")
 	linksUpdated := 0
@@ -134,8 +212,8 @@ func (r *SnapshotReconciler) Reconcile(ctx context.Context, resource interface{}
 			continue // This device has no parent
 		}
 
-		parentDevice, found := deviceMapBySerial[parentSerial]
-		if !found {
+		parentDevice, err := r.findParent(ctx, snapshotDeviceMap, parentSerial)
+		if err != nil {
 			r.logger.Errorf("RECONCILER (Pass 2): Parent device with serial %s not found for child %s", parentSerial, dev.Spec.SerialNumber)
 			continue
 		}
@@ -151,30 +229,33 @@ func (r *SnapshotReconciler) Reconcile(ctx context.Context, resource interface{}
 
 		dev.Spec.ParentID = parentDevice.GetUID()
 		dev.Metadata.UpdatedAt = time.Now()
-
-		if err := r.client.Update(ctx, dev); err != nil {
-			r.logger.Errorf("RECONCILER (Pass 2): Failed to update parent link for %s: %v", dev.Spec.SerialNumber, err)
-		} else {
-			linksUpdated++
+		if err := tx.Update(dev); err != nil {
+			return nil, fmt.Errorf("failed to stage parent link for %s: %w", dev.Spec.SerialNumber, err)
 		}
+		markUpdated(dev)
+		linksUpdated++
 	}
 	// --- END PAYLOAD PROCESSING ---
 
-	// 4. Set phase to "Completed"
+	// 4. Set phase to "Completed", in the same transaction
 	snapshot.Status.Phase = "Completed"
 	snapshot.Status.Message = fmt.Sprintf("Snapshot processed. %d devices created/updated. %d parent links updated.", processedCount, linksUpdated)
 	snapshot.Status.Ready = true
-	if err := r.client.Update(ctx, &snapshot); err != nil {
-		return reconcile.Result{}, fmt.Errorf("failed to update snapshot status to Completed: %w", err)
+	snapshot.Status.Attempts = 0
+	snapshot.Status.ProcessingSince = nil
+	if err := tx.Update(snapshot); err != nil {
+		return nil, fmt.Errorf("failed to stage snapshot status: %w", err)
 	}
 
-	r.logger.Infof("RECONCILER: Successfully reconciled %s", snapshot.GetName())
-
-	return reconcile.Result{}, nil
+	r.logger.Infof("RECONCILER: Committing %d writes for snapshot %s", tx.Len(), snapshot.GetName())
+	if err := tx.Commit(ctx); err != nil {
+		return nil, err
+	}
+	return applied, nil
 }
 
-// createNewDevice is a helper to build and save a new device
-func (r *SnapshotReconciler) createNewDevice(ctx context.Context, spec device.DeviceSpec) (*device.Device, error) {
+// createNewDevice is a helper to build a new device and stage its creation
+func (r *SnapshotReconciler) createNewDevice(tx *storage.Transaction, spec device.DeviceSpec) (*device.Device, error) {
 	newDevice := &device.Device{
 		Resource: fabResource.Resource{
 			APIVersion:    "v1",
@@ -194,41 +275,134 @@ func (r *SnapshotReconciler) createNewDevice(ctx context.Context, spec device.De
 	newDevice.Metadata.CreatedAt = now
 	newDevice.Metadata.UpdatedAt = now
 
-	if err := r.client.Create(ctx, newDevice); err != nil {
-		return nil, fmt.Errorf("failed to create device %s: %w", spec.SerialNumber, err)
+	if err := tx.Create(newDevice); err != nil {
+		return nil, fmt.Errorf("failed to stage device %s: %w", spec.SerialNumber, err)
 	}
 
 	return newDevice, nil
 }
 
-// buildDeviceMapBySerial fetches all devices and creates a map of [SerialNumber] -> *Device
-func (r *SnapshotReconciler) buildDeviceMapBySerial(ctx context.Context) (map[string]*device.Device, error) {
-	deviceList, err := r.client.List(ctx, "Device")
+// publishDeviceEvent announces a device write, so Device reconcilers (e.g. topology) see it
+func (r *SnapshotReconciler) publishDeviceEvent(ctx context.Context, action string, dev *device.Device) {
+	var err error
+	if action == "created" {
+		err = events.PublishResourceCreated(ctx, "Device", dev.GetUID(), dev.GetName(), dev)
+	} else {
+		err = events.PublishResourceUpdated(ctx, "Device", dev.GetUID(), dev.GetName(), dev, nil)
+	}
 	if err != nil {
-		return nil, err
+		r.logger.Warnf("RECONCILER: Failed to publish %s event for device %s: %v", action, dev.GetUID(), err)
 	}
+}
 
-	deviceMap := make(map[string]*device.Device)
-	for _, item := range deviceList {
-		dev, ok := item.(*device.Device)
-		if !ok {
-			r.logger.Errorf("RECONCILER: Found non-device item in storage, skipping.")
-			continue
-		}
-		if dev.Spec.SerialNumber != "" {
-			deviceMap[dev.Spec.SerialNumber] = dev
+// snapshotSerials returns every serial number a payload refers to, including parent serials
+func snapshotSerials(specs []device.DeviceSpec) []string {
+	serials := make([]string, 0, len(specs)*2)
+	for _, spec := range specs {
+		serials = append(serials, spec.SerialNumber, spec.ParentSerialNumber)
+	}
+	return serials
+}
+
+// findParent resolves a parent serial number, preferring devices from the current snapshot
+func (r *SnapshotReconciler) findParent(ctx context.Context, snapshotDeviceMap map[string]*device.Device, parentSerial string) (*device.Device, error) {
+	if parent, found := snapshotDeviceMap[parentSerial]; found {
+		return parent, nil
+	}
+	return r.client.GetDeviceBySerial(ctx, parentSerial)
+}
+
+// errSnapshotCompleted is returned by updateSnapshot when the latest copy of
+// the snapshot has been completed by another reconcile
+var errSnapshotCompleted = errors.New("snapshot already completed")
+
+// updateSnapshot saves a snapshot, reapplying mutate to the latest copy on a
+// resourceVersion conflict, and announces the status change. A snapshot that
+// is Completed is left alone and errSnapshotCompleted is returned, so a stale
+// reconcile can't move it back to Processing or Error.
+func (r *SnapshotReconciler) updateSnapshot(ctx context.Context, snapshot *discoverysnapshot.DiscoverySnapshot, mutate func(*discoverysnapshot.DiscoverySnapshot)) error {
+	err := updateWithRetry(ctx, r.client, "DiscoverySnapshot", snapshot.GetUID(), snapshot, func(s *discoverysnapshot.DiscoverySnapshot) error {
+		if s.Status.Phase == "Completed" {
+			return errSnapshotCompleted
 		}
+		mutate(s)
+		return nil
+	})
+	if err != nil {
+		return err
 	}
-	return deviceMap, nil
+	publishStatusUpdated(ctx, r.logger, "DiscoverySnapshot", snapshot.GetUID(), snapshot.GetName(), snapshot)
+	return nil
 }
 
-// failSnapshot is a helper to update the snapshot's status to Error
-func (r *SnapshotReconciler) failSnapshot(ctx context.Context, snapshot *discoverysnapshot.DiscoverySnapshot, message string, err error) (reconcile.Result, error) {
-	snapshot.Status.Phase = "Error"
-	snapshot.Status.Message = fmt.Sprintf("%s: %v", message, err)
-	if updateErr := r.client.Update(ctx, snapshot); updateErr != nil {
+// maxSnapshotAttempts is how many times a snapshot failing with a retryable
+// error is reconciled before it is left in Error for good
+const maxSnapshotAttempts = 5
+
+// snapshotRetryBackoff is the delay before the first retry of a failed
+// snapshot; it doubles with every attempt
+const snapshotRetryBackoff = 30 * time.Second
+
+// failSnapshot is a helper to update the snapshot's status to Error.
+// retryable marks whether the snapshot should be tried again: it is requeued
+// with exponential backoff until it has failed maxSnapshotAttempts times. The
+// time of the next attempt is kept in status.nextAttemptAt, so the
+// startup/periodic resync doesn't retry it any sooner.
+func (r *SnapshotReconciler) failSnapshot(ctx context.Context, snapshot *discoverysnapshot.DiscoverySnapshot, message string, err error, retryable bool) (reconcile.Result, error) {
+	attempts := 0
+	if updateErr := r.updateSnapshot(ctx, snapshot, func(s *discoverysnapshot.DiscoverySnapshot) {
+		s.Status.Phase = "Error"
+		s.Status.Message = fmt.Sprintf("%s: %v", message, err)
+		s.Status.Ready = false
+		s.Status.Retryable = false
+		s.Status.NextAttemptAt = nil
+		s.Status.ProcessingSince = nil
+		if retryable {
+			s.Status.Attempts++
+			attempts = s.Status.Attempts
+			if attempts < maxSnapshotAttempts {
+				next := time.Now().Add(snapshotRetryBackoff << (attempts - 1))
+				s.Status.Retryable = true
+				s.Status.NextAttemptAt = &next
+			} else {
+				s.Status.Message += fmt.Sprintf(" (gave up after %d attempts)", attempts)
+			}
+		}
+	}); errors.Is(updateErr, errSnapshotCompleted) {
+		return reconcile.Result{}, nil
+	} else if updateErr != nil {
 		return reconcile.Result{}, updateErr
 	}
+	if !retryable || attempts >= maxSnapshotAttempts {
+		// Retrying can't help (the payload won't change), or has been tried enough
+		r.logger.Errorf("RECONCILER: %s", snapshot.Status.Message)
+		return reconcile.Result{}, nil
+	}
 	// Return the original error to trigger a retry
-	return reconcile.Result{}, err
-}
\ No newline at end of file
+	backoff := snapshotRetryBackoff << (attempts - 1)
+	r.logger.Warnf("RECONCILER: %s (attempt %d of %d, retrying in %s): %v", message, attempts, maxSnapshotAttempts, backoff, err)
+	return reconcile.Result{RequeueAfter: backoff}, err
+}
+
+// transient reports whether a failed snapshot may succeed if reconciled
+// again: storage I/O errors, cancelled contexts and lost resourceVersion
+// races are transient. Resources that are gone and data that can't be
+// encoded or decoded are not.
+func transient(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	var unsupportedType *json.UnsupportedTypeError
+	var unsupportedValue *json.UnsupportedValueError
+	var marshalerErr *json.MarshalerError
+	switch {
+	case errors.Is(err, fabricaStorage.ErrNotFound),
+		errors.Is(err, fabricaStorage.ErrInvalidData),
+		errors.As(err, &syntaxErr),
+		errors.As(err, &typeErr),
+		errors.As(err, &unsupportedType),
+		errors.As(err, &unsupportedValue),
+		errors.As(err, &marshalerErr):
+		return false
+	}
+	return true
+}
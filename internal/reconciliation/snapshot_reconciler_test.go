@@ -0,0 +1,250 @@
+package reconciliation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/openchami/fabrica/pkg/events"
+	fabResource "github.com/openchami/fabrica/pkg/resource"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+)
+
+// quietLogger discards reconciler logs, keeping errors for the test output
+type quietLogger struct{ t *testing.T }
+
+func (l quietLogger) Infof(format string, args ...interface{})  {}
+func (l quietLogger) Warnf(format string, args ...interface{})  {}
+func (l quietLogger) Debugf(format string, args ...interface{}) {}
+func (l quietLogger) Errorf(format string, args ...interface{}) { l.t.Logf(format, args...) }
+
+// initTestStorage points storage at a fresh data directory with the same
+// journaled, versioned and indexed backend the server uses
+func initTestStorage(t *testing.T) {
+	t.Helper()
+	ctx := context.Background()
+	if err := storage.InitJournaledFileBackend(t.TempDir()); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.InitVersioning(ctx); err != nil {
+		t.Fatal(err)
+	}
+	if err := storage.InitIndex(ctx); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { storage.Backend.Close() })
+}
+
+// saveSnapshot stores a DiscoverySnapshot of specs and returns it as the
+// reconciler receives it
+func saveSnapshot(t *testing.T, name string, specs []device.DeviceSpec) json.RawMessage {
+	t.Helper()
+	data, err := json.Marshal(specs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	uid, err := fabResource.GenerateUIDForResource("DiscoverySnapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	snapshot := &discoverysnapshot.DiscoverySnapshot{
+		Resource: fabResource.Resource{APIVersion: "v1", Kind: "DiscoverySnapshot", SchemaVersion: "v1"},
+		Spec:     discoverysnapshot.DiscoverySnapshotSpec{RawData: data},
+	}
+	snapshot.Metadata.UID = uid
+	snapshot.Metadata.Name = name
+	if err := storage.SaveDiscoverySnapshot(context.Background(), snapshot); err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(snapshot)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return raw
+}
+
+// TestConcurrentSnapshotsSameSerials reconciles overlapping snapshots of the
+// same hardware at once and checks each serial number ends up as exactly one
+// Device, linked to the one parent Device.
+func TestConcurrentSnapshotsSameSerials(t *testing.T) {
+	initTestStorage(t)
+	ctx := context.Background()
+	r := NewSnapshotReconciler(nil, storage.NewVersionedClient(), quietLogger{t})
+
+	const snapshots = 64
+	shared := []device.DeviceSpec{{DeviceType: "Chassis", SerialNumber: "CH-1"}}
+	for n := 1; n <= 4; n++ {
+		shared = append(shared, device.DeviceSpec{DeviceType: "Node", SerialNumber: fmt.Sprintf("N-%d", n), ParentSerialNumber: "CH-1"})
+	}
+
+	raws := make([]json.RawMessage, snapshots)
+	for i := range raws {
+		specs := append([]device.DeviceSpec{}, shared...)
+		if i%2 == 1 {
+			// Children before their parent, so the serials are met in another order
+			for l, r := 0, len(specs)-1; l < r; l, r = l+1, r-1 {
+				specs[l], specs[r] = specs[r], specs[l]
+			}
+		}
+		// Each snapshot also sees a DIMM of its own and one it shares with the next
+		specs = append(specs,
+			device.DeviceSpec{DeviceType: "DIMM", SerialNumber: fmt.Sprintf("DIMM-%d", i), ParentSerialNumber: "N-1"},
+			device.DeviceSpec{DeviceType: "DIMM", SerialNumber: fmt.Sprintf("DIMM-%d", (i+1)%snapshots), ParentSerialNumber: "N-1"},
+		)
+		raws[i] = saveSnapshot(t, fmt.Sprintf("snapshot-%d", i), specs)
+	}
+
+	var wg sync.WaitGroup
+	start := make(chan struct{})
+	errs := make(chan error, snapshots)
+	for _, raw := range raws {
+		wg.Add(1)
+		go func(raw json.RawMessage) {
+			defer wg.Done()
+			<-start
+			if _, err := r.Reconcile(ctx, raw); err != nil {
+				errs <- err
+			}
+		}(raw)
+	}
+	close(start)
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Errorf("Reconcile: %v", err)
+	}
+
+	devices, err := storage.LoadAllDevices(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bySerial := make(map[string][]*device.Device)
+	for _, dev := range devices {
+		bySerial[dev.Spec.SerialNumber] = append(bySerial[dev.Spec.SerialNumber], dev)
+	}
+	if want := len(shared) + snapshots; len(bySerial) != want {
+		t.Errorf("got %d serial numbers, want %d", len(bySerial), want)
+	}
+	for serial, devs := range bySerial {
+		if len(devs) != 1 {
+			t.Errorf("serial %s has %d Devices, want 1", serial, len(devs))
+		}
+	}
+
+	chassis, node := bySerial["CH-1"], bySerial["N-1"]
+	if len(chassis) != 1 || len(node) != 1 {
+		t.FailNow()
+	}
+	for serial, devs := range bySerial {
+		var want string
+		switch devs[0].Spec.DeviceType {
+		case "Node":
+			want = chassis[0].GetUID()
+		case "DIMM":
+			want = node[0].GetUID()
+		}
+		if devs[0].Spec.ParentID != want {
+			t.Errorf("%s has parent %q, want %q", serial, devs[0].Spec.ParentID, want)
+		}
+	}
+
+	all, err := storage.LoadAllDiscoverySnapshots(ctx)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, snapshot := range all {
+		if snapshot.Status.Phase != "Completed" {
+			t.Errorf("snapshot %s is %s (%s), want Completed", snapshot.GetName(), snapshot.Status.Phase, snapshot.Status.Message)
+		}
+	}
+}
+
+// TestCompletedSnapshotNotReapplied reconciles one snapshot several times at
+// once, and once more from its stale copy afterwards, and checks its devices
+// are announced once, the stale reconcile writes nothing and the snapshot
+// stays Completed.
+func TestCompletedSnapshotNotReapplied(t *testing.T) {
+	initTestStorage(t)
+	ctx := context.Background()
+	bus := events.NewInMemoryEventBus(100, 1)
+	bus.Start()
+	t.Cleanup(func() { bus.Close() })
+	useEventBus(t, bus)
+	received := collect(t, bus)
+	r := NewSnapshotReconciler(bus, storage.NewVersionedClient(), quietLogger{t})
+
+	raw := saveSnapshot(t, "rack-1", []device.DeviceSpec{
+		{DeviceType: "Chassis", SerialNumber: "CH-1"},
+		{DeviceType: "Node", SerialNumber: "N-1", ParentSerialNumber: "CH-1"},
+	})
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := r.Reconcile(ctx, raw); err != nil {
+				t.Errorf("Reconcile: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	var snapshot discoverysnapshot.DiscoverySnapshot
+	if err := json.Unmarshal(raw, &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	versions := func() map[string]string {
+		t.Helper()
+		stored, err := storage.LoadDiscoverySnapshot(ctx, snapshot.GetUID())
+		if err != nil {
+			t.Fatal(err)
+		}
+		if stored.Status.Phase != "Completed" {
+			t.Errorf("snapshot is %s (%s), want Completed", stored.Status.Phase, stored.Status.Message)
+		}
+		devices, err := storage.LoadAllDevices(ctx)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if len(devices) != 2 {
+			t.Errorf("got %d devices, want 2", len(devices))
+		}
+		got := map[string]string{stored.GetUID(): storage.ResourceVersionOf(&stored.Metadata)}
+		for _, dev := range devices {
+			got[dev.GetUID()] = storage.ResourceVersionOf(&dev.Metadata)
+		}
+		return got
+	}
+	before := versions()
+	if _, err := r.Reconcile(ctx, raw); err != nil {
+		t.Fatalf("Reconcile of the stale copy: %v", err)
+	}
+	if after := versions(); !maps.Equal(before, after) {
+		t.Errorf("reconciling the stale copy rewrote resources: versions %v, then %v", before, after)
+	}
+
+	counts := make(map[string]int)
+	for {
+		select {
+		case event := <-received:
+			if event.ResourceKind() == "Device" && !isStatusEvent(event) {
+				counts[event.Type()]++
+			}
+			continue
+		case <-time.After(500 * time.Millisecond):
+		}
+		break
+	}
+	// The devices are written in one transaction, linked as they are created
+	want := map[string]int{"io.fabrica.device.created": 2}
+	if !maps.Equal(counts, want) {
+		t.Errorf("device events %v, want %v", counts, want)
+	}
+}
@@ -0,0 +1,121 @@
+package reconciliation
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/openchami/fabrica/pkg/reconcile"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+)
+
+// SnapshotResyncer re-enqueues DiscoverySnapshots that never finished reconciling.
+//
+// The event bus is in-memory, so a snapshot saved just before a restart, or
+// one left in "Processing" when the process died mid-reconcile, would
+// otherwise never be picked up again. The resyncer scans storage once at
+// startup and then on a fixed interval to also catch dropped events.
+type SnapshotResyncer struct {
+	client     *storage.VersionedClient
+	controller *reconcile.Controller
+	logger     reconcile.Logger
+	interval   time.Duration
+	started    time.Time // snapshots Processing since before this were left by an earlier process
+}
+
+// snapshotProcessingLease is how long a snapshot may stay Processing in this
+// process before the resync assumes its reconcile is stuck and enqueues it again
+const snapshotProcessingLease = 10 * time.Minute
+
+// NewSnapshotResyncer creates a resyncer. An interval of 0 disables the
+// periodic resync; the startup scan still runs.
+func NewSnapshotResyncer(controller *reconcile.Controller, client *storage.VersionedClient, logger reconcile.Logger, interval time.Duration) *SnapshotResyncer {
+	return &SnapshotResyncer{
+		client:     client,
+		controller: controller,
+		logger:     logger,
+		interval:   interval,
+		started:    time.Now(),
+	}
+}
+
+// Start runs the startup scan and, if an interval is set, keeps resyncing until ctx is cancelled.
+func (r *SnapshotResyncer) Start(ctx context.Context) error {
+	if _, err := r.Resync(ctx, "Startup resync"); err != nil {
+		return err
+	}
+	if r.interval <= 0 {
+		return nil
+	}
+
+	go func() {
+		ticker := time.NewTicker(r.interval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+				if _, err := r.Resync(ctx, "Periodic resync"); err != nil {
+					r.logger.Errorf("RESYNC: %v", err)
+				}
+			}
+		}
+	}()
+	return nil
+}
+
+// Resync enqueues every snapshot that still needs reconciling and returns how many it enqueued.
+func (r *SnapshotResyncer) Resync(ctx context.Context, reason string) (int, error) {
+	items, err := r.client.List(ctx, "DiscoverySnapshot")
+	if err != nil {
+		return 0, fmt.Errorf("failed to list snapshots: %w", err)
+	}
+
+	now := time.Now()
+	enqueued := 0
+	for _, item := range items {
+		snapshot, ok := item.(*discoverysnapshot.DiscoverySnapshot)
+		if !ok || !needsReconcile(snapshot, now, r.started) {
+			continue
+		}
+		request := reconcile.ReconcileRequest{
+			ResourceKind: "DiscoverySnapshot",
+			ResourceUID:  snapshot.GetUID(),
+			Reason:       reason,
+		}
+		if err := r.controller.Enqueue(request); err != nil {
+			r.logger.Errorf("RESYNC: Failed to enqueue snapshot %s: %v", snapshot.GetUID(), err)
+			continue
+		}
+		enqueued++
+	}
+
+	if enqueued > 0 {
+		r.logger.Infof("RESYNC: %s enqueued %d of %d snapshots", reason, enqueued, len(items))
+	}
+	return enqueued, nil
+}
+
+// needsReconcile reports whether a snapshot was never processed, was
+// interrupted, or failed with an error that a retry might fix.
+//
+// A snapshot Processing since after started is being reconciled by this
+// process, so it is only taken over once its lease has run out; a retryable
+// Error waits for its nextAttemptAt.
+func needsReconcile(snapshot *discoverysnapshot.DiscoverySnapshot, now, started time.Time) bool {
+	switch snapshot.Status.Phase {
+	case "":
+		return true
+	case "Processing":
+		since := snapshot.Status.ProcessingSince
+		return since == nil || since.Before(started) || now.Sub(*since) >= snapshotProcessingLease
+	case "Error":
+		next := snapshot.Status.NextAttemptAt
+		return snapshot.Status.Retryable && (next == nil || !now.Before(*next))
+	default:
+		return false
+	}
+}
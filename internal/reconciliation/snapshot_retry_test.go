@@ -0,0 +1,119 @@
+package reconciliation
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"testing"
+	"time"
+
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+)
+
+func TestTransient(t *testing.T) {
+	var syntaxErr error = &json.SyntaxError{}
+	tests := []struct {
+		err  error
+		want bool
+	}{
+		{fmt.Errorf("failed to commit transaction: %w", storage.ErrConflict), true},
+		{fmt.Errorf("failed to look up device: %w", fs.ErrPermission), true},
+		{context.DeadlineExceeded, true},
+		{fmt.Errorf("DiscoverySnapshot snap-1: %w", fabricaStorage.ErrNotFound), false},
+		{fmt.Errorf("invalid JSON data: %w", fabricaStorage.ErrInvalidData), false},
+		{fmt.Errorf("failed to marshal Device: %w", &json.UnsupportedValueError{}), false},
+		{fmt.Errorf("failed to decode: %w", syntaxErr), false},
+	}
+	for _, tt := range tests {
+		if got := transient(tt.err); got != tt.want {
+			t.Errorf("transient(%v) = %v, want %v", tt.err, got, tt.want)
+		}
+	}
+}
+
+// TestFailSnapshotGivesUp checks that a snapshot failing with a transient
+// error is retried with growing backoff, and left in Error for good after
+// maxSnapshotAttempts.
+func TestFailSnapshotGivesUp(t *testing.T) {
+	initTestStorage(t)
+	ctx := context.Background()
+	r := NewSnapshotReconciler(nil, storage.NewVersionedClient(), quietLogger{t})
+
+	var snapshot discoverysnapshot.DiscoverySnapshot
+	if err := json.Unmarshal(saveSnapshot(t, "flaky", nil), &snapshot); err != nil {
+		t.Fatal(err)
+	}
+	ioErr := fmt.Errorf("failed to commit transaction: %w", fs.ErrPermission)
+
+	var backoff time.Duration
+	for attempt := 1; attempt <= maxSnapshotAttempts; attempt++ {
+		result, err := r.failSnapshot(ctx, &snapshot, "Failed to apply snapshot", ioErr, true)
+		if snapshot.Status.Attempts != attempt {
+			t.Fatalf("attempt %d: status.attempts = %d", attempt, snapshot.Status.Attempts)
+		}
+		if attempt < maxSnapshotAttempts {
+			if err == nil || !snapshot.Status.Retryable {
+				t.Fatalf("attempt %d: err %v, retryable %v; want a retry", attempt, err, snapshot.Status.Retryable)
+			}
+			if want := max(2*backoff, snapshotRetryBackoff); result.RequeueAfter != want {
+				t.Errorf("attempt %d: backoff %s, want %s", attempt, result.RequeueAfter, want)
+			}
+			// The resync waits for the backoff too
+			now := time.Now()
+			if needsReconcile(&snapshot, now, now) {
+				t.Errorf("attempt %d: resync would retry before the backoff", attempt)
+			}
+			if !needsReconcile(&snapshot, now.Add(result.RequeueAfter), now) {
+				t.Errorf("attempt %d: resync would not retry after the backoff", attempt)
+			}
+			backoff = result.RequeueAfter
+			continue
+		}
+		if err != nil || result.RequeueAfter != 0 || snapshot.Status.Retryable || needsReconcile(&snapshot, time.Now().Add(time.Hour), time.Now()) {
+			t.Errorf("last attempt: err %v, requeue after %s, retryable %v; want to give up", err, result.RequeueAfter, snapshot.Status.Retryable)
+		}
+	}
+
+	// A permanent error is never retried
+	var bad discoverysnapshot.DiscoverySnapshot
+	if err := json.Unmarshal(saveSnapshot(t, "bad", nil), &bad); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := r.failSnapshot(ctx, &bad, "Failed to parse rawData", &json.SyntaxError{}, false); err != nil || bad.Status.Retryable || bad.Status.Attempts != 0 {
+		t.Errorf("permanent error: err %v, retryable %v, attempts %d", err, bad.Status.Retryable, bad.Status.Attempts)
+	}
+}
+
+// TestNeedsReconcileProcessing checks that the resync leaves a snapshot this
+// process is reconciling alone until its lease runs out, and takes over one
+// left Processing by an earlier process at once.
+func TestNeedsReconcileProcessing(t *testing.T) {
+	started := time.Now()
+	at := func(d time.Duration) *time.Time {
+		when := started.Add(d)
+		return &when
+	}
+	tests := []struct {
+		name  string
+		since *time.Time
+		now   time.Time
+		want  bool
+	}{
+		{"in flight", at(time.Minute), started.Add(2 * time.Minute), false},
+		{"lease expired", at(time.Minute), started.Add(time.Minute + snapshotProcessingLease), true},
+		{"earlier process", at(-time.Minute), started, true},
+		{"no start time", nil, started, true},
+	}
+	for _, tt := range tests {
+		var snapshot discoverysnapshot.DiscoverySnapshot
+		snapshot.Status.Phase = "Processing"
+		snapshot.Status.ProcessingSince = tt.since
+		if got := needsReconcile(&snapshot, tt.now, started); got != tt.want {
+			t.Errorf("%s: needsReconcile = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
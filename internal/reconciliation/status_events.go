@@ -0,0 +1,59 @@
+package reconciliation
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/openchami/fabrica/pkg/events"
+	"github.com/openchami/fabrica/pkg/reconcile"
+)
+
+// statusEventSource is the "source" in the metadata of the events announcing
+// a reconciler's status writes
+const statusEventSource = "reconciler"
+
+// publishStatusUpdated announces a status write made by a reconciler, so
+// watches and webhooks see it as a MODIFIED/updated resource
+func publishStatusUpdated(ctx context.Context, logger reconcile.Logger, kind, uid, name string, resource interface{}) {
+	metadata := map[string]interface{}{
+		"updateType": "status",
+		"source":     statusEventSource,
+	}
+	if err := events.PublishResourceUpdated(ctx, kind, uid, name, resource, metadata); err != nil {
+		logger.Warnf("RECONCILER: Failed to publish status update event for %s %s: %v", kind, uid, err)
+	}
+}
+
+// isStatusEvent reports whether an event announces a reconciler's status write
+func isStatusEvent(event events.Event) bool {
+	var change struct {
+		Metadata struct {
+			Source string `json:"source"`
+		} `json:"metadata"`
+	}
+	if json.Unmarshal(event.Data(), &change) != nil {
+		return false
+	}
+	return change.Metadata.Source == statusEventSource
+}
+
+// WithoutStatusEvents returns a view of bus whose subscribers don't receive
+// the events of reconciler status writes. The reconcile controller subscribes
+// through it, so a reconciler's own status write doesn't make the resource be
+// reconciled again.
+func WithoutStatusEvents(bus events.EventBus) events.EventBus {
+	return &statusFilterBus{EventBus: bus}
+}
+
+type statusFilterBus struct {
+	events.EventBus
+}
+
+func (b *statusFilterBus) Subscribe(eventType string, handler events.EventHandler) (events.SubscriptionID, error) {
+	return b.EventBus.Subscribe(eventType, func(ctx context.Context, event events.Event) error {
+		if isStatusEvent(event) {
+			return nil
+		}
+		return handler(ctx, event)
+	})
+}
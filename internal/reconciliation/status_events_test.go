@@ -0,0 +1,101 @@
+package reconciliation
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/openchami/fabrica/pkg/events"
+	fabResource "github.com/openchami/fabrica/pkg/resource"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// useEventBus enables event creation and makes bus the global event bus for the test
+func useEventBus(t *testing.T, bus events.EventBus) {
+	config := events.GetEventConfig()
+	enabled := *config
+	enabled.Enabled = true
+	events.SetEventConfig(&enabled)
+	previous := events.GetGlobalEventBus()
+	events.SetGlobalEventBus(bus)
+	t.Cleanup(func() {
+		events.SetGlobalEventBus(previous)
+		events.SetEventConfig(config)
+	})
+}
+
+// collect subscribes to every event on bus and returns the channel they're sent to
+func collect(t *testing.T, bus events.EventBus) chan events.Event {
+	t.Helper()
+	ch := make(chan events.Event, 100)
+	if _, err := bus.Subscribe("**", func(ctx context.Context, event events.Event) error {
+		ch <- event
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	return ch
+}
+
+// TestStatusWritesPublishEvents checks that a topology status write is
+// published as an updated event, and that the controller's view of the bus
+// (WithoutStatusEvents) doesn't see it.
+func TestStatusWritesPublishEvents(t *testing.T) {
+	initTestStorage(t)
+	ctx := context.Background()
+	bus := events.NewInMemoryEventBus(100, 1)
+	bus.Start()
+	t.Cleanup(func() { bus.Close() })
+	useEventBus(t, bus)
+
+	all := collect(t, bus)
+	controller := collect(t, WithoutStatusEvents(bus))
+
+	uid, err := fabResource.GenerateUIDForResource("Device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := &device.Device{
+		Resource: fabResource.Resource{APIVersion: "v1", Kind: "Device", SchemaVersion: "v1"},
+		Spec:     device.DeviceSpec{DeviceType: "Chassis", SerialNumber: "CH-1"},
+	}
+	dev.Metadata.UID = uid
+	dev.Metadata.Name = "chassis"
+	if err := storage.SaveDevice(ctx, dev); err != nil {
+		t.Fatal(err)
+	}
+
+	raw, err := json.Marshal(dev)
+	if err != nil {
+		t.Fatal(err)
+	}
+	r := NewDeviceTopologyReconciler(bus, storage.NewVersionedClient(), quietLogger{t})
+	if _, err := r.Reconcile(ctx, json.RawMessage(raw)); err != nil {
+		t.Fatal(err)
+	}
+
+	select {
+	case event := <-all:
+		if !isStatusEvent(event) || event.ResourceUID() != uid {
+			t.Fatalf("got event %s for %s, want the status update of %s", event.Type(), event.ResourceUID(), uid)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("status write was not published")
+	}
+
+	// An ordinary update after it is the first event the controller sees
+	if err := events.PublishResourceUpdated(ctx, "Device", uid, "chassis", dev, nil); err != nil {
+		t.Fatal(err)
+	}
+	select {
+	case event := <-controller:
+		if isStatusEvent(event) {
+			t.Fatal("controller received a status event")
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("controller did not receive the update")
+	}
+}
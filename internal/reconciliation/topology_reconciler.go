@@ -0,0 +1,272 @@
+package reconciliation
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+
+	"github.com/openchami/fabrica/pkg/events"
+	"github.com/openchami/fabrica/pkg/reconcile"
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// DeviceTopologyReconciler keeps the topology fields of Device.Status current:
+// ChildrenDeviceIds, Depth and RootDeviceID.
+//
+// It is registered for the "Device" kind, so it runs whenever a Device is
+// created or updated. Deleted devices can't be loaded by the controller, so
+// deletions are picked up from the event bus directly (see Start). Its status
+// writes are announced as updated events, which the controller ignores (see
+// WithoutStatusEvents).
+type DeviceTopologyReconciler struct {
+	reconcile.BaseReconciler
+	client *storage.VersionedClient
+	logger reconcile.Logger
+
+	// mu serializes topology updates; a reparent touches several devices
+	mu sync.Mutex
+	// observedParents remembers the last ParentID seen for each device, so a
+	// reparented (or deleted) device can be removed from its old parent's children
+	observedParents map[string]string
+}
+
+func NewDeviceTopologyReconciler(eb events.EventBus, client *storage.VersionedClient, logger reconcile.Logger) *DeviceTopologyReconciler {
+	return &DeviceTopologyReconciler{
+		BaseReconciler: reconcile.BaseReconciler{
+			EventBus: eb,
+			Logger:   logger,
+		},
+		client:          client,
+		logger:          logger,
+		observedParents: make(map[string]string),
+	}
+}
+
+func (r *DeviceTopologyReconciler) GetResourceKind() string {
+	return "Device"
+}
+
+// Start brings the topology of every stored Device up to date and subscribes
+// to Device deletions. Call it once, before the controller starts.
+func (r *DeviceTopologyReconciler) Start(ctx context.Context) error {
+	if err := r.Resync(ctx); err != nil {
+		return err
+	}
+
+	deletedType := fmt.Sprintf("%s.device.deleted", events.GetEventConfig().EventTypePrefix)
+	if _, err := r.EventBus.Subscribe(deletedType, r.handleDeviceDeleted); err != nil {
+		return fmt.Errorf("failed to subscribe to %s: %w", deletedType, err)
+	}
+	return nil
+}
+
+// Resync recomputes the topology of every Device in storage.
+func (r *DeviceTopologyReconciler) Resync(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	items, err := r.client.List(ctx, "Device")
+	if err != nil {
+		return fmt.Errorf("failed to list devices: %w", err)
+	}
+
+	updated := 0
+	for _, item := range items {
+		dev, ok := item.(*device.Device)
+		if !ok {
+			continue
+		}
+		r.observedParents[dev.GetUID()] = dev.Spec.ParentID
+
+		changed, err := r.syncDevice(ctx, dev.GetUID())
+		if err != nil {
+			r.logger.Errorf("TOPOLOGY: Failed to sync device %s: %v", dev.GetUID(), err)
+			continue
+		}
+		if changed {
+			updated++
+		}
+	}
+	r.logger.Infof("TOPOLOGY: Resynced %d devices (%d updated)", len(items), updated)
+	return nil
+}
+
+// Reconcile updates the topology around a created or updated Device:
+// the device itself, its descendants, its parent and (after a reparent) its old parent.
+func (r *DeviceTopologyReconciler) Reconcile(ctx context.Context, resource interface{}) (reconcile.Result, error) {
+	raw, ok := resource.(json.RawMessage)
+	if !ok {
+		return reconcile.Result{}, fmt.Errorf("received resource is not json.RawMessage, but %T", resource)
+	}
+	var dev device.Device
+	if err := json.Unmarshal(raw, &dev); err != nil {
+		return reconcile.Result{}, fmt.Errorf("failed to unmarshal device: %w", err)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	uid := dev.GetUID()
+	oldParent, seen := r.observedParents[uid]
+	newParent := dev.Spec.ParentID
+	r.observedParents[uid] = newParent
+
+	if err := r.syncSubtree(ctx, uid); err != nil {
+		return reconcile.Result{}, err
+	}
+
+	// The parent's children list changes when the device is new or reparented
+	if newParent != "" && (!seen || oldParent != newParent) {
+		if _, err := r.syncDevice(ctx, newParent); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+	if seen && oldParent != "" && oldParent != newParent {
+		r.logger.Infof("TOPOLOGY: Device %s moved from parent %s to %q", uid, oldParent, newParent)
+		if _, err := r.syncDevice(ctx, oldParent); err != nil {
+			return reconcile.Result{}, err
+		}
+	}
+
+	return reconcile.Result{}, nil
+}
+
+// handleDeviceDeleted drops a deleted device from its parent's children list
+// and re-roots the devices that were below it.
+func (r *DeviceTopologyReconciler) handleDeviceDeleted(ctx context.Context, event events.Event) error {
+	uid := event.ResourceUID()
+	if uid == "" {
+		return nil
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	parent := r.observedParents[uid]
+	delete(r.observedParents, uid)
+
+	if parent != "" {
+		if _, err := r.syncDevice(ctx, parent); err != nil {
+			r.logger.Errorf("TOPOLOGY: Failed to sync parent %s of deleted device %s: %v", parent, uid, err)
+		}
+	}
+
+	// Orphaned children keep their ParentID, but are now roots of their own subtree
+	orphans, err := r.client.ListDeviceChildren(ctx, uid)
+	if err != nil {
+		return fmt.Errorf("failed to list children of deleted device %s: %w", uid, err)
+	}
+	for _, child := range orphans {
+		if err := r.syncSubtree(ctx, child.GetUID()); err != nil {
+			r.logger.Errorf("TOPOLOGY: Failed to sync orphaned device %s: %v", child.GetUID(), err)
+		}
+	}
+	return nil
+}
+
+// syncSubtree syncs a device and, if its depth or root changed, everything below it.
+func (r *DeviceTopologyReconciler) syncSubtree(ctx context.Context, uid string) error {
+	visited := make(map[string]bool)
+	queue := []string{uid}
+	for len(queue) > 0 {
+		current := queue[0]
+		queue = queue[1:]
+		if visited[current] {
+			continue
+		}
+		visited[current] = true
+
+		changed, err := r.syncDevice(ctx, current)
+		if err != nil {
+			return err
+		}
+		// Descendants only need updating when the ancestry above them changed
+		if !changed {
+			continue
+		}
+
+		children, err := r.client.ListDeviceChildren(ctx, current)
+		if err != nil {
+			return fmt.Errorf("failed to list children of %s: %w", current, err)
+		}
+		for _, child := range children {
+			queue = append(queue, child.GetUID())
+		}
+	}
+	return nil
+}
+
+// syncDevice recomputes one device's topology fields and saves them if they changed.
+// Returns whether Depth or RootDeviceID changed (which affects the device's descendants).
+func (r *DeviceTopologyReconciler) syncDevice(ctx context.Context, uid string) (bool, error) {
+	item, err := r.client.Get(ctx, "Device", uid)
+	if err != nil {
+		if errors.Is(err, fabricaStorage.ErrNotFound) {
+			return false, nil // Nothing to update
+		}
+		return false, fmt.Errorf("failed to load device %s: %w", uid, err)
+	}
+	dev := item.(*device.Device)
+
+	depth, root := r.ancestry(ctx, dev)
+
+	children, err := r.client.ListDeviceChildren(ctx, uid)
+	if err != nil {
+		return false, fmt.Errorf("failed to list children of %s: %w", uid, err)
+	}
+	childIDs := make([]string, 0, len(children))
+	for _, child := range children {
+		childIDs = append(childIDs, child.GetUID())
+	}
+	sort.Strings(childIDs)
+
+	ancestryChanged := dev.Status.Depth != depth || dev.Status.RootDeviceID != root
+	if !ancestryChanged && slices.Equal(dev.Status.ChildrenDeviceIds, childIDs) {
+		return false, nil
+	}
+
+	err = updateWithRetry(ctx, r.client, "Device", uid, dev, func(d *device.Device) error {
+		d.Status.ChildrenDeviceIds = childIDs
+		d.Status.Depth = depth
+		d.Status.RootDeviceID = root
+		return nil
+	})
+	if err != nil {
+		return false, fmt.Errorf("failed to update topology of %s: %w", uid, err)
+	}
+	publishStatusUpdated(ctx, r.logger, "Device", uid, dev.GetName(), dev)
+	return ancestryChanged, nil
+}
+
+// ancestry walks up the ParentID chain and returns the device's depth and root UID.
+// A missing parent ends the walk, so orphans become roots; so does a cycle.
+func (r *DeviceTopologyReconciler) ancestry(ctx context.Context, dev *device.Device) (int, string) {
+	depth := 0
+	root := dev.GetUID()
+	visited := map[string]bool{root: true}
+
+	parentID := dev.Spec.ParentID
+	for parentID != "" {
+		if visited[parentID] {
+			r.logger.Warnf("TOPOLOGY: Parent cycle detected at device %s (from %s)", parentID, dev.GetUID())
+			break
+		}
+		item, err := r.client.Get(ctx, "Device", parentID)
+		if err != nil {
+			break // Dangling ParentID
+		}
+		parent := item.(*device.Device)
+		visited[parentID] = true
+		depth++
+		root = parent.GetUID()
+		parentID = parent.Spec.ParentID
+	}
+	return depth, root
+}
@@ -0,0 +1,106 @@
+package reconciliation
+
+import (
+	"context"
+	"encoding/json"
+	"slices"
+	"testing"
+
+	fabResource "github.com/openchami/fabrica/pkg/resource"
+
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// saveTopologyDevice stores a Device below parentID and returns its UID
+func saveTopologyDevice(t *testing.T, name, parentID string) string {
+	t.Helper()
+	uid, err := fabResource.GenerateUIDForResource("Device")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dev := &device.Device{
+		Resource: fabResource.Resource{APIVersion: "v1", Kind: "Device", SchemaVersion: "v1"},
+		Spec:     device.DeviceSpec{DeviceType: "Node", SerialNumber: name, ParentID: parentID},
+	}
+	dev.Metadata.UID = uid
+	dev.Metadata.Name = name
+	if err := storage.SaveDevice(context.Background(), dev); err != nil {
+		t.Fatal(err)
+	}
+	return uid
+}
+
+// checkTopology compares a stored Device's topology fields with the expected ones
+func checkTopology(t *testing.T, name, uid string, depth int, root string, children ...string) {
+	t.Helper()
+	dev, err := storage.LoadDevice(context.Background(), uid)
+	if err != nil {
+		t.Fatal(err)
+	}
+	slices.Sort(children)
+	if children == nil {
+		children = []string{}
+	}
+	got := dev.Status.ChildrenDeviceIds
+	if got == nil {
+		got = []string{}
+	}
+	if dev.Status.Depth != depth || dev.Status.RootDeviceID != root || !slices.Equal(got, children) {
+		t.Errorf("%s: depth %d, root %s, children %v; want depth %d, root %s, children %v",
+			name, dev.Status.Depth, dev.Status.RootDeviceID, got, depth, root, children)
+	}
+}
+
+// TestTopologyResyncAndReparent builds a small tree, resyncs it, then moves a
+// device to another parent and a subtree to the top, checking the children,
+// depth and root of every device after each step.
+func TestTopologyResyncAndReparent(t *testing.T) {
+	initTestStorage(t)
+	ctx := context.Background()
+	r := NewDeviceTopologyReconciler(nil, storage.NewVersionedClient(), quietLogger{t})
+
+	chassis := saveTopologyDevice(t, "chassis", "")
+	node1 := saveTopologyDevice(t, "node-1", chassis)
+	node2 := saveTopologyDevice(t, "node-2", chassis)
+	dimm := saveTopologyDevice(t, "dimm", node1)
+
+	if err := r.Resync(ctx); err != nil {
+		t.Fatal(err)
+	}
+	checkTopology(t, "chassis", chassis, 0, chassis, node1, node2)
+	checkTopology(t, "node-1", node1, 1, chassis, dimm)
+	checkTopology(t, "node-2", node2, 1, chassis)
+	checkTopology(t, "dimm", dimm, 2, chassis)
+
+	reparent := func(uid, parentID string) {
+		t.Helper()
+		dev, err := storage.LoadDevice(ctx, uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		dev.Spec.ParentID = parentID
+		if err := storage.SaveDevice(ctx, dev); err != nil {
+			t.Fatal(err)
+		}
+		raw, err := json.Marshal(dev)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := r.Reconcile(ctx, json.RawMessage(raw)); err != nil {
+			t.Fatalf("Reconcile: %v", err)
+		}
+	}
+
+	// The DIMM moves to node-2: both nodes' children change
+	reparent(dimm, node2)
+	checkTopology(t, "node-1", node1, 1, chassis)
+	checkTopology(t, "node-2", node2, 1, chassis, dimm)
+	checkTopology(t, "dimm", dimm, 2, chassis)
+
+	// node-2 leaves the chassis: it and the DIMM below it get a new root
+	reparent(node2, "")
+	checkTopology(t, "chassis", chassis, 0, chassis, node1)
+	checkTopology(t, "node-2", node2, 0, node2, dimm)
+	checkTopology(t, "dimm", dimm, 1, node2)
+}
@@ -0,0 +1,232 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+// Package selector parses and evaluates Kubernetes-style label and field
+// selectors, e.g. "rack=r12,role in (compute,login)".
+package selector
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// Operator is how a requirement compares a key's value.
+type Operator string
+
+const (
+	Equals       Operator = "="
+	NotEquals    Operator = "!="
+	In           Operator = "in"
+	NotIn        Operator = "notin"
+	Exists       Operator = "exists"
+	DoesNotExist Operator = "!"
+)
+
+// Requirement is one comma-separated term of a selector.
+type Requirement struct {
+	Key      string
+	Operator Operator
+	Values   []string
+}
+
+// Selector is a list of requirements that must all match. An empty selector matches everything.
+type Selector []Requirement
+
+// setRequirement matches "key in (a,b)" and "key notin (a,b)"
+var setRequirement = regexp.MustCompile(`^(\S+)\s+(in|notin)\s*\((.*)\)$`)
+
+// ParseLabels parses a label selector. It supports "key=value", "key==value",
+// "key!=value", "key in (v1,v2)", "key notin (v1,v2)", "key" (the label
+// exists) and "!key" (it doesn't).
+func ParseLabels(s string) (Selector, error) {
+	var sel Selector
+	for _, term := range splitTerms(s) {
+		req, err := parseRequirement(term, true)
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// ParseFields parses a field selector: "field=value", "field==value" and
+// "field!=value" terms.
+func ParseFields(s string) (Selector, error) {
+	var sel Selector
+	for _, term := range splitTerms(s) {
+		req, err := parseRequirement(term, false)
+		if err != nil {
+			return nil, err
+		}
+		sel = append(sel, req)
+	}
+	return sel, nil
+}
+
+// Matches reports whether every requirement holds. get returns a key's value
+// and whether the key is set at all.
+func (s Selector) Matches(get func(key string) (string, bool)) bool {
+	for _, req := range s {
+		if !req.Matches(get) {
+			return false
+		}
+	}
+	return true
+}
+
+// MatchesLabels reports whether a set of labels satisfies the selector.
+func (s Selector) MatchesLabels(labels map[string]string) bool {
+	return s.Matches(func(key string) (string, bool) {
+		value, ok := labels[key]
+		return value, ok
+	})
+}
+
+// Matches reports whether the requirement holds.
+func (r Requirement) Matches(get func(key string) (string, bool)) bool {
+	value, ok := get(r.Key)
+	switch r.Operator {
+	case Equals:
+		return ok && value == r.Values[0]
+	case NotEquals:
+		return !ok || value != r.Values[0]
+	case In:
+		return ok && contains(r.Values, value)
+	case NotIn:
+		return !ok || !contains(r.Values, value)
+	case Exists:
+		return ok
+	case DoesNotExist:
+		return !ok
+	}
+	return false
+}
+
+// String formats the requirement in selector syntax.
+func (r Requirement) String() string {
+	switch r.Operator {
+	case In, NotIn:
+		return fmt.Sprintf("%s %s (%s)", r.Key, r.Operator, strings.Join(r.Values, ","))
+	case Exists:
+		return r.Key
+	case DoesNotExist:
+		return "!" + r.Key
+	}
+	return r.Key + string(r.Operator) + r.Values[0]
+}
+
+// String formats the selector in selector syntax.
+func (s Selector) String() string {
+	terms := make([]string, len(s))
+	for i, req := range s {
+		terms[i] = req.String()
+	}
+	return strings.Join(terms, ",")
+}
+
+// parseRequirement parses one term; set-based and existence terms only in label selectors
+func parseRequirement(term string, labels bool) (Requirement, error) {
+	if labels {
+		if m := setRequirement.FindStringSubmatch(term); m != nil {
+			var values []string
+			for _, v := range strings.Split(m[3], ",") {
+				v = strings.TrimSpace(v)
+				if err := validLabelValue(v, term); err != nil {
+					return Requirement{}, err
+				}
+				values = append(values, v)
+			}
+			return Requirement{Key: m[1], Operator: Operator(m[2]), Values: values}, nil
+		}
+	}
+
+	for _, op := range []string{"!=", "==", "="} {
+		key, value, found := strings.Cut(term, op)
+		if !found {
+			continue
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		if err := validKey(key, term); err != nil {
+			return Requirement{}, err
+		}
+		if labels {
+			if err := validLabelValue(value, term); err != nil {
+				return Requirement{}, err
+			}
+		}
+		operator := Equals
+		if op == "!=" {
+			operator = NotEquals
+		}
+		return Requirement{Key: key, Operator: operator, Values: []string{value}}, nil
+	}
+
+	if labels {
+		operator := Exists
+		key := term
+		if strings.HasPrefix(term, "!") {
+			operator = DoesNotExist
+			key = strings.TrimSpace(term[1:])
+		}
+		if err := validKey(key, term); err != nil {
+			return Requirement{}, err
+		}
+		return Requirement{Key: key, Operator: operator}, nil
+	}
+	return Requirement{}, fmt.Errorf("invalid selector term %q: expected field=value or field!=value", term)
+}
+
+func validKey(key, term string) error {
+	if key == "" || strings.ContainsAny(key, " \t(),!=") {
+		return fmt.Errorf("invalid selector term %q", term)
+	}
+	return nil
+}
+
+// validLabelValue rejects label values that can only come from a malformed term
+func validLabelValue(value, term string) error {
+	if strings.ContainsAny(value, " \t(),!=") {
+		return fmt.Errorf("invalid selector term %q", term)
+	}
+	return nil
+}
+
+// splitTerms splits a selector on the commas that aren't inside parentheses
+func splitTerms(s string) []string {
+	var terms []string
+	depth, start := 0, 0
+	for i, c := range s {
+		switch c {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, s[start:i])
+				start = i + 1
+			}
+		}
+	}
+	terms = append(terms, s[start:])
+
+	result := terms[:0]
+	for _, term := range terms {
+		if term = strings.TrimSpace(term); term != "" {
+			result = append(result, term)
+		}
+	}
+	return result
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
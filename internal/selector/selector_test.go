@@ -0,0 +1,152 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package selector
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseLabels(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     Selector
+	}{
+		{"", nil},
+		{"rack=r12", Selector{{Key: "rack", Operator: Equals, Values: []string{"r12"}}}},
+		{"rack == r12", Selector{{Key: "rack", Operator: Equals, Values: []string{"r12"}}}},
+		{"rack!=r12", Selector{{Key: "rack", Operator: NotEquals, Values: []string{"r12"}}}},
+		{"rack=", Selector{{Key: "rack", Operator: Equals, Values: []string{""}}}},
+		{"role in (compute, login)", Selector{{Key: "role", Operator: In, Values: []string{"compute", "login"}}}},
+		{"role notin (service)", Selector{{Key: "role", Operator: NotIn, Values: []string{"service"}}}},
+		{"gpu", Selector{{Key: "gpu", Operator: Exists}}},
+		{"!gpu", Selector{{Key: "gpu", Operator: DoesNotExist}}},
+		{"rack=r12, role in (compute,login),!gpu,", Selector{
+			{Key: "rack", Operator: Equals, Values: []string{"r12"}},
+			{Key: "role", Operator: In, Values: []string{"compute", "login"}},
+			{Key: "gpu", Operator: DoesNotExist},
+		}},
+		{"example.com/tier=gold", Selector{{Key: "example.com/tier", Operator: Equals, Values: []string{"gold"}}}},
+	}
+	for _, tt := range tests {
+		got, err := ParseLabels(tt.selector)
+		if err != nil {
+			t.Errorf("ParseLabels(%q): %v", tt.selector, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseLabels(%q) = %#v, want %#v", tt.selector, got, tt.want)
+			continue
+		}
+		// The parsed selector formats back to an equivalent one
+		again, err := ParseLabels(got.String())
+		if err != nil || !reflect.DeepEqual(again, got) {
+			t.Errorf("ParseLabels(%q) formats as %q, which parses to %#v (%v)", tt.selector, got.String(), again, err)
+		}
+	}
+}
+
+func TestParseLabelsMalformed(t *testing.T) {
+	for _, selector := range []string{
+		"=r12",
+		"!",
+		"!=r12",
+		"rack=r1 r2",
+		"rack=a=b",
+		"rack in (r1",
+		"rack in r1,r2",
+		"rack in (r1,(r2))",
+		"rack notin (r 1)",
+		"bad key=r1",
+		"rack!",
+		"(rack)",
+	} {
+		if got, err := ParseLabels(selector); err == nil {
+			t.Errorf("ParseLabels(%q) = %#v, want an error", selector, got)
+		}
+	}
+}
+
+func TestParseFields(t *testing.T) {
+	tests := []struct {
+		selector string
+		want     Selector
+	}{
+		{"", nil},
+		{"spec.deviceType=DIMM", Selector{{Key: "spec.deviceType", Operator: Equals, Values: []string{"DIMM"}}}},
+		{"spec.deviceType==DIMM", Selector{{Key: "spec.deviceType", Operator: Equals, Values: []string{"DIMM"}}}},
+		{"status.phase!=Ready", Selector{{Key: "status.phase", Operator: NotEquals, Values: []string{"Ready"}}}},
+		{"spec.parentID=", Selector{{Key: "spec.parentID", Operator: Equals, Values: []string{""}}}},
+		// Field values aren't restricted like label values are
+		{"spec.properties.model=X 1 (rev 2)", Selector{{Key: "spec.properties.model", Operator: Equals, Values: []string{"X 1 (rev 2)"}}}},
+		{"metadata.name=node-1,spec.deviceType!=CPU", Selector{
+			{Key: "metadata.name", Operator: Equals, Values: []string{"node-1"}},
+			{Key: "spec.deviceType", Operator: NotEquals, Values: []string{"CPU"}},
+		}},
+	}
+	for _, tt := range tests {
+		got, err := ParseFields(tt.selector)
+		if err != nil {
+			t.Errorf("ParseFields(%q): %v", tt.selector, err)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("ParseFields(%q) = %#v, want %#v", tt.selector, got, tt.want)
+		}
+	}
+
+	// Set-based and existence terms are label selector syntax only
+	for _, selector := range []string{
+		"spec.deviceType in (DIMM,CPU)",
+		"spec.deviceType notin (DIMM)",
+		"spec.parentID",
+		"!spec.parentID",
+		"=DIMM",
+		"spec deviceType=DIMM",
+	} {
+		if got, err := ParseFields(selector); err == nil {
+			t.Errorf("ParseFields(%q) = %#v, want an error", selector, got)
+		}
+	}
+}
+
+func TestMatches(t *testing.T) {
+	labels := map[string]string{"rack": "r12", "role": "compute", "empty": ""}
+	tests := []struct {
+		selector string
+		want     bool
+	}{
+		{"", true},
+		{"rack=r12", true},
+		{"rack=r13", false},
+		{"missing=", false},
+		{"empty=", true},
+		{"rack!=r13", true},
+		{"rack!=r12", false},
+		{"missing!=r12", true},
+		{"role in (compute,login)", true},
+		{"role in (login)", false},
+		{"missing in (login)", false},
+		{"role notin (login)", true},
+		{"role notin (compute)", false},
+		{"missing notin (compute)", true},
+		{"rack", true},
+		{"empty", true},
+		{"missing", false},
+		{"!missing", true},
+		{"!rack", false},
+		{"rack=r12,role=login", false},
+		{"rack=r12,!missing,role in (compute)", true},
+	}
+	for _, tt := range tests {
+		sel, err := ParseLabels(tt.selector)
+		if err != nil {
+			t.Fatalf("ParseLabels(%q): %v", tt.selector, err)
+		}
+		if got := sel.MatchesLabels(labels); got != tt.want {
+			t.Errorf("%q matches %v = %v, want %v", tt.selector, labels, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,354 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+)
+
+const (
+	// BackupFormat identifies the layout of backup archives
+	BackupFormat = "inventory-api-backup/v1"
+
+	// backupManifest is the first file of a backup archive
+	backupManifest = "manifest.json"
+)
+
+// StoredResource is a resource exactly as stored.
+type StoredResource struct {
+	Kind string
+	UID  string
+	Data json.RawMessage
+}
+
+// Snapshotter is implemented by backends that can read every resource of
+// several kinds as of a single point in time, with no write in between.
+type Snapshotter interface {
+	// Snapshot returns the readable resources in kind and UID order, and a
+	// description of each resource that couldn't be read
+	Snapshot(ctx context.Context, kinds []string) ([]StoredResource, []string, error)
+}
+
+// BackupManifest describes the contents of a backup archive.
+type BackupManifest struct {
+	Format    string    `json:"format"`
+	CreatedAt time.Time `json:"createdAt"`
+
+	// ResourceVersion is the highest resourceVersion in the backup
+	ResourceVersion uint64 `json:"resourceVersion"`
+
+	// Counts is the number of resources of each kind
+	Counts map[string]int `json:"counts"`
+
+	// Files maps each resource file in the archive to the SHA-256 of its content
+	Files map[string]string `json:"files"`
+
+	// Skipped describes resources that couldn't be read and aren't in the backup
+	Skipped []string `json:"skipped,omitempty"`
+
+	// SecretsRedacted is set when Subscription signing secrets were left out
+	SecretsRedacted bool `json:"secretsRedacted,omitempty"`
+}
+
+// BackupOptions controls what WriteBackup puts in an archive.
+type BackupOptions struct {
+	// IncludeSecrets keeps each Subscription's signing secret (spec.secret);
+	// by default it is removed, and restored subscriptions deliver unsigned
+	// until it is set again
+	IncludeSecrets bool
+}
+
+// WriteBackup writes a tar.gz archive of every resource in backend to w: a
+// manifest followed by one <kind>s/<uid>.json file per resource, laid out
+// as in a file backend's data directory.
+//
+// The resources are read as of one point in time when backend (or a
+// backend it wraps) is a Snapshotter, so a backup can be taken while the
+// server is writing.
+func WriteBackup(ctx context.Context, backend fabricaStorage.StorageBackend, w io.Writer, opts BackupOptions) (*BackupManifest, error) {
+	var resources []StoredResource
+	var skipped []string
+	var err error
+	if s, ok := findSnapshotter(backend); ok {
+		resources, skipped, err = s.Snapshot(ctx, ResourceKinds)
+	} else {
+		resources, skipped, err = snapshotKinds(ctx, backend, ResourceKinds)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if !opts.IncludeSecrets {
+		for i, r := range resources {
+			if r.Kind != "Subscription" {
+				continue
+			}
+			if resources[i].Data, err = redactSecret(r.Data); err != nil {
+				return nil, fmt.Errorf("failed to redact Subscription %s: %w", r.UID, err)
+			}
+		}
+	}
+
+	manifest := &BackupManifest{
+		Format:          BackupFormat,
+		CreatedAt:       time.Now().UTC().Truncate(time.Second),
+		Counts:          make(map[string]int, len(ResourceKinds)),
+		Files:           make(map[string]string, len(resources)),
+		Skipped:         skipped,
+		SecretsRedacted: !opts.IncludeSecrets,
+	}
+	for _, kind := range ResourceKinds {
+		manifest.Counts[kind] = 0
+	}
+	for _, r := range resources {
+		manifest.Counts[r.Kind]++
+		manifest.Files[backupPath(r.Kind, r.UID)] = fileChecksum(r.Data)
+		if v, err := strconv.ParseUint(ResourceVersion(r.Data), 10, 64); err == nil && v > manifest.ResourceVersion {
+			manifest.ResourceVersion = v
+		}
+	}
+	manifestData, err := json.MarshalIndent(manifest, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode manifest: %w", err)
+	}
+
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	writeFile := func(name string, data []byte) error {
+		header := &tar.Header{
+			Name:     name,
+			Mode:     0644,
+			Size:     int64(len(data)),
+			ModTime:  manifest.CreatedAt,
+			Typeflag: tar.TypeReg,
+		}
+		if err := tw.WriteHeader(header); err != nil {
+			return err
+		}
+		_, err := tw.Write(data)
+		return err
+	}
+	if err := writeFile(backupManifest, manifestData); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	for _, r := range resources {
+		if err := writeFile(backupPath(r.Kind, r.UID), r.Data); err != nil {
+			return nil, fmt.Errorf("failed to write backup: %w", err)
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return nil, fmt.Errorf("failed to write backup: %w", err)
+	}
+	return manifest, nil
+}
+
+// redactSecret removes spec.secret from a stored Subscription, leaving the
+// rest of it as it was
+func redactSecret(data json.RawMessage) (json.RawMessage, error) {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return nil, err
+	}
+	var spec map[string]json.RawMessage
+	if err := json.Unmarshal(fields["spec"], &spec); err != nil || spec == nil {
+		return data, nil
+	}
+	if _, ok := spec["secret"]; !ok {
+		return data, nil
+	}
+	delete(spec, "secret")
+	specData, err := json.Marshal(spec)
+	if err != nil {
+		return nil, err
+	}
+	fields["spec"] = specData
+	return json.Marshal(fields)
+}
+
+// ReadBackup reads a backup archive written by WriteBackup and checks every
+// file against the manifest.
+func ReadBackup(r io.Reader) (*BackupManifest, []StoredResource, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, nil, fmt.Errorf("not a backup archive: %w", err)
+	}
+	defer gz.Close()
+	tr := tar.NewReader(gz)
+
+	kinds := make(map[string]string, len(ResourceKinds))
+	for _, kind := range ResourceKinds {
+		kinds[kindDirName(kind)] = kind
+	}
+
+	var manifest *BackupManifest
+	var resources []StoredResource
+	seen := make(map[string]bool)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read backup archive: %w", err)
+		}
+		if header.Typeflag == tar.TypeDir {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read %s from backup archive: %w", header.Name, err)
+		}
+
+		if manifest == nil {
+			if header.Name != backupManifest {
+				return nil, nil, fmt.Errorf("backup archive doesn't start with %s", backupManifest)
+			}
+			manifest = &BackupManifest{}
+			if err := json.Unmarshal(data, manifest); err != nil {
+				return nil, nil, fmt.Errorf("invalid backup manifest: %w", err)
+			}
+			if manifest.Format != BackupFormat {
+				return nil, nil, fmt.Errorf("unsupported backup format %q (expected %s)", manifest.Format, BackupFormat)
+			}
+			continue
+		}
+
+		sum, ok := manifest.Files[header.Name]
+		if !ok {
+			return nil, nil, fmt.Errorf("%s in backup archive isn't in the manifest", header.Name)
+		}
+		if fileChecksum(data) != sum {
+			return nil, nil, fmt.Errorf("%s in backup archive doesn't match its checksum", header.Name)
+		}
+		dir, file := path.Split(header.Name)
+		kind, ok := kinds[strings.TrimSuffix(dir, "/")]
+		uid := strings.TrimSuffix(file, ".json")
+		if !ok || uid == "" || uid == file {
+			return nil, nil, fmt.Errorf("unexpected file %s in backup archive", header.Name)
+		}
+		seen[header.Name] = true
+		resources = append(resources, StoredResource{Kind: kind, UID: uid, Data: data})
+	}
+
+	if manifest == nil {
+		return nil, nil, fmt.Errorf("backup archive has no %s", backupManifest)
+	}
+	for name := range manifest.Files {
+		if !seen[name] {
+			return nil, nil, fmt.Errorf("%s is missing from the backup archive", name)
+		}
+	}
+	return manifest, resources, nil
+}
+
+// Restore loads a backup archive into backend, which must hold no
+// resources. The archive is read and checked in full before anything is
+// written; resources are then written as stored, keeping their UIDs and
+// resourceVersions.
+func Restore(ctx context.Context, backend fabricaStorage.StorageBackend, r io.Reader) (*BackupManifest, error) {
+	manifest, resources, err := ReadBackup(r)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, kind := range ResourceKinds {
+		uids, err := backend.List(ctx, kind)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s resources: %w", kind, err)
+		}
+		if len(uids) > 0 {
+			return nil, fmt.Errorf("the destination already holds %d %s resources; restore needs an empty one", len(uids), kind)
+		}
+	}
+
+	const batchSize = 500
+	for start := 0; start < len(resources); start += batchSize {
+		end := min(start+batchSize, len(resources))
+		ops := make([]BatchOp, 0, end-start)
+		for _, r := range resources[start:end] {
+			ops = append(ops, BatchOp{Kind: r.Kind, UID: r.UID, Data: r.Data})
+		}
+		if err := applyBatch(ctx, backend, ops); err != nil {
+			return nil, fmt.Errorf("failed to restore resources (%d of %d written): %w", start, len(resources), err)
+		}
+	}
+	return manifest, nil
+}
+
+// findSnapshotter returns the first backend in a chain of wrappers that is a Snapshotter
+func findSnapshotter(backend fabricaStorage.StorageBackend) (Snapshotter, bool) {
+	for backend != nil {
+		if s, ok := backend.(Snapshotter); ok {
+			return s, true
+		}
+		w, ok := backend.(unwrapper)
+		if !ok {
+			break
+		}
+		backend = w.Unwrap()
+	}
+	return nil, false
+}
+
+// snapshotKinds reads every resource of kinds one at a time, skipping those
+// that can't be read. It's only a point-in-time view if nothing writes to
+// backend meanwhile.
+func snapshotKinds(ctx context.Context, backend fabricaStorage.StorageBackend, kinds []string) ([]StoredResource, []string, error) {
+	var resources []StoredResource
+	var skipped []string
+	for _, kind := range kinds {
+		uids, err := backend.List(ctx, kind)
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to list %s resources: %w", kind, err)
+		}
+		sort.Strings(uids)
+		for _, uid := range uids {
+			if uid == "" {
+				skipped = append(skipped, fmt.Sprintf("%s with an empty UID", kind))
+				continue
+			}
+			data, err := backend.Load(ctx, kind, uid)
+			switch {
+			case errors.Is(err, fabricaStorage.ErrNotFound):
+				continue
+			case errors.Is(err, fabricaStorage.ErrInvalidData):
+				skipped = append(skipped, fmt.Sprintf("%s %s: %v", kind, uid, err))
+				continue
+			case err != nil:
+				return nil, nil, fmt.Errorf("failed to load %s %s: %w", kind, uid, err)
+			}
+			resources = append(resources, StoredResource{Kind: kind, UID: uid, Data: data})
+		}
+	}
+	return resources, skipped, nil
+}
+
+// backupPath is the archive path of a resource
+func backupPath(kind, uid string) string {
+	return kindDirName(kind) + "/" + uid + ".json"
+}
+
+// fileChecksum returns the SHA-256 of data as stored
+func fileChecksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,49 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+// TestBackupRedactsSecrets checks that Subscription secrets are only in a
+// backup when asked for, and that the rest of the subscription is kept.
+func TestBackupRedactsSecrets(t *testing.T) {
+	ctx := context.Background()
+	backend := newMemBackend()
+	sub := `{"kind":"Subscription","metadata":{"uid":"sub-1"},"spec":{"url":"https://hooks.example.com","secret":"s3cret"}}`
+	if err := backend.Save(ctx, "Subscription", "sub-1", json.RawMessage(sub)); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, include := range []bool{false, true} {
+		var archive bytes.Buffer
+		manifest, err := WriteBackup(ctx, backend, &archive, BackupOptions{IncludeSecrets: include})
+		if err != nil {
+			t.Fatal(err)
+		}
+		if manifest.SecretsRedacted == include {
+			t.Errorf("includeSecrets=%v: SecretsRedacted = %v", include, manifest.SecretsRedacted)
+		}
+		_, resources, err := ReadBackup(&archive)
+		if err != nil {
+			t.Fatalf("includeSecrets=%v: %v", include, err)
+		}
+		if len(resources) != 1 {
+			t.Fatalf("includeSecrets=%v: %d resources in the backup, want 1", include, len(resources))
+		}
+		data := string(resources[0].Data)
+		if got := strings.Contains(data, "s3cret"); got != include {
+			t.Errorf("includeSecrets=%v: backup has the secret = %v: %s", include, got, data)
+		}
+		if !strings.Contains(data, "https://hooks.example.com") {
+			t.Errorf("includeSecrets=%v: url missing from %s", include, data)
+		}
+	}
+}
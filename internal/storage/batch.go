@@ -0,0 +1,198 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+)
+
+// BatchOp is one write of a batch: a Save of Data, or a Delete when Data is nil.
+type BatchOp struct {
+	Kind string          `json:"kind"`
+	UID  string          `json:"uid"`
+	Data json.RawMessage `json:"data,omitempty"`
+}
+
+// Batcher is implemented by backends that can apply several writes
+// atomically: after ApplyBatch returns, and after a crash during it, either
+// every op has been applied or none has.
+type Batcher interface {
+	ApplyBatch(ctx context.Context, ops []BatchOp) error
+}
+
+// BatchWrite is one compare-and-swap write of a batch (see CompareAndSwap).
+type BatchWrite struct {
+	Kind string
+	UID  string
+
+	// Expected is the resourceVersion the write is conditional on: "" creates
+	// the resource, or for a delete deletes it unconditionally
+	Expected string
+
+	// Build returns the resource JSON carrying the new resourceVersion; nil deletes the resource
+	Build func(version string) (json.RawMessage, error)
+}
+
+// batchWriter is implemented by backends that support compare-and-swap batches.
+type batchWriter interface {
+	CompareAndSwapBatch(ctx context.Context, writes []BatchWrite) error
+}
+
+// ErrNoBatches is returned for a transaction on a backend that can't apply
+// it atomically: one that isn't versioned, or doesn't wrap a Batcher.
+var ErrNoBatches = errors.New("storage backend does not support atomic compare-and-swap batches")
+
+// CompareAndSwapBatch applies writes atomically if every resource is still at
+// its expected resourceVersion, giving each write its own new version.
+//
+// Returns:
+//   - error: ErrConflict if any stored version differs from its expected one (nothing is written),
+//     ErrNoBatches if the wrapped backend isn't a Batcher
+func (b *VersionedBackend) CompareAndSwapBatch(ctx context.Context, writes []BatchWrite) error {
+	batcher, ok := b.StorageBackend.(Batcher)
+	if !ok {
+		return ErrNoBatches
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, w := range writes {
+		if w.Expected == "" && w.Build == nil {
+			continue // unconditional delete
+		}
+		if err := b.checkVersion(ctx, w.Kind, w.UID, w.Expected); err != nil {
+			return err
+		}
+	}
+
+	ops := make([]BatchOp, 0, len(writes))
+	version := b.current
+	for _, w := range writes {
+		op := BatchOp{Kind: w.Kind, UID: w.UID}
+		if w.Build != nil {
+			version++
+			data, err := w.Build(strconv.FormatUint(version, 10))
+			if err != nil {
+				return err
+			}
+			op.Data = data
+		}
+		ops = append(ops, op)
+	}
+
+	if err := batcher.ApplyBatch(ctx, ops); err != nil {
+		return err
+	}
+	b.current = version
+	return nil
+}
+
+// applyBatch applies ops with the backend's Batcher, or one by one if it has
+// none (for copies such as restores and migrations, which can be redone)
+func applyBatch(ctx context.Context, backend fabricaStorage.StorageBackend, ops []BatchOp) error {
+	if batcher, ok := backend.(Batcher); ok {
+		return batcher.ApplyBatch(ctx, ops)
+	}
+	for _, op := range ops {
+		var err error
+		if op.Data == nil {
+			err = backend.Delete(ctx, op.Kind, op.UID)
+			if errors.Is(err, fabricaStorage.ErrNotFound) {
+				err = nil
+			}
+		} else {
+			err = backend.Save(ctx, op.Kind, op.UID, op.Data)
+		}
+		if err != nil {
+			return fmt.Errorf("failed to write %s %s: %w", op.Kind, op.UID, err)
+		}
+	}
+	return nil
+}
+
+// CompareAndSwapBatch forwards a batch to the wrapped backend and updates the
+// device index when it succeeds. The wrapped backend must be versioned
+// (ErrNoBatches otherwise).
+func (b *IndexedBackend) CompareAndSwapBatch(ctx context.Context, writes []BatchWrite) error {
+	writer, ok := b.StorageBackend.(batchWriter)
+	if !ok {
+		return ErrNoBatches
+	}
+
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	// Capture what is written, to index it
+	written := make([]json.RawMessage, len(writes))
+	wrapped := make([]BatchWrite, len(writes))
+	for i, w := range writes {
+		wrapped[i] = w
+		if w.Build != nil {
+			build := w.Build
+			wrapped[i].Build = func(version string) (json.RawMessage, error) {
+				data, err := build(version)
+				written[i] = data
+				return data, err
+			}
+		}
+	}
+
+	if err := writer.CompareAndSwapBatch(ctx, wrapped); err != nil {
+		return err
+	}
+
+	for i, w := range writes {
+		if w.Kind != "Device" {
+			continue
+		}
+		if w.Build == nil {
+			b.index.remove(w.UID)
+		} else {
+			b.indexDevice(w.UID, written[i])
+		}
+	}
+	return nil
+}
+
+// ApplyBatch implements Batcher with an SQLite transaction.
+func (b *SQLiteBackend) ApplyBatch(ctx context.Context, ops []BatchOp) error {
+	// Create any missing tables first: that can't happen inside the transaction
+	for _, op := range ops {
+		if _, err := b.table(ctx, op.Kind); err != nil {
+			return err
+		}
+	}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	for _, op := range ops {
+		if op.Data == nil {
+			err = b.delete(ctx, tx, op.Kind, op.UID)
+			if errors.Is(err, fabricaStorage.ErrNotFound) {
+				err = nil
+			}
+		} else {
+			err = b.save(ctx, tx, op.Kind, op.UID, op.Data)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
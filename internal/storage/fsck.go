@@ -0,0 +1,429 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"sort"
+	"strings"
+
+	"github.com/openchami/fabrica/pkg/resource"
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// Checks reported by Fsck
+const (
+	FsckUnparseable     = "unparseable"
+	FsckEmptyUID        = "empty-uid"
+	FsckUIDMismatch     = "uid-mismatch"
+	FsckKindMismatch    = "kind-mismatch"
+	FsckPrefixMismatch  = "prefix-mismatch"
+	FsckDanglingParent  = "dangling-parent"
+	FsckParentCycle     = "parent-cycle"
+	FsckDuplicateSerial = "duplicate-serial"
+	FsckStaleTopology   = "stale-topology"
+)
+
+// FsckIssue is one problem found by Fsck.
+type FsckIssue struct {
+	Check   string `json:"check"`
+	Kind    string `json:"kind"`
+	UID     string `json:"uid"`
+	Message string `json:"message"`
+
+	// Repair describes what --fix does about the issue ("" if it needs a person)
+	Repair string `json:"repair,omitempty"`
+	// Fixed is set once the repair has been applied
+	Fixed bool `json:"fixed,omitempty"`
+}
+
+// Quarantiner is implemented by backends that can move a resource out of
+// the way without deleting it, even one that can't be parsed.
+type Quarantiner interface {
+	// Quarantine moves a resource aside and returns where it went
+	Quarantine(ctx context.Context, kind, uid string) (string, error)
+}
+
+// fsckResource is a stored resource that passed the per-resource checks
+type fsckResource struct {
+	kind    string
+	uid     string
+	data    json.RawMessage
+	patched bool
+	issues  []int // indexes of the issues its patch repairs
+
+	device *device.Device // decoded, for Devices
+}
+
+// Fsck checks every stored resource of ResourceKinds and returns the
+// problems found. backend must be bare (as returned by OpenBackend).
+//
+// Resources that can't be trusted (unparseable, without a usable UID, or of
+// the wrong kind) are quarantined by fix; metadata that disagrees with where
+// a resource is stored is corrected, as are dangling parent links, parent
+// cycles and stale topology fields. Duplicate serial numbers are only
+// reported, since which device is right needs a person to decide.
+//
+// With fix, quarantined resources are moved aside first, then every
+// correction is written as one atomic batch with new resourceVersions. Fsck
+// should be run while the server is stopped.
+func Fsck(ctx context.Context, backend fabricaStorage.StorageBackend, fix bool) ([]FsckIssue, error) {
+	var quarantiner Quarantiner
+	for b := backend; b != nil; {
+		if q, ok := b.(Quarantiner); ok {
+			quarantiner = q
+			break
+		}
+		w, ok := b.(unwrapper)
+		if !ok {
+			break
+		}
+		b = w.Unwrap()
+	}
+	if fix && quarantiner == nil {
+		return nil, fmt.Errorf("storage backend can't quarantine resources")
+	}
+
+	var issues []FsckIssue
+	quarantine := func(issue FsckIssue) error {
+		issue.Repair = "quarantine"
+		if fix {
+			where, err := quarantiner.Quarantine(ctx, issue.Kind, issue.UID)
+			if err != nil {
+				return fmt.Errorf("failed to quarantine %s %q: %w", issue.Kind, issue.UID, err)
+			}
+			issue.Repair = "quarantine to " + where
+			issue.Fixed = true
+		}
+		issues = append(issues, issue)
+		return nil
+	}
+
+	prefixes := resource.GetRegisteredPrefixes()
+	var resources []*fsckResource
+	for _, kind := range ResourceKinds {
+		uids, err := backend.List(ctx, kind)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list %s resources: %w", kind, err)
+		}
+		sort.Strings(uids)
+
+		for _, uid := range uids {
+			if uid == "" {
+				if err := quarantine(FsckIssue{Check: FsckEmptyUID, Kind: kind, Message: "stored without a UID"}); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			data, err := backend.Load(ctx, kind, uid)
+			if errors.Is(err, fabricaStorage.ErrNotFound) {
+				continue
+			}
+			var env storedEnvelope
+			var dev *device.Device
+			if err == nil {
+				err = json.Unmarshal(data, &env)
+			}
+			if err == nil && kind == "Device" {
+				dev = &device.Device{}
+				err = json.Unmarshal(data, dev)
+			}
+			if err != nil {
+				if !errors.Is(err, fabricaStorage.ErrInvalidData) && !isJSONError(err) {
+					return nil, fmt.Errorf("failed to load %s %s: %w", kind, uid, err)
+				}
+				if err := quarantine(FsckIssue{Check: FsckUnparseable, Kind: kind, UID: uid, Message: err.Error()}); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			if prefix := prefixes[kind]; prefix != "" && !strings.HasPrefix(uid, prefix+"-") {
+				issue := FsckIssue{Check: FsckPrefixMismatch, Kind: kind, UID: uid,
+					Message: fmt.Sprintf("UID doesn't have the %s- prefix of a %s", prefix, kind)}
+				if err := quarantine(issue); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			if env.Kind != "" && env.Kind != kind {
+				issue := FsckIssue{Check: FsckKindMismatch, Kind: kind, UID: uid,
+					Message: fmt.Sprintf("stored as a %s but its kind is %q", kind, env.Kind)}
+				if err := quarantine(issue); err != nil {
+					return nil, err
+				}
+				continue
+			}
+
+			r := &fsckResource{kind: kind, uid: uid, data: data, device: dev}
+			if env.Kind == "" {
+				env.Kind = kind
+				r.patched = true
+				r.issues = append(r.issues, len(issues))
+				issues = append(issues, FsckIssue{Check: FsckKindMismatch, Kind: kind, UID: uid,
+					Message: "kind is empty", Repair: "set kind to " + kind})
+			}
+			switch env.Metadata.UID {
+			case uid:
+			case "":
+				env.Metadata.UID = uid
+				r.patched = true
+				r.issues = append(r.issues, len(issues))
+				issues = append(issues, FsckIssue{Check: FsckEmptyUID, Kind: kind, UID: uid,
+					Message: "metadata.uid is empty", Repair: "set metadata.uid to " + uid})
+			default:
+				r.issues = append(r.issues, len(issues))
+				issues = append(issues, FsckIssue{Check: FsckUIDMismatch, Kind: kind, UID: uid,
+					Message: fmt.Sprintf("metadata.uid is %s", env.Metadata.UID), Repair: "set metadata.uid to " + uid})
+				env.Metadata.UID = uid
+				r.patched = true
+			}
+			if r.patched {
+				if r.data, err = json.Marshal(&env); err != nil {
+					return nil, fmt.Errorf("failed to encode %s %s: %w", kind, uid, err)
+				}
+			}
+			resources = append(resources, r)
+		}
+	}
+
+	if err := fsckDevices(resources, &issues); err != nil {
+		return nil, err
+	}
+
+	if !fix {
+		return issues, nil
+	}
+
+	var writes []BatchWrite
+	var repaired []int
+	for _, r := range resources {
+		if !r.patched {
+			continue
+		}
+		data := r.data
+		writes = append(writes, BatchWrite{
+			Kind:     r.kind,
+			UID:      r.uid,
+			Expected: ResourceVersion(data),
+			Build: func(version string) (json.RawMessage, error) {
+				var env storedEnvelope
+				if err := json.Unmarshal(data, &env); err != nil {
+					return nil, err
+				}
+				if env.Metadata.Annotations == nil {
+					env.Metadata.Annotations = make(map[string]string)
+				}
+				env.Metadata.Annotations[ResourceVersionAnnotation] = version
+				return json.Marshal(&env)
+			},
+		})
+		repaired = append(repaired, r.issues...)
+	}
+	if len(writes) > 0 {
+		versioned, err := NewVersionedBackend(ctx, backend)
+		if err != nil {
+			return issues, err
+		}
+		if err := versioned.CompareAndSwapBatch(ctx, writes); err != nil {
+			return issues, fmt.Errorf("failed to write repairs: %w", err)
+		}
+		for _, i := range repaired {
+			issues[i].Fixed = true
+		}
+	}
+	return issues, nil
+}
+
+// fsckDevices checks the parent links, serial numbers and topology fields of
+// the devices among resources, patching the devices that need repair
+func fsckDevices(resources []*fsckResource, issues *[]FsckIssue) error {
+	byUID := make(map[string]*fsckResource)
+	var uids []string
+	for _, r := range resources {
+		if r.device != nil {
+			byUID[r.uid] = r
+			uids = append(uids, r.uid)
+		}
+	}
+	sort.Strings(uids)
+
+	report := func(r *fsckResource, issue FsckIssue) {
+		r.patched = true
+		r.issues = append(r.issues, len(*issues))
+		*issues = append(*issues, issue)
+	}
+	clearParent := func(r *fsckResource) error {
+		r.device.Spec.ParentID = ""
+		data, err := setField(r.data, "spec", "parentID", nil)
+		if err != nil {
+			return fmt.Errorf("failed to patch Device %s: %w", r.uid, err)
+		}
+		r.data = data
+		return nil
+	}
+
+	// Parent links to devices that don't exist
+	for _, uid := range uids {
+		r := byUID[uid]
+		parentID := r.device.Spec.ParentID
+		if parentID == "" || byUID[parentID] != nil {
+			continue
+		}
+		report(r, FsckIssue{Check: FsckDanglingParent, Kind: "Device", UID: uid,
+			Message: fmt.Sprintf("parent %s doesn't exist", parentID),
+			Repair:  "clear parentID (parentSerialNumber is kept)"})
+		if err := clearParent(r); err != nil {
+			return err
+		}
+	}
+
+	// Parent cycles, each broken at its smallest UID
+	const (
+		unvisited = iota
+		onPath
+		done
+	)
+	state := make(map[string]int, len(uids))
+	for _, uid := range uids {
+		var path []string
+		current := uid
+		for current != "" && state[current] == unvisited {
+			state[current] = onPath
+			path = append(path, current)
+			current = byUID[current].device.Spec.ParentID
+		}
+		if current != "" && state[current] == onPath {
+			cycle := path[slices.Index(path, current):]
+			smallest := slices.Min(cycle)
+			r := byUID[smallest]
+			report(r, FsckIssue{Check: FsckParentCycle, Kind: "Device", UID: smallest,
+				Message: "parent cycle " + strings.Join(append(cycle, current), " -> "),
+				Repair:  "clear parentID"})
+			if err := clearParent(r); err != nil {
+				return err
+			}
+		}
+		for _, p := range path {
+			state[p] = done
+		}
+	}
+
+	// Serial numbers shared by several devices
+	bySerial := make(map[string][]string)
+	for _, uid := range uids {
+		if serial := byUID[uid].device.Spec.SerialNumber; serial != "" {
+			bySerial[serial] = append(bySerial[serial], uid)
+		}
+	}
+	serials := make([]string, 0, len(bySerial))
+	for serial, shared := range bySerial {
+		if len(shared) > 1 {
+			serials = append(serials, serial)
+		}
+	}
+	sort.Strings(serials)
+	for _, serial := range serials {
+		shared := bySerial[serial]
+		*issues = append(*issues, FsckIssue{Check: FsckDuplicateSerial, Kind: "Device", UID: shared[0],
+			Message: fmt.Sprintf("serial number %s is shared by %s", serial, strings.Join(shared, ", "))})
+	}
+
+	// Topology fields, computed as the topology reconciler does
+	children := make(map[string][]string)
+	for _, uid := range uids {
+		if parentID := byUID[uid].device.Spec.ParentID; parentID != "" {
+			children[parentID] = append(children[parentID], uid)
+		}
+	}
+	for _, uid := range uids {
+		r := byUID[uid]
+		depth, root := 0, uid
+		visited := map[string]bool{uid: true}
+		for parentID := r.device.Spec.ParentID; parentID != "" && !visited[parentID]; parentID = byUID[parentID].device.Spec.ParentID {
+			visited[parentID] = true
+			depth++
+			root = parentID
+		}
+
+		status := &r.device.Status
+		var stale []string
+		if !slices.Equal(status.ChildrenDeviceIds, children[uid]) {
+			stale = append(stale, fmt.Sprintf("childrenDeviceIds is %v, should be %v", status.ChildrenDeviceIds, children[uid]))
+		}
+		if status.Depth != depth {
+			stale = append(stale, fmt.Sprintf("depth is %d, should be %d", status.Depth, depth))
+		}
+		if status.RootDeviceID != root {
+			stale = append(stale, fmt.Sprintf("rootDeviceId is %q, should be %q", status.RootDeviceID, root))
+		}
+		if len(stale) == 0 {
+			continue
+		}
+		report(r, FsckIssue{Check: FsckStaleTopology, Kind: "Device", UID: uid,
+			Message: strings.Join(stale, "; "), Repair: "recompute topology fields"})
+
+		var childIDs any
+		if len(children[uid]) > 0 {
+			childIDs = children[uid]
+		}
+		data := r.data
+		var err error
+		for _, f := range []struct {
+			name  string
+			value any
+		}{{"childrenDeviceIds", childIDs}, {"depth", depth}, {"rootDeviceId", root}} {
+			if data, err = setField(data, "status", f.name, f.value); err != nil {
+				return fmt.Errorf("failed to patch Device %s: %w", uid, err)
+			}
+		}
+		r.data = data
+	}
+	return nil
+}
+
+// setField sets a field of one of a resource's top-level objects (spec or
+// status), or removes it when value is nil, keeping every other field as stored
+func setField(data json.RawMessage, object, field string, value any) (json.RawMessage, error) {
+	var top map[string]json.RawMessage
+	if err := json.Unmarshal(data, &top); err != nil {
+		return nil, err
+	}
+	obj := make(map[string]json.RawMessage)
+	if raw, ok := top[object]; ok && string(raw) != "null" {
+		if err := json.Unmarshal(raw, &obj); err != nil {
+			return nil, err
+		}
+	}
+	if value == nil {
+		delete(obj, field)
+	} else {
+		raw, err := json.Marshal(value)
+		if err != nil {
+			return nil, err
+		}
+		obj[field] = raw
+	}
+	raw, err := json.Marshal(obj)
+	if err != nil {
+		return nil, err
+	}
+	top[object] = raw
+	return json.Marshal(top)
+}
+
+// isJSONError reports whether err comes from decoding malformed or mistyped JSON
+func isJSONError(err error) bool {
+	var syntaxErr *json.SyntaxError
+	var typeErr *json.UnmarshalTypeError
+	return errors.As(err, &syntaxErr) || errors.As(err, &typeErr)
+}
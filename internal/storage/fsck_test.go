@@ -0,0 +1,143 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// issueKeys returns "check kind uid" for each issue, sorted
+func issueKeys(issues []FsckIssue) []string {
+	keys := make([]string, len(issues))
+	for i, issue := range issues {
+		keys[i] = fmt.Sprintf("%s %s %s", issue.Check, issue.Kind, issue.UID)
+	}
+	slices.Sort(keys)
+	return keys
+}
+
+// TestFsckFixture runs fsck over testdata/fsck, a data directory holding a
+// snapshot file with an empty UID, debug-* files without a kind and devices
+// with each kind of problem, first reporting and then repairing.
+func TestFsckFixture(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	if err := os.CopyFS(dir, os.DirFS("testdata/fsck")); err != nil {
+		t.Fatal(err)
+	}
+	backend, err := OpenBackend(ctx, "file:"+dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer backend.Close()
+
+	want := []string{
+		"dangling-parent Device dev-00000004",
+		"duplicate-serial Device dev-00000002",
+		"empty-uid Device dev-00000008",
+		"empty-uid DiscoverySnapshot ",
+		"kind-mismatch DiscoverySnapshot dis-a1b2c3d4",
+		"parent-cycle Device dev-00000005",
+		"prefix-mismatch Device debug-node",
+		"prefix-mismatch DiscoverySnapshot debug-20250114",
+		"stale-topology Device dev-00000001",
+		"stale-topology Device dev-00000004",
+		"stale-topology Device dev-00000005",
+		"stale-topology Device dev-00000006",
+		"uid-mismatch Device dev-00000009",
+		"unparseable DiscoverySnapshot debug-truncated",
+	}
+
+	// Without fix nothing is changed, so a second report is the same
+	for range 2 {
+		issues, err := Fsck(ctx, backend, false)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := issueKeys(issues); !slices.Equal(got, want) {
+			t.Fatalf("issues:\n  %q\nwant\n  %q", got, want)
+		}
+	}
+
+	issues, err := Fsck(ctx, backend, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, issue := range issues {
+		if fixed := issue.Check != FsckDuplicateSerial; issue.Fixed != fixed {
+			t.Errorf("%s %s %q: fixed = %v, want %v", issue.Check, issue.Kind, issue.UID, issue.Fixed, fixed)
+		}
+	}
+
+	// The empty-UID and debug-* files were moved aside, not deleted
+	for _, path := range []string{
+		".quarantine/discoverysnapshots/.json",
+		".quarantine/discoverysnapshots/debug-20250114.json",
+		".quarantine/discoverysnapshots/debug-truncated.json",
+		".quarantine/devices/debug-node.json",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("quarantined file: %v", err)
+		}
+	}
+	uids, err := backend.List(ctx, "DiscoverySnapshot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fmt.Sprint(uids) != "[dis-a1b2c3d4]" {
+		t.Errorf("snapshots left: %q", uids)
+	}
+
+	// Only what needs a person is left
+	issues, err = Fsck(ctx, backend, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := issueKeys(issues); fmt.Sprint(got) != "[duplicate-serial Device dev-00000002]" {
+		t.Errorf("issues after fixing: %q", got)
+	}
+
+	// Repairs keep the rest of each resource and give it a new resourceVersion
+	load := func(uid string) *device.Device {
+		data, err := backend.Load(ctx, "Device", uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if ResourceVersion(data) == "" {
+			t.Errorf("%s has no resourceVersion after its repair", uid)
+		}
+		dev := &device.Device{}
+		if err := json.Unmarshal(data, dev); err != nil {
+			t.Fatal(err)
+		}
+		return dev
+	}
+	if dev := load("dev-00000004"); dev.Spec.ParentID != "" || dev.Status.RootDeviceID != "dev-00000004" || dev.Spec.SerialNumber != "SN4" {
+		t.Errorf("dangling parent repaired as %+v", dev)
+	}
+	if dev := load("dev-00000005"); dev.Spec.ParentID != "" {
+		t.Errorf("cycle not broken at dev-00000005: parent %s", dev.Spec.ParentID)
+	}
+	if dev := load("dev-00000006"); dev.Spec.ParentID != "dev-00000005" || dev.Status.Depth != 1 {
+		t.Errorf("dev-00000006 is %+v", dev)
+	}
+	if dev := load("dev-00000001"); fmt.Sprint(dev.Status.ChildrenDeviceIds) != "[dev-00000002 dev-00000003]" {
+		t.Errorf("children of dev-00000001: %v", dev.Status.ChildrenDeviceIds)
+	}
+	if dev := load("dev-00000008"); dev.Metadata.UID != "dev-00000008" || dev.Metadata.Name != "node-8" {
+		t.Errorf("empty metadata.uid repaired as %+v", dev.Metadata)
+	}
+	if dev := load("dev-00000009"); dev.Metadata.UID != "dev-00000009" {
+		t.Errorf("mismatched metadata.uid repaired as %s", dev.Metadata.UID)
+	}
+}
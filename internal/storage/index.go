@@ -0,0 +1,608 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// DeviceIndex is an in-memory secondary index over stored Devices.
+//
+// It maps serial numbers to UIDs and parent UIDs to their children so that
+// the reconciler can do point lookups instead of loading every Device on
+// disk for each snapshot, and MAC addresses and Redfish URIs to the Devices
+// that carry them in their properties.
+type DeviceIndex struct {
+	mu           sync.RWMutex
+	bySerial     map[string]string              // serialNumber -> uid
+	children     map[string]map[string]struct{} // parentID -> set of child uids
+	byMAC        map[string]map[string]struct{} // normalized MAC -> set of uids
+	byRedfishURI map[string]map[string]struct{} // normalized redfish_uri -> set of uids
+	entries      map[string]deviceIndexEntry    // uid -> indexed fields
+}
+
+// deviceIndexEntry holds the indexed fields of a single Device.
+type deviceIndexEntry struct {
+	SerialNumber string
+	ParentID     string
+	MACs         []string
+	RedfishURI   string
+}
+
+// indexedDeviceFields is the minimal shape decoded from stored Device JSON.
+type indexedDeviceFields struct {
+	Spec struct {
+		SerialNumber string                     `json:"serialNumber"`
+		ParentID     string                     `json:"parentID"`
+		Properties   map[string]json.RawMessage `json:"properties"`
+	} `json:"spec"`
+}
+
+// NewDeviceIndex creates an empty device index.
+func NewDeviceIndex() *DeviceIndex {
+	return &DeviceIndex{
+		bySerial:     make(map[string]string),
+		children:     make(map[string]map[string]struct{}),
+		byMAC:        make(map[string]map[string]struct{}),
+		byRedfishURI: make(map[string]map[string]struct{}),
+		entries:      make(map[string]deviceIndexEntry),
+	}
+}
+
+// put records (or replaces) the indexed fields for a Device UID.
+func (i *DeviceIndex) put(uid string, entry deviceIndexEntry) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	i.removeLocked(uid)
+
+	i.entries[uid] = entry
+	if entry.SerialNumber != "" {
+		i.bySerial[entry.SerialNumber] = uid
+	}
+	if entry.ParentID != "" {
+		addToSet(i.children, entry.ParentID, uid)
+	}
+	for _, mac := range entry.MACs {
+		addToSet(i.byMAC, mac, uid)
+	}
+	if entry.RedfishURI != "" {
+		addToSet(i.byRedfishURI, entry.RedfishURI, uid)
+	}
+}
+
+// remove drops a Device UID from the index.
+func (i *DeviceIndex) remove(uid string) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.removeLocked(uid)
+}
+
+func (i *DeviceIndex) removeLocked(uid string) {
+	old, ok := i.entries[uid]
+	if !ok {
+		return
+	}
+	delete(i.entries, uid)
+	if old.SerialNumber != "" && i.bySerial[old.SerialNumber] == uid {
+		delete(i.bySerial, old.SerialNumber)
+	}
+	if old.ParentID != "" {
+		removeFromSet(i.children, old.ParentID, uid)
+	}
+	for _, mac := range old.MACs {
+		removeFromSet(i.byMAC, mac, uid)
+	}
+	if old.RedfishURI != "" {
+		removeFromSet(i.byRedfishURI, old.RedfishURI, uid)
+	}
+}
+
+func addToSet(sets map[string]map[string]struct{}, key, uid string) {
+	if sets[key] == nil {
+		sets[key] = make(map[string]struct{})
+	}
+	sets[key][uid] = struct{}{}
+}
+
+func removeFromSet(sets map[string]map[string]struct{}, key, uid string) {
+	delete(sets[key], uid)
+	if len(sets[key]) == 0 {
+		delete(sets, key)
+	}
+}
+
+// sortedSet returns the members of a set, sorted.
+func sortedSet(set map[string]struct{}) []string {
+	uids := make([]string, 0, len(set))
+	for uid := range set {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids
+}
+
+// reset clears every entry from the index.
+func (i *DeviceIndex) reset() {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.bySerial = make(map[string]string)
+	i.children = make(map[string]map[string]struct{})
+	i.byMAC = make(map[string]map[string]struct{})
+	i.byRedfishURI = make(map[string]map[string]struct{})
+	i.entries = make(map[string]deviceIndexEntry)
+}
+
+// UIDBySerial returns the UID of the Device with the given serial number.
+func (i *DeviceIndex) UIDBySerial(serial string) (string, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	uid, ok := i.bySerial[serial]
+	return uid, ok
+}
+
+// ChildUIDs returns the UIDs of all Devices whose ParentID is parentUID, sorted.
+func (i *DeviceIndex) ChildUIDs(parentUID string) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return sortedSet(i.children[parentUID])
+}
+
+// UIDsByMAC returns the UIDs of all Devices with a MAC-valued property equal
+// to mac (in any notation net.ParseMAC accepts), sorted.
+func (i *DeviceIndex) UIDsByMAC(mac string) []string {
+	normalized, ok := NormalizeMAC(mac)
+	if !ok {
+		return nil
+	}
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return sortedSet(i.byMAC[normalized])
+}
+
+// UIDsByRedfishURI returns the UIDs of all Devices whose redfish_uri property
+// is uri, sorted.
+func (i *DeviceIndex) UIDsByRedfishURI(uri string) []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return sortedSet(i.byRedfishURI[NormalizeRedfishURI(uri)])
+}
+
+// Len returns the number of indexed Devices.
+func (i *DeviceIndex) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.entries)
+}
+
+// IndexedBackend wraps a StorageBackend and keeps a DeviceIndex up to date
+// on every Save and Delete of a Device.
+//
+// All other operations are delegated to the wrapped backend unchanged.
+type IndexedBackend struct {
+	fabricaStorage.StorageBackend
+	index *DeviceIndex
+
+	// writeMu keeps the index in the same order as the writes that reach the backend
+	writeMu sync.Mutex
+}
+
+// Compile-time check that IndexedBackend implements fabricaStorage.StorageBackend
+var _ fabricaStorage.StorageBackend = (*IndexedBackend)(nil)
+
+// NewIndexedBackend wraps backend and builds the device index from its
+// current contents.
+func NewIndexedBackend(ctx context.Context, backend fabricaStorage.StorageBackend) (*IndexedBackend, error) {
+	b := &IndexedBackend{
+		StorageBackend: backend,
+		index:          NewDeviceIndex(),
+	}
+	if err := b.Rebuild(ctx); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// Unwrap returns the wrapped backend.
+func (b *IndexedBackend) Unwrap() fabricaStorage.StorageBackend {
+	return b.StorageBackend
+}
+
+// Index returns the device index maintained by this backend.
+func (b *IndexedBackend) Index() *DeviceIndex {
+	return b.index
+}
+
+// Rebuild discards the index and repopulates it from the wrapped backend.
+func (b *IndexedBackend) Rebuild(ctx context.Context) error {
+	uids, err := b.StorageBackend.List(ctx, "Device")
+	if err != nil {
+		return fmt.Errorf("failed to list devices for index: %w", err)
+	}
+
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	b.index.reset()
+	for _, uid := range uids {
+		raw, err := b.StorageBackend.Load(ctx, "Device", uid)
+		if err != nil {
+			// Unreadable files are skipped, the same way LoadAll skips them
+			continue
+		}
+		b.indexDevice(uid, raw)
+	}
+	return nil
+}
+
+// indexDevice decodes the indexed fields from raw Device JSON and records them.
+func (b *IndexedBackend) indexDevice(uid string, data json.RawMessage) {
+	var fields indexedDeviceFields
+	if err := json.Unmarshal(data, &fields); err != nil {
+		b.index.remove(uid)
+		return
+	}
+	b.index.put(uid, deviceIndexEntry{
+		SerialNumber: fields.Spec.SerialNumber,
+		ParentID:     fields.Spec.ParentID,
+		MACs:         PropertyMACs(fields.Spec.Properties),
+		RedfishURI:   propertyRedfishURI(fields.Spec.Properties),
+	})
+}
+
+// Save implements StorageBackend.Save and updates the device index.
+func (b *IndexedBackend) Save(ctx context.Context, resourceType, uid string, data json.RawMessage) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	if err := b.StorageBackend.Save(ctx, resourceType, uid, data); err != nil {
+		return err
+	}
+	if resourceType == "Device" {
+		b.indexDevice(uid, data)
+	}
+	return nil
+}
+
+// SaveWithVersion implements StorageBackend.SaveWithVersion and updates the device index.
+func (b *IndexedBackend) SaveWithVersion(ctx context.Context, resourceType, uid string, data json.RawMessage, version string) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	if err := b.StorageBackend.SaveWithVersion(ctx, resourceType, uid, data, version); err != nil {
+		return err
+	}
+	if resourceType == "Device" {
+		b.indexDevice(uid, data)
+	}
+	return nil
+}
+
+// Delete implements StorageBackend.Delete and updates the device index.
+func (b *IndexedBackend) Delete(ctx context.Context, resourceType, uid string) error {
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	if err := b.StorageBackend.Delete(ctx, resourceType, uid); err != nil {
+		return err
+	}
+	if resourceType == "Device" {
+		b.index.remove(uid)
+	}
+	return nil
+}
+
+// CompareAndSwap forwards a compare-and-swap write to the wrapped backend and
+// updates the device index when it succeeds.
+func (b *IndexedBackend) CompareAndSwap(ctx context.Context, resourceType, uid, expected string, build func(version string) (json.RawMessage, error)) error {
+	writer, ok := b.StorageBackend.(versionedWriter)
+	if !ok {
+		// Without compare-and-swap underneath this is a plain write
+		data, err := build(expected)
+		if err != nil {
+			return err
+		}
+		return b.Save(ctx, resourceType, uid, data)
+	}
+
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	var written json.RawMessage
+	err := writer.CompareAndSwap(ctx, resourceType, uid, expected, func(version string) (json.RawMessage, error) {
+		data, err := build(version)
+		written = data
+		return data, err
+	})
+	if err != nil {
+		return err
+	}
+	if resourceType == "Device" {
+		b.indexDevice(uid, written)
+	}
+	return nil
+}
+
+// CompareAndDelete forwards a conditional delete to the wrapped backend and
+// updates the device index when it succeeds.
+func (b *IndexedBackend) CompareAndDelete(ctx context.Context, resourceType, uid, expected string) error {
+	writer, ok := b.StorageBackend.(versionedWriter)
+	if !ok {
+		return b.Delete(ctx, resourceType, uid)
+	}
+
+	b.writeMu.Lock()
+	defer b.writeMu.Unlock()
+
+	if err := writer.CompareAndDelete(ctx, resourceType, uid, expected); err != nil {
+		return err
+	}
+	if resourceType == "Device" {
+		b.index.remove(uid)
+	}
+	return nil
+}
+
+// InitIndex wraps the current Backend in an IndexedBackend and builds the index.
+// Call it after Init or InitJournaledFileBackend, and after InitVersioning.
+func InitIndex(ctx context.Context) error {
+	ensureBackend()
+
+	indexed, err := NewIndexedBackend(ctx, Backend)
+	if err != nil {
+		return fmt.Errorf("failed to build device index: %w", err)
+	}
+	Backend = indexed
+	return nil
+}
+
+// deviceIndex returns the index of backend, or nil if it is not indexed.
+func deviceIndex(backend fabricaStorage.StorageBackend) *DeviceIndex {
+	if indexed, ok := backend.(*IndexedBackend); ok {
+		return indexed.Index()
+	}
+	return nil
+}
+
+// GetDeviceBySerial returns the Device with the given serial number.
+//
+// When the backend is an IndexedBackend this is a single point lookup;
+// otherwise it falls back to scanning every Device.
+//
+// Returns:
+//   - *device.Device: The matching Device
+//   - error: fabricaStorage.ErrNotFound if no Device has that serial number
+func (c *StorageClient) GetDeviceBySerial(ctx context.Context, serial string) (*device.Device, error) {
+	if idx := deviceIndex(c.backend); idx != nil {
+		uid, ok := idx.UIDBySerial(serial)
+		if !ok {
+			return nil, fabricaStorage.ErrNotFound
+		}
+		item, err := c.Get(ctx, "Device", uid)
+		if err != nil {
+			return nil, err
+		}
+		return item.(*device.Device), nil
+	}
+
+	items, err := c.List(ctx, "Device")
+	if err != nil {
+		return nil, err
+	}
+	for _, item := range items {
+		if dev, ok := item.(*device.Device); ok && dev.Spec.SerialNumber == serial {
+			return dev, nil
+		}
+	}
+	return nil, fabricaStorage.ErrNotFound
+}
+
+// ListDeviceChildren returns the Devices whose ParentID is parentUID.
+//
+// When the backend is an IndexedBackend only the children are loaded;
+// otherwise it falls back to scanning every Device.
+func (c *StorageClient) ListDeviceChildren(ctx context.Context, parentUID string) ([]*device.Device, error) {
+	if idx := deviceIndex(c.backend); idx != nil {
+		uids := idx.ChildUIDs(parentUID)
+		children := make([]*device.Device, 0, len(uids))
+		for _, uid := range uids {
+			item, err := c.Get(ctx, "Device", uid)
+			if err != nil {
+				if errors.Is(err, fabricaStorage.ErrNotFound) {
+					continue
+				}
+				return nil, err
+			}
+			children = append(children, item.(*device.Device))
+		}
+		return children, nil
+	}
+
+	items, err := c.List(ctx, "Device")
+	if err != nil {
+		return nil, err
+	}
+	var children []*device.Device
+	for _, item := range items {
+		if dev, ok := item.(*device.Device); ok && dev.Spec.ParentID == parentUID {
+			children = append(children, dev)
+		}
+	}
+	return children, nil
+}
+
+// ListDevicesByMAC returns the Devices with a MAC-valued property (such as
+// mac_address) equal to mac, in any notation net.ParseMAC accepts.
+//
+// When the backend is an IndexedBackend only the matches are loaded;
+// otherwise it falls back to scanning every Device.
+func (c *StorageClient) ListDevicesByMAC(ctx context.Context, mac string) ([]*device.Device, error) {
+	normalized, ok := NormalizeMAC(mac)
+	if !ok {
+		return nil, fmt.Errorf("invalid MAC address %q", mac)
+	}
+	if idx := deviceIndex(c.backend); idx != nil {
+		return c.loadDevices(ctx, idx.UIDsByMAC(normalized))
+	}
+	return c.scanDevices(ctx, func(dev *device.Device) bool {
+		for _, m := range PropertyMACs(dev.Spec.Properties) {
+			if m == normalized {
+				return true
+			}
+		}
+		return false
+	})
+}
+
+// ListDevicesByRedfishURI returns the Devices whose redfish_uri property is
+// uri. The URI may be given with or without the /redfish/v1 prefix, or as a
+// full URL. URIs are only unique per BMC, so several Devices can match.
+//
+// When the backend is an IndexedBackend only the matches are loaded;
+// otherwise it falls back to scanning every Device.
+func (c *StorageClient) ListDevicesByRedfishURI(ctx context.Context, uri string) ([]*device.Device, error) {
+	if idx := deviceIndex(c.backend); idx != nil {
+		return c.loadDevices(ctx, idx.UIDsByRedfishURI(uri))
+	}
+	normalized := NormalizeRedfishURI(uri)
+	return c.scanDevices(ctx, func(dev *device.Device) bool {
+		return propertyRedfishURI(dev.Spec.Properties) == normalized
+	})
+}
+
+// loadDevices loads Devices by UID, skipping any deleted since they were indexed.
+func (c *StorageClient) loadDevices(ctx context.Context, uids []string) ([]*device.Device, error) {
+	devices := make([]*device.Device, 0, len(uids))
+	for _, uid := range uids {
+		item, err := c.Get(ctx, "Device", uid)
+		if err != nil {
+			if errors.Is(err, fabricaStorage.ErrNotFound) {
+				continue
+			}
+			return nil, err
+		}
+		devices = append(devices, item.(*device.Device))
+	}
+	return devices, nil
+}
+
+// scanDevices loads every Device and keeps those that match.
+func (c *StorageClient) scanDevices(ctx context.Context, match func(dev *device.Device) bool) ([]*device.Device, error) {
+	items, err := c.List(ctx, "Device")
+	if err != nil {
+		return nil, err
+	}
+	var devices []*device.Device
+	for _, item := range items {
+		if dev, ok := item.(*device.Device); ok && match(dev) {
+			devices = append(devices, dev)
+		}
+	}
+	return devices, nil
+}
+
+// NormalizeMAC returns mac in lowercase colon-separated form (e.g.
+// "aa:bb:cc:dd:ee:ff"), accepting any notation net.ParseMAC does.
+func NormalizeMAC(mac string) (string, bool) {
+	hw, err := net.ParseMAC(strings.TrimSpace(mac))
+	if err != nil {
+		return "", false
+	}
+	return hw.String(), true
+}
+
+// NormalizeRedfishURI reduces a Redfish URI to its path below /redfish/v1,
+// without a trailing slash (e.g. "/Systems/1"), the form the collector stores.
+func NormalizeRedfishURI(uri string) string {
+	uri = strings.TrimSpace(uri)
+	if u, err := url.Parse(uri); err == nil && u.Scheme != "" {
+		uri = u.Path
+	}
+	uri = strings.TrimPrefix(uri, "/redfish/v1")
+	uri = strings.TrimSuffix(uri, "/")
+	if uri != "" && !strings.HasPrefix(uri, "/") {
+		uri = "/" + uri
+	}
+	return uri
+}
+
+// isMACProperty reports whether a property key names a MAC address: its last
+// namespace segment has a "mac" word (mac, mac_address, bmc_mac, mac_addresses, ...).
+func isMACProperty(key string) bool {
+	segment := key[strings.LastIndex(key, ".")+1:]
+	for _, word := range strings.Split(segment, "_") {
+		switch word {
+		case "mac", "macs", "macaddr", "macaddress":
+			return true
+		}
+	}
+	return false
+}
+
+// PropertyMACs returns the normalized MAC addresses in MAC-valued
+// properties, which may hold a string or a list of strings.
+func PropertyMACs(props map[string]json.RawMessage) []string {
+	var macs []string
+	for key, raw := range props {
+		if !isMACProperty(key) {
+			continue
+		}
+		var values []string
+		var single string
+		if err := json.Unmarshal(raw, &single); err == nil {
+			values = []string{single}
+		} else if err := json.Unmarshal(raw, &values); err != nil {
+			continue
+		}
+		for _, value := range values {
+			if mac, ok := NormalizeMAC(value); ok {
+				macs = append(macs, mac)
+			}
+		}
+	}
+	sort.Strings(macs)
+	return macs
+}
+
+// propertyRedfishURI returns the normalized redfish_uri property, if any.
+func propertyRedfishURI(props map[string]json.RawMessage) string {
+	var uri string
+	if err := json.Unmarshal(props["redfish_uri"], &uri); err != nil {
+		return ""
+	}
+	return NormalizeRedfishURI(uri)
+}
+
+// LoadDeviceBySerial loads the Device with the given serial number.
+func LoadDeviceBySerial(ctx context.Context, serial string) (*device.Device, error) {
+	return NewStorageClient().GetDeviceBySerial(ctx, serial)
+}
+
+// LoadDevicesByMAC loads the Devices with a MAC-valued property equal to mac.
+func LoadDevicesByMAC(ctx context.Context, mac string) ([]*device.Device, error) {
+	return NewStorageClient().ListDevicesByMAC(ctx, mac)
+}
+
+// LoadDevicesByRedfishURI loads the Devices whose redfish_uri property is uri.
+func LoadDevicesByRedfishURI(ctx context.Context, uri string) ([]*device.Device, error) {
+	return NewStorageClient().ListDevicesByRedfishURI(ctx, uri)
+}
+
+// LoadDeviceChildren loads the Devices whose ParentID is parentUID.
+func LoadDeviceChildren(ctx context.Context, parentUID string) ([]*device.Device, error) {
+	return NewStorageClient().ListDeviceChildren(ctx, parentUID)
+}
@@ -0,0 +1,210 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"sync"
+	"testing"
+
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// memBackend is a StorageBackend that keeps resources in memory
+type memBackend struct {
+	mu        sync.RWMutex
+	resources map[string]map[string]json.RawMessage
+}
+
+func newMemBackend() *memBackend {
+	return &memBackend{resources: make(map[string]map[string]json.RawMessage)}
+}
+
+func (m *memBackend) LoadAll(ctx context.Context, resourceType string) ([]json.RawMessage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	all := make([]json.RawMessage, 0, len(m.resources[resourceType]))
+	for _, data := range m.resources[resourceType] {
+		all = append(all, data)
+	}
+	return all, nil
+}
+
+func (m *memBackend) Load(ctx context.Context, resourceType, uid string) (json.RawMessage, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	data, ok := m.resources[resourceType][uid]
+	if !ok {
+		return nil, fabricaStorage.ErrNotFound
+	}
+	return data, nil
+}
+
+func (m *memBackend) Save(ctx context.Context, resourceType, uid string, data json.RawMessage) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if m.resources[resourceType] == nil {
+		m.resources[resourceType] = make(map[string]json.RawMessage)
+	}
+	m.resources[resourceType][uid] = data
+	return nil
+}
+
+func (m *memBackend) Delete(ctx context.Context, resourceType, uid string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.resources[resourceType][uid]; !ok {
+		return fabricaStorage.ErrNotFound
+	}
+	delete(m.resources[resourceType], uid)
+	return nil
+}
+
+func (m *memBackend) Exists(ctx context.Context, resourceType, uid string) (bool, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	_, ok := m.resources[resourceType][uid]
+	return ok, nil
+}
+
+func (m *memBackend) List(ctx context.Context, resourceType string) ([]string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	uids := make([]string, 0, len(m.resources[resourceType]))
+	for uid := range m.resources[resourceType] {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	return uids, nil
+}
+
+func (m *memBackend) Close() error { return nil }
+
+// ApplyBatch applies ops under one lock, so a batch is atomic
+func (m *memBackend) ApplyBatch(ctx context.Context, ops []BatchOp) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for _, op := range ops {
+		if op.Data == nil {
+			delete(m.resources[op.Kind], op.UID)
+			continue
+		}
+		if m.resources[op.Kind] == nil {
+			m.resources[op.Kind] = make(map[string]json.RawMessage)
+		}
+		m.resources[op.Kind][op.UID] = op.Data
+	}
+	return nil
+}
+
+func (m *memBackend) LoadWithVersion(ctx context.Context, resourceType, uid, version string) (json.RawMessage, string, error) {
+	data, err := m.Load(ctx, resourceType, uid)
+	return data, version, err
+}
+
+func (m *memBackend) LoadAllWithVersion(ctx context.Context, resourceType, version string) ([]json.RawMessage, error) {
+	return m.LoadAll(ctx, resourceType)
+}
+
+func (m *memBackend) SaveWithVersion(ctx context.Context, resourceType, uid string, data json.RawMessage, version string) error {
+	return m.Save(ctx, resourceType, uid, data)
+}
+
+const benchmarkDevices = 100_000
+
+var (
+	benchmarkOnce    sync.Once
+	benchmarkBackend *memBackend
+)
+
+// loadBenchmarkDevices returns a backend holding benchmarkDevices Devices:
+// nodes of 9 devices each, a Node with 8 children (CPUs, DIMMs, NICs)
+func loadBenchmarkDevices(b *testing.B) *memBackend {
+	benchmarkOnce.Do(func() {
+		backend := newMemBackend()
+		ctx := context.Background()
+		for i := 0; i < benchmarkDevices; i++ {
+			dev := &device.Device{}
+			dev.APIVersion = "v1"
+			dev.Kind = "Device"
+			dev.Metadata.UID = fmt.Sprintf("dev-%08x", i)
+			dev.Metadata.Name = fmt.Sprintf("device-%d", i)
+			dev.Spec = device.DeviceSpec{
+				DeviceType:   "DIMM",
+				Manufacturer: "Example",
+				PartNumber:   "PN-1",
+				SerialNumber: fmt.Sprintf("SN%08d", i),
+				Properties: map[string]json.RawMessage{
+					"redfish_uri": json.RawMessage(fmt.Sprintf(`"/redfish/v1/Systems/%d"`, i)),
+				},
+			}
+			if node := i - i%9; node != i {
+				dev.Spec.ParentID = fmt.Sprintf("dev-%08x", node)
+			} else {
+				dev.Spec.DeviceType = "Node"
+				dev.Spec.Properties["mac_address"] = json.RawMessage(fmt.Sprintf(`"02:00:00:%02x:%02x:%02x"`, i>>16&0xff, i>>8&0xff, i&0xff))
+			}
+			data, err := json.Marshal(dev)
+			if err != nil {
+				panic(err)
+			}
+			backend.Save(ctx, "Device", dev.Metadata.UID, data)
+		}
+		benchmarkBackend = backend
+	})
+	return benchmarkBackend
+}
+
+// BenchmarkDeviceLookup compares the indexed lookups the reconciler and
+// lookup endpoints use with the full scans they replace, over 100k Devices.
+func BenchmarkDeviceLookup(b *testing.B) {
+	ctx := context.Background()
+	backend := loadBenchmarkDevices(b)
+	indexed, err := NewIndexedBackend(ctx, backend)
+	if err != nil {
+		b.Fatal(err)
+	}
+	clients := map[string]*StorageClient{
+		"indexed": {backend: indexed},
+		"scan":    {backend: backend},
+	}
+
+	for _, mode := range []string{"indexed", "scan"} {
+		c := clients[mode]
+		b.Run("BySerial/"+mode, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				i := n * 7919 % benchmarkDevices
+				dev, err := c.GetDeviceBySerial(ctx, fmt.Sprintf("SN%08d", i))
+				if err != nil || dev.Metadata.UID != fmt.Sprintf("dev-%08x", i) {
+					b.Fatalf("GetDeviceBySerial(%d) = %v, %v", i, dev, err)
+				}
+			}
+		})
+		b.Run("Children/"+mode, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				node := n * 7919 % benchmarkDevices / 9 * 9
+				children, err := c.ListDeviceChildren(ctx, fmt.Sprintf("dev-%08x", node))
+				if err != nil || len(children) != min(8, benchmarkDevices-node-1) {
+					b.Fatalf("ListDeviceChildren(%d) = %d children, %v", node, len(children), err)
+				}
+			}
+		})
+		b.Run("ByMAC/"+mode, func(b *testing.B) {
+			for n := 0; n < b.N; n++ {
+				node := n * 7919 % benchmarkDevices / 9 * 9
+				mac := fmt.Sprintf("02:00:00:%02x:%02x:%02x", node>>16&0xff, node>>8&0xff, node&0xff)
+				devices, err := c.ListDevicesByMAC(ctx, mac)
+				if err != nil || len(devices) != 1 {
+					b.Fatalf("ListDevicesByMAC(%s) = %d devices, %v", mac, len(devices), err)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,390 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+)
+
+const (
+	// journalDir holds the write-ahead journal under the data directory
+	journalDir = ".journal"
+
+	// journalFile is a committed batch that may not have been fully applied yet;
+	// journalTempFile is one still being written, which never counts
+	journalFile     = "batch.json"
+	journalTempFile = "batch.json.tmp"
+)
+
+// ErrJournalPending is returned for writes while a batch that could be
+// neither applied nor undone is waiting in the journal. Writes resume once
+// the journal has been replayed.
+var ErrJournalPending = errors.New("a partially applied batch is waiting in the journal")
+
+// journalEntry is one write of a journaled batch, with the file's previous
+// content so a batch that fails part way can be undone.
+type journalEntry struct {
+	Kind   string          `json:"kind"`
+	UID    string          `json:"uid"`
+	Data   json.RawMessage `json:"data,omitempty"`   // nil deletes the file
+	Before json.RawMessage `json:"before,omitempty"` // nil if the file didn't exist
+}
+
+// JournaledFileBackend is fabrica's FileBackend (one JSON file per resource)
+// with atomic batches.
+//
+// A batch is first written to a journal, which is committed by renaming it
+// into place, and then applied file by file with write-and-rename. If the
+// process dies part way, the journal is replayed when the backend is opened
+// again, so a batch is either wholly applied or not at all. Reads through
+// the backend never see a batch half applied.
+//
+// If a failed batch can't be undone either, its journal is kept and the
+// backend fails closed: every write first tries to replay the journal, and is
+// refused with ErrJournalPending until that succeeds.
+type JournaledFileBackend struct {
+	*fabricaStorage.FileBackend
+	baseDir string
+
+	mu sync.RWMutex // writers (and batches) exclude readers
+	// journalKept is set while the journal of a failed batch is waiting to be replayed
+	journalKept bool
+}
+
+// Compile-time checks
+var (
+	_ fabricaStorage.StorageBackend = (*JournaledFileBackend)(nil)
+	_ Batcher                       = (*JournaledFileBackend)(nil)
+	_ Snapshotter                   = (*JournaledFileBackend)(nil)
+)
+
+// NewJournaledFileBackend opens a file backend in baseDir, finishing any
+// batch that was interrupted.
+func NewJournaledFileBackend(baseDir string) (*JournaledFileBackend, error) {
+	fileBackend, err := fabricaStorage.NewFileBackend(baseDir)
+	if err != nil {
+		return nil, err
+	}
+	b := &JournaledFileBackend{FileBackend: fileBackend, baseDir: baseDir}
+	if err := b.recover(); err != nil {
+		return nil, err
+	}
+	return b, nil
+}
+
+// LoadAll implements StorageBackend.LoadAll.
+func (b *JournaledFileBackend) LoadAll(ctx context.Context, resourceType string) ([]json.RawMessage, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.FileBackend.LoadAll(ctx, resourceType)
+}
+
+// Load implements StorageBackend.Load.
+func (b *JournaledFileBackend) Load(ctx context.Context, resourceType, uid string) (json.RawMessage, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.FileBackend.Load(ctx, resourceType, uid)
+}
+
+// Exists implements StorageBackend.Exists.
+func (b *JournaledFileBackend) Exists(ctx context.Context, resourceType, uid string) (bool, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.FileBackend.Exists(ctx, resourceType, uid)
+}
+
+// List implements StorageBackend.List.
+func (b *JournaledFileBackend) List(ctx context.Context, resourceType string) ([]string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.FileBackend.List(ctx, resourceType)
+}
+
+// Save implements StorageBackend.Save.
+func (b *JournaledFileBackend) Save(ctx context.Context, resourceType, uid string, data json.RawMessage) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.recoverKept(); err != nil {
+		return err
+	}
+	return b.FileBackend.Save(ctx, resourceType, uid, data)
+}
+
+// Delete implements StorageBackend.Delete.
+func (b *JournaledFileBackend) Delete(ctx context.Context, resourceType, uid string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.recoverKept(); err != nil {
+		return err
+	}
+	return b.FileBackend.Delete(ctx, resourceType, uid)
+}
+
+// Snapshot implements Snapshotter, holding off writers while it reads.
+func (b *JournaledFileBackend) Snapshot(ctx context.Context, kinds []string) ([]StoredResource, []string, error) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return snapshotKinds(ctx, b.FileBackend, kinds)
+}
+
+// ApplyBatch implements Batcher.
+func (b *JournaledFileBackend) ApplyBatch(ctx context.Context, ops []BatchOp) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if err := b.recoverKept(); err != nil {
+		return err
+	}
+
+	entries := make([]journalEntry, 0, len(ops))
+	for _, op := range ops {
+		path, err := b.path(op.Kind, op.UID)
+		if err != nil {
+			return err
+		}
+		if op.Data != nil && !json.Valid(op.Data) {
+			return fmt.Errorf("invalid JSON data for %s %s: %w", op.Kind, op.UID, fabricaStorage.ErrInvalidData)
+		}
+		before, err := os.ReadFile(path)
+		if err != nil && !errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("failed to read %s: %w", path, err)
+		}
+		entries = append(entries, journalEntry{Kind: op.Kind, UID: op.UID, Data: op.Data, Before: before})
+	}
+
+	// The batch is committed once the journal is in place
+	if err := b.writeJournal(entries); err != nil {
+		return err
+	}
+
+	if err := b.apply(entries, false); err != nil {
+		// Put back what was there; if even that fails, keep the journal so
+		// the batch is completed before the next write (or when the backend
+		// is next opened)
+		if undoErr := b.apply(entries, true); undoErr != nil {
+			b.journalKept = true
+			return fmt.Errorf("batch partially applied, it will be completed from the journal: %w", err)
+		}
+		b.removeJournal()
+		return err
+	}
+	return b.removeJournal()
+}
+
+// path returns the file of a resource, laid out as fabrica's FileBackend does
+func (b *JournaledFileBackend) path(kind, uid string) (string, error) {
+	if uid == "" || strings.ContainsAny(uid, `/\`) || uid == "." || uid == ".." {
+		return "", fmt.Errorf("invalid UID %q: %w", uid, fabricaStorage.ErrInvalidData)
+	}
+	return filepath.Join(b.kindDir(kind), uid+".json"), nil
+}
+
+// kindDir returns the directory of a kind's files
+func (b *JournaledFileBackend) kindDir(kind string) string {
+	return filepath.Join(b.baseDir, kindDirName(kind))
+}
+
+// kindDirName names the directory of a kind's files, as fabrica's FileBackend does
+func kindDirName(kind string) string {
+	dir := strings.ToLower(kind)
+	if !strings.HasSuffix(dir, "s") {
+		dir += "s"
+	}
+	return dir
+}
+
+// writeJournal durably writes the journal and renames it into place. It
+// never replaces a journal that is still there.
+func (b *JournaledFileBackend) writeJournal(entries []journalEntry) error {
+	dir := filepath.Join(b.baseDir, journalDir)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create journal directory: %w", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, journalFile)); err == nil {
+		b.journalKept = true
+		return ErrJournalPending
+	} else if !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to check journal: %w", err)
+	}
+	data, err := json.Marshal(entries)
+	if err != nil {
+		return fmt.Errorf("failed to encode journal: %w", err)
+	}
+	if err := writeFileSync(filepath.Join(dir, journalFile), data); err != nil {
+		return fmt.Errorf("failed to write journal: %w", err)
+	}
+	return nil
+}
+
+// removeJournal marks the batch as fully applied
+func (b *JournaledFileBackend) removeJournal() error {
+	dir := filepath.Join(b.baseDir, journalDir)
+	if err := os.Remove(filepath.Join(dir, journalFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove journal: %w", err)
+	}
+	return syncDir(dir)
+}
+
+// apply writes each entry's new content, or its previous content when undoing
+func (b *JournaledFileBackend) apply(entries []journalEntry, undo bool) error {
+	dirs := make(map[string]struct{})
+	for _, e := range entries {
+		path, err := b.path(e.Kind, e.UID)
+		if err != nil {
+			return err
+		}
+		data := e.Data
+		if undo {
+			data = e.Before
+		}
+		if data == nil {
+			if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+				return fmt.Errorf("failed to delete %s: %w", path, err)
+			}
+		} else {
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return fmt.Errorf("failed to create directory for %s: %w", path, err)
+			}
+			if err := writeFileSync(path, data); err != nil {
+				return fmt.Errorf("failed to write %s: %w", path, err)
+			}
+		}
+		dirs[filepath.Dir(path)] = struct{}{}
+	}
+	for dir := range dirs {
+		if err := syncDir(dir); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// recover replays a committed journal left by an interrupted batch and
+// discards one that was never committed
+func (b *JournaledFileBackend) recover() error {
+	dir := filepath.Join(b.baseDir, journalDir)
+	if err := os.Remove(filepath.Join(dir, journalTempFile)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("failed to remove uncommitted journal: %w", err)
+	}
+
+	data, err := os.ReadFile(filepath.Join(dir, journalFile))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read journal: %w", err)
+	}
+	var entries []journalEntry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return fmt.Errorf("failed to decode journal %s: %w", filepath.Join(dir, journalFile), err)
+	}
+	if err := b.apply(entries, false); err != nil {
+		return fmt.Errorf("failed to replay journal: %w", err)
+	}
+	return b.removeJournal()
+}
+
+// recoverKept replays a journal kept by a failed batch; the caller holds mu
+// for writing. Until the replay succeeds it returns ErrJournalPending.
+func (b *JournaledFileBackend) recoverKept() error {
+	if !b.journalKept {
+		return nil
+	}
+	if err := b.recover(); err != nil {
+		return fmt.Errorf("%w: %v", ErrJournalPending, err)
+	}
+	b.journalKept = false
+	return nil
+}
+
+// writeFileSync replaces path with data atomically and durably: it writes
+// and syncs a temporary file, then renames it over path
+func writeFileSync(path string, data []byte) error {
+	tmp := path + ".tmp"
+	f, err := os.OpenFile(tmp, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmp)
+		return err
+	}
+	if err := f.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, path); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return syncDir(filepath.Dir(path))
+}
+
+// syncDir makes renames and removals in dir durable
+func syncDir(dir string) error {
+	d, err := os.Open(dir)
+	if err != nil {
+		return err
+	}
+	defer d.Close()
+	if err := d.Sync(); err != nil {
+		return fmt.Errorf("failed to sync %s: %w", dir, err)
+	}
+	return nil
+}
+
+// quarantineDir holds resources moved aside by Quarantine, under the data directory
+const quarantineDir = ".quarantine"
+
+// Quarantine implements Quarantiner: it moves a resource's file (even one
+// that can't be parsed, or has an empty UID) out of the kind's directory into
+// <data-dir>/.quarantine, where it can be inspected or moved back by hand.
+func (b *JournaledFileBackend) Quarantine(ctx context.Context, kind, uid string) (string, error) {
+	if strings.ContainsAny(uid, `/\`) || uid == "." || uid == ".." {
+		return "", fmt.Errorf("invalid UID %q: %w", uid, fabricaStorage.ErrInvalidData)
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	src := filepath.Join(b.kindDir(kind), uid+".json")
+	dstDir := filepath.Join(b.baseDir, quarantineDir, filepath.Base(b.kindDir(kind)))
+	if err := os.MkdirAll(dstDir, 0755); err != nil {
+		return "", fmt.Errorf("failed to create quarantine directory: %w", err)
+	}
+	dst := filepath.Join(dstDir, uid+".json")
+	for i := 1; ; i++ {
+		if _, err := os.Stat(dst); errors.Is(err, os.ErrNotExist) {
+			break
+		}
+		dst = filepath.Join(dstDir, fmt.Sprintf("%s.%d.json", uid, i))
+	}
+	if err := os.Rename(src, dst); err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return "", fabricaStorage.ErrNotFound
+		}
+		return "", fmt.Errorf("failed to quarantine %s: %w", src, err)
+	}
+	if err := syncDir(filepath.Dir(src)); err != nil {
+		return "", err
+	}
+	return dst, syncDir(dstDir)
+}
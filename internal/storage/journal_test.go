@@ -0,0 +1,75 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestJournalKeptFailsClosed checks that after a batch that could be neither
+// applied nor undone, writes are refused until its journal has been replayed,
+// and that the kept journal isn't overwritten meanwhile.
+func TestJournalKeptFailsClosed(t *testing.T) {
+	ctx := context.Background()
+	dir := t.TempDir()
+	b, err := NewJournaledFileBackend(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := b.Save(ctx, "Device", "dev-1", json.RawMessage(`{"v":0}`)); err != nil {
+		t.Fatal(err)
+	}
+
+	// A directory where dev-1's temporary file goes makes both writing the
+	// batch and putting back the old content fail
+	blocker := filepath.Join(dir, "devices", "dev-1.json.tmp")
+	if err := os.MkdirAll(filepath.Join(blocker, "x"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	batch := []BatchOp{{Kind: "Device", UID: "dev-1", Data: json.RawMessage(`{"v":1}`)}}
+	if err := b.ApplyBatch(ctx, batch); err == nil {
+		t.Fatal("batch succeeded with its file blocked")
+	}
+	if _, err := os.Stat(filepath.Join(dir, journalDir, journalFile)); err != nil {
+		t.Fatalf("journal was not kept: %v", err)
+	}
+
+	other := []BatchOp{{Kind: "Device", UID: "dev-2", Data: json.RawMessage(`{"v":2}`)}}
+	if err := b.ApplyBatch(ctx, other); !errors.Is(err, ErrJournalPending) {
+		t.Fatalf("batch with a kept journal: got %v, want ErrJournalPending", err)
+	}
+	if err := b.Save(ctx, "Device", "dev-2", json.RawMessage(`{"v":2}`)); !errors.Is(err, ErrJournalPending) {
+		t.Fatalf("save with a kept journal: got %v, want ErrJournalPending", err)
+	}
+	if exists, _ := b.Exists(ctx, "Device", "dev-2"); exists {
+		t.Fatal("dev-2 was written while the journal was kept")
+	}
+
+	// Once the journal can be replayed, the kept batch is completed first
+	if err := os.RemoveAll(blocker); err != nil {
+		t.Fatal(err)
+	}
+	if err := b.ApplyBatch(ctx, other); err != nil {
+		t.Fatalf("batch after the journal can be replayed: %v", err)
+	}
+	data, err := b.Load(ctx, "Device", "dev-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != `{"v":1}` {
+		t.Errorf("dev-1 = %s, want the kept batch's {\"v\":1}", data)
+	}
+	if exists, _ := b.Exists(ctx, "Device", "dev-2"); !exists {
+		t.Error("dev-2 was not written")
+	}
+	if _, err := os.Stat(filepath.Join(dir, journalDir, journalFile)); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("journal still present after replay: %v", err)
+	}
+}
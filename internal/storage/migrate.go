@@ -0,0 +1,273 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+)
+
+// OpenBackend opens a storage backend from a spec of the form
+// "file:<data-dir>" or "sqlite:<dsn>". A spec without a scheme is a data
+// directory for the file backend.
+//
+// The backend is opened bare (without versioning or the device index), so
+// resources are read and written exactly as stored.
+func OpenBackend(ctx context.Context, spec string) (fabricaStorage.StorageBackend, error) {
+	scheme, location, ok := strings.Cut(spec, ":")
+	if !ok {
+		scheme, location = "file", spec
+	}
+	if location == "" {
+		return nil, fmt.Errorf("storage spec %q has no location", spec)
+	}
+	switch scheme {
+	case "file":
+		return NewJournaledFileBackend(location)
+	case "sqlite":
+		if !strings.HasPrefix(location, "file:") {
+			if err := os.MkdirAll(filepath.Dir(location), 0755); err != nil {
+				return nil, fmt.Errorf("failed to create directory for %s: %w", location, err)
+			}
+		}
+		return NewSQLiteBackend(ctx, location)
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q in %q (expected file:<dir> or sqlite:<dsn>)", scheme, spec)
+	}
+}
+
+// Checksum returns the SHA-256 of a stored resource, ignoring insignificant
+// whitespace so that backends which reformat JSON still compare equal.
+func Checksum(data json.RawMessage) string {
+	var compact bytes.Buffer
+	if err := json.Compact(&compact, data); err != nil {
+		compact.Reset()
+		compact.Write(data)
+	}
+	sum := sha256.Sum256(compact.Bytes())
+	return hex.EncodeToString(sum[:])
+}
+
+// MigrateOptions configures Migrate.
+type MigrateOptions struct {
+	// Kinds to copy (default ResourceKinds)
+	Kinds []string
+
+	// BatchSize is how many resources are written per batch (default 500)
+	BatchSize int
+
+	// Progress, if set, is called after each batch is written with the running totals of its kind
+	Progress func(kind string, stats *MigrateStats)
+}
+
+// MigrateStats counts what Migrate did with the resources of one kind.
+type MigrateStats struct {
+	// Copied were written to the destination
+	Copied int
+	// Unchanged were already in the destination with the same content (from an earlier run)
+	Unchanged int
+	// Skipped couldn't be read from the source (see Problems)
+	Skipped int
+	// Problems describes each skipped resource
+	Problems []string
+}
+
+// Migrate copies every resource from src to dst byte for byte, so UIDs,
+// timestamps, labels, parent links and resourceVersions are kept.
+//
+// Resources are loaded one at a time and written in atomic batches when
+// dst is a Batcher, so memory use doesn't grow with the inventory. A
+// resource already in dst with the same checksum is left alone, which makes
+// an interrupted migration resumable by running it again.
+func Migrate(ctx context.Context, src, dst fabricaStorage.StorageBackend, opts MigrateOptions) (map[string]*MigrateStats, error) {
+	kinds := opts.Kinds
+	if len(kinds) == 0 {
+		kinds = ResourceKinds
+	}
+	batchSize := opts.BatchSize
+	if batchSize <= 0 {
+		batchSize = 500
+	}
+
+	result := make(map[string]*MigrateStats, len(kinds))
+	for _, kind := range kinds {
+		stats := &MigrateStats{}
+		result[kind] = stats
+
+		uids, err := src.List(ctx, kind)
+		if err != nil {
+			return result, fmt.Errorf("failed to list source %s resources: %w", kind, err)
+		}
+		sort.Strings(uids)
+
+		var batch []BatchOp
+		flush := func() error {
+			if len(batch) == 0 {
+				return nil
+			}
+			if err := applyBatch(ctx, dst, batch); err != nil {
+				return fmt.Errorf("failed to write %s resources: %w", kind, err)
+			}
+			stats.Copied += len(batch)
+			batch = batch[:0]
+			if opts.Progress != nil {
+				opts.Progress(kind, stats)
+			}
+			return nil
+		}
+
+		for _, uid := range uids {
+			if uid == "" {
+				stats.Skipped++
+				stats.Problems = append(stats.Problems, fmt.Sprintf("%s with an empty UID", kind))
+				continue
+			}
+			data, err := src.Load(ctx, kind, uid)
+			if err != nil {
+				stats.Skipped++
+				stats.Problems = append(stats.Problems, fmt.Sprintf("%s %s: %v", kind, uid, err))
+				continue
+			}
+
+			existing, err := dst.Load(ctx, kind, uid)
+			switch {
+			case err == nil && Checksum(existing) == Checksum(data):
+				stats.Unchanged++
+				continue
+			case err != nil && !errors.Is(err, fabricaStorage.ErrNotFound):
+				return result, fmt.Errorf("failed to read destination %s %s: %w", kind, uid, err)
+			}
+
+			batch = append(batch, BatchOp{Kind: kind, UID: uid, Data: data})
+			if len(batch) >= batchSize {
+				if err := flush(); err != nil {
+					return result, err
+				}
+			}
+		}
+		if err := flush(); err != nil {
+			return result, err
+		}
+	}
+	return result, nil
+}
+
+// VerifyResult compares the resources of one kind in two backends.
+type VerifyResult struct {
+	// Source and Destination count the readable resources in each backend
+	Source      int
+	Destination int
+
+	// SourceChecksum and DestinationChecksum cover every resource's UID and
+	// checksum in UID order
+	SourceChecksum      string
+	DestinationChecksum string
+
+	// Missing are in the source but not the destination, Different are in
+	// both with different content, and Extra are only in the destination
+	Missing   []string
+	Different []string
+	Extra     []string
+}
+
+// OK reports whether the destination holds exactly the source's resources.
+func (v *VerifyResult) OK() bool {
+	return v.Source == v.Destination && v.SourceChecksum == v.DestinationChecksum &&
+		len(v.Missing) == 0 && len(v.Different) == 0 && len(v.Extra) == 0
+}
+
+// Verify compares the resources of each kind in src and dst by count and
+// checksum. Resources that can't be read from src (which Migrate skips) are
+// left out of the comparison.
+func Verify(ctx context.Context, src, dst fabricaStorage.StorageBackend, kinds []string) (map[string]*VerifyResult, error) {
+	if len(kinds) == 0 {
+		kinds = ResourceKinds
+	}
+	result := make(map[string]*VerifyResult, len(kinds))
+	for _, kind := range kinds {
+		srcSums, err := checksums(ctx, src, kind)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read source %s resources: %w", kind, err)
+		}
+		dstSums, err := checksums(ctx, dst, kind)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read destination %s resources: %w", kind, err)
+		}
+
+		v := &VerifyResult{
+			Source:              len(srcSums),
+			Destination:         len(dstSums),
+			SourceChecksum:      combinedChecksum(srcSums),
+			DestinationChecksum: combinedChecksum(dstSums),
+		}
+		for uid, sum := range srcSums {
+			dstSum, ok := dstSums[uid]
+			switch {
+			case !ok:
+				v.Missing = append(v.Missing, uid)
+			case dstSum != sum:
+				v.Different = append(v.Different, uid)
+			}
+		}
+		for uid := range dstSums {
+			if _, ok := srcSums[uid]; !ok {
+				v.Extra = append(v.Extra, uid)
+			}
+		}
+		sort.Strings(v.Missing)
+		sort.Strings(v.Different)
+		sort.Strings(v.Extra)
+		result[kind] = v
+	}
+	return result, nil
+}
+
+// checksums returns the checksum of every readable resource of a kind, by UID
+func checksums(ctx context.Context, backend fabricaStorage.StorageBackend, kind string) (map[string]string, error) {
+	uids, err := backend.List(ctx, kind)
+	if err != nil {
+		return nil, err
+	}
+	sums := make(map[string]string, len(uids))
+	for _, uid := range uids {
+		if uid == "" {
+			continue
+		}
+		data, err := backend.Load(ctx, kind, uid)
+		if err != nil {
+			if errors.Is(err, fabricaStorage.ErrNotFound) || errors.Is(err, fabricaStorage.ErrInvalidData) {
+				continue
+			}
+			return nil, err
+		}
+		sums[uid] = Checksum(data)
+	}
+	return sums, nil
+}
+
+// combinedChecksum hashes "uid checksum" lines in UID order
+func combinedChecksum(sums map[string]string) string {
+	uids := make([]string, 0, len(sums))
+	for uid := range sums {
+		uids = append(uids, uid)
+	}
+	sort.Strings(uids)
+	h := sha256.New()
+	for _, uid := range uids {
+		fmt.Fprintf(h, "%s %s\n", uid, sums[uid])
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,141 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestMigrateFileToSQLite migrates a file store to SQLite, interrupts the
+// first run, resumes it and checks the result with Verify.
+func TestMigrateFileToSQLite(t *testing.T) {
+	ctx := context.Background()
+	srcDir := t.TempDir()
+	dstSpec := "sqlite:" + filepath.Join(t.TempDir(), "inventory.db")
+
+	src, err := OpenBackend(ctx, "file:"+srcDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer src.Close()
+	for i := 1; i <= 10; i++ {
+		uid := fmt.Sprintf("dev-%02d", i)
+		data := fmt.Sprintf(`{"apiVersion":"v1","kind":"Device","metadata":{"uid":%q,"name":"node-%d",`+
+			`"labels":{"rack":"r%d"},"annotations":{%q:"%d"},"createdAt":"2025-01-01T00:00:0%dZ"},`+
+			`"spec":{"deviceType":"Node","parentID":"dev-01","properties":{"speedMHz":3200}}}`,
+			uid, i, i%3, ResourceVersionAnnotation, 100+i, i%10)
+		if err := src.Save(ctx, "Device", uid, json.RawMessage(data)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	snapshot := `{"kind":"DiscoverySnapshot","metadata":{"uid":"snap-1"},"status":{"phase":"Completed"}}`
+	if err := src.Save(ctx, "DiscoverySnapshot", "snap-1", json.RawMessage(snapshot)); err != nil {
+		t.Fatal(err)
+	}
+	// A resource that can't be read is skipped, not copied or fatal
+	if err := os.WriteFile(filepath.Join(srcDir, "devices", "dev-bad.json"), []byte("{"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// The first run stops after its first batch, as if the process were killed
+	dst, err := OpenBackend(ctx, dstSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	runCtx, cancel := context.WithCancel(ctx)
+	_, err = Migrate(runCtx, src, dst, MigrateOptions{
+		BatchSize: 4,
+		Progress:  func(kind string, stats *MigrateStats) { cancel() },
+	})
+	if err == nil {
+		t.Fatal("interrupted migration succeeded")
+	}
+	verify, err := Verify(ctx, src, dst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := verify["Device"]; v.OK() || v.Destination != 4 || len(v.Missing) != 6 {
+		t.Fatalf("after the interrupted run: %+v", v)
+	}
+	dst.Close()
+
+	// A source change before the rerun is copied over the earlier copy
+	changed := `{"apiVersion":"v1","kind":"Device","metadata":{"uid":"dev-02","name":"node-2-renamed"},"spec":{"deviceType":"Node"}}`
+	if err := src.Save(ctx, "Device", "dev-02", json.RawMessage(changed)); err != nil {
+		t.Fatal(err)
+	}
+
+	dst, err = OpenBackend(ctx, dstSpec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer dst.Close()
+	stats, err := Migrate(ctx, src, dst, MigrateOptions{BatchSize: 4})
+	if err != nil {
+		t.Fatal(err)
+	}
+	devices := stats["Device"]
+	if devices.Unchanged != 3 || devices.Copied != 7 || devices.Skipped != 1 {
+		t.Errorf("resumed run: %d unchanged, %d copied, %d skipped; want 3, 7 and 1 (problems %q)",
+			devices.Unchanged, devices.Copied, devices.Skipped, devices.Problems)
+	}
+	if stats["DiscoverySnapshot"].Copied != 1 {
+		t.Errorf("resumed run copied %d snapshots, want 1", stats["DiscoverySnapshot"].Copied)
+	}
+
+	verify, err = Verify(ctx, src, dst, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for kind, v := range verify {
+		if !v.OK() {
+			t.Errorf("%s: %+v", kind, v)
+		}
+	}
+	if v := verify["Device"]; v.Source != 10 || v.SourceChecksum != v.DestinationChecksum {
+		t.Errorf("devices: %+v", v)
+	}
+
+	// Resources are copied as they are, resourceVersions included
+	for _, uid := range []string{"dev-01", "dev-02", "dev-10"} {
+		want, _ := src.Load(ctx, "Device", uid)
+		got, err := dst.Load(ctx, "Device", uid)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if Checksum(got) != Checksum(want) || ResourceVersion(got) != ResourceVersion(want) {
+			t.Errorf("%s: copied as %s, want %s", uid, got, want)
+		}
+	}
+
+	// Running it again finds nothing to do
+	stats, err = Migrate(ctx, src, dst, MigrateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stats["Device"].Copied != 0 || stats["Device"].Unchanged != 10 {
+		t.Errorf("third run: %+v", stats["Device"])
+	}
+
+	// Verify notices a destination that drifted
+	if err := dst.Delete(ctx, "Device", "dev-03"); err != nil {
+		t.Fatal(err)
+	}
+	if err := dst.Save(ctx, "Device", "dev-99", json.RawMessage(`{"metadata":{"uid":"dev-99"}}`)); err != nil {
+		t.Fatal(err)
+	}
+	verify, err = Verify(ctx, src, dst, []string{"Device"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if v := verify["Device"]; v.OK() || fmt.Sprint(v.Missing) != "[dev-03]" || fmt.Sprint(v.Extra) != "[dev-99]" {
+		t.Errorf("after drift: %+v", v)
+	}
+}
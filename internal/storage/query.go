@@ -0,0 +1,133 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/internal/selector"
+	"github.com/user/inventory-api/pkg/resources/device"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+)
+
+// Sort orders of a ListQuery
+const (
+	SortByUID       = "uid"
+	SortByName      = "name"
+	SortByCreatedAt = "createdAt"
+	SortByUpdatedAt = "updatedAt"
+)
+
+// ErrUnsupportedQuery is returned when the backend can't run a ListQuery
+// itself; the caller should load everything and filter in memory instead.
+var ErrUnsupportedQuery = errors.New("query not supported by storage backend")
+
+// ListQuery filters, sorts and pages a list of resources.
+type ListQuery struct {
+	// Labels and Fields select resources. Field keys are JSON paths such as
+	// spec.deviceType; spec.properties.<key> compares a string property by its
+	// value and any other property by its compact JSON text.
+	Labels selector.Selector
+	Fields selector.Selector
+
+	// SortBy is one of the SortBy constants ("" sorts by UID); ties are broken by UID
+	SortBy     string
+	Descending bool
+
+	// Limit is the page size (0: no limit)
+	Limit int
+
+	// After starts the page after this sort key
+	After *ListCursor
+}
+
+// ListCursor is the sort key of the last resource of a page.
+type ListCursor struct {
+	Name string
+	Time int64 // createdAt or updatedAt in Unix nanoseconds, for those sort orders
+	UID  string
+}
+
+// ListResult is one page of a ListQuery.
+type ListResult struct {
+	Items []json.RawMessage
+
+	// Total is the number of resources matching the query, across all pages
+	Total int
+
+	// More reports whether there are resources after this page
+	More bool
+}
+
+// Querier is implemented by backends that filter, sort and page lists themselves.
+type Querier interface {
+	Query(ctx context.Context, resourceType string, q ListQuery) (*ListResult, error)
+}
+
+// unwrapper is implemented by backends that wrap another backend.
+type unwrapper interface {
+	Unwrap() fabricaStorage.StorageBackend
+}
+
+// findQuerier returns the Querier in a chain of wrapped backends, if any.
+// Reads pass through the wrappers unchanged, so querying the innermost
+// backend directly is safe.
+func findQuerier(backend fabricaStorage.StorageBackend) (Querier, bool) {
+	for backend != nil {
+		if q, ok := backend.(Querier); ok {
+			return q, true
+		}
+		w, ok := backend.(unwrapper)
+		if !ok {
+			break
+		}
+		backend = w.Unwrap()
+	}
+	return nil, false
+}
+
+// QueryDevices runs a ListQuery over Devices in the backend.
+//
+// Returns:
+//   - error: ErrUnsupportedQuery if the backend can't run it
+func QueryDevices(ctx context.Context, q ListQuery) ([]*device.Device, *ListResult, error) {
+	return queryTyped[device.Device](ctx, "Device", q)
+}
+
+// QueryDiscoverySnapshots runs a ListQuery over DiscoverySnapshots in the backend.
+//
+// Returns:
+//   - error: ErrUnsupportedQuery if the backend can't run it
+func QueryDiscoverySnapshots(ctx context.Context, q ListQuery) ([]*discoverysnapshot.DiscoverySnapshot, *ListResult, error) {
+	return queryTyped[discoverysnapshot.DiscoverySnapshot](ctx, "DiscoverySnapshot", q)
+}
+
+func queryTyped[T any](ctx context.Context, kind string, q ListQuery) ([]*T, *ListResult, error) {
+	ensureBackend()
+
+	querier, ok := findQuerier(Backend)
+	if !ok {
+		return nil, nil, ErrUnsupportedQuery
+	}
+	result, err := querier.Query(ctx, kind, q)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	items := make([]*T, 0, len(result.Items))
+	for _, raw := range result.Items {
+		item := new(T)
+		if err := json.Unmarshal(raw, item); err != nil {
+			return nil, nil, fmt.Errorf("failed to unmarshal %s: %w", kind, err)
+		}
+		items = append(items, item)
+	}
+	return items, result, nil
+}
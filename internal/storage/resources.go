@@ -0,0 +1,250 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+// Hand-written storage helpers. storage_generated.go is regenerated by
+// fabrica, so anything it doesn't emit lives here and wraps it: the
+// journaled file backend, writes that keep the new resourceVersion in the
+// caller's object, and the Subscription resource.
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openchami/fabrica/pkg/reconcile"
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+	"github.com/user/inventory-api/pkg/resources/subscription"
+)
+
+// InitJournaledFileBackend initializes file-based storage like
+// InitFileBackend, and finishes any batch of writes that was interrupted
+// (see JournaledFileBackend).
+func InitJournaledFileBackend(dataDir string) error {
+	backend, err := NewJournaledFileBackend(dataDir)
+	if err != nil {
+		return fmt.Errorf("failed to create file backend: %w", err)
+	}
+	Init(backend)
+	return nil
+}
+
+// SaveDeviceVersioned stores a Device like SaveDevice, as a compare-and-swap
+// on its resourceVersion, and sets the stored resourceVersion in its
+// metadata so it can be written again or returned to a client.
+func SaveDeviceVersioned(ctx context.Context, device *device.Device) error {
+	ensureBackend()
+
+	if err := saveVersioned(ctx, Backend, "Device", &device.Metadata, device); err != nil {
+		return fmt.Errorf("failed to save Device: %w", err)
+	}
+
+	return nil
+}
+
+// SaveDiscoverySnapshotVersioned stores a DiscoverySnapshot like
+// SaveDiscoverySnapshot, and sets the stored resourceVersion in its metadata
+// (see SaveDeviceVersioned).
+func SaveDiscoverySnapshotVersioned(ctx context.Context, discoverySnapshot *discoverysnapshot.DiscoverySnapshot) error {
+	ensureBackend()
+
+	if err := saveVersioned(ctx, Backend, "DiscoverySnapshot", &discoverySnapshot.Metadata, discoverySnapshot); err != nil {
+		return fmt.Errorf("failed to save DiscoverySnapshot: %w", err)
+	}
+
+	return nil
+}
+
+// VersionedClient is a StorageClient whose Update is a compare-and-swap on
+// the resource's resourceVersion and sets the stored resourceVersion in the
+// resource, so a reconciler can write the same object more than once.
+type VersionedClient struct {
+	*StorageClient
+}
+
+// Compile-time check that VersionedClient implements reconcile.ClientInterface
+var _ reconcile.ClientInterface = (*VersionedClient)(nil)
+
+// NewVersionedClient creates a VersionedClient that wraps the configured backend.
+func NewVersionedClient() *VersionedClient {
+	return &VersionedClient{StorageClient: NewStorageClient()}
+}
+
+// Update updates an existing resource. It fails with ErrConflict if the
+// resource changed since it was read.
+func (c *VersionedClient) Update(ctx context.Context, resource interface{}) error {
+	switch res := resource.(type) {
+	case *device.Device:
+		return saveVersioned(ctx, c.backend, "Device", &res.Metadata, res)
+	case *discoverysnapshot.DiscoverySnapshot:
+		return saveVersioned(ctx, c.backend, "DiscoverySnapshot", &res.Metadata, res)
+	case *subscription.Subscription:
+		return saveVersioned(ctx, c.backend, "Subscription", &res.Metadata, res)
+	default:
+		return fmt.Errorf("unknown resource type: %T", resource)
+	}
+}
+
+// Subscription storage operations
+
+// LoadAllSubscriptions retrieves all Subscription resources.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//
+// Returns:
+//   - []*subscription.Subscription: Slice of Subscription resources
+//   - error: Any error that occurred during loading
+func LoadAllSubscriptions(ctx context.Context) ([]*subscription.Subscription, error) {
+	ensureBackend()
+
+	rawData, err := Backend.LoadAll(ctx, "Subscription")
+	if err != nil {
+		return nil, fmt.Errorf("failed to load all subscriptions: %w", err)
+	}
+
+	subscriptions := make([]*subscription.Subscription, 0, len(rawData))
+	for _, raw := range rawData {
+		subscription := &subscription.Subscription{}
+		if err := json.Unmarshal(raw, subscription); err != nil {
+			return nil, fmt.Errorf("failed to unmarshal Subscription: %w", err)
+		}
+		subscriptions = append(subscriptions, subscription)
+	}
+
+	return subscriptions, nil
+}
+
+// LoadSubscription retrieves a single Subscription resource by UID.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - uid: Unique identifier of the Subscription resource
+//
+// Returns:
+//   - *subscription.Subscription: The Subscription resource
+//   - error: fabricaStorage.ErrNotFound if resource doesn't exist, other errors for failures
+func LoadSubscription(ctx context.Context, uid string) (*subscription.Subscription, error) {
+	ensureBackend()
+
+	rawData, err := Backend.Load(ctx, "Subscription", uid)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Subscription %s: %w", uid, err)
+	}
+
+	subscription := &subscription.Subscription{}
+	if err := json.Unmarshal(rawData, subscription); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal Subscription: %w", err)
+	}
+
+	return subscription, nil
+}
+
+// SaveSubscription stores a Subscription resource, as a compare-and-swap on its
+// resourceVersion (see SaveDeviceVersioned).
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - subscription: The Subscription resource to save
+//
+// Returns:
+//   - error: Any error that occurred during saving
+func SaveSubscription(ctx context.Context, subscription *subscription.Subscription) error {
+	ensureBackend()
+
+	if err := saveVersioned(ctx, Backend, "Subscription", &subscription.Metadata, subscription); err != nil {
+		return fmt.Errorf("failed to save Subscription: %w", err)
+	}
+
+	return nil
+}
+
+// UpdateSubscription updates an existing Subscription resource, as a
+// compare-and-swap on its resourceVersion.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - subscription: The Subscription resource to update
+//
+// Returns:
+//   - error: fabricaStorage.ErrNotFound if resource doesn't exist, other errors for failures
+func UpdateSubscription(ctx context.Context, subscription *subscription.Subscription) error {
+	ensureBackend()
+
+	// Check if resource exists first
+	exists, err := Backend.Exists(ctx, "Subscription", subscription.Metadata.UID)
+	if err != nil {
+		return fmt.Errorf("failed to check Subscription existence: %w", err)
+	}
+	if !exists {
+		return fabricaStorage.ErrNotFound
+	}
+
+	if err := saveVersioned(ctx, Backend, "Subscription", &subscription.Metadata, subscription); err != nil {
+		return fmt.Errorf("failed to update Subscription: %w", err)
+	}
+
+	return nil
+}
+
+// DeleteSubscription removes a Subscription resource by UID.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - uid: Unique identifier of the Subscription resource
+//
+// Returns:
+//   - error: fabricaStorage.ErrNotFound if resource doesn't exist, other errors for failures
+func DeleteSubscription(ctx context.Context, uid string) error {
+	ensureBackend()
+
+	if err := Backend.Delete(ctx, "Subscription", uid); err != nil {
+		return fmt.Errorf("failed to delete Subscription %s: %w", uid, err)
+	}
+
+	return nil
+}
+
+// ExistsSubscription checks if a Subscription resource exists.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//   - uid: Unique identifier of the Subscription resource
+//
+// Returns:
+//   - bool: true if the resource exists
+//   - error: Any error that occurred during the check
+func ExistsSubscription(ctx context.Context, uid string) (bool, error) {
+	ensureBackend()
+
+	exists, err := Backend.Exists(ctx, "Subscription", uid)
+	if err != nil {
+		return false, fmt.Errorf("failed to check Subscription existence: %w", err)
+	}
+
+	return exists, nil
+}
+
+// ListSubscriptionUIDs returns UIDs of all Subscription resources.
+//
+// Parameters:
+//   - ctx: Context for cancellation and timeouts
+//
+// Returns:
+//   - []string: Array of Subscription resource UIDs
+//   - error: Any error that occurred during listing
+func ListSubscriptionUIDs(ctx context.Context) ([]string, error) {
+	ensureBackend()
+
+	uids, err := Backend.List(ctx, "Subscription")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list Subscription UIDs: %w", err)
+	}
+
+	return uids, nil
+}
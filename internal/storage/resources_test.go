@@ -0,0 +1,53 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// TestVersionedWritesKeepResourceVersion checks that the hand-written
+// wrappers leave the stored resourceVersion in the object, so writing it
+// again isn't a conflict, while the generated SaveDevice still compares.
+func TestVersionedWritesKeepResourceVersion(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewVersionedBackend(ctx, newMemBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	previous := Backend
+	Init(backend)
+	t.Cleanup(func() { Init(previous) })
+
+	dev := newVersionedTestDevice("dev-1")
+	if err := SaveDeviceVersioned(ctx, dev); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	created := ResourceVersionOf(&dev.Metadata)
+	if created == "" {
+		t.Fatal("no resourceVersion set on create")
+	}
+	dev.Spec.DeviceType = "Switch"
+	if err := SaveDeviceVersioned(ctx, dev); err != nil {
+		t.Fatalf("second write of the same object: %v", err)
+	}
+	if ResourceVersionOf(&dev.Metadata) == created {
+		t.Error("resourceVersion unchanged by an update")
+	}
+
+	client := NewVersionedClient()
+	dev.Spec.DeviceType = "Node"
+	if err := client.Update(ctx, dev); err != nil {
+		t.Fatalf("client update: %v", err)
+	}
+
+	stale := newVersionedTestDevice("dev-1")
+	stale.Metadata.Annotations = map[string]string{ResourceVersionAnnotation: created}
+	if err := SaveDevice(ctx, stale); !errors.Is(err, ErrConflict) {
+		t.Errorf("generated SaveDevice with a stale version: got %v, want ErrConflict", err)
+	}
+}
@@ -0,0 +1,637 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/openchami/fabrica/pkg/resource"
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+	_ "modernc.org/sqlite" // pure-Go SQLite driver, registered as "sqlite"
+
+	"github.com/user/inventory-api/internal/selector"
+)
+
+// sqliteColumn is a column generated from a JSON path of the stored resource, with an index.
+type sqliteColumn struct {
+	Name string
+	Path string
+}
+
+// sqliteGeneratedColumns are the indexed columns of each kind's table beyond
+// the common ones (uid, name, created_at, updated_at, resource_version).
+var sqliteGeneratedColumns = map[string][]sqliteColumn{
+	"Device": {
+		{Name: "serial_number", Path: "$.spec.serialNumber"},
+		{Name: "parent_id", Path: "$.spec.parentID"},
+		{Name: "device_type", Path: "$.spec.deviceType"},
+	},
+}
+
+// sqliteDefaultPragmas apply to every connection unless the DSN sets its own
+// _pragma parameters: wait for locks instead of failing, and let readers run
+// alongside the writer.
+const sqliteDefaultPragmas = "_pragma=busy_timeout(10000)&_pragma=journal_mode(WAL)&_pragma=synchronous(NORMAL)"
+
+// SQLiteBackend stores resources in an embedded SQLite database, one table
+// per kind with the resource as a JSON column. Common metadata and the
+// columns in sqliteGeneratedColumns are indexed, and lists can be filtered,
+// sorted and paged in SQL (see Query).
+type SQLiteBackend struct {
+	db *sql.DB
+
+	mu     sync.Mutex
+	tables map[string]string // kind -> table, for tables known to exist
+}
+
+// Compile-time checks
+var (
+	_ fabricaStorage.StorageBackend = (*SQLiteBackend)(nil)
+	_ Querier                       = (*SQLiteBackend)(nil)
+	_ Snapshotter                   = (*SQLiteBackend)(nil)
+)
+
+// NewSQLiteBackend opens (creating if needed) the SQLite database at dsn,
+// e.g. "/var/lib/inventory/inventory.db" or "file:inventory.db?_pragma=...".
+func NewSQLiteBackend(ctx context.Context, dsn string) (*SQLiteBackend, error) {
+	if !strings.Contains(dsn, "_pragma=") {
+		if strings.Contains(dsn, "?") {
+			dsn += "&" + sqliteDefaultPragmas
+		} else {
+			dsn += "?" + sqliteDefaultPragmas
+		}
+	}
+	if !strings.Contains(dsn, "_txlock=") {
+		// Take the write lock when a transaction starts, not at its first write,
+		// so concurrent transactions queue on busy_timeout instead of deadlocking
+		dsn += "&_txlock=immediate"
+	}
+
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+	if sqliteInMemory(dsn) {
+		// Every connection to an in-memory database gets a database of its
+		// own, so the pool must keep to one connection to see one database
+		db.SetMaxOpenConns(1)
+	}
+	if err := db.PingContext(ctx); err != nil {
+		db.Close()
+		return nil, fmt.Errorf("failed to open sqlite database: %w", err)
+	}
+
+	b := &SQLiteBackend{db: db, tables: make(map[string]string)}
+	for _, kind := range ResourceKinds {
+		if _, err := b.table(ctx, kind); err != nil {
+			db.Close()
+			return nil, err
+		}
+	}
+	return b, nil
+}
+
+// InitSQLiteBackend initializes storage with an SQLite database.
+func InitSQLiteBackend(ctx context.Context, dsn string) error {
+	backend, err := NewSQLiteBackend(ctx, dsn)
+	if err != nil {
+		return fmt.Errorf("failed to create sqlite backend: %w", err)
+	}
+	Backend = backend
+	return nil
+}
+
+// sqliteInMemory reports whether a DSN opens an in-memory database
+// (":memory:", "file::memory:", "file:name?mode=memory" or the memdb VFS)
+func sqliteInMemory(dsn string) bool {
+	location, query, _ := strings.Cut(strings.TrimPrefix(dsn, "file:"), "?")
+	if location == "" || location == ":memory:" {
+		return true
+	}
+	params, err := url.ParseQuery(query)
+	if err != nil {
+		return false
+	}
+	return params.Get("mode") == "memory" || params.Get("vfs") == "memdb"
+}
+
+// tableName turns a kind into its table name: DiscoverySnapshot -> discovery_snapshots
+func tableName(kind string) string {
+	var b strings.Builder
+	for i, r := range kind {
+		if unicode.IsUpper(r) {
+			if i > 0 {
+				b.WriteByte('_')
+			}
+			r = unicode.ToLower(r)
+		}
+		b.WriteRune(r)
+	}
+	if !strings.HasSuffix(b.String(), "s") {
+		b.WriteByte('s')
+	}
+	return b.String()
+}
+
+// validKind keeps kinds (which become table names) to plain identifiers
+var validKind = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*$`)
+
+// table returns the table of a kind, creating it and its indexes the first time
+func (b *SQLiteBackend) table(ctx context.Context, kind string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if table, ok := b.tables[kind]; ok {
+		return table, nil
+	}
+	if !validKind.MatchString(kind) {
+		return "", fmt.Errorf("invalid resource type %q: %w", kind, fabricaStorage.ErrInvalidData)
+	}
+
+	table := tableName(kind)
+	columns := []string{
+		"uid TEXT PRIMARY KEY",
+		"name TEXT NOT NULL DEFAULT ''",
+		"created_at INTEGER NOT NULL DEFAULT 0",
+		"updated_at INTEGER NOT NULL DEFAULT 0",
+		"resource_version INTEGER NOT NULL DEFAULT 0",
+		"data TEXT NOT NULL CHECK (json_valid(data))",
+	}
+	indexes := []string{
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_name ON %[1]s (name, uid)", table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_created_at ON %[1]s (created_at, uid)", table),
+		fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_updated_at ON %[1]s (updated_at, uid)", table),
+	}
+	for _, col := range sqliteGeneratedColumns[kind] {
+		columns = append(columns, fmt.Sprintf("%s TEXT GENERATED ALWAYS AS (json_extract(data, '%s')) VIRTUAL", col.Name, col.Path))
+		indexes = append(indexes, fmt.Sprintf("CREATE INDEX IF NOT EXISTS %[1]s_%[2]s ON %[1]s (%[2]s)", table, col.Name))
+	}
+
+	stmts := append([]string{fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (\n\t%s\n)", table, strings.Join(columns, ",\n\t"))}, indexes...)
+	for _, stmt := range stmts {
+		if _, err := b.db.ExecContext(ctx, stmt); err != nil {
+			return "", fmt.Errorf("failed to create table %s: %w", table, err)
+		}
+	}
+	b.tables[kind] = table
+	return table, nil
+}
+
+// LoadAll implements StorageBackend.LoadAll.
+func (b *SQLiteBackend) LoadAll(ctx context.Context, resourceType string) ([]json.RawMessage, error) {
+	table, err := b.table(ctx, resourceType)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := b.db.QueryContext(ctx, fmt.Sprintf("SELECT data FROM %s ORDER BY uid", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s resources: %w", resourceType, err)
+	}
+	return scanData(rows)
+}
+
+// Load implements StorageBackend.Load.
+func (b *SQLiteBackend) Load(ctx context.Context, resourceType, uid string) (json.RawMessage, error) {
+	table, err := b.table(ctx, resourceType)
+	if err != nil {
+		return nil, err
+	}
+	var data string
+	err = b.db.QueryRowContext(ctx, fmt.Sprintf("SELECT data FROM %s WHERE uid = ?", table), uid).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, fabricaStorage.ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load %s %s: %w", resourceType, uid, err)
+	}
+	return json.RawMessage(data), nil
+}
+
+// Save implements StorageBackend.Save, inserting or replacing the resource.
+func (b *SQLiteBackend) Save(ctx context.Context, resourceType, uid string, data json.RawMessage) error {
+	return b.save(ctx, b.db, resourceType, uid, data)
+}
+
+// sqlExecer is satisfied by both *sql.DB and *sql.Tx
+type sqlExecer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+func (b *SQLiteBackend) save(ctx context.Context, exec sqlExecer, resourceType, uid string, data json.RawMessage) error {
+	table, err := b.table(ctx, resourceType)
+	if err != nil {
+		return err
+	}
+	var env struct {
+		Metadata resource.Metadata `json:"metadata"`
+	}
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("invalid JSON data: %w", fabricaStorage.ErrInvalidData)
+	}
+	version, _ := strconv.ParseInt(ResourceVersionOf(&env.Metadata), 10, 64)
+
+	_, err = exec.ExecContext(ctx, fmt.Sprintf(`INSERT INTO %s (uid, name, created_at, updated_at, resource_version, data)
+		VALUES (?, ?, ?, ?, ?, ?)
+		ON CONFLICT (uid) DO UPDATE SET name = excluded.name, created_at = excluded.created_at,
+			updated_at = excluded.updated_at, resource_version = excluded.resource_version, data = excluded.data`, table),
+		uid, env.Metadata.Name, env.Metadata.CreatedAt.UnixNano(), env.Metadata.UpdatedAt.UnixNano(), version, string(data))
+	if err != nil {
+		return fmt.Errorf("failed to save %s %s: %w", resourceType, uid, err)
+	}
+	return nil
+}
+
+// Delete implements StorageBackend.Delete.
+func (b *SQLiteBackend) Delete(ctx context.Context, resourceType, uid string) error {
+	return b.delete(ctx, b.db, resourceType, uid)
+}
+
+func (b *SQLiteBackend) delete(ctx context.Context, exec sqlExecer, resourceType, uid string) error {
+	table, err := b.table(ctx, resourceType)
+	if err != nil {
+		return err
+	}
+	result, err := exec.ExecContext(ctx, fmt.Sprintf("DELETE FROM %s WHERE uid = ?", table), uid)
+	if err != nil {
+		return fmt.Errorf("failed to delete %s %s: %w", resourceType, uid, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return fabricaStorage.ErrNotFound
+	}
+	return nil
+}
+
+// Exists implements StorageBackend.Exists.
+func (b *SQLiteBackend) Exists(ctx context.Context, resourceType, uid string) (bool, error) {
+	table, err := b.table(ctx, resourceType)
+	if err != nil {
+		return false, err
+	}
+	var one int
+	err = b.db.QueryRowContext(ctx, fmt.Sprintf("SELECT 1 FROM %s WHERE uid = ?", table), uid).Scan(&one)
+	if errors.Is(err, sql.ErrNoRows) {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to check %s %s: %w", resourceType, uid, err)
+	}
+	return true, nil
+}
+
+// List implements StorageBackend.List, returning UIDs in order.
+func (b *SQLiteBackend) List(ctx context.Context, resourceType string) ([]string, error) {
+	table, err := b.table(ctx, resourceType)
+	if err != nil {
+		return nil, err
+	}
+	rows, err := b.db.QueryContext(ctx, fmt.Sprintf("SELECT uid FROM %s ORDER BY uid", table))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list %s resources: %w", resourceType, err)
+	}
+	defer rows.Close()
+
+	var uids []string
+	for rows.Next() {
+		var uid string
+		if err := rows.Scan(&uid); err != nil {
+			return nil, err
+		}
+		uids = append(uids, uid)
+	}
+	return uids, rows.Err()
+}
+
+// Snapshot implements Snapshotter, reading every table in one transaction.
+func (b *SQLiteBackend) Snapshot(ctx context.Context, kinds []string) ([]StoredResource, []string, error) {
+	tables := make([]string, len(kinds))
+	for i, kind := range kinds {
+		table, err := b.table(ctx, kind)
+		if err != nil {
+			return nil, nil, err
+		}
+		tables[i] = table
+	}
+
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var resources []StoredResource
+	for i, kind := range kinds {
+		rows, err := tx.QueryContext(ctx, fmt.Sprintf("SELECT uid, data FROM %s ORDER BY uid", tables[i]))
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to load %s resources: %w", kind, err)
+		}
+		for rows.Next() {
+			var uid, data string
+			if err := rows.Scan(&uid, &data); err != nil {
+				rows.Close()
+				return nil, nil, err
+			}
+			resources = append(resources, StoredResource{Kind: kind, UID: uid, Data: json.RawMessage(data)})
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return nil, nil, fmt.Errorf("failed to load %s resources: %w", kind, err)
+		}
+	}
+	return resources, nil, tx.Commit()
+}
+
+// Close implements StorageBackend.Close.
+func (b *SQLiteBackend) Close() error {
+	return b.db.Close()
+}
+
+// LoadWithVersion implements StorageBackend.LoadWithVersion. Resources are
+// stored in a single schema version, so only that ("" or v1) can be loaded.
+func (b *SQLiteBackend) LoadWithVersion(ctx context.Context, resourceType, uid, version string) (json.RawMessage, string, error) {
+	if version != "" && version != "v1" {
+		return nil, "", fmt.Errorf("unsupported version %s for %s", version, resourceType)
+	}
+	data, err := b.Load(ctx, resourceType, uid)
+	return data, "v1", err
+}
+
+// LoadAllWithVersion implements StorageBackend.LoadAllWithVersion (see LoadWithVersion).
+func (b *SQLiteBackend) LoadAllWithVersion(ctx context.Context, resourceType, version string) ([]json.RawMessage, error) {
+	if version != "" && version != "v1" {
+		return nil, fmt.Errorf("unsupported version %s for %s", version, resourceType)
+	}
+	return b.LoadAll(ctx, resourceType)
+}
+
+// SaveWithVersion implements StorageBackend.SaveWithVersion (see LoadWithVersion).
+func (b *SQLiteBackend) SaveWithVersion(ctx context.Context, resourceType, uid string, data json.RawMessage, version string) error {
+	if version != "" && version != "v1" {
+		return fmt.Errorf("unsupported version %s for %s", version, resourceType)
+	}
+	return b.Save(ctx, resourceType, uid, data)
+}
+
+// MaxResourceVersion returns the highest resourceVersion stored in any table,
+// so versioning can be seeded without loading every resource.
+func (b *SQLiteBackend) MaxResourceVersion(ctx context.Context) (uint64, error) {
+	var max uint64
+	for _, kind := range ResourceKinds {
+		table, err := b.table(ctx, kind)
+		if err != nil {
+			return 0, err
+		}
+		var v sql.NullInt64
+		if err := b.db.QueryRowContext(ctx, fmt.Sprintf("SELECT MAX(resource_version) FROM %s", table)).Scan(&v); err != nil {
+			return 0, fmt.Errorf("failed to read resourceVersion of %s: %w", table, err)
+		}
+		if v.Valid && uint64(v.Int64) > max {
+			max = uint64(v.Int64)
+		}
+	}
+	return max, nil
+}
+
+// Query implements Querier: the selection, sort order and page are all done in SQL.
+func (b *SQLiteBackend) Query(ctx context.Context, resourceType string, q ListQuery) (*ListResult, error) {
+	table, err := b.table(ctx, resourceType)
+	if err != nil {
+		return nil, err
+	}
+
+	var conds []string
+	var args []any
+	for _, req := range q.Labels {
+		cond, condArgs, err := labelCondition(req)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	for _, req := range q.Fields {
+		cond, condArgs, err := fieldCondition(resourceType, req)
+		if err != nil {
+			return nil, err
+		}
+		conds = append(conds, cond)
+		args = append(args, condArgs...)
+	}
+	where := "1"
+	if len(conds) > 0 {
+		where = strings.Join(conds, " AND ")
+	}
+
+	// Count and page from the same snapshot of the table
+	tx, err := b.db.BeginTx(ctx, &sql.TxOptions{ReadOnly: true})
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s resources: %w", resourceType, err)
+	}
+	defer tx.Rollback()
+
+	result := &ListResult{}
+	if err := tx.QueryRowContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s WHERE %s", table, where), args...).Scan(&result.Total); err != nil {
+		return nil, fmt.Errorf("failed to count %s resources: %w", resourceType, err)
+	}
+
+	sortColumn := map[string]string{
+		"":              "",
+		SortByUID:       "",
+		SortByName:      "name",
+		SortByCreatedAt: "created_at",
+		SortByUpdatedAt: "updated_at",
+	}
+	column, ok := sortColumn[q.SortBy]
+	if !ok {
+		return nil, fmt.Errorf("unknown sort order %q: %w", q.SortBy, ErrUnsupportedQuery)
+	}
+	direction, compare := "ASC", ">"
+	if q.Descending {
+		direction, compare = "DESC", "<"
+	}
+
+	pageWhere, pageArgs := where, append([]any(nil), args...)
+	if q.After != nil {
+		// Keyset pagination: start after the last row of the previous page
+		switch column {
+		case "":
+			pageWhere += fmt.Sprintf(" AND uid %s ?", compare)
+			pageArgs = append(pageArgs, q.After.UID)
+		case "name":
+			pageWhere += fmt.Sprintf(" AND (name, uid) %s (?, ?)", compare)
+			pageArgs = append(pageArgs, q.After.Name, q.After.UID)
+		default:
+			pageWhere += fmt.Sprintf(" AND (%s, uid) %s (?, ?)", column, compare)
+			pageArgs = append(pageArgs, q.After.Time, q.After.UID)
+		}
+	}
+	orderBy := "uid " + direction
+	if column != "" {
+		orderBy = fmt.Sprintf("%s %s, uid %s", column, direction, direction)
+	}
+	stmt := fmt.Sprintf("SELECT data FROM %s WHERE %s ORDER BY %s", table, pageWhere, orderBy)
+	if q.Limit > 0 {
+		// One extra row tells whether there is another page
+		stmt += fmt.Sprintf(" LIMIT %d", q.Limit+1)
+	}
+
+	rows, err := tx.QueryContext(ctx, stmt, pageArgs...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s resources: %w", resourceType, err)
+	}
+	result.Items, err = scanData(rows)
+	if err != nil {
+		return nil, fmt.Errorf("failed to query %s resources: %w", resourceType, err)
+	}
+	if q.Limit > 0 && len(result.Items) > q.Limit {
+		result.Items = result.Items[:q.Limit]
+		result.More = true
+	}
+	return result, nil
+}
+
+// scanData reads a single data column from every row and closes rows
+func scanData(rows *sql.Rows) ([]json.RawMessage, error) {
+	defer rows.Close()
+	var items []json.RawMessage
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		items = append(items, json.RawMessage(data))
+	}
+	return items, rows.Err()
+}
+
+// jsonPathKey quotes a map key for a JSON path. Keys with quotes or
+// backslashes can't be expressed, so those queries fall back to memory.
+func jsonPathKey(key string) (string, error) {
+	if strings.ContainsAny(key, `"\`) {
+		return "", fmt.Errorf("key %q: %w", key, ErrUnsupportedQuery)
+	}
+	return `"` + key + `"`, nil
+}
+
+// labelCondition translates a label requirement to SQL
+func labelCondition(req selector.Requirement) (string, []any, error) {
+	key, err := jsonPathKey(req.Key)
+	if err != nil {
+		return "", nil, err
+	}
+	path := "$.metadata.labels." + key
+	return requirementCondition(req, "json_extract(data, ?)", []any{path}, "json_type(data, ?) IS NOT NULL", []any{path})
+}
+
+// fieldPath matches the plain field paths fieldCondition accepts (spec.deviceType, status.phase, ...)
+var fieldPath = regexp.MustCompile(`^[A-Za-z][A-Za-z0-9]*(\.[A-Za-z][A-Za-z0-9]*)*$`)
+
+// fieldCondition translates a field requirement to SQL, with the same
+// semantics as the in-memory filter: plain fields are always present ("" if
+// unset) and properties are missing when unset.
+func fieldCondition(resourceType string, req selector.Requirement) (string, []any, error) {
+	if key, ok := strings.CutPrefix(req.Key, "spec.properties."); ok {
+		quoted, err := jsonPathKey(key)
+		if err != nil {
+			return "", nil, err
+		}
+		path := "$.spec.properties." + quoted
+		// A string compares by its value, null as "", anything else by its JSON text
+		value := "(CASE json_type(data, ?) WHEN 'text' THEN json_extract(data, ?) WHEN 'null' THEN '' ELSE data -> ? END)"
+		return requirementCondition(req, value, []any{path, path, path}, "json_type(data, ?) IS NOT NULL", []any{path})
+	}
+	if !fieldPath.MatchString(req.Key) {
+		return "", nil, fmt.Errorf("field %q: %w", req.Key, ErrUnsupportedQuery)
+	}
+
+	path := "$." + req.Key
+	for _, col := range sqliteGeneratedColumns[resourceType] {
+		if col.Path == path {
+			if req.Operator == selector.Equals && req.Values[0] != "" {
+				// A bare comparison can use the column's index
+				return col.Name + " = ?", []any{req.Values[0]}, nil
+			}
+			return requirementCondition(req, fmt.Sprintf("COALESCE(%s, '')", col.Name), nil, "1", nil)
+		}
+	}
+	return requirementCondition(req, "COALESCE(json_extract(data, ?), '')", []any{path}, "1", nil)
+}
+
+// requirementCondition builds the SQL for a requirement from an expression
+// for the key's value and one for whether the key is set
+func requirementCondition(req selector.Requirement, value string, valueArgs []any, exists string, existsArgs []any) (string, []any, error) {
+	var args []any
+	switch req.Operator {
+	case selector.Equals:
+		args = append(append(append(args, existsArgs...), valueArgs...), req.Values[0])
+		return fmt.Sprintf("(%s AND %s = ?)", exists, value), args, nil
+	case selector.NotEquals:
+		args = append(append(append(args, existsArgs...), valueArgs...), req.Values[0])
+		return fmt.Sprintf("(NOT %s OR %s <> ?)", exists, value), args, nil
+	case selector.In, selector.NotIn:
+		args = append(append(args, existsArgs...), valueArgs...)
+		placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(req.Values)), ", ")
+		for _, v := range req.Values {
+			args = append(args, v)
+		}
+		if req.Operator == selector.In {
+			return fmt.Sprintf("(%s AND %s IN (%s))", exists, value, placeholders), args, nil
+		}
+		return fmt.Sprintf("(NOT %s OR %s NOT IN (%s))", exists, value, placeholders), args, nil
+	case selector.Exists:
+		return exists, existsArgs, nil
+	case selector.DoesNotExist:
+		return "NOT " + exists, existsArgs, nil
+	}
+	return "", nil, fmt.Errorf("operator %q: %w", req.Operator, ErrUnsupportedQuery)
+}
+
+// Quarantine implements Quarantiner: it moves a resource's row into the
+// quarantine table, where it can be inspected or moved back by hand.
+func (b *SQLiteBackend) Quarantine(ctx context.Context, kind, uid string) (string, error) {
+	table, err := b.table(ctx, kind)
+	if err != nil {
+		return "", err
+	}
+	if _, err := b.db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS quarantine (
+	kind TEXT NOT NULL,
+	uid TEXT NOT NULL,
+	data TEXT NOT NULL,
+	quarantined_at INTEGER NOT NULL
+)`); err != nil {
+		return "", fmt.Errorf("failed to create quarantine table: %w", err)
+	}
+
+	tx, err := b.db.BeginTx(ctx, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	result, err := tx.ExecContext(ctx, fmt.Sprintf(`INSERT INTO quarantine (kind, uid, data, quarantined_at)
+		SELECT ?, uid, data, unixepoch() FROM %s WHERE uid = ?`, table), kind, uid)
+	if err != nil {
+		return "", fmt.Errorf("failed to quarantine %s %s: %w", kind, uid, err)
+	}
+	if n, err := result.RowsAffected(); err == nil && n == 0 {
+		return "", fabricaStorage.ErrNotFound
+	}
+	if err := b.delete(ctx, tx, kind, uid); err != nil {
+		return "", err
+	}
+	if err := tx.Commit(); err != nil {
+		return "", fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return "quarantine table", nil
+}
@@ -0,0 +1,63 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"testing"
+	"time"
+)
+
+// TestSQLiteInMemory checks that every query on an in-memory database sees
+// the same database, however many run at once.
+func TestSQLiteInMemory(t *testing.T) {
+	ctx := context.Background()
+	for _, dsn := range []string{":memory:", "file::memory:", "file:inventory?mode=memory"} {
+		t.Run(dsn, func(t *testing.T) {
+			b, err := NewSQLiteBackend(ctx, dsn)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer b.Close()
+
+			// Hold a connection, as a running query does, while others are used
+			conn, err := b.db.Conn(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			saved := make(chan error, 20)
+			for i := 0; i < 20; i++ {
+				go func(i int) {
+					uid := fmt.Sprintf("dev-%02d", i)
+					saved <- b.Save(ctx, "Device", uid, json.RawMessage(fmt.Sprintf(`{"kind":"Device","metadata":{"uid":%q}}`, uid)))
+				}(i)
+			}
+			time.Sleep(20 * time.Millisecond)
+			conn.Close()
+			for i := 0; i < 20; i++ {
+				if err := <-saved; err != nil {
+					t.Error(err)
+				}
+			}
+
+			uids, err := b.List(ctx, "Device")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(uids) != 20 {
+				t.Errorf("%d devices listed, want 20", len(uids))
+			}
+			result, err := b.Query(ctx, "Device", ListQuery{Limit: 5})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if result.Total != 20 || len(result.Items) != 5 || !result.More {
+				t.Errorf("query: total %d, %d items, more %v", result.Total, len(result.Items), result.More)
+			}
+		})
+	}
+}
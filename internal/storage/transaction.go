@@ -0,0 +1,154 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/openchami/fabrica/pkg/resource"
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+	"github.com/user/inventory-api/pkg/resources/subscription"
+)
+
+// Transaction stages creates, updates and deletes and commits them together:
+// either every write is stored or none is.
+//
+// Each write is a compare-and-swap on the resourceVersion the resource had
+// when it was first staged, so Commit fails with ErrConflict (and writes
+// nothing) if any of them changed in the meantime. Resources are encoded
+// at Commit, so changes made to a staged resource after staging it are
+// included. A Transaction is not safe for concurrent use and is committed once.
+type Transaction struct {
+	backend fabricaStorage.StorageBackend
+
+	writes []*stagedWrite
+	byKey  map[string]*stagedWrite
+}
+
+// stagedWrite is one write of a transaction
+type stagedWrite struct {
+	kind     string
+	uid      string
+	expected string
+	meta     *resource.Metadata // nil for deletes
+	obj      interface{}
+}
+
+// Begin starts a transaction on the client's backend.
+func (c *StorageClient) Begin() *Transaction {
+	return &Transaction{backend: c.backend, byKey: make(map[string]*stagedWrite)}
+}
+
+// Create stages a new resource. Commit fails with ErrConflict if it has
+// been created (with a resourceVersion) in the meantime.
+func (tx *Transaction) Create(resource interface{}) error {
+	return tx.Update(resource)
+}
+
+// Update stages a write of a resource. Staging the same resource again
+// replaces the earlier write but keeps its expected resourceVersion.
+func (tx *Transaction) Update(resource interface{}) error {
+	kind, meta, err := resourceMetadata(resource)
+	if err != nil {
+		return err
+	}
+	tx.stage(&stagedWrite{kind: kind, uid: meta.UID, expected: ResourceVersionOf(meta), meta: meta, obj: resource})
+	return nil
+}
+
+// Delete stages the deletion of a resource, conditional on it being at the
+// expected resourceVersion ("" deletes unconditionally).
+func (tx *Transaction) Delete(kind, uid, expected string) {
+	tx.stage(&stagedWrite{kind: kind, uid: uid, expected: expected})
+}
+
+// Len returns the number of staged writes.
+func (tx *Transaction) Len() int {
+	return len(tx.writes)
+}
+
+func (tx *Transaction) stage(w *stagedWrite) {
+	key := w.kind + "/" + w.uid
+	if prev, ok := tx.byKey[key]; ok {
+		w.expected = prev.expected
+		*prev = *w
+		return
+	}
+	tx.byKey[key] = w
+	tx.writes = append(tx.writes, w)
+}
+
+// Commit stores every staged write atomically. On success each staged
+// resource carries its new resourceVersion.
+//
+// Returns:
+//   - error: ErrConflict if a resource changed since it was staged, fabricaStorage.ErrNotFound
+//     if one with an expected version no longer exists; nothing is written in either case.
+//     ErrNoBatches if the backend can't commit atomically (it isn't versioned, or its storage
+//     isn't a Batcher); nothing is written then either.
+func (tx *Transaction) Commit(ctx context.Context) error {
+	if len(tx.writes) == 0 {
+		return nil
+	}
+
+	writer, ok := tx.backend.(batchWriter)
+	if !ok {
+		return ErrNoBatches
+	}
+
+	batch := make([]BatchWrite, 0, len(tx.writes))
+	for _, w := range tx.writes {
+		bw := BatchWrite{Kind: w.kind, UID: w.uid, Expected: w.expected}
+		if w.meta != nil {
+			bw.Build = func(version string) (json.RawMessage, error) {
+				if w.meta.Annotations == nil {
+					w.meta.Annotations = make(map[string]string)
+				}
+				w.meta.Annotations[ResourceVersionAnnotation] = version
+				data, err := json.Marshal(w.obj)
+				if err != nil {
+					return nil, fmt.Errorf("failed to marshal %s: %w", w.kind, err)
+				}
+				return data, nil
+			}
+		}
+		batch = append(batch, bw)
+	}
+
+	if err := writer.CompareAndSwapBatch(ctx, batch); err != nil {
+		// Put back the versions the resources were read at, so the caller can retry
+		for _, w := range tx.writes {
+			if w.meta == nil {
+				continue
+			}
+			if w.expected == "" {
+				delete(w.meta.Annotations, ResourceVersionAnnotation)
+			} else {
+				w.meta.Annotations[ResourceVersionAnnotation] = w.expected
+			}
+		}
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// resourceMetadata returns the kind and metadata of a typed resource
+func resourceMetadata(resource interface{}) (string, *resource.Metadata, error) {
+	switch res := resource.(type) {
+	case *device.Device:
+		return "Device", &res.Metadata, nil
+	case *discoverysnapshot.DiscoverySnapshot:
+		return "DiscoverySnapshot", &res.Metadata, nil
+	case *subscription.Subscription:
+		return "Subscription", &res.Metadata, nil
+	default:
+		return "", nil, fmt.Errorf("unknown resource type: %T", resource)
+	}
+}
@@ -0,0 +1,54 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+)
+
+// unbatchedBackend hides memBackend's ApplyBatch
+type unbatchedBackend struct {
+	fabricaStorage.StorageBackend
+}
+
+// TestCommitNeedsAtomicBatches checks that a transaction on a backend that
+// can't apply it atomically fails with ErrNoBatches and writes nothing,
+// instead of writing one resource at a time.
+func TestCommitNeedsAtomicBatches(t *testing.T) {
+	ctx := context.Background()
+	versionedUnbatched, err := NewVersionedBackend(ctx, unbatchedBackend{newMemBackend()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	indexedUnversioned, err := NewIndexedBackend(ctx, newMemBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	backends := map[string]fabricaStorage.StorageBackend{
+		"unversioned":           newMemBackend(),
+		"versioned, no batches": versionedUnbatched,
+		"indexed, unversioned":  indexedUnversioned,
+	}
+	for name, backend := range backends {
+		tx := (&StorageClient{backend: backend}).Begin()
+		dev := newVersionedTestDevice("dev-1")
+		if err := tx.Create(dev); err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(ctx); !errors.Is(err, ErrNoBatches) {
+			t.Errorf("%s: Commit got %v, want ErrNoBatches", name, err)
+		}
+		if exists, _ := backend.Exists(ctx, "Device", "dev-1"); exists {
+			t.Errorf("%s: a failed commit wrote dev-1", name)
+		}
+		if ResourceVersionOf(&dev.Metadata) != "" {
+			t.Errorf("%s: a failed commit left a resourceVersion", name)
+		}
+	}
+}
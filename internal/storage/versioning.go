@@ -0,0 +1,290 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+
+	"github.com/openchami/fabrica/pkg/resource"
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+)
+
+// ResourceVersionAnnotation is the metadata annotation that carries a
+// resource's resourceVersion.
+//
+// fabrica's resource.Metadata has no resourceVersion field, so the storage
+// layer keeps it in this reserved annotation. It is set on every write and
+// must not be edited by hand: the API rejects it on creates, and on updates
+// accepts it only as the version the client read (the write then applies
+// only if the stored resource is still at that version).
+const ResourceVersionAnnotation = "inventory.openchami.io/resource-version"
+
+// ResourceKinds lists every resource kind stored by this service.
+var ResourceKinds = []string{"Device", "DiscoverySnapshot", "Subscription"}
+
+// ErrConflict is returned when a write's expected resourceVersion no longer
+// matches the stored resource (another writer got there first).
+var ErrConflict = errors.New("resource version conflict")
+
+// storedEnvelope decodes a stored resource without interpreting its spec or status,
+// so the metadata can be rewritten while keeping the rest byte-for-byte.
+type storedEnvelope struct {
+	resource.Resource
+	Spec   json.RawMessage `json:"spec,omitempty"`
+	Status json.RawMessage `json:"status,omitempty"`
+}
+
+// ResourceVersion returns the resourceVersion recorded in stored resource JSON,
+// or "" if it has none.
+func ResourceVersion(data json.RawMessage) string {
+	var env storedEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return ""
+	}
+	return env.Metadata.Annotations[ResourceVersionAnnotation]
+}
+
+// ResourceVersionOf returns the resourceVersion carried by a resource's metadata.
+func ResourceVersionOf(meta *resource.Metadata) string {
+	return meta.Annotations[ResourceVersionAnnotation]
+}
+
+// VersionedBackend wraps a StorageBackend and gives every write a
+// monotonically increasing resourceVersion with compare-and-swap semantics.
+//
+// The version counter is global across kinds and is seeded at startup from
+// the highest resourceVersion already in storage.
+type VersionedBackend struct {
+	fabricaStorage.StorageBackend
+
+	mu      sync.Mutex // serializes the read-compare-write of every write
+	current uint64     // last resourceVersion handed out
+}
+
+// Compile-time check that VersionedBackend implements fabricaStorage.StorageBackend
+var _ fabricaStorage.StorageBackend = (*VersionedBackend)(nil)
+
+// maxVersioner is implemented by backends that can report the highest stored
+// resourceVersion without loading every resource.
+type maxVersioner interface {
+	MaxResourceVersion(ctx context.Context) (uint64, error)
+}
+
+// NewVersionedBackend wraps backend and seeds the version counter from the
+// resources of every kind in ResourceKinds.
+func NewVersionedBackend(ctx context.Context, backend fabricaStorage.StorageBackend) (*VersionedBackend, error) {
+	b := &VersionedBackend{StorageBackend: backend}
+	if seeder, ok := backend.(maxVersioner); ok {
+		max, err := seeder.MaxResourceVersion(ctx)
+		if err != nil {
+			return nil, err
+		}
+		b.current = max
+		return b, nil
+	}
+	for _, kind := range ResourceKinds {
+		rawData, err := backend.LoadAll(ctx, kind)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load %s resources: %w", kind, err)
+		}
+		for _, raw := range rawData {
+			if v, err := strconv.ParseUint(ResourceVersion(raw), 10, 64); err == nil && v > b.current {
+				b.current = v
+			}
+		}
+	}
+	return b, nil
+}
+
+// currentVersion returns the stored resourceVersion of a resource, or "" if it doesn't exist.
+func (b *VersionedBackend) currentVersion(ctx context.Context, resourceType, uid string) (string, bool, error) {
+	raw, err := b.StorageBackend.Load(ctx, resourceType, uid)
+	if err != nil {
+		if errors.Is(err, fabricaStorage.ErrNotFound) {
+			return "", false, nil
+		}
+		return "", false, err
+	}
+	return ResourceVersion(raw), true, nil
+}
+
+// CompareAndSwap writes a resource only if its stored resourceVersion equals expected.
+//
+// build is called with the new resourceVersion and must return the resource
+// JSON carrying that version. There are no unconditional writes: an empty
+// expected version creates the resource, and is a conflict if it already
+// exists with a version (a client that drops the version doesn't get to
+// overwrite whatever is stored).
+//
+// Returns:
+//   - error: ErrConflict if the stored version differs from expected
+func (b *VersionedBackend) CompareAndSwap(ctx context.Context, resourceType, uid, expected string, build func(version string) (json.RawMessage, error)) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.checkVersion(ctx, resourceType, uid, expected); err != nil {
+		return err
+	}
+
+	next := strconv.FormatUint(b.current+1, 10)
+	data, err := build(next)
+	if err != nil {
+		return err
+	}
+	if err := b.StorageBackend.Save(ctx, resourceType, uid, data); err != nil {
+		return err
+	}
+	b.current++
+	return nil
+}
+
+// checkVersion returns ErrConflict unless the stored resource is at expected.
+// An empty expected version is for creates: the resource must not exist.
+// Resources written before versioning existed have no version to compare,
+// so they match any expected version.
+func (b *VersionedBackend) checkVersion(ctx context.Context, resourceType, uid, expected string) error {
+	current, exists, err := b.currentVersion(ctx, resourceType, uid)
+	if err != nil {
+		return err
+	}
+	switch {
+	case expected == "" && current != "":
+		return fmt.Errorf("%s %s already exists at resourceVersion %s: %w", resourceType, uid, current, ErrConflict)
+	case expected != "" && !exists:
+		return fmt.Errorf("%s %s: %w", resourceType, uid, fabricaStorage.ErrNotFound)
+	case current != "" && current != expected:
+		return fmt.Errorf("%s %s is at resourceVersion %s, not %s: %w", resourceType, uid, current, expected, ErrConflict)
+	}
+	return nil
+}
+
+// CompareAndDelete deletes a resource only if its stored resourceVersion equals expected.
+// An empty expected version deletes unconditionally: unlike a write, a
+// delete can't hide a change it didn't see behind its own.
+func (b *VersionedBackend) CompareAndDelete(ctx context.Context, resourceType, uid, expected string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if expected != "" {
+		current, exists, err := b.currentVersion(ctx, resourceType, uid)
+		if err != nil {
+			return err
+		}
+		if !exists {
+			return fabricaStorage.ErrNotFound
+		}
+		if current != "" && current != expected {
+			return fmt.Errorf("%s %s is at resourceVersion %s, not %s: %w", resourceType, uid, current, expected, ErrConflict)
+		}
+	}
+	return b.StorageBackend.Delete(ctx, resourceType, uid)
+}
+
+// Save implements StorageBackend.Save.
+// The resourceVersion inside data is the expected version; the stored copy gets a new one.
+func (b *VersionedBackend) Save(ctx context.Context, resourceType, uid string, data json.RawMessage) error {
+	var env storedEnvelope
+	if err := json.Unmarshal(data, &env); err != nil {
+		return fmt.Errorf("invalid JSON data: %w", fabricaStorage.ErrInvalidData)
+	}
+	return b.CompareAndSwap(ctx, resourceType, uid, ResourceVersionOf(&env.Metadata), func(version string) (json.RawMessage, error) {
+		if env.Metadata.Annotations == nil {
+			env.Metadata.Annotations = make(map[string]string)
+		}
+		env.Metadata.Annotations[ResourceVersionAnnotation] = version
+		return json.Marshal(&env)
+	})
+}
+
+// Delete implements StorageBackend.Delete.
+func (b *VersionedBackend) Delete(ctx context.Context, resourceType, uid string) error {
+	return b.CompareAndDelete(ctx, resourceType, uid, "")
+}
+
+// versionedWriter is implemented by backends that support compare-and-swap writes.
+type versionedWriter interface {
+	CompareAndSwap(ctx context.Context, resourceType, uid, expected string, build func(version string) (json.RawMessage, error)) error
+	CompareAndDelete(ctx context.Context, resourceType, uid, expected string) error
+}
+
+// InitVersioning wraps the current Backend in a VersionedBackend.
+// Call it after Init or InitJournaledFileBackend and before InitIndex.
+func InitVersioning(ctx context.Context) error {
+	ensureBackend()
+
+	versioned, err := NewVersionedBackend(ctx, Backend)
+	if err != nil {
+		return fmt.Errorf("failed to initialize resource versioning: %w", err)
+	}
+	Backend = versioned
+	return nil
+}
+
+// saveVersioned writes obj with compare-and-swap on the resourceVersion in meta,
+// and on success leaves the new resourceVersion in meta.
+//
+// Backends without compare-and-swap support get a plain Save.
+func saveVersioned(ctx context.Context, backend fabricaStorage.StorageBackend, resourceType string, meta *resource.Metadata, obj interface{}) error {
+	writer, ok := backend.(versionedWriter)
+	if !ok {
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return fmt.Errorf("failed to marshal %s: %w", resourceType, err)
+		}
+		return backend.Save(ctx, resourceType, meta.UID, data)
+	}
+
+	expected := ResourceVersionOf(meta)
+	err := writer.CompareAndSwap(ctx, resourceType, meta.UID, expected, func(version string) (json.RawMessage, error) {
+		if meta.Annotations == nil {
+			meta.Annotations = make(map[string]string)
+		}
+		meta.Annotations[ResourceVersionAnnotation] = version
+		data, err := json.Marshal(obj)
+		if err != nil {
+			return nil, fmt.Errorf("failed to marshal %s: %w", resourceType, err)
+		}
+		return data, nil
+	})
+	if err != nil && expected != ResourceVersionOf(meta) {
+		// Put back the version the caller read, so a retry compares against it
+		if expected == "" {
+			delete(meta.Annotations, ResourceVersionAnnotation)
+		} else {
+			meta.Annotations[ResourceVersionAnnotation] = expected
+		}
+	}
+	return err
+}
+
+// DeleteIfVersion deletes a resource only if it is still at the expected resourceVersion.
+// An empty expected version deletes unconditionally.
+//
+// Returns:
+//   - error: ErrConflict if the resource has changed, fabricaStorage.ErrNotFound if it doesn't exist
+func DeleteIfVersion(ctx context.Context, resourceType, uid, expected string) error {
+	ensureBackend()
+
+	if writer, ok := Backend.(versionedWriter); ok {
+		if err := writer.CompareAndDelete(ctx, resourceType, uid, expected); err != nil {
+			return fmt.Errorf("failed to delete %s %s: %w", resourceType, uid, err)
+		}
+		return nil
+	}
+	if err := Backend.Delete(ctx, resourceType, uid); err != nil {
+		return fmt.Errorf("failed to delete %s %s: %w", resourceType, uid, err)
+	}
+	return nil
+}
+
+// Unwrap returns the wrapped backend.
+func (b *VersionedBackend) Unwrap() fabricaStorage.StorageBackend {
+	return b.StorageBackend
+}
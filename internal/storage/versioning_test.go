@@ -0,0 +1,102 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+func newVersionedTestDevice(uid string) *device.Device {
+	dev := &device.Device{Spec: device.DeviceSpec{DeviceType: "Node", SerialNumber: uid}}
+	dev.APIVersion = "v1"
+	dev.Kind = "Device"
+	dev.Metadata.UID = uid
+	dev.Metadata.Name = uid
+	return dev
+}
+
+// TestCompareAndSwapExpectedVersion checks that no write skips the
+// resourceVersion check: a write without a version is a create.
+func TestCompareAndSwapExpectedVersion(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewVersionedBackend(ctx, newMemBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dev := newVersionedTestDevice("dev-1")
+	if err := saveVersioned(ctx, backend, "Device", &dev.Metadata, dev); err != nil {
+		t.Fatalf("create: %v", err)
+	}
+	read := ResourceVersionOf(&dev.Metadata)
+	if read == "" {
+		t.Fatal("create left no resourceVersion")
+	}
+
+	// Creating it again, or updating it without the version it was read at
+	again := newVersionedTestDevice("dev-1")
+	if err := saveVersioned(ctx, backend, "Device", &again.Metadata, again); !errors.Is(err, ErrConflict) {
+		t.Errorf("write without a version over a stored resource: got %v, want ErrConflict", err)
+	}
+
+	if err := saveVersioned(ctx, backend, "Device", &dev.Metadata, dev); err != nil {
+		t.Fatalf("update at the version read: %v", err)
+	}
+	stale := newVersionedTestDevice("dev-1")
+	stale.Metadata.Annotations = map[string]string{ResourceVersionAnnotation: read}
+	if err := saveVersioned(ctx, backend, "Device", &stale.Metadata, stale); !errors.Is(err, ErrConflict) {
+		t.Errorf("update at a stale version: got %v, want ErrConflict", err)
+	}
+	if got := ResourceVersionOf(&stale.Metadata); got != read {
+		t.Errorf("failed update left resourceVersion %q, want %q", got, read)
+	}
+
+	missing := newVersionedTestDevice("dev-2")
+	missing.Metadata.Annotations = map[string]string{ResourceVersionAnnotation: read}
+	if err := saveVersioned(ctx, backend, "Device", &missing.Metadata, missing); !errors.Is(err, fabricaStorage.ErrNotFound) {
+		t.Errorf("update of a missing resource: got %v, want ErrNotFound", err)
+	}
+
+	// Deletes without a version stay unconditional
+	if err := backend.CompareAndDelete(ctx, "Device", "dev-1", ""); err != nil {
+		t.Errorf("unconditional delete: %v", err)
+	}
+}
+
+// TestCompareAndSwapBatchCreateConflict checks that a transaction creating a
+// resource that has been stored in the meantime writes nothing.
+func TestCompareAndSwapBatchCreateConflict(t *testing.T) {
+	ctx := context.Background()
+	backend, err := NewVersionedBackend(ctx, newMemBackend())
+	if err != nil {
+		t.Fatal(err)
+	}
+	client := &StorageClient{backend: backend}
+
+	stored := newVersionedTestDevice("dev-1")
+	if err := saveVersioned(ctx, backend, "Device", &stored.Metadata, stored); err != nil {
+		t.Fatal(err)
+	}
+
+	tx := client.Begin()
+	if err := tx.Create(newVersionedTestDevice("dev-2")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Create(newVersionedTestDevice("dev-1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(ctx); !errors.Is(err, ErrConflict) {
+		t.Fatalf("Commit: got %v, want ErrConflict", err)
+	}
+	if exists, _ := backend.Exists(ctx, "Device", "dev-2"); exists {
+		t.Error("a failed commit wrote dev-2")
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ErrLocked is returned by LockWriter when another process holds the
+// store's writer lock.
+var ErrLocked = errors.New("storage is in use by another process")
+
+// writerLockFile is the lock file in a file backend's data directory
+const writerLockFile = ".writer.lock"
+
+// LockWriter takes the writer lock of the store given by spec (file:<dir> or
+// sqlite:<dsn>, as taken by OpenBackend) and returns a function releasing it.
+//
+// Only one process may write to a store: the resourceVersion counter, the
+// device index and the reconciler's serial number locks are kept in memory,
+// so a second writer would hand out the same resourceVersions and create
+// duplicate Devices. The lock is an exclusive lock on a file next to the
+// store, held until it is released or the process exits.
+//
+// Returns:
+//   - error: ErrLocked if another process holds the lock
+func LockWriter(spec string) (func() error, error) {
+	path := writerLockPath(spec)
+	if path == "" {
+		// An in-memory database can't be shared
+		return func() error { return nil }, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create directory for %s: %w", path, err)
+	}
+	f, err := os.OpenFile(path, os.O_RDWR|os.O_CREATE, 0644)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open writer lock: %w", err)
+	}
+	if err := lockFile(f); err != nil {
+		f.Close()
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	// Record the holder, for whoever finds the store locked
+	f.Truncate(0)
+	fmt.Fprintf(f, "%d\n", os.Getpid())
+
+	return func() error {
+		unlockFile(f)
+		return f.Close()
+	}, nil
+}
+
+// writerLockPath returns the lock file of a store, or "" for an in-memory database
+func writerLockPath(spec string) string {
+	scheme, location, ok := strings.Cut(spec, ":")
+	if !ok {
+		scheme, location = "file", spec
+	}
+	if scheme != "sqlite" {
+		return filepath.Join(location, writerLockFile)
+	}
+	if sqliteInMemory(location) {
+		return ""
+	}
+	path, _, _ := strings.Cut(strings.TrimPrefix(location, "file:"), "?")
+	return path + writerLockFile
+}
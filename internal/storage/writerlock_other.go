@@ -0,0 +1,14 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+//go:build !unix
+
+package storage
+
+import "os"
+
+// Without flock the lock file is only advisory: a second writer isn't detected
+func lockFile(f *os.File) error { return nil }
+
+func unlockFile(f *os.File) error { return nil }
@@ -0,0 +1,53 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+//go:build unix
+
+package storage
+
+import (
+	"errors"
+	"path/filepath"
+	"testing"
+)
+
+func TestLockWriter(t *testing.T) {
+	spec := "file:" + t.TempDir()
+	unlock, err := LockWriter(spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LockWriter(spec); !errors.Is(err, ErrLocked) {
+		t.Fatalf("second LockWriter: got %v, want ErrLocked", err)
+	}
+	if err := unlock(); err != nil {
+		t.Fatal(err)
+	}
+	unlock, err = LockWriter(spec)
+	if err != nil {
+		t.Fatalf("LockWriter after release: %v", err)
+	}
+	unlock()
+}
+
+func TestWriterLockPath(t *testing.T) {
+	dir := t.TempDir()
+	tests := map[string]string{
+		dir:                                    filepath.Join(dir, writerLockFile),
+		"file:" + dir:                          filepath.Join(dir, writerLockFile),
+		"sqlite:" + dir + "/inventory.db":      dir + "/inventory.db" + writerLockFile,
+		"sqlite:file:" + dir + "/inv.db?_fk=1": dir + "/inv.db" + writerLockFile,
+		"sqlite::memory:":                      "",
+		"sqlite::memory:?_pragma=foreign_keys(1)":  "",
+		"sqlite:file::memory:?cache=shared":        "",
+		"sqlite:file:inv?mode=memory&cache=shared": "",
+		"sqlite:file:/inv.db?vfs=memdb":            "",
+		"sqlite:file:" + dir + "/inv.db?mode=rwc":  dir + "/inv.db" + writerLockFile,
+	}
+	for spec, want := range tests {
+		if got := writerLockPath(spec); got != want {
+			t.Errorf("writerLockPath(%q) = %q, want %q", spec, got, want)
+		}
+	}
+}
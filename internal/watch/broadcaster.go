@@ -0,0 +1,401 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+// Package watch turns resource events from the event bus into per-kind watch
+// streams of ADDED/MODIFIED/DELETED events.
+//
+// Every watch event gets a resourceVersion: a position in this server's watch
+// stream, increasing by one per event, prefixed with an epoch chosen when the
+// broadcaster is created ("<epoch>-<position>"). A client that lost its
+// connection passes back the last resourceVersion (or CloudEvent ID) it saw
+// and receives what it missed, as long as it is still in the broadcaster's
+// history. The stream starts over when the server restarts, so a
+// resourceVersion from another epoch has expired.
+//
+// The bus may deliver events about the same object out of order (the
+// in-memory bus runs each handler in its own goroutine). The broadcaster
+// compares the storage resourceVersion carried by each object and drops an
+// event older than one it already sent for that object, so watchers only see
+// each object move forward.
+package watch
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/openchami/fabrica/pkg/events"
+
+	"github.com/user/inventory-api/internal/storage"
+)
+
+// EventType is the kind of change a watch event reports.
+type EventType string
+
+const (
+	Added    EventType = "ADDED"
+	Modified EventType = "MODIFIED"
+	Deleted  EventType = "DELETED"
+
+	// Bookmark carries no object; it only reports the current resourceVersion.
+	Bookmark EventType = "BOOKMARK"
+
+	// Error ends a stream; the object is {"message": "..."}.
+	Error EventType = "ERROR"
+)
+
+const (
+	// DefaultHistorySize is how many events are kept for resuming watches
+	DefaultHistorySize = 4096
+
+	// Events buffered per watcher before it is dropped as too slow
+	watcherBuffer = 256
+
+	// deletedVersion is the last storage resourceVersion of a deleted object,
+	// newer than any update of it that arrives late
+	deletedVersion = math.MaxUint64
+)
+
+// ErrExpired is returned when a watch asks to resume from a resourceVersion or
+// event ID that is no longer (or was never) in the history. The client should
+// list again and watch from the start.
+var ErrExpired = errors.New("resourceVersion is too old or unknown; list again")
+
+// ErrTooSlow ends a watch whose client didn't keep up.
+var ErrTooSlow = errors.New("watcher fell behind and was dropped; resume from the last resourceVersion")
+
+// Event is one change delivered to watchers.
+type Event struct {
+	Type            EventType       `json:"type"`
+	ResourceVersion string          `json:"resourceVersion"`
+	EventID         string          `json:"eventId,omitempty"`
+	Object          json.RawMessage `json:"object,omitempty"`
+
+	kind          string
+	seq           uint64
+	objectKey     string // kind/uid
+	objectVersion uint64 // storage resourceVersion of the object, 0 if it has none
+}
+
+// Options says which events a watch receives and where it starts.
+type Options struct {
+	// Kind is the resource kind to watch (e.g. "Device")
+	Kind string
+
+	// ResourceVersion resumes after this watch resourceVersion. Empty starts now.
+	ResourceVersion string
+
+	// EventID resumes after the event with this CloudEvent ID (used if ResourceVersion is empty)
+	EventID string
+}
+
+// Broadcaster subscribes to every event on a bus and fans resource events out to watchers.
+type Broadcaster struct {
+	bus         events.EventBus
+	historySize int
+	subID       events.SubscriptionID
+
+	// epoch identifies this broadcaster's stream in its resourceVersions
+	epoch string
+
+	mu       sync.Mutex
+	seq      uint64
+	history  []Event
+	watchers map[*Watcher]struct{}
+
+	// latest is the storage resourceVersion of the last event sent for each
+	// object in history (deletedVersion once it is deleted)
+	latest map[string]uint64
+}
+
+// NewBroadcaster creates a broadcaster keeping the last historySize events for resuming.
+func NewBroadcaster(bus events.EventBus, historySize int) *Broadcaster {
+	if historySize <= 0 {
+		historySize = DefaultHistorySize
+	}
+	return &Broadcaster{
+		bus:         bus,
+		historySize: historySize,
+		epoch:       newEpoch(),
+		watchers:    make(map[*Watcher]struct{}),
+		latest:      make(map[string]uint64),
+	}
+}
+
+// newEpoch returns a random stream epoch
+func newEpoch() string {
+	b := make([]byte, 4)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// version returns the resourceVersion of stream position seq
+func (b *Broadcaster) version(seq uint64) string {
+	return b.epoch + "-" + strconv.FormatUint(seq, 10)
+}
+
+// Start subscribes the broadcaster to the bus.
+func (b *Broadcaster) Start() error {
+	id, err := b.bus.Subscribe("**", b.handleEvent)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe watch broadcaster: %w", err)
+	}
+	b.subID = id
+	return nil
+}
+
+// Stop unsubscribes from the bus and ends every watch.
+func (b *Broadcaster) Stop() error {
+	b.mu.Lock()
+	for w := range b.watchers {
+		b.closeWatcher(w, nil)
+	}
+	b.mu.Unlock()
+
+	if b.subID == "" {
+		return nil
+	}
+	return b.bus.Unsubscribe(b.subID)
+}
+
+// handleEvent converts a resource event into a watch event and sends it to matching watchers
+func (b *Broadcaster) handleEvent(ctx context.Context, event events.Event) error {
+	kind := event.ResourceKind()
+	if kind == "" {
+		return nil
+	}
+	typ, ok := eventType(event.Type())
+	if !ok {
+		return nil
+	}
+	object, err := eventObject(event, kind, typ)
+	if err != nil {
+		return err
+	}
+
+	key := kind + "/" + event.ResourceUID()
+	var version uint64
+	if typ == Deleted {
+		version = deletedVersion
+	} else {
+		// Objects without a storage resourceVersion are never dropped
+		version, _ = strconv.ParseUint(storage.ResourceVersion(object), 10, 64)
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if last, ok := b.latest[key]; ok && version != 0 && version <= last {
+		// A change already sent for this object is newer (or the same)
+		return nil
+	}
+	if version != 0 {
+		b.latest[key] = version
+	}
+
+	b.seq++
+	we := Event{
+		Type:            typ,
+		ResourceVersion: b.version(b.seq),
+		EventID:         event.ID(),
+		Object:          object,
+		kind:            kind,
+		seq:             b.seq,
+		objectKey:       key,
+		objectVersion:   version,
+	}
+	b.history = append(b.history, we)
+	if len(b.history) > 2*b.historySize {
+		// Trim in batches so appends stay cheap, and forget objects whose
+		// events are gone with them
+		b.history = append([]Event(nil), b.history[len(b.history)-b.historySize:]...)
+		b.latest = make(map[string]uint64)
+		for _, ev := range b.history {
+			if ev.objectVersion != 0 {
+				b.latest[ev.objectKey] = ev.objectVersion
+			}
+		}
+	}
+
+	for w := range b.watchers {
+		if w.kind == kind {
+			b.send(w, we)
+		}
+	}
+	return nil
+}
+
+// Watch starts a watch. Events after the requested position are replayed
+// first. It also returns the resourceVersion the watch starts at, which a
+// client that lists after starting the watch can use as a bookmark.
+func (b *Broadcaster) Watch(opts Options) (*Watcher, string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	var replay []Event
+	if opts.ResourceVersion != "" || opts.EventID != "" {
+		since, err := b.resolve(opts)
+		if err != nil {
+			return nil, "", err
+		}
+		for _, ev := range b.history {
+			if ev.seq > since && ev.kind == opts.Kind {
+				replay = append(replay, ev)
+			}
+		}
+	}
+
+	w := &Watcher{
+		kind: opts.Kind,
+		ch:   make(chan Event, len(replay)+watcherBuffer),
+		b:    b,
+	}
+	for _, ev := range replay {
+		w.ch <- ev
+	}
+	b.watchers[w] = struct{}{}
+	return w, b.version(b.seq), nil
+}
+
+// resolve turns a resume position into the last sequence number the client saw
+func (b *Broadcaster) resolve(opts Options) (uint64, error) {
+	if opts.ResourceVersion != "" {
+		epoch, position, ok := strings.Cut(opts.ResourceVersion, "-")
+		if !ok {
+			epoch, position = "", opts.ResourceVersion
+		}
+		since, err := strconv.ParseUint(position, 10, 64)
+		if err != nil {
+			return 0, fmt.Errorf("invalid resourceVersion %q", opts.ResourceVersion)
+		}
+		if epoch != b.epoch || since > b.seq {
+			// From before a restart (the stream starts over) or made up
+			return 0, ErrExpired
+		}
+		if since < b.seq && (len(b.history) == 0 || b.history[0].seq > since+1) {
+			return 0, ErrExpired
+		}
+		return since, nil
+	}
+
+	for _, ev := range b.history {
+		if ev.EventID == opts.EventID {
+			return ev.seq, nil
+		}
+	}
+	return 0, ErrExpired
+}
+
+// send queues an event for a watcher, dropping the watcher if its buffer is full. Callers hold b.mu.
+func (b *Broadcaster) send(w *Watcher, ev Event) {
+	select {
+	case w.ch <- ev:
+	default:
+		b.closeWatcher(w, ErrTooSlow)
+	}
+}
+
+// closeWatcher ends a watch. Callers hold b.mu.
+func (b *Broadcaster) closeWatcher(w *Watcher, err error) {
+	if _, ok := b.watchers[w]; !ok {
+		return
+	}
+	delete(b.watchers, w)
+	w.err = err
+	close(w.ch)
+}
+
+// Watcher is one client's watch stream.
+type Watcher struct {
+	kind string
+	ch   chan Event
+	b    *Broadcaster
+	err  error
+}
+
+// Events returns the watch's events. It is closed when the watch ends; Err says why.
+func (w *Watcher) Events() <-chan Event {
+	return w.ch
+}
+
+// Err returns why the watch ended (nil if it was stopped).
+func (w *Watcher) Err() error {
+	w.b.mu.Lock()
+	defer w.b.mu.Unlock()
+	return w.err
+}
+
+// Bookmark returns the current stream resourceVersion if every event up to it
+// has already been received from Events, so a client can safely resume from it.
+func (w *Watcher) Bookmark() (string, bool) {
+	w.b.mu.Lock()
+	defer w.b.mu.Unlock()
+	if _, ok := w.b.watchers[w]; !ok || len(w.ch) > 0 {
+		return "", false
+	}
+	return w.b.version(w.b.seq), true
+}
+
+// Stop ends the watch.
+func (w *Watcher) Stop() {
+	w.b.mu.Lock()
+	defer w.b.mu.Unlock()
+	w.b.closeWatcher(w, nil)
+}
+
+// eventType maps an event's action (the last segment of its type) to a watch event type
+func eventType(cloudEventType string) (EventType, bool) {
+	action := cloudEventType[strings.LastIndex(cloudEventType, ".")+1:]
+	switch action {
+	case "created":
+		return Added, true
+	case "updated", "patched":
+		return Modified, true
+	case "deleted":
+		return Deleted, true
+	default:
+		return "", false
+	}
+}
+
+// eventObject extracts the resource from an event. Delete events don't carry
+// the resource, so a stub with its kind, UID, name and labels is sent instead.
+func eventObject(event events.Event, kind string, typ EventType) (json.RawMessage, error) {
+	var change struct {
+		ResourceUID  string          `json:"resourceUID"`
+		ResourceName string          `json:"resourceName"`
+		Resource     json.RawMessage `json:"resource"`
+		Metadata     struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+	}
+	if err := json.Unmarshal(event.Data(), &change); err != nil {
+		return nil, fmt.Errorf("failed to decode event %s: %w", event.ID(), err)
+	}
+
+	switch {
+	case len(change.Resource) > 0 && string(change.Resource) != "null":
+		return change.Resource, nil
+	case typ == Deleted:
+		stub := map[string]interface{}{
+			"kind": kind,
+			"metadata": map[string]interface{}{
+				"uid":    change.ResourceUID,
+				"name":   change.ResourceName,
+				"labels": change.Metadata.Labels,
+			},
+		}
+		return json.Marshal(stub)
+	default:
+		// Events built with events.NewResourceEvent carry the resource itself as data
+		return event.Data(), nil
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package watch
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/openchami/fabrica/pkg/events"
+
+	"github.com/user/inventory-api/internal/storage"
+)
+
+// enableEvents turns fabrica's event creation on for the test
+func enableEvents(t *testing.T) {
+	config := events.GetEventConfig()
+	enabled := *config
+	enabled.Enabled = true
+	events.SetEventConfig(&enabled)
+	t.Cleanup(func() { events.SetEventConfig(config) })
+}
+
+func deviceEvent(t *testing.T, action, uid string) events.Event {
+	t.Helper()
+	event, err := events.NewResourceEvent(action, "Device", uid, events.ResourceChangeData{
+		Action:       action,
+		ResourceKind: "Device",
+		ResourceUID:  uid,
+		Resource:     map[string]string{"kind": "Device"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *event
+}
+
+// TestResumeAcrossRestart checks that a resourceVersion resumes a watch on
+// the broadcaster that issued it, and has expired on a new one (as after a
+// server restart), even where the new stream has reached the same position.
+func TestResumeAcrossRestart(t *testing.T) {
+	enableEvents(t)
+	ctx := context.Background()
+	before := NewBroadcaster(nil, 0)
+	for _, uid := range []string{"dev-1", "dev-2", "dev-3"} {
+		if err := before.handleEvent(ctx, deviceEvent(t, "updated", uid)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	first := before.history[0]
+	if !strings.HasPrefix(first.ResourceVersion, before.epoch+"-") {
+		t.Fatalf("resourceVersion %q has no epoch", first.ResourceVersion)
+	}
+
+	w, _, err := before.Watch(Options{Kind: "Device", ResourceVersion: first.ResourceVersion})
+	if err != nil {
+		t.Fatalf("resume on the same broadcaster: %v", err)
+	}
+	if n := len(w.Events()); n != 2 {
+		t.Errorf("replayed %d events, want 2", n)
+	}
+	w.Stop()
+
+	after := NewBroadcaster(nil, 0)
+	for _, uid := range []string{"dev-4", "dev-5", "dev-6"} {
+		if err := after.handleEvent(ctx, deviceEvent(t, "updated", uid)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, rv := range []string{first.ResourceVersion, "1"} {
+		if _, _, err := after.Watch(Options{Kind: "Device", ResourceVersion: rv}); !errors.Is(err, ErrExpired) {
+			t.Errorf("resume from %q after a restart: got %v, want ErrExpired", rv, err)
+		}
+	}
+	if _, _, err := after.Watch(Options{Kind: "Device", ResourceVersion: after.epoch + "-x"}); err == nil || errors.Is(err, ErrExpired) {
+		t.Errorf("malformed resourceVersion: got %v, want an invalid resourceVersion error", err)
+	}
+}
+
+// versionedDeviceEvent is a Device event whose object has the given storage resourceVersion
+func versionedDeviceEvent(t *testing.T, action, uid, version string) events.Event {
+	t.Helper()
+	object := map[string]any{
+		"kind":     "Device",
+		"metadata": map[string]any{"uid": uid, "annotations": map[string]string{storage.ResourceVersionAnnotation: version}},
+	}
+	event, err := events.NewResourceEvent(action, "Device", uid, events.ResourceChangeData{
+		Action:       action,
+		ResourceKind: "Device",
+		ResourceUID:  uid,
+		Resource:     object,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *event
+}
+
+// TestStaleObjectEventsDropped checks that an event carrying an older (or
+// the same) storage resourceVersion than one already sent for its object,
+// or arriving after the object's delete, isn't sent to watchers.
+func TestStaleObjectEventsDropped(t *testing.T) {
+	enableEvents(t)
+	ctx := context.Background()
+	b := NewBroadcaster(nil, 0)
+	w, _, err := b.Watch(Options{Kind: "Device"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, event := range []events.Event{
+		versionedDeviceEvent(t, "created", "dev-1", "3"),
+		versionedDeviceEvent(t, "updated", "dev-1", "5"),
+		versionedDeviceEvent(t, "updated", "dev-1", "4"), // late
+		versionedDeviceEvent(t, "updated", "dev-1", "5"), // duplicate
+		versionedDeviceEvent(t, "updated", "dev-2", "4"), // another object
+		versionedDeviceEvent(t, "updated", "dev-1", "7"),
+		deviceEvent(t, "deleted", "dev-1"),
+		versionedDeviceEvent(t, "updated", "dev-1", "8"), // after the delete
+		deviceEvent(t, "updated", "dev-3"),               // no version
+		deviceEvent(t, "updated", "dev-3"),
+	} {
+		if err := b.handleEvent(ctx, event); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	var got []string
+	for len(w.Events()) > 0 {
+		ev := <-w.Events()
+		var object struct {
+			Metadata struct {
+				UID string `json:"uid"`
+			} `json:"metadata"`
+		}
+		if err := json.Unmarshal(ev.Object, &object); err != nil {
+			t.Fatal(err)
+		}
+		got = append(got, fmt.Sprintf("%s %s %s", ev.Type, object.Metadata.UID, storage.ResourceVersion(ev.Object)))
+	}
+	want := []string{
+		"ADDED dev-1 3",
+		"MODIFIED dev-1 5",
+		"MODIFIED dev-2 4",
+		"MODIFIED dev-1 7",
+		"DELETED  ",
+		"MODIFIED  ",
+		"MODIFIED  ",
+	}
+	if !slices.Equal(got, want) {
+		t.Errorf("watch events:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,376 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+// Package webhook delivers inventory events to the HTTP endpoints of
+// Subscription resources.
+//
+// Each matching event is POSTed in CloudEvents binary mode: the event data is
+// the request body and the attributes are ce-* headers. When the subscription
+// has a secret, each delivery is signed with HMAC-SHA256 over its timestamp,
+// the event's ID and type and the body (see Sign), so a receiver can reject a
+// replayed or altered request. Failed deliveries are retried with exponential
+// backoff; after Spec.MaxAttempts the event is dead-lettered in the
+// subscription's status.
+//
+// Deliveries never connect to loopback, link-local or metadata addresses
+// unless allowed with subscription.SetAllowedNetworks, however the URL's host
+// name resolves or where a redirect leads.
+package webhook
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/cloudevents/sdk-go/v2/binding"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
+	"github.com/openchami/fabrica/pkg/events"
+	"github.com/openchami/fabrica/pkg/reconcile"
+	fabricaStorage "github.com/openchami/fabrica/pkg/storage"
+
+	"github.com/user/inventory-api/internal/eventmatch"
+	"github.com/user/inventory-api/internal/storage"
+	"github.com/user/inventory-api/pkg/resources/subscription"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 signature of a delivery (see Sign)
+	SignatureHeader = "X-Inventory-Signature-256"
+
+	// TimestampHeader carries the Unix time (in seconds) a delivery was signed at
+	TimestampHeader = "X-Inventory-Timestamp"
+
+	// SignatureTolerance is how far a delivery's timestamp may be from the
+	// receiver's clock for Verify to accept it. Receivers should also remember
+	// the ce-id of deliveries within this window to reject replays.
+	SignatureTolerance = 5 * time.Minute
+
+	defaultMaxAttempts = 5
+	initialBackoff     = time.Second
+	maxBackoff         = time.Minute
+	requestTimeout     = 10 * time.Second
+
+	// Status updates retry this many times on a resourceVersion conflict
+	maxConflictRetries = 5
+)
+
+// Dispatcher subscribes to every event on a bus and delivers it to the
+// subscriptions it matches.
+type Dispatcher struct {
+	bus        events.EventBus
+	httpClient *http.Client
+	logger     reconcile.Logger
+	subID      events.SubscriptionID
+}
+
+// NewDispatcher creates a dispatcher reading from bus. Pass a durable consumer
+// (see the file and NATS buses) so events published while the server was down
+// are still delivered.
+func NewDispatcher(bus events.EventBus, logger reconcile.Logger) *Dispatcher {
+	// No proxy: the dialer has to see the address it connects to
+	dialer := &net.Dialer{Timeout: requestTimeout, Control: checkDestination}
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+	transport.Proxy = nil
+	transport.DialContext = dialer.DialContext
+	return &Dispatcher{
+		bus:        bus,
+		httpClient: &http.Client{Timeout: requestTimeout, Transport: transport},
+		logger:     logger,
+	}
+}
+
+// checkDestination refuses to connect to an address subscriptions may not
+// reach. It runs for every connection, after the host name is resolved.
+func checkDestination(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return fmt.Errorf("unexpected webhook address %q: %w", address, err)
+	}
+	return subscription.CheckAddress(addrPort.Addr())
+}
+
+// Start subscribes the dispatcher to the bus.
+func (d *Dispatcher) Start() error {
+	id, err := d.bus.Subscribe("**", d.handleEvent)
+	if err != nil {
+		return fmt.Errorf("failed to subscribe webhook dispatcher: %w", err)
+	}
+	d.subID = id
+	return nil
+}
+
+// Stop unsubscribes the dispatcher.
+func (d *Dispatcher) Stop() error {
+	if d.subID == "" {
+		return nil
+	}
+	return d.bus.Unsubscribe(d.subID)
+}
+
+// handleEvent delivers one event to every matching subscription, in parallel.
+// It returns once each delivery succeeded or was dead-lettered, so a durable
+// consumer only moves past the event after that.
+func (d *Dispatcher) handleEvent(ctx context.Context, event events.Event) error {
+	kind := event.ResourceKind()
+	if kind == "Subscription" {
+		// Never forward changes to subscriptions themselves (they carry secrets)
+		return nil
+	}
+
+	subs, err := storage.LoadAllSubscriptions(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load subscriptions: %w", err)
+	}
+
+	labels := eventLabels(event)
+	errs := make(chan error, len(subs))
+	matched := 0
+	for _, sub := range subs {
+		if !matches(sub, event.Type(), kind, labels) {
+			continue
+		}
+		matched++
+		go func(sub *subscription.Subscription) {
+			errs <- d.deliver(ctx, sub, event)
+		}(sub)
+	}
+
+	var firstErr error
+	for i := 0; i < matched; i++ {
+		if err := <-errs; err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	return firstErr
+}
+
+// matches reports whether a subscription wants an event
+func matches(sub *subscription.Subscription, eventType, kind string, labels map[string]string) bool {
+	if sub.Spec.ResourceKind != "" && !strings.EqualFold(sub.Spec.ResourceKind, kind) {
+		return false
+	}
+	if len(sub.Spec.EventTypes) > 0 {
+		found := false
+		for _, pattern := range sub.Spec.EventTypes {
+			if eventmatch.Matches(eventType, pattern) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	for k, v := range sub.Spec.LabelSelector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
+
+// eventLabels returns the labels of the resource an event is about. Create and
+// update events carry the resource; delete events carry its labels in metadata.
+func eventLabels(event events.Event) map[string]string {
+	var data struct {
+		Metadata struct {
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Resource struct {
+			Metadata struct {
+				Labels map[string]string `json:"labels"`
+			} `json:"metadata"`
+		} `json:"resource"`
+	}
+	if err := json.Unmarshal(event.Data(), &data); err != nil {
+		return nil
+	}
+	if data.Resource.Metadata.Labels != nil {
+		return data.Resource.Metadata.Labels
+	}
+	return data.Metadata.Labels
+}
+
+// deliver POSTs an event to a subscription, retrying with backoff, and records
+// the outcome. It only returns an error if ctx was cancelled before the outcome
+// was known; the event is then left for redelivery rather than dead-lettered.
+func (d *Dispatcher) deliver(ctx context.Context, sub *subscription.Subscription, event events.Event) error {
+	maxAttempts := sub.Spec.MaxAttempts
+	if maxAttempts <= 0 {
+		maxAttempts = defaultMaxAttempts
+	}
+
+	backoff := initialBackoff
+	var statusCode, attempts int
+	var err error
+retry:
+	for attempts < maxAttempts {
+		attempts++
+		statusCode, err = d.post(ctx, sub, event)
+		if err == nil || !retryable(statusCode) || attempts == maxAttempts {
+			break
+		}
+		d.logger.Warnf("WEBHOOK: Delivery of %s to %s failed (attempt %d/%d), retrying in %s: %v",
+			event.ID(), sub.GetName(), attempts, maxAttempts, backoff, err)
+
+		select {
+		case <-ctx.Done():
+			break retry
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+
+	record := subscription.DeliveryRecord{
+		EventID:    event.ID(),
+		EventType:  event.Type(),
+		Time:       time.Now(),
+		Attempts:   attempts,
+		StatusCode: statusCode,
+	}
+	if err == nil {
+		d.updateStatus(ctx, sub.GetUID(), func(s *subscription.Subscription) {
+			s.Status.Delivered++
+			s.Status.ConsecutiveFailures = 0
+			s.Status.LastDelivery = &record
+			s.Status.Phase = "Active"
+			s.Status.Message = ""
+			s.Status.Ready = true
+		})
+		return nil
+	}
+
+	d.logger.Errorf("WEBHOOK: Dead-lettering %s for %s after %d attempts: %v", event.ID(), sub.GetName(), attempts, err)
+	record.Error = err.Error()
+	deadLetter := subscription.DeadLetter{DeliveryRecord: record}
+	if data, marshalErr := json.Marshal(event); marshalErr == nil {
+		deadLetter.Event = data
+	}
+	d.updateStatus(ctx, sub.GetUID(), func(s *subscription.Subscription) {
+		s.Status.Failed++
+		s.Status.ConsecutiveFailures++
+		s.Status.LastDelivery = &record
+		s.Status.DeadLetters = append(s.Status.DeadLetters, deadLetter)
+		if over := len(s.Status.DeadLetters) - subscription.MaxDeadLetters; over > 0 {
+			s.Status.DeadLetters = s.Status.DeadLetters[over:]
+		}
+		s.Status.Phase = "Failing"
+		s.Status.Message = fmt.Sprintf("%d consecutive events dead-lettered: %s", s.Status.ConsecutiveFailures, record.Error)
+		s.Status.Ready = false
+	})
+	return nil
+}
+
+// post sends one delivery attempt and returns the HTTP status code (0 if no response)
+func (d *Dispatcher) post(ctx context.Context, sub *subscription.Subscription, event events.Event) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, sub.Spec.URL, nil)
+	if err != nil {
+		return 0, fmt.Errorf("invalid subscription url: %w", err)
+	}
+	// Binary mode: attributes as ce-* headers, data as the body
+	if err := cehttp.WriteRequest(binding.WithForceBinary(ctx), binding.ToMessage(&event.Event), req); err != nil {
+		return 0, fmt.Errorf("failed to encode event: %w", err)
+	}
+	if sub.Spec.Secret != "" {
+		timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+		req.Header.Set(TimestampHeader, timestamp)
+		req.Header.Set(SignatureHeader, Sign(sub.Spec.Secret, timestamp, event.ID(), event.Type(), event.Data()))
+	}
+
+	resp, err := d.httpClient.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return resp.StatusCode, fmt.Errorf("endpoint returned %s", resp.Status)
+	}
+	return resp.StatusCode, nil
+}
+
+// retryable reports whether another attempt may succeed after a failure with this status
+func retryable(statusCode int) bool {
+	switch {
+	case statusCode == 0: // no response (network error, timeout)
+		return true
+	case statusCode == http.StatusRequestTimeout, statusCode == http.StatusTooManyRequests:
+		return true
+	default:
+		return statusCode >= 500
+	}
+}
+
+// Sign returns the SignatureHeader value for a delivery: "sha256=" and the
+// hex HMAC-SHA256, keyed with secret, of
+//
+//	timestamp + "." + ce-id + "." + ce-type + "." + body
+//
+// where timestamp is the TimestampHeader value. Receivers recompute it to
+// verify the delivery (see Verify).
+func Sign(secret, timestamp, id, eventType string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(timestamp + "." + id + "." + eventType + "."))
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks a delivery's signature and that its timestamp is within
+// SignatureTolerance of now. It is the receiver's side of Sign.
+func Verify(secret, signature, timestamp, id, eventType string, body []byte, now time.Time) error {
+	seconds, err := strconv.ParseInt(timestamp, 10, 64)
+	if err != nil {
+		return fmt.Errorf("invalid %s %q", TimestampHeader, timestamp)
+	}
+	if skew := now.Sub(time.Unix(seconds, 0)).Abs(); skew > SignatureTolerance {
+		return fmt.Errorf("delivery timestamp is %s away from now", skew.Round(time.Second))
+	}
+	if !hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, id, eventType, body))) {
+		return errors.New("signature mismatch")
+	}
+	return nil
+}
+
+// updateStatus applies mutate to the latest copy of a subscription's status,
+// retrying on a resourceVersion conflict with a concurrent delivery or API write.
+func (d *Dispatcher) updateStatus(ctx context.Context, uid string, mutate func(*subscription.Subscription)) {
+	for attempt := 0; attempt < maxConflictRetries; attempt++ {
+		sub, err := storage.LoadSubscription(ctx, uid)
+		if err != nil {
+			if !errors.Is(err, fabricaStorage.ErrNotFound) {
+				d.logger.Errorf("WEBHOOK: Failed to load subscription %s: %v", uid, err)
+			}
+			return // deleted while we were delivering
+		}
+		mutate(sub)
+		err = storage.UpdateSubscription(ctx, sub)
+		if err == nil {
+			return
+		}
+		if !errors.Is(err, storage.ErrConflict) {
+			d.logger.Errorf("WEBHOOK: Failed to update status of subscription %s: %v", uid, err)
+			return
+		}
+	}
+	d.logger.Errorf("WEBHOOK: Gave up updating status of subscription %s after %d conflicts", uid, maxConflictRetries)
+}
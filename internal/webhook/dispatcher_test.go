@@ -0,0 +1,101 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/openchami/fabrica/pkg/events"
+	"github.com/openchami/fabrica/pkg/reconcile"
+
+	"github.com/user/inventory-api/pkg/resources/subscription"
+)
+
+func TestVerify(t *testing.T) {
+	now := time.Now()
+	timestamp := strconv.FormatInt(now.Unix(), 10)
+	body := []byte(`{"resourceUID":"dev-1"}`)
+	signature := Sign("s3cret", timestamp, "evt-1", "io.fabrica.device.created", body)
+
+	if err := Verify("s3cret", signature, timestamp, "evt-1", "io.fabrica.device.created", body, now); err != nil {
+		t.Fatalf("valid delivery: %v", err)
+	}
+	tests := []struct {
+		name                             string
+		secret, timestamp, id, eventType string
+		body                             string
+		now                              time.Time
+	}{
+		{"other secret", "other", timestamp, "evt-1", "io.fabrica.device.created", string(body), now},
+		{"other id", "s3cret", timestamp, "evt-2", "io.fabrica.device.created", string(body), now},
+		{"other type", "s3cret", timestamp, "evt-1", "io.fabrica.device.deleted", string(body), now},
+		{"other body", "s3cret", timestamp, "evt-1", "io.fabrica.device.created", `{"resourceUID":"dev-2"}`, now},
+		{"other timestamp", "s3cret", strconv.FormatInt(now.Unix()-1, 10), "evt-1", "io.fabrica.device.created", string(body), now},
+		{"replayed late", "s3cret", timestamp, "evt-1", "io.fabrica.device.created", string(body), now.Add(SignatureTolerance + time.Second)},
+		{"bad timestamp", "s3cret", "yesterday", "evt-1", "io.fabrica.device.created", string(body), now},
+	}
+	for _, tt := range tests {
+		if err := Verify(tt.secret, signature, tt.timestamp, tt.id, tt.eventType, []byte(tt.body), tt.now); err == nil {
+			t.Errorf("%s: delivery verified", tt.name)
+		}
+	}
+}
+
+func testEvent(t *testing.T) events.Event {
+	t.Helper()
+	config := events.GetEventConfig()
+	enabled := *config
+	enabled.Enabled = true
+	events.SetEventConfig(&enabled)
+	t.Cleanup(func() { events.SetEventConfig(config) })
+
+	event, err := events.NewResourceEvent("created", "Device", "dev-1", events.ResourceChangeData{
+		Action:       "created",
+		ResourceKind: "Device",
+		ResourceUID:  "dev-1",
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return *event
+}
+
+// TestPostSignedDelivery checks that a delivery carries a timestamp and a
+// signature the receiver can verify, and that it only reaches a loopback
+// receiver once loopback is allowed.
+func TestPostSignedDelivery(t *testing.T) {
+	event := testEvent(t)
+	received := make(chan error, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- Verify("s3cret", r.Header.Get(SignatureHeader), r.Header.Get(TimestampHeader),
+			r.Header.Get("ce-id"), r.Header.Get("ce-type"), body, time.Now())
+	}))
+	t.Cleanup(server.Close)
+
+	d := NewDispatcher(nil, reconcile.NewDefaultLogger())
+	sub := &subscription.Subscription{Spec: subscription.SubscriptionSpec{URL: server.URL, Secret: "s3cret"}}
+
+	if _, err := d.post(context.Background(), sub, event); err == nil || !strings.Contains(err.Error(), "loopback") {
+		t.Fatalf("delivery to loopback: got %v, want it refused", err)
+	}
+
+	subscription.SetAllowedNetworks([]netip.Prefix{netip.MustParsePrefix("127.0.0.0/8")})
+	t.Cleanup(func() { subscription.SetAllowedNetworks(nil) })
+	if _, err := d.post(context.Background(), sub, event); err != nil {
+		t.Fatalf("delivery to allowed loopback: %v", err)
+	}
+	if err := <-received; err != nil {
+		t.Errorf("receiver could not verify the delivery: %v", err)
+	}
+}
@@ -0,0 +1,64 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+)
+
+// TokenEnv and TokenFileEnv are the environment variables ResolveToken
+// reads a bearer token, or the file holding one, from
+const (
+	TokenEnv     = "INVENTORY_API_TOKEN"
+	TokenFileEnv = "INVENTORY_API_TOKEN_FILE"
+)
+
+// WithToken returns a new client that sends token as a bearer token, for a
+// server that requires JWT authentication
+func (c *Client) WithToken(token string) *Client {
+	return &Client{
+		baseURL:    c.baseURL,
+		httpClient: c.httpClient,
+		version:    c.version,
+		token:      token,
+	}
+}
+
+// authorize adds the client's bearer token, if any, to a request
+func (c *Client) authorize(req *http.Request) {
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+}
+
+// ResolveToken returns the bearer token to use: token if it is set, else
+// the contents of tokenFile, else $INVENTORY_API_TOKEN, else the contents of
+// the file in $INVENTORY_API_TOKEN_FILE. It returns "" when none is set.
+func ResolveToken(token, tokenFile string) (string, error) {
+	if token != "" {
+		return strings.TrimSpace(token), nil
+	}
+	if tokenFile == "" {
+		if token := os.Getenv(TokenEnv); token != "" {
+			return strings.TrimSpace(token), nil
+		}
+		tokenFile = os.Getenv(TokenFileEnv)
+	}
+	if tokenFile == "" {
+		return "", nil
+	}
+	data, err := os.ReadFile(tokenFile)
+	if err != nil {
+		return "", fmt.Errorf("failed to read token file: %w", err)
+	}
+	token = strings.TrimSpace(string(data))
+	if token == "" {
+		return "", fmt.Errorf("token file %s is empty", tokenFile)
+	}
+	return token, nil
+}
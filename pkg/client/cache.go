@@ -0,0 +1,153 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"sort"
+	"sync"
+)
+
+// IndexFunc returns the values an object is indexed under (none to leave it out of the index).
+type IndexFunc[T any] func(obj *T) []string
+
+// Indexer is a thread-safe in-memory cache of objects keyed by UID, with
+// secondary indexes. Objects returned from it are shared with the cache and
+// must not be modified.
+type Indexer[T any] struct {
+	keyFunc  func(obj *T) string
+	indexers map[string]IndexFunc[T]
+
+	mu      sync.RWMutex
+	items   map[string]*T
+	indices map[string]map[string]map[string]struct{} // index name -> value -> keys
+}
+
+func newIndexer[T any](keyFunc func(obj *T) string, indexers map[string]IndexFunc[T]) *Indexer[T] {
+	indices := make(map[string]map[string]map[string]struct{}, len(indexers))
+	for name := range indexers {
+		indices[name] = make(map[string]map[string]struct{})
+	}
+	return &Indexer[T]{
+		keyFunc:  keyFunc,
+		indexers: indexers,
+		items:    make(map[string]*T),
+		indices:  indices,
+	}
+}
+
+// Get returns the object with a UID.
+func (i *Indexer[T]) Get(key string) (*T, bool) {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	obj, ok := i.items[key]
+	return obj, ok
+}
+
+// List returns every object, ordered by UID.
+func (i *Indexer[T]) List() []*T {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return i.sorted(i.items)
+}
+
+// Len returns the number of cached objects.
+func (i *Indexer[T]) Len() int {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	return len(i.items)
+}
+
+// ByIndex returns the objects indexed under value in the named index, ordered by UID.
+func (i *Indexer[T]) ByIndex(index, value string) []*T {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+
+	keys := i.indices[index][value]
+	objs := make(map[string]*T, len(keys))
+	for key := range keys {
+		objs[key] = i.items[key]
+	}
+	return i.sorted(objs)
+}
+
+// sorted returns the objects of a map ordered by key. Callers hold i.mu.
+func (i *Indexer[T]) sorted(objs map[string]*T) []*T {
+	keys := make([]string, 0, len(objs))
+	for key := range objs {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	result := make([]*T, 0, len(keys))
+	for _, key := range keys {
+		result = append(result, objs[key])
+	}
+	return result
+}
+
+// set adds or replaces an object and returns the previous version, if any
+func (i *Indexer[T]) set(obj *T) (*T, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	key := i.keyFunc(obj)
+	old, existed := i.items[key]
+	if existed {
+		i.unindex(key, old)
+	}
+	i.items[key] = obj
+	i.index(key, obj)
+	return old, existed
+}
+
+// remove deletes an object by UID and returns it, if it was cached
+func (i *Indexer[T]) remove(key string) (*T, bool) {
+	i.mu.Lock()
+	defer i.mu.Unlock()
+
+	old, existed := i.items[key]
+	if existed {
+		i.unindex(key, old)
+		delete(i.items, key)
+	}
+	return old, existed
+}
+
+// keys returns the UIDs of every cached object
+func (i *Indexer[T]) keys() []string {
+	i.mu.RLock()
+	defer i.mu.RUnlock()
+	keys := make([]string, 0, len(i.items))
+	for key := range i.items {
+		keys = append(keys, key)
+	}
+	return keys
+}
+
+// index adds an object to every index. Callers hold i.mu.
+func (i *Indexer[T]) index(key string, obj *T) {
+	for name, fn := range i.indexers {
+		for _, value := range fn(obj) {
+			keys := i.indices[name][value]
+			if keys == nil {
+				keys = make(map[string]struct{})
+				i.indices[name][value] = keys
+			}
+			keys[key] = struct{}{}
+		}
+	}
+}
+
+// unindex removes an object from every index. Callers hold i.mu.
+func (i *Indexer[T]) unindex(key string, obj *T) {
+	for name, fn := range i.indexers {
+		for _, value := range fn(obj) {
+			keys := i.indices[name][value]
+			delete(keys, key)
+			if len(keys) == 0 {
+				delete(i.indices[name], value)
+			}
+		}
+	}
+}
@@ -60,9 +60,11 @@ import (
 	"net/http"
 	"net/url"
 	"path"
+	"strings"
 
 	"github.com/user/inventory-api/pkg/resources/device"
 	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+	"github.com/user/inventory-api/pkg/resources/subscription"
 )
 
 // Client provides access to the inventory API
@@ -70,6 +72,7 @@ type Client struct {
 	baseURL    *url.URL
 	httpClient *http.Client
 	version    string // Optional API version for Accept/Content-Type headers
+	token      string // Optional bearer token for the Authorization header
 }
 
 // ErrorResponse represents an API error response
@@ -100,6 +103,7 @@ func (c *Client) WithVersion(version string) *Client {
 		baseURL:    c.baseURL,
 		httpClient: c.httpClient,
 		version:    version,
+		token:      c.token,
 	}
 }
 
@@ -114,8 +118,15 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, body in
 		reqBody = bytes.NewBuffer(jsonData)
 	}
 
+	// The endpoint may carry a query string (see ListOptions)
+	// and escaped path segments (e.g. a serial number with a "/")
+	endpoint, rawQuery, _ := strings.Cut(endpoint, "?")
 	u := *c.baseURL
-	u.Path = path.Join(u.Path, endpoint)
+	u.RawPath = path.Join(u.EscapedPath(), endpoint)
+	if unescaped, err := url.PathUnescape(u.RawPath); err == nil {
+		u.Path = unescaped
+	}
+	u.RawQuery = rawQuery
 
 	req, err := http.NewRequestWithContext(ctx, method, u.String(), reqBody)
 	if err != nil {
@@ -134,6 +145,7 @@ func (c *Client) doRequest(ctx context.Context, method, endpoint string, body in
 		req.Header.Set("Content-Type", contentType)
 	}
 	req.Header.Set("Accept", acceptType)
+	c.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -182,6 +194,7 @@ func (c *Client) doPatchRequest(ctx context.Context, endpoint string, patchData
 		acceptType = fmt.Sprintf("application/json;version=%s", c.version)
 	}
 	req.Header.Set("Accept", acceptType)
+	c.authorize(req)
 
 	resp, err := c.httpClient.Do(req)
 	if err != nil {
@@ -384,3 +397,90 @@ func (c *Client) DeleteDiscoverySnapshot(ctx context.Context, uid string) error
 	}
 	return nil
 }
+
+// GetSubscriptions retrieves all subscriptions
+func (c *Client) GetSubscriptions(ctx context.Context) ([]subscription.Subscription, error) {
+	var response []subscription.Subscription
+	if err := c.doRequest(ctx, "GET", "/subscriptions", nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+// GetSubscription retrieves a specific Subscription by UID
+func (c *Client) GetSubscription(ctx context.Context, uid string) (*subscription.Subscription, error) {
+	var result subscription.Subscription
+	endpoint := fmt.Sprintf("/subscriptions/%s", uid)
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// CreateSubscription creates a new Subscription
+func (c *Client) CreateSubscription(ctx context.Context, req CreateSubscriptionRequest) (*subscription.Subscription, error) {
+	var result subscription.Subscription
+	if err := c.doRequest(ctx, "POST", "/subscriptions", req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpdateSubscription updates an existing Subscription
+func (c *Client) UpdateSubscription(ctx context.Context, uid string, req UpdateSubscriptionRequest) (*subscription.Subscription, error) {
+	var result subscription.Subscription
+	endpoint := fmt.Sprintf("/subscriptions/%s", uid)
+	if err := c.doRequest(ctx, "PUT", endpoint, req, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PatchSubscription patches an existing Subscription spec with the specified patch data and content type
+func (c *Client) PatchSubscription(ctx context.Context, uid string, patchData []byte, contentType string) (*subscription.Subscription, error) {
+	var result subscription.Subscription
+	endpoint := fmt.Sprintf("/subscriptions/%s", uid)
+	if err := c.doPatchRequest(ctx, endpoint, patchData, contentType, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// UpdateSubscriptionStatus updates only the status of an existing Subscription
+// This method is intended for controllers, reconcilers, and monitoring systems.
+// It preserves the spec and only updates the status portion of the resource.
+func (c *Client) UpdateSubscriptionStatus(ctx context.Context, uid string, status subscription.SubscriptionStatus) (*subscription.Subscription, error) {
+	var result subscription.Subscription
+	endpoint := fmt.Sprintf("/subscriptions/%s/status", uid)
+	if err := c.doRequest(ctx, "PUT", endpoint, status, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// PatchSubscriptionStatus patches only the status of an existing Subscription
+// Supports JSON Merge Patch by default. Use PatchSubscriptionStatusWithType for other patch formats.
+func (c *Client) PatchSubscriptionStatus(ctx context.Context, uid string, patchData []byte) (*subscription.Subscription, error) {
+	return c.PatchSubscriptionStatusWithType(ctx, uid, patchData, "application/merge-patch+json")
+}
+
+// PatchSubscriptionStatusWithType patches status with a specific patch content type
+// Supported types: application/merge-patch+json, application/json-patch+json, application/fabrica-patch+json
+func (c *Client) PatchSubscriptionStatusWithType(ctx context.Context, uid string, patchData []byte, contentType string) (*subscription.Subscription, error) {
+	var result subscription.Subscription
+	endpoint := fmt.Sprintf("/subscriptions/%s/status", uid)
+	if err := c.doPatchRequest(ctx, endpoint, patchData, contentType, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// DeleteSubscription deletes a Subscription by UID
+func (c *Client) DeleteSubscription(ctx context.Context, uid string) error {
+	endpoint := fmt.Sprintf("/subscriptions/%s", uid)
+	var response DeleteResponse
+	if err := c.doRequest(ctx, "DELETE", endpoint, nil, &response); err != nil {
+		return err
+	}
+	return nil
+}
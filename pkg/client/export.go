@@ -0,0 +1,129 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+)
+
+// Export formats
+const (
+	ExportNDJSON = "ndjson"
+	ExportCSV    = "csv"
+
+	// ExportSMDLocation and ExportSMDFRU are SMD hardware inventory by
+	// location (HWInvByLoc) and by FRU (HWInvByFRU), as JSON arrays
+	ExportSMDLocation = "smd-location"
+	ExportSMDFRU      = "smd-fru"
+)
+
+// Import actions (ImportRecordResult.Action)
+const (
+	ImportCreate    = "create"
+	ImportUpdate    = "update"
+	ImportUnchanged = "unchanged"
+	ImportError     = "error"
+)
+
+// ImportChange is one field an import changes on an existing Device.
+type ImportChange struct {
+	Field string `json:"field"`
+	From  any    `json:"from,omitempty"`
+	To    any    `json:"to,omitempty"`
+}
+
+// ImportRecordResult is what an import did (or, in a dry run, would do)
+// with the record on Line.
+type ImportRecordResult struct {
+	Line         int            `json:"line"`
+	SerialNumber string         `json:"serialNumber,omitempty"`
+	Action       string         `json:"action"`
+	UID          string         `json:"uid,omitempty"`
+	Changes      []ImportChange `json:"changes,omitempty"`
+	Error        string         `json:"error,omitempty"`
+}
+
+// ImportResult is the outcome of an import.
+type ImportResult struct {
+	DryRun    bool                 `json:"dryRun"`
+	Created   int                  `json:"created"`
+	Updated   int                  `json:"updated"`
+	Unchanged int                  `json:"unchanged"`
+	Failed    int                  `json:"failed"`
+	Records   []ImportRecordResult `json:"records"`
+}
+
+// ExportDevices streams every device matching opts' selectors and sort order
+// (its Limit and Continue are ignored) in one of the Export formats. The caller must close
+// the returned reader.
+func (c *Client) ExportDevices(ctx context.Context, format string, opts ListOptions) (io.ReadCloser, error) {
+	opts.Limit, opts.Continue = 0, ""
+	query := opts.query()
+	if format != "" {
+		query.Set("format", format)
+	}
+	return c.doStream(ctx, http.MethodGet, "/export", query, "", nil)
+}
+
+// ImportDevices upserts NDJSON devices (as written by ExportDevices) by
+// serial number. Records with errors are reported in the result and skipped;
+// the rest are committed together. With dryRun nothing is written and the
+// result shows what would change.
+func (c *Client) ImportDevices(ctx context.Context, ndjson io.Reader, dryRun bool) (*ImportResult, error) {
+	query := url.Values{}
+	if dryRun {
+		query.Set("dryRun", "true")
+	}
+	body, err := c.doStream(ctx, http.MethodPost, "/import", query, "application/x-ndjson", ndjson)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+
+	var result ImportResult
+	if err := json.NewDecoder(body).Decode(&result); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal response: %w", err)
+	}
+	return &result, nil
+}
+
+// doStream performs a request with a raw body and returns the response body
+// unread, for requests and responses that aren't single JSON documents
+func (c *Client) doStream(ctx context.Context, method, endpoint string, query url.Values, contentType string, body io.Reader) (io.ReadCloser, error) {
+	u := *c.baseURL
+	u.Path = path.Join(u.Path, endpoint)
+	u.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, method, u.String(), body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	if contentType != "" {
+		req.Header.Set("Content-Type", contentType)
+	}
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("request failed: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		respBody, _ := io.ReadAll(io.LimitReader(resp.Body, 64*1024))
+		var errorResp ErrorResponse
+		if err := json.Unmarshal(respBody, &errorResp); err != nil {
+			return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, strings.TrimSpace(string(respBody)))
+		}
+		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errorResp.Error)
+	}
+	return resp.Body, nil
+}
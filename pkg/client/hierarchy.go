@@ -0,0 +1,79 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// DeviceTreeNode is a Device with its descendants nested below it.
+type DeviceTreeNode struct {
+	Device   device.Device     `json:"device"`
+	Children []*DeviceTreeNode `json:"children,omitempty"`
+}
+
+// GetDeviceChildren retrieves the Devices directly below a Device.
+func (c *Client) GetDeviceChildren(ctx context.Context, uid string) ([]device.Device, error) {
+	var result []device.Device
+	if err := c.doRequest(ctx, "GET", fmt.Sprintf("/devices/%s/children", uid), nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetDeviceDescendants retrieves every Device below a Device, breadth first,
+// down to depth levels (0 for all).
+func (c *Client) GetDeviceDescendants(ctx context.Context, uid string, depth int) ([]device.Device, error) {
+	var result []device.Device
+	if err := c.doRequest(ctx, "GET", depthEndpoint(fmt.Sprintf("/devices/%s/descendants", uid), depth), nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetDeviceAncestors retrieves a Device's parent, its parent's parent and so
+// on up to the root.
+func (c *Client) GetDeviceAncestors(ctx context.Context, uid string) ([]device.Device, error) {
+	var result []device.Device
+	if err := c.doRequest(ctx, "GET", fmt.Sprintf("/devices/%s/ancestors", uid), nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetDeviceTree retrieves a Device with its descendants nested below it, down
+// to depth levels (0 for all).
+func (c *Client) GetDeviceTree(ctx context.Context, uid string, depth int) (*DeviceTreeNode, error) {
+	var result DeviceTreeNode
+	if err := c.doRequest(ctx, "GET", depthEndpoint(fmt.Sprintf("/devices/%s/tree", uid), depth), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+func depthEndpoint(endpoint string, depth int) string {
+	if depth > 0 {
+		return endpoint + "?" + url.Values{"depth": {strconv.Itoa(depth)}}.Encode()
+	}
+	return endpoint
+}
+
+// GetDeviceHBOM streams a CycloneDX hardware BOM (JSON) of a Device and
+// everything below it, or of every Device when uid is empty. The caller must
+// close the returned reader.
+func (c *Client) GetDeviceHBOM(ctx context.Context, uid string) (io.ReadCloser, error) {
+	endpoint := "/hbom"
+	if uid != "" {
+		endpoint = fmt.Sprintf("/devices/%s/hbom", uid)
+	}
+	return c.doStream(ctx, http.MethodGet, endpoint, nil, "", nil)
+}
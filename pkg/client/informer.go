@@ -0,0 +1,405 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"errors"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+)
+
+const (
+	// Backoff between reconnects after a watch fails
+	informerInitialBackoff = time.Second
+	informerMaxBackoff     = 30 * time.Second
+)
+
+// ResourceVersionAnnotation is the metadata annotation in which the server
+// keeps the resourceVersion an object was stored with. It increases with
+// every write, so the informer uses it to tell an older copy from a newer one.
+const ResourceVersionAnnotation = "inventory.openchami.io/resource-version"
+
+// ResourceEventHandlerFuncs receives changes to an informer's cache. Any of
+// the funcs may be nil. They are called one at a time from the informer's
+// goroutine, after the cache has been updated.
+type ResourceEventHandlerFuncs[T any] struct {
+	// AddFunc is called for a new object
+	AddFunc func(obj *T)
+
+	// UpdateFunc is called for a changed object, and for every object on each
+	// resync (then oldObj and newObj are the same)
+	UpdateFunc func(oldObj, newObj *T)
+
+	// DeleteFunc is called with the last cached version of a deleted object
+	DeleteFunc func(obj *T)
+}
+
+// WatchFunc opens a watch stream, e.g. Client.WatchDevices.
+type WatchFunc[T any] func(ctx context.Context, opts WatchOptions) (*Watch[T], error)
+
+// Informer keeps an indexed in-memory cache of one resource kind up to date
+// and calls handlers as it changes.
+//
+// Run lists every resource (as the start of a watch with SendInitialEvents,
+// so no change between the list and the watch is missed), then applies
+// changes from the watch. When the watch drops it resumes from the last
+// resourceVersion, and lists again if the server no longer has that history.
+// A watched change older than the cached object (by ResourceVersionAnnotation)
+// is dropped; a list replaces the cache as it is.
+type Informer[T any] struct {
+	watch   WatchFunc[T]
+	keyFunc func(obj *T) string
+	indexer *Indexer[T]
+	resync  time.Duration
+
+	mu       sync.Mutex
+	handlers []ResourceEventHandlerFuncs[T]
+
+	synced     chan struct{}
+	syncedOnce sync.Once
+
+	// resourceVersion is the watch position the cache reflects ("" until listed)
+	resourceVersion string
+}
+
+// NewInformer creates an informer. keyFunc returns an object's UID; indexers
+// are the secondary indexes kept on the cache; resync is how often every
+// cached object is passed to UpdateFunc again (0 disables it).
+func NewInformer[T any](watch WatchFunc[T], keyFunc func(obj *T) string, indexers map[string]IndexFunc[T], resync time.Duration) *Informer[T] {
+	return &Informer[T]{
+		watch:   watch,
+		keyFunc: keyFunc,
+		indexer: newIndexer(keyFunc, indexers),
+		resync:  resync,
+		synced:  make(chan struct{}),
+	}
+}
+
+// AddEventHandler registers handler funcs. Add them before Run to see the initial list.
+func (inf *Informer[T]) AddEventHandler(handler ResourceEventHandlerFuncs[T]) {
+	inf.mu.Lock()
+	defer inf.mu.Unlock()
+	inf.handlers = append(inf.handlers, handler)
+}
+
+// Indexer returns the informer's cache.
+func (inf *Informer[T]) Indexer() *Indexer[T] {
+	return inf.indexer
+}
+
+// HasSynced reports whether the initial list has been loaded into the cache.
+func (inf *Informer[T]) HasSynced() bool {
+	select {
+	case <-inf.synced:
+		return true
+	default:
+		return false
+	}
+}
+
+// WaitForSync blocks until the initial list has been loaded or ctx is done.
+func (inf *Informer[T]) WaitForSync(ctx context.Context) error {
+	select {
+	case <-inf.synced:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Run keeps the cache up to date until ctx is done.
+func (inf *Informer[T]) Run(ctx context.Context) error {
+	var resyncC <-chan time.Time
+	if inf.resync > 0 {
+		ticker := time.NewTicker(inf.resync)
+		defer ticker.Stop()
+		resyncC = ticker.C
+	}
+
+	backoff := informerInitialBackoff
+	for {
+		progressed, err := inf.watchOnce(ctx, resyncC)
+		if ctx.Err() != nil {
+			return nil
+		}
+		if errors.Is(err, ErrWatchExpired) {
+			// The server lost our position: list again right away
+			inf.resourceVersion = ""
+			continue
+		}
+		if progressed {
+			backoff = informerInitialBackoff
+		}
+
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > informerMaxBackoff {
+			backoff = informerMaxBackoff
+		}
+	}
+}
+
+// watchOnce runs one watch connection until it ends. It reports whether any
+// event was received, so Run can reset its backoff.
+func (inf *Informer[T]) watchOnce(ctx context.Context, resyncC <-chan time.Time) (bool, error) {
+	listing := inf.resourceVersion == ""
+	w, err := inf.watch(ctx, WatchOptions{
+		ResourceVersion:   inf.resourceVersion,
+		SendInitialEvents: listing,
+	})
+	if err != nil {
+		return false, err
+	}
+	defer w.Stop()
+
+	// Objects of the initial list, applied as a whole at its closing BOOKMARK
+	var initial []*T
+	progressed := false
+
+	for {
+		select {
+		case <-ctx.Done():
+			return progressed, ctx.Err()
+
+		case <-resyncC:
+			for _, obj := range inf.indexer.List() {
+				inf.notifyUpdate(obj, obj)
+			}
+
+		case event, ok := <-w.Events():
+			if !ok {
+				return progressed, w.Err()
+			}
+			progressed = true
+
+			obj := event.Object
+			switch {
+			case event.Type == WatchBookmark:
+				if listing {
+					inf.replace(initial)
+					initial, listing = nil, false
+					inf.syncedOnce.Do(func() { close(inf.synced) })
+				}
+			case listing:
+				// Only ADDED events until the BOOKMARK; the position isn't
+				// ours until the whole list is applied
+				initial = append(initial, &obj)
+				continue
+			case event.Type == WatchDeleted:
+				if old, existed := inf.indexer.remove(inf.keyFunc(&obj)); existed {
+					inf.notifyDelete(old)
+				}
+			default:
+				inf.apply(&obj)
+			}
+			inf.resourceVersion = event.ResourceVersion
+		}
+	}
+}
+
+// apply adds or updates one object from the watch and notifies handlers,
+// unless the cached copy is newer
+func (inf *Informer[T]) apply(obj *T) {
+	if cached, ok := inf.indexer.Get(inf.keyFunc(obj)); ok && storedVersion(obj) < storedVersion(cached) {
+		return
+	}
+	inf.store(obj)
+}
+
+// store adds or updates one object and notifies handlers
+func (inf *Informer[T]) store(obj *T) {
+	if old, existed := inf.indexer.set(obj); existed {
+		inf.notifyUpdate(old, obj)
+	} else {
+		inf.notifyAdd(obj)
+	}
+}
+
+// replace makes the cache hold exactly objs, notifying handlers of the differences
+func (inf *Informer[T]) replace(objs []*T) {
+	keep := make(map[string]struct{}, len(objs))
+	for _, obj := range objs {
+		keep[inf.keyFunc(obj)] = struct{}{}
+	}
+	for _, key := range inf.indexer.keys() {
+		if _, ok := keep[key]; ok {
+			continue
+		}
+		if old, existed := inf.indexer.remove(key); existed {
+			inf.notifyDelete(old)
+		}
+	}
+	for _, obj := range objs {
+		inf.store(obj)
+	}
+}
+
+// storedVersion returns the resourceVersion obj was stored with, 0 if it has none
+func storedVersion(obj any) uint64 {
+	annotated, ok := obj.(interface {
+		GetAnnotation(key string) (string, bool)
+	})
+	if !ok {
+		return 0
+	}
+	value, _ := annotated.GetAnnotation(ResourceVersionAnnotation)
+	version, _ := strconv.ParseUint(value, 10, 64)
+	return version
+}
+
+func (inf *Informer[T]) eventHandlers() []ResourceEventHandlerFuncs[T] {
+	inf.mu.Lock()
+	defer inf.mu.Unlock()
+	return append([]ResourceEventHandlerFuncs[T](nil), inf.handlers...)
+}
+
+func (inf *Informer[T]) notifyAdd(obj *T) {
+	for _, h := range inf.eventHandlers() {
+		if h.AddFunc != nil {
+			h.AddFunc(obj)
+		}
+	}
+}
+
+func (inf *Informer[T]) notifyUpdate(oldObj, newObj *T) {
+	for _, h := range inf.eventHandlers() {
+		if h.UpdateFunc != nil {
+			h.UpdateFunc(oldObj, newObj)
+		}
+	}
+}
+
+func (inf *Informer[T]) notifyDelete(obj *T) {
+	for _, h := range inf.eventHandlers() {
+		if h.DeleteFunc != nil {
+			h.DeleteFunc(obj)
+		}
+	}
+}
+
+// Index names on the caches of NewDeviceInformer and NewDiscoverySnapshotInformer
+const (
+	// IndexSerialNumber indexes devices by Spec.SerialNumber
+	IndexSerialNumber = "serialNumber"
+
+	// IndexParentID indexes devices by Spec.ParentID
+	IndexParentID = "parentID"
+
+	// IndexLabel indexes resources by each "key=value" label (see LabelIndexValue)
+	IndexLabel = "label"
+)
+
+// LabelIndexValue returns the IndexLabel value for a label.
+func LabelIndexValue(key, value string) string {
+	return key + "=" + value
+}
+
+// labelIndexFunc indexes a resource under each of its labels
+func labelIndexFunc(labels map[string]string) []string {
+	values := make([]string, 0, len(labels))
+	for key, value := range labels {
+		values = append(values, LabelIndexValue(key, value))
+	}
+	return values
+}
+
+// DeviceInformer is an informer for devices, indexed by serial number, parent and label.
+type DeviceInformer struct {
+	*Informer[device.Device]
+}
+
+// NewDeviceInformer creates a device informer. Call Run to start it.
+func NewDeviceInformer(c *Client, resync time.Duration) *DeviceInformer {
+	indexers := map[string]IndexFunc[device.Device]{
+		IndexSerialNumber: func(d *device.Device) []string {
+			if d.Spec.SerialNumber == "" {
+				return nil
+			}
+			return []string{d.Spec.SerialNumber}
+		},
+		IndexParentID: func(d *device.Device) []string {
+			if d.Spec.ParentID == "" {
+				return nil
+			}
+			return []string{d.Spec.ParentID}
+		},
+		IndexLabel: func(d *device.Device) []string {
+			return labelIndexFunc(d.GetLabels())
+		},
+	}
+	return &DeviceInformer{
+		Informer: NewInformer(c.WatchDevices, (*device.Device).GetUID, indexers, resync),
+	}
+}
+
+// Get returns the cached device with a UID.
+func (i *DeviceInformer) Get(uid string) (*device.Device, bool) {
+	return i.indexer.Get(uid)
+}
+
+// List returns every cached device.
+func (i *DeviceInformer) List() []*device.Device {
+	return i.indexer.List()
+}
+
+// GetBySerialNumber returns the cached device with a serial number.
+func (i *DeviceInformer) GetBySerialNumber(serialNumber string) (*device.Device, bool) {
+	devices := i.indexer.ByIndex(IndexSerialNumber, serialNumber)
+	if len(devices) == 0 {
+		return nil, false
+	}
+	return devices[0], true
+}
+
+// ListChildren returns the cached devices whose parent is parentID.
+func (i *DeviceInformer) ListChildren(parentID string) []*device.Device {
+	return i.indexer.ByIndex(IndexParentID, parentID)
+}
+
+// ListByLabel returns the cached devices with a label.
+func (i *DeviceInformer) ListByLabel(key, value string) []*device.Device {
+	return i.indexer.ByIndex(IndexLabel, LabelIndexValue(key, value))
+}
+
+// DiscoverySnapshotInformer is an informer for discoverysnapshots, indexed by label.
+type DiscoverySnapshotInformer struct {
+	*Informer[discoverysnapshot.DiscoverySnapshot]
+}
+
+// NewDiscoverySnapshotInformer creates a discoverysnapshot informer. Call Run to start it.
+func NewDiscoverySnapshotInformer(c *Client, resync time.Duration) *DiscoverySnapshotInformer {
+	indexers := map[string]IndexFunc[discoverysnapshot.DiscoverySnapshot]{
+		IndexLabel: func(s *discoverysnapshot.DiscoverySnapshot) []string {
+			return labelIndexFunc(s.GetLabels())
+		},
+	}
+	return &DiscoverySnapshotInformer{
+		Informer: NewInformer(c.WatchDiscoverySnapshots, (*discoverysnapshot.DiscoverySnapshot).GetUID, indexers, resync),
+	}
+}
+
+// Get returns the cached discoverysnapshot with a UID.
+func (i *DiscoverySnapshotInformer) Get(uid string) (*discoverysnapshot.DiscoverySnapshot, bool) {
+	return i.indexer.Get(uid)
+}
+
+// List returns every cached discoverysnapshot.
+func (i *DiscoverySnapshotInformer) List() []*discoverysnapshot.DiscoverySnapshot {
+	return i.indexer.List()
+}
+
+// ListByLabel returns the cached discoverysnapshots with a label.
+func (i *DiscoverySnapshotInformer) ListByLabel(key, value string) []*discoverysnapshot.DiscoverySnapshot {
+	return i.indexer.ByIndex(IndexLabel, LabelIndexValue(key, value))
+}
@@ -0,0 +1,137 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// testDevice is a device with a UID and, unless version is empty, a stored resourceVersion
+func testDevice(uid, version string) device.Device {
+	var dev device.Device
+	dev.Metadata.UID = uid
+	if version != "" {
+		dev.SetAnnotation(ResourceVersionAnnotation, version)
+	}
+	return dev
+}
+
+// scriptedWatch returns a watch that delivers events and then ends with err,
+// or stays open until stopped if err is nil
+func scriptedWatch(err error, events ...WatchEvent[device.Device]) *Watch[device.Device] {
+	ch := make(chan WatchEvent[device.Device], len(events))
+	for _, event := range events {
+		ch <- event
+	}
+	w := &Watch[device.Device]{events: ch, cancel: func() {}, err: err}
+	if err != nil {
+		close(ch)
+	}
+	return w
+}
+
+// TestInformerOrderingDeleteRelist runs an informer through a list, a late
+// update, deletes and a relist after the watch expired, and checks the
+// handler calls and the final cache.
+func TestInformerOrderingDeleteRelist(t *testing.T) {
+	watches := []*Watch[device.Device]{
+		scriptedWatch(ErrWatchExpired,
+			WatchEvent[device.Device]{Type: WatchAdded, Object: testDevice("dev-1", "2")},
+			WatchEvent[device.Device]{Type: WatchAdded, Object: testDevice("dev-2", "4")},
+			WatchEvent[device.Device]{Type: WatchBookmark, ResourceVersion: "e-2"},
+			WatchEvent[device.Device]{Type: WatchModified, ResourceVersion: "e-3", Object: testDevice("dev-1", "5")},
+			WatchEvent[device.Device]{Type: WatchModified, ResourceVersion: "e-4", Object: testDevice("dev-1", "3")}, // late
+			WatchEvent[device.Device]{Type: WatchDeleted, ResourceVersion: "e-5", Object: testDevice("dev-2", "")},
+			WatchEvent[device.Device]{Type: WatchDeleted, ResourceVersion: "e-6", Object: testDevice("dev-9", "")}, // never cached
+		),
+		// The relist replaces the cache as it is, even with an older copy (e.g. after a restore)
+		scriptedWatch(nil,
+			WatchEvent[device.Device]{Type: WatchAdded, Object: testDevice("dev-1", "1")},
+			WatchEvent[device.Device]{Type: WatchAdded, Object: testDevice("dev-3", "7")},
+			WatchEvent[device.Device]{Type: WatchBookmark, ResourceVersion: "f-7"},
+		),
+	}
+	var calls []WatchOptions
+	watch := func(ctx context.Context, opts WatchOptions) (*Watch[device.Device], error) {
+		if len(calls) == len(watches) {
+			t.Errorf("unexpected watch %+v", opts)
+			<-ctx.Done()
+			return nil, ctx.Err()
+		}
+		w := watches[len(calls)]
+		calls = append(calls, opts)
+		return w, nil
+	}
+
+	var mu sync.Mutex
+	var log []string
+	record := func(format string, args ...any) {
+		mu.Lock()
+		defer mu.Unlock()
+		log = append(log, fmt.Sprintf(format, args...))
+	}
+	inf := NewInformer(watch, (*device.Device).GetUID, nil, 0)
+	inf.AddEventHandler(ResourceEventHandlerFuncs[device.Device]{
+		AddFunc: func(obj *device.Device) { record("add %s %d", obj.GetUID(), storedVersion(obj)) },
+		UpdateFunc: func(oldObj, newObj *device.Device) {
+			record("update %s %d->%d", newObj.GetUID(), storedVersion(oldObj), storedVersion(newObj))
+		},
+		DeleteFunc: func(obj *device.Device) { record("delete %s %d", obj.GetUID(), storedVersion(obj)) },
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		inf.Run(ctx)
+	}()
+
+	want := []string{
+		"add dev-1 2",
+		"add dev-2 4",
+		"update dev-1 2->5",
+		"delete dev-2 4",
+		"update dev-1 5->1",
+		"add dev-3 7",
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		mu.Lock()
+		n := len(log)
+		mu.Unlock()
+		if n >= len(want) || time.Now().After(deadline) {
+			break
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	cancel()
+	<-done
+
+	if !slices.Equal(log, want) {
+		t.Errorf("handler calls:\n%s\nwant:\n%s", strings.Join(log, "\n"), strings.Join(want, "\n"))
+	}
+	if len(calls) != 2 || !calls[0].SendInitialEvents || !calls[1].SendInitialEvents || calls[1].ResourceVersion != "" {
+		t.Errorf("watch calls %+v, want two lists", calls)
+	}
+	if inf.resourceVersion != "f-7" {
+		t.Errorf("resourceVersion %q, want f-7", inf.resourceVersion)
+	}
+	var cached []string
+	for _, dev := range inf.Indexer().List() {
+		cached = append(cached, fmt.Sprintf("%s %d", dev.GetUID(), storedVersion(dev)))
+	}
+	slices.Sort(cached)
+	if want := []string{"dev-1 1", "dev-3 7"}; !slices.Equal(cached, want) {
+		t.Errorf("cache %v, want %v", cached, want)
+	}
+}
@@ -0,0 +1,38 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"strings"
+)
+
+// DHCP configuration formats
+const (
+	DHCPDnsmasq = "dnsmasq"
+	DHCPISC     = "isc"
+	DHCPKea     = "kea"
+)
+
+// DHCPReservations returns DHCP host reservations for the MAC addresses of
+// the Devices of deviceTypes (Nodes when empty; "*" for all) matching opts'
+// selectors, rendered in one of the DHCP formats.
+func (c *Client) DHCPReservations(ctx context.Context, format string, deviceTypes []string, opts ListOptions) ([]byte, error) {
+	query := ListOptions{LabelSelector: opts.LabelSelector, FieldSelector: opts.FieldSelector}.query()
+	if format != "" {
+		query.Set("format", format)
+	}
+	if len(deviceTypes) > 0 {
+		query.Set("deviceType", strings.Join(deviceTypes, ","))
+	}
+	body, err := c.doStream(ctx, http.MethodGet, "/integrations/dhcp", query, "", nil)
+	if err != nil {
+		return nil, err
+	}
+	defer body.Close()
+	return io.ReadAll(body)
+}
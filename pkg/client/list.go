@@ -0,0 +1,187 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"iter"
+	"net/url"
+	"strconv"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+)
+
+// Sort orders for ListOptions.SortBy
+const (
+	SortByUID       = "uid"
+	SortByName      = "name"
+	SortByCreatedAt = "createdAt"
+	SortByUpdatedAt = "updatedAt"
+)
+
+// DefaultPageSize is the page size iterators use when ListOptions.Limit is 0.
+const DefaultPageSize = 500
+
+// ListOptions filters, sorts and pages a list request.
+type ListOptions struct {
+	// LabelSelector selects by label, e.g. "rack=r12,role in (compute,login)"
+	LabelSelector string
+
+	// FieldSelector selects by field, e.g. "spec.deviceType=DIMM,spec.manufacturer=Hynix".
+	// Devices support spec.deviceType, spec.manufacturer, spec.partNumber,
+	// spec.serialNumber, spec.parentID, spec.parentSerialNumber, status.phase,
+	// metadata.name, metadata.uid and spec.properties.<key>.
+	FieldSelector string
+
+	// SortBy is one of the SortBy constants (default: by UID)
+	SortBy string
+
+	// Descending reverses the sort order
+	Descending bool
+
+	// Limit is the page size (the server allows at most 1000). 0 lists
+	// everything in one response, except for iterators which use DefaultPageSize.
+	Limit int
+
+	// Continue is the token from the previous page
+	Continue string
+}
+
+// ListPage is one page of a list.
+type ListPage[T any] struct {
+	Items []T `json:"items"`
+
+	// Continue fetches the next page; empty on the last page
+	Continue string `json:"continue,omitempty"`
+
+	// Total is the number of resources matching the request, across all pages
+	Total int `json:"total"`
+}
+
+// query encodes the options as list query parameters
+func (o ListOptions) query() url.Values {
+	query := url.Values{}
+	if o.LabelSelector != "" {
+		query.Set("labelSelector", o.LabelSelector)
+	}
+	if o.FieldSelector != "" {
+		query.Set("fieldSelector", o.FieldSelector)
+	}
+	if o.SortBy != "" {
+		query.Set("sortBy", o.SortBy)
+	}
+	if o.Descending {
+		query.Set("order", "desc")
+	}
+	if o.Limit > 0 {
+		query.Set("limit", strconv.Itoa(o.Limit))
+	}
+	if o.Continue != "" {
+		query.Set("continue", o.Continue)
+	}
+	return query
+}
+
+// endpoint appends the options to a list endpoint
+func (o ListOptions) endpoint(endpoint string) string {
+	if query := o.query(); len(query) > 0 {
+		return endpoint + "?" + query.Encode()
+	}
+	return endpoint
+}
+
+// ListDevices retrieves every device matching opts. With opts.Limit set it
+// fetches them a page of that size at a time.
+func (c *Client) ListDevices(ctx context.Context, opts ListOptions) ([]device.Device, error) {
+	return listAll[device.Device](ctx, c, "/devices", opts)
+}
+
+// ListDevicesPage retrieves one page of devices. opts.Limit defaults to DefaultPageSize.
+func (c *Client) ListDevicesPage(ctx context.Context, opts ListOptions) (*ListPage[device.Device], error) {
+	return listPage[device.Device](ctx, c, "/devices", opts)
+}
+
+// IterateDevices yields every device matching opts, fetching pages as it goes.
+// Iteration stops after the first error.
+//
+//	for d, err := range c.IterateDevices(ctx, client.ListOptions{FieldSelector: "spec.deviceType=DIMM"}) {
+//		if err != nil { ... }
+//	}
+func (c *Client) IterateDevices(ctx context.Context, opts ListOptions) iter.Seq2[device.Device, error] {
+	return iterate[device.Device](ctx, c, "/devices", opts)
+}
+
+// ListDiscoverySnapshots retrieves every discoverysnapshot matching opts. With
+// opts.Limit set it fetches them a page of that size at a time.
+func (c *Client) ListDiscoverySnapshots(ctx context.Context, opts ListOptions) ([]discoverysnapshot.DiscoverySnapshot, error) {
+	return listAll[discoverysnapshot.DiscoverySnapshot](ctx, c, "/discoverysnapshots", opts)
+}
+
+// ListDiscoverySnapshotsPage retrieves one page of discoverysnapshots. opts.Limit defaults to DefaultPageSize.
+func (c *Client) ListDiscoverySnapshotsPage(ctx context.Context, opts ListOptions) (*ListPage[discoverysnapshot.DiscoverySnapshot], error) {
+	return listPage[discoverysnapshot.DiscoverySnapshot](ctx, c, "/discoverysnapshots", opts)
+}
+
+// IterateDiscoverySnapshots yields every discoverysnapshot matching opts,
+// fetching pages as it goes. Iteration stops after the first error.
+func (c *Client) IterateDiscoverySnapshots(ctx context.Context, opts ListOptions) iter.Seq2[discoverysnapshot.DiscoverySnapshot, error] {
+	return iterate[discoverysnapshot.DiscoverySnapshot](ctx, c, "/discoverysnapshots", opts)
+}
+
+// listAll fetches a whole list, in one response or page by page
+func listAll[T any](ctx context.Context, c *Client, endpoint string, opts ListOptions) ([]T, error) {
+	if opts.Limit == 0 && opts.Continue == "" {
+		var response []T
+		if err := c.doRequest(ctx, "GET", opts.endpoint(endpoint), nil, &response); err != nil {
+			return nil, err
+		}
+		return response, nil
+	}
+
+	var items []T
+	for item, err := range iterate[T](ctx, c, endpoint, opts) {
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// listPage fetches one page of a list
+func listPage[T any](ctx context.Context, c *Client, endpoint string, opts ListOptions) (*ListPage[T], error) {
+	if opts.Limit == 0 {
+		opts.Limit = DefaultPageSize
+	}
+	var page ListPage[T]
+	if err := c.doRequest(ctx, "GET", opts.endpoint(endpoint), nil, &page); err != nil {
+		return nil, err
+	}
+	return &page, nil
+}
+
+// iterate yields the items of a list page by page
+func iterate[T any](ctx context.Context, c *Client, endpoint string, opts ListOptions) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		for {
+			page, err := listPage[T](ctx, c, endpoint, opts)
+			if err != nil {
+				var zero T
+				yield(zero, err)
+				return
+			}
+			for _, item := range page.Items {
+				if !yield(item, nil) {
+					return
+				}
+			}
+			if page.Continue == "" {
+				return
+			}
+			opts.Continue = page.Continue
+		}
+	}
+}
@@ -0,0 +1,43 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"context"
+	"net/url"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// GetDeviceBySerial retrieves the Device with a serial number.
+func (c *Client) GetDeviceBySerial(ctx context.Context, serial string) (*device.Device, error) {
+	var result device.Device
+	if err := c.doRequest(ctx, "GET", "/devices/by-serial/"+url.PathEscape(serial), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetDeviceByMAC retrieves the Device with a MAC-valued property (such as
+// mac_address) equal to mac. It fails if several Devices have the address.
+func (c *Client) GetDeviceByMAC(ctx context.Context, mac string) (*device.Device, error) {
+	var result device.Device
+	if err := c.doRequest(ctx, "GET", "/devices/by-mac/"+url.PathEscape(mac), nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// GetDevicesByRedfishURI retrieves the Devices whose redfish_uri property is
+// uri (with or without the /redfish/v1 prefix, or a full URL). Redfish URIs
+// are only unique per BMC, so several Devices can match.
+func (c *Client) GetDevicesByRedfishURI(ctx context.Context, uri string) ([]device.Device, error) {
+	var result []device.Device
+	endpoint := "/devices/by-redfish-uri?" + url.Values{"uri": {uri}}.Encode()
+	if err := c.doRequest(ctx, "GET", endpoint, nil, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
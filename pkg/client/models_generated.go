@@ -36,6 +36,7 @@ package client
 import (
 	"github.com/user/inventory-api/pkg/resources/device"
 	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+	"github.com/user/inventory-api/pkg/resources/subscription"
 )
 
 // CreateDeviceRequest represents a request to create a Device
@@ -70,6 +71,22 @@ type UpdateDiscoverySnapshotRequest struct {
 	Annotations                             map[string]string `json:"annotations,omitempty"`
 }
 
+// CreateSubscriptionRequest represents a request to create a Subscription
+type CreateSubscriptionRequest struct {
+	subscription.SubscriptionSpec `json:",inline"`
+	Name                          string            `json:"name" validate:"required"`
+	Labels                        map[string]string `json:"labels,omitempty"`
+	Annotations                   map[string]string `json:"annotations,omitempty"`
+}
+
+// UpdateSubscriptionRequest represents a request to update a Subscription
+type UpdateSubscriptionRequest struct {
+	subscription.SubscriptionSpec `json:",inline,omitempty"`
+	Name                          string            `json:"name,omitempty"`
+	Labels                        map[string]string `json:"labels,omitempty"`
+	Annotations                   map[string]string `json:"annotations,omitempty"`
+}
+
 // DeleteResponse represents a successful deletion response
 type DeleteResponse struct {
 	Message string `json:"message"`
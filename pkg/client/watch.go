@@ -0,0 +1,204 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package client
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"path"
+	"sync"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+)
+
+// WatchEventType is the kind of change a watch event reports.
+type WatchEventType string
+
+const (
+	WatchAdded    WatchEventType = "ADDED"
+	WatchModified WatchEventType = "MODIFIED"
+	WatchDeleted  WatchEventType = "DELETED"
+	WatchBookmark WatchEventType = "BOOKMARK"
+	watchError    WatchEventType = "ERROR"
+)
+
+// maxWatchLine bounds one NDJSON line (one resource) on a watch stream
+const maxWatchLine = 16 << 20
+
+// ErrWatchExpired is returned when the server no longer has the history to
+// resume a watch from the requested position. List again and watch from the start.
+var ErrWatchExpired = errors.New("watch resourceVersion expired")
+
+// WatchOptions says where a watch starts.
+type WatchOptions struct {
+	// ResourceVersion resumes after this watch resourceVersion (from an earlier event or bookmark)
+	ResourceVersion string
+
+	// EventID resumes after the event with this CloudEvent ID
+	EventID string
+
+	// SendInitialEvents starts the watch with an ADDED event for every existing
+	// resource, followed by a BOOKMARK
+	SendInitialEvents bool
+}
+
+// WatchEvent is one change to a resource of type T.
+//
+// For DELETED events Object only has the kind and the UID, name and labels in
+// its metadata. BOOKMARK events have no Object.
+type WatchEvent[T any] struct {
+	Type            WatchEventType `json:"type"`
+	ResourceVersion string         `json:"resourceVersion"`
+	EventID         string         `json:"eventId,omitempty"`
+	Object          T              `json:"object"`
+}
+
+// Watch is a running watch stream.
+type Watch[T any] struct {
+	events chan WatchEvent[T]
+	cancel context.CancelFunc
+
+	mu  sync.Mutex
+	err error
+}
+
+// Events returns the watch's events. It is closed when the watch ends; Err says why.
+func (w *Watch[T]) Events() <-chan WatchEvent[T] {
+	return w.events
+}
+
+// Err returns why the watch ended: nil if it was stopped, otherwise the
+// stream or server error. Resume with the last ResourceVersion seen; on
+// ErrWatchExpired, list again instead.
+func (w *Watch[T]) Err() error {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return w.err
+}
+
+// Stop ends the watch.
+func (w *Watch[T]) Stop() {
+	w.cancel()
+}
+
+// WatchDevices streams changes to devices.
+func (c *Client) WatchDevices(ctx context.Context, opts WatchOptions) (*Watch[device.Device], error) {
+	return startWatch[device.Device](ctx, c, "/devices", opts)
+}
+
+// WatchDiscoverySnapshots streams changes to discoverysnapshots.
+func (c *Client) WatchDiscoverySnapshots(ctx context.Context, opts WatchOptions) (*Watch[discoverysnapshot.DiscoverySnapshot], error) {
+	return startWatch[discoverysnapshot.DiscoverySnapshot](ctx, c, "/discoverysnapshots", opts)
+}
+
+// startWatch opens an NDJSON watch stream on a list endpoint
+func startWatch[T any](ctx context.Context, c *Client, endpoint string, opts WatchOptions) (*Watch[T], error) {
+	u := *c.baseURL
+	u.Path = path.Join(u.Path, endpoint)
+	query := url.Values{"watch": {"true"}}
+	if opts.ResourceVersion != "" {
+		query.Set("resourceVersion", opts.ResourceVersion)
+	}
+	if opts.EventID != "" {
+		query.Set("eventId", opts.EventID)
+	}
+	if opts.SendInitialEvents {
+		query.Set("sendInitialEvents", "true")
+	}
+	u.RawQuery = query.Encode()
+
+	ctx, cancel := context.WithCancel(ctx)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, u.String(), nil)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	req.Header.Set("Accept", "application/x-ndjson")
+	c.authorize(req)
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		cancel()
+		return nil, fmt.Errorf("watch request failed: %w", err)
+	}
+	if resp.StatusCode >= 400 {
+		defer resp.Body.Close()
+		defer cancel()
+		body, _ := io.ReadAll(resp.Body)
+		if resp.StatusCode == http.StatusGone {
+			return nil, ErrWatchExpired
+		}
+		var errorResp ErrorResponse
+		if err := json.Unmarshal(body, &errorResp); err != nil {
+			return nil, fmt.Errorf("HTTP error %d: %s", resp.StatusCode, string(body))
+		}
+		return nil, fmt.Errorf("API error (%d): %s", resp.StatusCode, errorResp.Error)
+	}
+
+	w := &Watch[T]{
+		events: make(chan WatchEvent[T]),
+		cancel: cancel,
+	}
+	go w.read(ctx, resp.Body)
+	return w, nil
+}
+
+// read decodes the stream until it ends or the watch is stopped
+func (w *Watch[T]) read(ctx context.Context, body io.ReadCloser) {
+	defer close(w.events)
+	defer body.Close()
+
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64<<10), maxWatchLine)
+	for scanner.Scan() {
+		var header struct {
+			Type   WatchEventType `json:"type"`
+			Object struct {
+				Message string `json:"message"`
+			} `json:"object"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &header); err != nil {
+			w.setErr(fmt.Errorf("failed to decode watch event: %w", err))
+			return
+		}
+		if header.Type == watchError {
+			w.setErr(fmt.Errorf("watch ended by server: %s", header.Object.Message))
+			return
+		}
+
+		var event WatchEvent[T]
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			w.setErr(fmt.Errorf("failed to decode watch event: %w", err))
+			return
+		}
+		select {
+		case w.events <- event:
+		case <-ctx.Done():
+			return
+		}
+	}
+
+	if ctx.Err() != nil {
+		return // stopped
+	}
+	if err := scanner.Err(); err != nil {
+		w.setErr(fmt.Errorf("watch stream failed: %w", err))
+		return
+	}
+	w.setErr(io.ErrUnexpectedEOF)
+}
+
+func (w *Watch[T]) setErr(err error) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	w.err = err
+}
@@ -25,7 +25,10 @@ const InventoryAPIHost = "http://localhost:8081"
 const DefaultUsername = "root"
 const DefaultPassword = "initial0"
 
-func CollectAndPost(bmcIP string) error {
+// CollectAndPost gathers the inventory of the BMC at bmcIP and posts it to
+// the inventory API as a DiscoverySnapshot, authenticating with token when
+// it isn't empty.
+func CollectAndPost(bmcIP, token string) error {
 	rfClient, err := NewRedfishClient(bmcIP, DefaultUsername, DefaultPassword)
 	if err != nil {
 		return fmt.Errorf("failed to initialize Redfish client: %w", err)
@@ -47,6 +50,9 @@ func CollectAndPost(bmcIP string) error {
 	if err != nil {
 		return fmt.Errorf("failed to create fabrica client: %w", err)
 	}
+	if token != "" {
+		sdkClient = sdkClient.WithToken(token)
+	}
 	ctx := context.Background()
 	fmt.Println("Creating new DiscoverySnapshot resource...")
 	snapshotSpec := discoverysnapshot.DiscoverySnapshotSpec{
@@ -41,8 +41,16 @@ type DeviceStatus struct {
 	Ready      bool   `json:"ready"`
 
 	// ChildrenDeviceIds is a read-only list of devices contained within this one.
-	// This field would be populated by a different reconciler, not by the snapshot.
+	// It is populated by the DeviceTopologyReconciler, not by the snapshot.
 	ChildrenDeviceIds []string `json:"childrenDeviceIds,omitempty"`
+
+	// Depth is the number of ancestors above this device (0 for a root device).
+	// Populated by the DeviceTopologyReconciler.
+	Depth int `json:"depth"`
+
+	// RootDeviceID is the UID of the top-most ancestor (the device itself if it has no parent).
+	// Populated by the DeviceTopologyReconciler.
+	RootDeviceID string `json:"rootDeviceId,omitempty"`
 }
 
 // Validate implements custom validation logic for Device
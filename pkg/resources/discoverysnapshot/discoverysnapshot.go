@@ -8,6 +8,7 @@ import (
 	"context"
 	"github.com/openchami/fabrica/pkg/resource"
 	"encoding/json"
+	"time"
 )
 
 // DiscoverySnapshot represents a DiscoverySnapshot resource
@@ -29,6 +30,19 @@ type DiscoverySnapshotStatus struct {
 	Phase      string `json:"phase,omitempty"`
 	Message    string `json:"message,omitempty"`
 	Ready      bool   `json:"ready"`
+
+	// Retryable reports whether an "Error" phase may succeed if the snapshot is
+	// reconciled again (false for e.g. an unparseable rawData payload).
+	Retryable bool `json:"retryable,omitempty"`
+	// Attempts counts the reconciles that failed with a retryable error; the
+	// snapshot is given up on (no longer Retryable) after a fixed number
+	Attempts int `json:"attempts,omitempty"`
+	// NextAttemptAt is when a retryable "Error" is due to be reconciled
+	// again; the resync leaves the snapshot alone until then
+	NextAttemptAt *time.Time `json:"nextAttemptAt,omitempty"`
+	// ProcessingSince is when the reconcile in progress set the "Processing"
+	// phase; the resync only takes the snapshot over once it is stale
+	ProcessingSince *time.Time `json:"processingSince,omitempty"`
 }
 
 // Validate implements custom validation logic for DiscoverySnapshot
@@ -10,6 +10,7 @@ import (
 	"github.com/openchami/fabrica/pkg/codegen"
 	"github.com/user/inventory-api/pkg/resources/device"
 	"github.com/user/inventory-api/pkg/resources/discoverysnapshot"
+	"github.com/user/inventory-api/pkg/resources/subscription"
 )
 
 // RegisterAllResources registers all discovered resources with the generator.
@@ -29,6 +30,13 @@ func RegisterAllResources(gen *codegen.Generator) error {
 	if hasVersioningMarker("DiscoverySnapshot") {
 		gen.SetResourceTag("DiscoverySnapshot", "versioning", "enabled")
 	}
+	if err := gen.RegisterResource(&subscription.Subscription{}); err != nil {
+		return fmt.Errorf("failed to register Subscription: %w", err)
+	}
+	// Set per-resource tags based on source markers
+	if hasVersioningMarker("Subscription") {
+		gen.SetResourceTag("Subscription", "versioning", "enabled")
+	}
 
 	return nil
 }
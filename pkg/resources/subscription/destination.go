@@ -0,0 +1,84 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package subscription
+
+import (
+	"fmt"
+	"net/netip"
+	"strings"
+	"sync"
+)
+
+// blockedNetworks are refused as webhook destinations unless allowed with
+// SetAllowedNetworks: loopback, link-local (which holds most cloud metadata
+// services), unspecified addresses and the metadata services outside it.
+var blockedNetworks = []netip.Prefix{
+	netip.MustParsePrefix("127.0.0.0/8"),
+	netip.MustParsePrefix("::1/128"),
+	netip.MustParsePrefix("169.254.0.0/16"),
+	netip.MustParsePrefix("fe80::/10"),
+	netip.MustParsePrefix("0.0.0.0/8"),
+	netip.MustParsePrefix("::/128"),
+	netip.MustParsePrefix("100.100.100.200/32"), // Alibaba Cloud metadata
+	netip.MustParsePrefix("fd00:ec2::254/128"),  // AWS metadata over IPv6
+}
+
+// hostAddresses are the addresses of host names that are checked before
+// they are resolved: the local machine and cloud metadata services
+var hostAddresses = map[string]netip.Addr{
+	"localhost":                netip.MustParseAddr("127.0.0.1"),
+	"metadata":                 netip.MustParseAddr("169.254.169.254"),
+	"metadata.google.internal": netip.MustParseAddr("169.254.169.254"),
+}
+
+var (
+	allowedMu sync.RWMutex
+	allowed   []netip.Prefix
+)
+
+// SetAllowedNetworks lets webhooks reach addresses in networks even where
+// they are blocked by default (e.g. 127.0.0.1/32 for a receiver on the
+// server's host). It is set once from the server configuration.
+func SetAllowedNetworks(networks []netip.Prefix) {
+	allowedMu.Lock()
+	defer allowedMu.Unlock()
+	allowed = append([]netip.Prefix(nil), networks...)
+}
+
+// CheckAddress returns an error if webhooks may not be delivered to addr.
+// The dispatcher calls it for every address it connects to, so a host name
+// that resolves to a blocked address is refused too.
+func CheckAddress(addr netip.Addr) error {
+	addr = addr.Unmap().WithZone("")
+	allowedMu.RLock()
+	defer allowedMu.RUnlock()
+	for _, network := range allowed {
+		if network.Contains(addr) {
+			return nil
+		}
+	}
+	for _, network := range blockedNetworks {
+		if network.Contains(addr) {
+			return fmt.Errorf("webhook destination %s is a loopback, link-local or metadata address", addr)
+		}
+	}
+	return nil
+}
+
+// checkHost refuses URL hosts that are blocked addresses or the names in
+// hostAddresses. Other names are checked when they are resolved, at delivery.
+func checkHost(host string) error {
+	if addr, err := netip.ParseAddr(host); err == nil {
+		return CheckAddress(addr)
+	}
+	name := strings.TrimSuffix(strings.ToLower(host), ".")
+	if strings.HasSuffix(name, ".localhost") {
+		name = "localhost"
+	}
+	if addr, ok := hostAddresses[name]; ok {
+		return CheckAddress(addr)
+	}
+	return nil
+}
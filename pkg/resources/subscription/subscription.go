@@ -0,0 +1,135 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package subscription
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/url"
+	"time"
+
+	"github.com/openchami/fabrica/pkg/resource"
+)
+
+// MaxDeadLetters bounds Status.DeadLetters; the oldest entries are dropped first.
+const MaxDeadLetters = 50
+
+// Subscription represents a Subscription resource: a webhook that receives
+// matching inventory events.
+type Subscription struct {
+	resource.Resource
+	Spec   SubscriptionSpec   `json:"spec" validate:"required"`
+	Status SubscriptionStatus `json:"status,omitempty"`
+}
+
+// SubscriptionSpec defines the desired state of Subscription
+type SubscriptionSpec struct {
+	// ResourceKind limits delivery to events about one kind (e.g. "Device").
+	// Empty matches every kind.
+	ResourceKind string `json:"resourceKind,omitempty"`
+
+	// EventTypes are event type patterns, e.g. "io.fabrica.device.created" or
+	// "io.fabrica.device.*" ("*" matches one segment, "**" the rest).
+	// Empty matches every event type.
+	EventTypes []string `json:"eventTypes,omitempty"`
+
+	// LabelSelector only matches events for resources carrying all of these labels.
+	LabelSelector map[string]string `json:"labelSelector,omitempty"`
+
+	// URL is the endpoint events are POSTed to (CloudEvents binary mode).
+	// Loopback, link-local and metadata addresses are refused (see CheckAddress).
+	URL string `json:"url" validate:"required"`
+
+	// Secret is the HMAC-SHA256 key used to sign each delivery. Empty disables signing.
+	// It is never returned by the API.
+	Secret string `json:"secret,omitempty"`
+
+	// MaxAttempts is how many times a delivery is tried before the event is
+	// dead-lettered (default 5).
+	MaxAttempts int `json:"maxAttempts,omitempty"`
+}
+
+// SubscriptionStatus defines the observed state of Subscription
+type SubscriptionStatus struct {
+	Phase   string `json:"phase,omitempty"`
+	Message string `json:"message,omitempty"`
+	Ready   bool   `json:"ready"`
+
+	// Delivered and Failed count events delivered and dead-lettered.
+	Delivered int64 `json:"delivered"`
+	Failed    int64 `json:"failed"`
+
+	// ConsecutiveFailures counts dead-lettered events since the last successful delivery.
+	ConsecutiveFailures int `json:"consecutiveFailures"`
+
+	// LastDelivery describes the most recent delivery, successful or not.
+	LastDelivery *DeliveryRecord `json:"lastDelivery,omitempty"`
+
+	// DeadLetters holds the most recent events that could not be delivered
+	// (at most MaxDeadLetters), so they can be inspected or replayed.
+	DeadLetters []DeadLetter `json:"deadLetters,omitempty"`
+}
+
+// DeliveryRecord describes one delivery of an event.
+type DeliveryRecord struct {
+	EventID    string    `json:"eventId"`
+	EventType  string    `json:"eventType"`
+	Time       time.Time `json:"time"`
+	Attempts   int       `json:"attempts"`
+	StatusCode int       `json:"statusCode,omitempty"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// DeadLetter is an event that was given up on, with the last error.
+type DeadLetter struct {
+	DeliveryRecord
+
+	// Event is the undelivered event in CloudEvents structured (JSON) form.
+	Event json.RawMessage `json:"event,omitempty"`
+}
+
+// Validate implements custom validation logic for Subscription
+func (r *Subscription) Validate(ctx context.Context) error {
+	u, err := url.Parse(r.Spec.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return errors.New("url must be an absolute http or https URL")
+	}
+	if err := checkHost(u.Hostname()); err != nil {
+		return err
+	}
+	if r.Spec.MaxAttempts < 0 {
+		return errors.New("maxAttempts must not be negative")
+	}
+
+	return nil
+}
+
+// Redacted returns a copy of the subscription without its secret, for API responses and events.
+func (r *Subscription) Redacted() *Subscription {
+	redacted := *r
+	redacted.Spec.Secret = ""
+	return &redacted
+}
+
+// GetKind returns the kind of the resource
+func (r *Subscription) GetKind() string {
+	return "Subscription"
+}
+
+// GetName returns the name of the resource
+func (r *Subscription) GetName() string {
+	return r.Metadata.Name
+}
+
+// GetUID returns the UID of the resource
+func (r *Subscription) GetUID() string {
+	return r.Metadata.UID
+}
+
+func init() {
+	// Register resource type prefix for storage
+	resource.RegisterResourcePrefix("Subscription", "sub")
+}
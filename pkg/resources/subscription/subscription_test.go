@@ -0,0 +1,52 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package subscription
+
+import (
+	"context"
+	"net/netip"
+	"testing"
+)
+
+func TestValidateURL(t *testing.T) {
+	tests := []struct {
+		url  string
+		want bool
+	}{
+		{"https://hooks.example.com/inventory", true},
+		{"http://10.1.2.3:8080/hook", true},
+		{"http://[2001:db8::1]/hook", true},
+		{"ftp://hooks.example.com", false},
+		{"/relative", false},
+		{"http://127.0.0.1:9000/", false},
+		{"http://127.1.2.3/", false},
+		{"http://localhost/", false},
+		{"http://api.localhost./", false},
+		{"http://[::1]/", false},
+		{"http://[::ffff:127.0.0.1]/", false},
+		{"http://169.254.169.254/latest/meta-data/", false},
+		{"http://metadata.google.internal/computeMetadata/v1/", false},
+		{"http://[fe80::1%25eth0]/", false},
+		{"http://0.0.0.0/", false},
+		{"http://100.100.100.200/", false},
+		{"http://[fd00:ec2::254]/", false},
+	}
+	for _, tt := range tests {
+		sub := &Subscription{Spec: SubscriptionSpec{URL: tt.url}}
+		if err := sub.Validate(context.Background()); (err == nil) != tt.want {
+			t.Errorf("Validate(%s) = %v, want valid %v", tt.url, err, tt.want)
+		}
+	}
+
+	// Allowed networks are accepted, by address and by name
+	SetAllowedNetworks([]netip.Prefix{netip.MustParsePrefix("127.0.0.1/32")})
+	t.Cleanup(func() { SetAllowedNetworks(nil) })
+	for _, url := range []string{"http://127.0.0.1:9000/", "http://localhost:9000/"} {
+		sub := &Subscription{Spec: SubscriptionSpec{URL: url}}
+		if err := sub.Validate(context.Background()); err != nil {
+			t.Errorf("Validate(%s) with 127.0.0.1/32 allowed: %v", url, err)
+		}
+	}
+}
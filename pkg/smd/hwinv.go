@@ -0,0 +1,436 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+// Package smd converts between Devices and the hardware inventory schema of
+// the OpenCHAMI State Management Database (SMD), as served under
+// /hsm/v2/Inventory/Hardware (by location) and
+// /hsm/v2/Inventory/HardwareByFRU (by FRU).
+//
+// SMD identifies locations by xname (e.g. x1000c0s0b0n0p1). A Device's
+// xname is its "xname" property; Devices without one get an xname derived
+// from their parent's when their SMD type has a fixed place below it (a
+// Processor, Memory, NodeAccel, Drive or NodeHsnNIC below a Node, a Node
+// below a NodeBMC, and so on). SMD fields the Device model has no place for
+// are kept in the "smd.location_info" and "smd.fru_info" properties, and the
+// FRU ID in "smd.fru_id", so an SMD import exports back unchanged.
+package smd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// Device properties used for SMD fields
+const (
+	PropertyXName        = "xname"
+	PropertyFRUID        = "smd.fru_id"
+	PropertyLocationInfo = "smd.location_info"
+	PropertyFRUInfo      = "smd.fru_info"
+)
+
+// Location status values
+const (
+	StatusPopulated = "Populated"
+	StatusEmpty     = "Empty"
+)
+
+// HWInvByLoc is an SMD hardware inventory location (HWInvByLoc.1.0.0). The
+// type-specific location info (e.g. NodeLocationInfo) is in Info and is
+// written as "<Type>LocationInfo".
+type HWInvByLoc struct {
+	ID                        string
+	Type                      string
+	Ordinal                   int
+	Status                    string
+	HWInventoryByLocationType string
+	PopulatedFRU              *HWInvByFRU
+	Info                      map[string]any
+}
+
+// HWInvByFRU is an SMD field-replaceable unit (HWInvByFRU.1.0.0). The
+// type-specific FRU info (e.g. NodeFRUInfo) is in Info and is written as
+// "<Type>FRUInfo".
+type HWInvByFRU struct {
+	FRUID                string
+	Type                 string
+	FRUSubtype           string
+	HWInventoryByFRUType string
+	Info                 map[string]any
+}
+
+// MarshalJSON writes the location with its info under "<Type>LocationInfo"
+func (l HWInvByLoc) MarshalJSON() ([]byte, error) {
+	out := map[string]any{
+		"ID":                        l.ID,
+		"Type":                      l.Type,
+		"Ordinal":                   l.Ordinal,
+		"Status":                    l.Status,
+		"HWInventoryByLocationType": l.HWInventoryByLocationType,
+	}
+	if l.PopulatedFRU != nil {
+		out["PopulatedFRU"] = l.PopulatedFRU
+	}
+	if len(l.Info) > 0 {
+		out[l.Type+"LocationInfo"] = l.Info
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads a location, taking its info from "<Type>LocationInfo"
+func (l *HWInvByLoc) UnmarshalJSON(data []byte) error {
+	var in struct {
+		ID                        string
+		Type                      string
+		Ordinal                   int
+		Status                    string
+		HWInventoryByLocationType string
+		PopulatedFRU              *HWInvByFRU
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*l = HWInvByLoc{
+		ID:                        in.ID,
+		Type:                      in.Type,
+		Ordinal:                   in.Ordinal,
+		Status:                    in.Status,
+		HWInventoryByLocationType: in.HWInventoryByLocationType,
+		PopulatedFRU:              in.PopulatedFRU,
+	}
+	return unmarshalInfo(data, in.Type+"LocationInfo", &l.Info)
+}
+
+// MarshalJSON writes the FRU with its info under "<Type>FRUInfo"
+func (f HWInvByFRU) MarshalJSON() ([]byte, error) {
+	out := map[string]any{
+		"FRUID":                f.FRUID,
+		"Type":                 f.Type,
+		"FRUSubtype":           f.FRUSubtype,
+		"HWInventoryByFRUType": f.HWInventoryByFRUType,
+	}
+	if len(f.Info) > 0 {
+		out[f.Type+"FRUInfo"] = f.Info
+	}
+	return json.Marshal(out)
+}
+
+// UnmarshalJSON reads a FRU, taking its info from "<Type>FRUInfo"
+func (f *HWInvByFRU) UnmarshalJSON(data []byte) error {
+	var in struct {
+		FRUID                string
+		Type                 string
+		FRUSubtype           string
+		HWInventoryByFRUType string
+	}
+	if err := json.Unmarshal(data, &in); err != nil {
+		return err
+	}
+	*f = HWInvByFRU{FRUID: in.FRUID, Type: in.Type, FRUSubtype: in.FRUSubtype, HWInventoryByFRUType: in.HWInventoryByFRUType}
+	return unmarshalInfo(data, in.Type+"FRUInfo", &f.Info)
+}
+
+// unmarshalInfo reads the object under key in data, if any
+func unmarshalInfo(data []byte, key string, info *map[string]any) error {
+	var fields map[string]json.RawMessage
+	if err := json.Unmarshal(data, &fields); err != nil {
+		return err
+	}
+	raw, ok := fields[key]
+	if !ok || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, info); err != nil {
+		return fmt.Errorf("invalid %s: %w", key, err)
+	}
+	return nil
+}
+
+// deviceTypes maps Device types to SMD types. SMD types map to themselves.
+var deviceTypes = map[string]string{
+	"CPU":         "Processor",
+	"DIMM":        "Memory",
+	"GPU":         "NodeAccel",
+	"Accelerator": "NodeAccel",
+	"Disk":        "Drive",
+	"NIC":         "NodeHsnNIC",
+	"HSNNIC":      "NodeHsnNIC",
+	"Rack":        "Cabinet",
+	"Blade":       "ComputeModule",
+	"BMC":         "NodeBMC",
+	"PSU":         "NodeEnclosurePowerSupply",
+	"PowerSupply": "NodeEnclosurePowerSupply",
+	"Switch":      "MgmtSwitch",
+	"PDU":         "CabinetPDU",
+}
+
+// smdTypes are the SMD component types with hardware inventory
+var smdTypes = map[string]bool{
+	"Cabinet": true, "Chassis": true, "ComputeModule": true, "RouterModule": true,
+	"NodeEnclosure": true, "NodeEnclosurePowerSupply": true, "HSNBoard": true,
+	"MgmtSwitch": true, "MgmtHLSwitch": true, "CDUMgmtSwitch": true,
+	"Node": true, "Processor": true, "NodeAccel": true, "NodeAccelRiser": true,
+	"Drive": true, "Memory": true, "NodeHsnNIC": true, "NodeBMC": true, "RouterBMC": true,
+	"CabinetPDU": true, "CabinetPDUPowerConnector": true, "CMMRectifier": true,
+}
+
+// importTypes maps SMD types to the Device types the collector uses
+var importTypes = map[string]string{
+	"Processor": "CPU",
+	"Memory":    "DIMM",
+}
+
+// childPlaces is where an SMD type sits below its parent: the parent's SMD
+// type and the xname letters (followed by the ordinal) that are appended
+var childPlaces = map[string]struct{ parent, letters string }{
+	"Chassis":                  {"Cabinet", "c"},
+	"ComputeModule":            {"Chassis", "s"},
+	"RouterModule":             {"Chassis", "r"},
+	"NodeEnclosure":            {"ComputeModule", "e"},
+	"NodeEnclosurePowerSupply": {"NodeEnclosure", "t"},
+	"NodeBMC":                  {"ComputeModule", "b"},
+	"Node":                     {"NodeBMC", "n"},
+	"Processor":                {"Node", "p"},
+	"Memory":                   {"Node", "d"},
+	"NodeAccel":                {"Node", "a"},
+	"NodeHsnNIC":               {"Node", "h"},
+	"Drive":                    {"Node", "g0k"},
+}
+
+// SMDType returns the SMD type of a Device type, or "" if it has none
+func SMDType(deviceType string) string {
+	if t, ok := deviceTypes[deviceType]; ok {
+		return t
+	}
+	if smdTypes[deviceType] {
+		return deviceType
+	}
+	return ""
+}
+
+// ByLocation returns the SMD locations of the Devices that have an SMD type
+// and an xname in xnames (see XNames), ordered by xname. Each is Populated
+// with the Device's FRU.
+func ByLocation(devices []*device.Device, xnames map[string]string) []HWInvByLoc {
+	locations := make([]HWInvByLoc, 0, len(xnames))
+	for _, dev := range devices {
+		xname, ok := xnames[dev.GetUID()]
+		if !ok {
+			continue
+		}
+		smdType := SMDType(dev.Spec.DeviceType)
+		fru := fruOf(dev, smdType)
+		info := objectProperty(dev, PropertyLocationInfo)
+		if info == nil {
+			info = map[string]any{}
+			if id := redfishID(dev); id != "" {
+				info["Id"] = id
+			}
+			if name := dev.GetName(); name != "" {
+				info["Name"] = name
+			}
+		}
+		locations = append(locations, HWInvByLoc{
+			ID:                        xname,
+			Type:                      smdType,
+			Ordinal:                   ordinal(xname),
+			Status:                    StatusPopulated,
+			HWInventoryByLocationType: "HWInvByLoc" + smdType,
+			PopulatedFRU:              &fru,
+			Info:                      info,
+		})
+	}
+	sort.Slice(locations, func(i, j int) bool { return lessXName(locations[i].ID, locations[j].ID) })
+	return locations
+}
+
+// ByFRU returns the SMD FRUs of the Devices that have an SMD type, ordered by FRU ID
+func ByFRU(devices []*device.Device) []HWInvByFRU {
+	frus := make([]HWInvByFRU, 0, len(devices))
+	for _, dev := range devices {
+		if smdType := SMDType(dev.Spec.DeviceType); smdType != "" {
+			frus = append(frus, fruOf(dev, smdType))
+		}
+	}
+	sort.Slice(frus, func(i, j int) bool { return frus[i].FRUID < frus[j].FRUID })
+	return frus
+}
+
+// XNames returns the xname of every Device (by UID) that has an SMD type and
+// either an xname property or a parent with an xname below which its type
+// has a fixed place
+func XNames(devices []*device.Device) map[string]string {
+	byUID := make(map[string]*device.Device, len(devices))
+	children := make(map[string][]*device.Device)
+	for _, dev := range devices {
+		byUID[dev.GetUID()] = dev
+	}
+	for _, dev := range devices {
+		if _, ok := byUID[dev.Spec.ParentID]; ok {
+			children[dev.Spec.ParentID] = append(children[dev.Spec.ParentID], dev)
+		}
+	}
+
+	// Devices with an xname property are where it says
+	xnames := make(map[string]string)
+	for _, dev := range devices {
+		if x := stringProperty(dev, PropertyXName); x != "" && SMDType(dev.Spec.DeviceType) != "" {
+			xnames[dev.GetUID()] = x
+		}
+	}
+
+	// Their descendants without one are placed below them by type, numbered
+	// in Redfish URI order after the ordinals their siblings already have
+	var derive func(dev *device.Device)
+	derive = func(dev *device.Device) {
+		xname, parentType := xnames[dev.GetUID()], SMDType(dev.Spec.DeviceType)
+		kids := children[dev.GetUID()]
+		sort.SliceStable(kids, func(i, j int) bool { return lessChild(kids[i], kids[j]) })
+		used := make(map[string]bool)
+		for _, kid := range kids {
+			if x, ok := xnames[kid.GetUID()]; ok {
+				used[x] = true
+			}
+		}
+		next := make(map[string]int)
+		for _, kid := range kids {
+			if _, ok := xnames[kid.GetUID()]; ok {
+				continue // has its own xname (or is in a parent cycle)
+			}
+			kidType := SMDType(kid.Spec.DeviceType)
+			where, ok := childPlaces[kidType]
+			if !ok || where.parent != parentType {
+				continue
+			}
+			n := next[kidType]
+			for used[xname+where.letters+strconv.Itoa(n)] {
+				n++
+			}
+			next[kidType] = n + 1
+			xnames[kid.GetUID()] = xname + where.letters + strconv.Itoa(n)
+			derive(kid)
+		}
+	}
+	for _, dev := range devices {
+		if stringProperty(dev, PropertyXName) != "" && SMDType(dev.Spec.DeviceType) != "" {
+			derive(dev)
+		}
+	}
+	return xnames
+}
+
+// fruOf returns the SMD FRU of a Device
+func fruOf(dev *device.Device, smdType string) HWInvByFRU {
+	info := objectProperty(dev, PropertyFRUInfo)
+	if info == nil {
+		info = map[string]any{}
+	}
+	fruID := stringProperty(dev, PropertyFRUID)
+	if fruID == "" {
+		fruID = FRUID(smdType, dev.Spec.Manufacturer, dev.Spec.PartNumber, dev.Spec.SerialNumber)
+	}
+
+	// The Device's fields win over imported info, except where the import
+	// filled them in: a part number from the model, a serial number from
+	// the FRU ID
+	if dev.Spec.Manufacturer != "" {
+		info["Manufacturer"] = dev.Spec.Manufacturer
+	}
+	if pn := dev.Spec.PartNumber; pn != "" && (info["PartNumber"] != nil || info["Model"] != pn) {
+		info["PartNumber"] = pn
+	}
+	if sn := dev.Spec.SerialNumber; sn != "" && sn != fruID {
+		info["SerialNumber"] = sn
+	}
+	return HWInvByFRU{
+		FRUID:                fruID,
+		Type:                 smdType,
+		HWInventoryByFRUType: "HWInvByFRU" + smdType,
+		Info:                 info,
+	}
+}
+
+var nonFRUIDChars = regexp.MustCompile(`[^A-Za-z0-9_-]+`)
+
+// FRUID builds an SMD FRU ID the way SMD does for Redfish hardware:
+// <type>.<manufacturer>.<part number>.<serial number>, without spaces or punctuation
+func FRUID(smdType, manufacturer, partNumber, serialNumber string) string {
+	parts := []string{smdType}
+	for _, part := range []string{manufacturer, partNumber, serialNumber} {
+		parts = append(parts, nonFRUIDChars.ReplaceAllString(part, ""))
+	}
+	return strings.Join(parts, ".")
+}
+
+var xnameOrdinal = regexp.MustCompile(`(\d+)$`)
+
+// ordinal is the number at the end of an xname
+func ordinal(xname string) int {
+	n, _ := strconv.Atoi(xnameOrdinal.FindString(xname))
+	return n
+}
+
+var xnameSegment = regexp.MustCompile(`[a-z]+\d+`)
+
+// lessXName orders xnames by their segments, comparing ordinals as numbers
+// (x1c0s2 before x1c0s10)
+func lessXName(a, b string) bool {
+	as, bs := xnameSegment.FindAllString(a, -1), xnameSegment.FindAllString(b, -1)
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		al, bl := strings.TrimRight(as[i], "0123456789"), strings.TrimRight(bs[i], "0123456789")
+		if al != bl {
+			return al < bl
+		}
+		return ordinal(as[i]) < ordinal(bs[i])
+	}
+	if len(as) != len(bs) {
+		return len(as) < len(bs)
+	}
+	return a < b
+}
+
+// lessChild orders siblings for deriving xnames: by Redfish URI (so
+// Processors/CPU1 comes before Processors/CPU2), then name
+func lessChild(a, b *device.Device) bool {
+	ua, ub := stringProperty(a, "redfish_uri"), stringProperty(b, "redfish_uri")
+	if ua != ub {
+		return ua < ub
+	}
+	return a.GetName() < b.GetName()
+}
+
+// redfishID is the last segment of a Device's Redfish URI
+func redfishID(dev *device.Device) string {
+	uri := strings.TrimRight(stringProperty(dev, "redfish_uri"), "/")
+	if uri == "" {
+		return ""
+	}
+	return uri[strings.LastIndex(uri, "/")+1:]
+}
+
+// stringProperty returns a string property of a Device, or ""
+func stringProperty(dev *device.Device, key string) string {
+	var value string
+	if raw, ok := dev.Spec.Properties[key]; ok {
+		json.Unmarshal(raw, &value)
+	}
+	return value
+}
+
+// objectProperty returns an object property of a Device, or nil
+func objectProperty(dev *device.Device, key string) map[string]any {
+	var value map[string]any
+	if raw, ok := dev.Spec.Properties[key]; ok {
+		json.Unmarshal(raw, &value)
+	}
+	return value
+}
@@ -0,0 +1,162 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package smd
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// ParseDump reads the locations in an SMD hardware inventory dump: a JSON
+// array of HWInvByLoc (GET /hsm/v2/Inventory/Hardware) or an HWInventory
+// object (GET /hsm/v2/Inventory/Hardware/Query/{xname}) in any format, whose
+// locations are in type arrays such as Nodes and Processors, possibly nested.
+func ParseDump(data []byte) ([]HWInvByLoc, error) {
+	var root any
+	if err := json.Unmarshal(data, &root); err != nil {
+		return nil, fmt.Errorf("invalid SMD inventory: %w", err)
+	}
+
+	var locations []HWInvByLoc
+	var walk func(v any) error
+	walk = func(v any) error {
+		switch v := v.(type) {
+		case []any:
+			for _, item := range v {
+				if err := walk(item); err != nil {
+					return err
+				}
+			}
+		case map[string]any:
+			if _, ok := v["HWInventoryByLocationType"]; ok {
+				raw, _ := json.Marshal(v)
+				var loc HWInvByLoc
+				if err := json.Unmarshal(raw, &loc); err != nil {
+					return fmt.Errorf("invalid SMD location %v: %w", v["ID"], err)
+				}
+				locations = append(locations, loc)
+			}
+			// Locations nest their components (e.g. a Node's Processors) in arrays
+			for key, child := range v {
+				if _, ok := child.([]any); ok && key != "PopulatedFRU" {
+					if err := walk(child); err != nil {
+						return err
+					}
+				}
+			}
+		}
+		return nil
+	}
+	if err := walk(root); err != nil {
+		return nil, err
+	}
+	if len(locations) == 0 {
+		return nil, fmt.Errorf("no SMD hardware inventory locations found")
+	}
+	return locations, nil
+}
+
+var xnameParent = regexp.MustCompile(`^(.+?)[a-z]+\d+$`)
+
+// DeviceSpecs converts SMD locations into the Device specs of a
+// DiscoverySnapshot. Each populated location is a Device with its xname,
+// FRU ID and SMD info kept as properties, linked by parentSerialNumber to the
+// Device at the nearest enclosing xname. A FRU without a serial number, or
+// whose serial number an earlier FRU already has, gets its FRU ID as serial
+// number. warnings describe those and the locations that are left out
+// (empty, or with neither serial number nor FRU ID).
+func DeviceSpecs(locations []HWInvByLoc) (specs []device.DeviceSpec, warnings []string) {
+	sort.Slice(locations, func(i, j int) bool { return lessXName(locations[i].ID, locations[j].ID) })
+
+	// The serial number each xname's Device gets
+	serials := make(map[string]string, len(locations))
+	seen := make(map[string]string, len(locations))
+	for _, loc := range locations {
+		fru := loc.PopulatedFRU
+		if loc.Status == StatusEmpty || fru == nil {
+			warnings = append(warnings, fmt.Sprintf("%s is empty; skipped", loc.ID))
+			continue
+		}
+		serial := infoString(fru.Info, "SerialNumber")
+		if other, dup := seen[serial]; serial != "" && dup {
+			// SMD doesn't require unique serial numbers; the FRU ID is unique
+			warnings = append(warnings, fmt.Sprintf("%s has serial number %s like %s; using FRU ID %s as its serial number", loc.ID, serial, other, fru.FRUID))
+			serial = ""
+		}
+		if serial == "" {
+			serial = fru.FRUID
+		}
+		if serial == "" {
+			warnings = append(warnings, fmt.Sprintf("%s has no serial number or FRU ID; skipped", loc.ID))
+			continue
+		}
+		seen[serial] = loc.ID
+		serials[loc.ID] = serial
+	}
+
+	for _, loc := range locations {
+		serial, ok := serials[loc.ID]
+		if !ok {
+			continue
+		}
+		fru := loc.PopulatedFRU
+		smdType := loc.Type
+		if smdType == "" {
+			smdType = fru.Type
+		}
+		deviceType := smdType
+		if t, ok := importTypes[smdType]; ok {
+			deviceType = t
+		}
+
+		spec := device.DeviceSpec{
+			DeviceType:   deviceType,
+			Manufacturer: infoString(fru.Info, "Manufacturer"),
+			PartNumber:   infoString(fru.Info, "PartNumber"),
+			SerialNumber: serial,
+			Properties:   map[string]json.RawMessage{},
+		}
+		if spec.PartNumber == "" {
+			spec.PartNumber = infoString(fru.Info, "Model")
+		}
+		for parent := loc.ID; ; {
+			m := xnameParent.FindStringSubmatch(parent)
+			if m == nil {
+				break
+			}
+			parent = m[1]
+			if s, ok := serials[parent]; ok {
+				spec.ParentSerialNumber = s
+				break
+			}
+		}
+		setProperty(spec.Properties, PropertyXName, loc.ID)
+		setProperty(spec.Properties, PropertyFRUID, fru.FRUID)
+		if len(loc.Info) > 0 {
+			setProperty(spec.Properties, PropertyLocationInfo, loc.Info)
+		}
+		if len(fru.Info) > 0 {
+			setProperty(spec.Properties, PropertyFRUInfo, fru.Info)
+		}
+		specs = append(specs, spec)
+	}
+	return specs, warnings
+}
+
+// infoString returns a string field of SMD info, or ""
+func infoString(info map[string]any, key string) string {
+	s, _ := info[key].(string)
+	return s
+}
+
+func setProperty(properties map[string]json.RawMessage, key string, value any) {
+	if raw, err := json.Marshal(value); err == nil {
+		properties[key] = raw
+	}
+}
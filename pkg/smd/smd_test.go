@@ -0,0 +1,241 @@
+// Copyright © 2025 OpenCHAMI a Series of LF Projects, LLC
+//
+// SPDX-License-Identifier: MIT
+
+package smd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/openchami/fabrica/pkg/resource"
+
+	"github.com/user/inventory-api/pkg/resources/device"
+)
+
+// devicesOf stores specs the way reconciling a DiscoverySnapshot does: each
+// is a Device, with parentSerialNumber resolved to the parent's UID
+func devicesOf(t *testing.T, specs []device.DeviceSpec) []*device.Device {
+	t.Helper()
+	uids := make(map[string]string, len(specs))
+	for i, spec := range specs {
+		uids[spec.SerialNumber] = fmt.Sprintf("dev-%04d", i)
+	}
+	devices := make([]*device.Device, len(specs))
+	for i, spec := range specs {
+		if spec.ParentSerialNumber != "" {
+			parent, ok := uids[spec.ParentSerialNumber]
+			if !ok {
+				t.Fatalf("%s: no Device with parent serial number %s", spec.SerialNumber, spec.ParentSerialNumber)
+			}
+			spec.ParentID = parent
+		}
+		dev := &device.Device{
+			Resource: resource.Resource{APIVersion: "v1", Kind: "Device", SchemaVersion: "v1"},
+			Spec:     spec,
+		}
+		dev.Metadata.Initialize(strings.ToLower(spec.SerialNumber), uids[spec.SerialNumber])
+		devices[i] = dev
+	}
+	return devices
+}
+
+// normalize decodes JSON into plain values, so documents compare regardless
+// of key order and number types
+func normalize(t *testing.T, v any) any {
+	t.Helper()
+	data, err := json.Marshal(v)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var out any
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	return out
+}
+
+// TestRoundTrip imports an SMD dump and exports the Devices it becomes,
+// which gives back the dump's populated locations and FRUs unchanged.
+func TestRoundTrip(t *testing.T) {
+	data, err := os.ReadFile("testdata/hwinv.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	locations, err := ParseDump(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(locations) != 7 {
+		t.Fatalf("parsed %d locations, want 7", len(locations))
+	}
+
+	specs, warnings := DeviceSpecs(locations)
+	if len(specs) != 6 {
+		t.Fatalf("%d device specs, want 6", len(specs))
+	}
+	wantWarnings := []string{
+		"x1000c0s0b0n0d1 is empty; skipped",
+		"x1000c0s0b0n0d2 has serial number S0000001 like x1000c0s0b0n0d0; using FRU ID Memory.Samsung.M393A4K40DB3CWE.S0000001-2 as its serial number",
+	}
+	if !slices.Equal(warnings, wantWarnings) {
+		t.Errorf("warnings:\n  %q\nwant\n  %q", warnings, wantWarnings)
+	}
+	bySerial := make(map[string]device.DeviceSpec)
+	for _, spec := range specs {
+		bySerial[spec.SerialNumber] = spec
+	}
+	if node := bySerial["NID00001"]; node.DeviceType != "Node" || node.PartNumber != "102072801" || node.ParentSerialNumber != "BQWK1234" {
+		t.Errorf("node imported as %+v", node)
+	}
+	if cpu := bySerial["Processor.AdvancedMicroDevicesInc.2B000F0000000001"]; cpu.DeviceType != "CPU" || cpu.ParentSerialNumber != "NID00001" {
+		t.Errorf("processor without a serial number imported as %+v", cpu)
+	}
+	if dimm := bySerial["S0000001"]; dimm.DeviceType != "DIMM" || dimm.ParentSerialNumber != "NID00001" {
+		t.Errorf("memory imported as %+v", dimm)
+	}
+
+	devices := devicesOf(t, specs)
+
+	// By location: everything but the empty location, ordered by xname
+	var dump []any
+	if err := json.Unmarshal(data, &dump); err != nil {
+		t.Fatal(err)
+	}
+	var populated []any
+	var frus []any
+	for _, loc := range dump {
+		if fru, ok := loc.(map[string]any)["PopulatedFRU"]; ok {
+			populated = append(populated, loc)
+			frus = append(frus, fru)
+		}
+	}
+	slices.SortFunc(populated, func(a, b any) int {
+		x, y := a.(map[string]any)["ID"].(string), b.(map[string]any)["ID"].(string)
+		switch {
+		case lessXName(x, y):
+			return -1
+		case lessXName(y, x):
+			return 1
+		}
+		return 0
+	})
+	exported := ByLocation(devices, XNames(devices))
+	if got := normalize(t, exported); !reflect.DeepEqual(got, populated) {
+		t.Errorf("by location:\n%s\nwant\n%s", jsonOf(t, got), jsonOf(t, populated))
+	}
+
+	// By FRU: the populated FRUs, ordered by FRU ID
+	slices.SortFunc(frus, func(a, b any) int {
+		return strings.Compare(a.(map[string]any)["FRUID"].(string), b.(map[string]any)["FRUID"].(string))
+	})
+	if got := normalize(t, ByFRU(devices)); !reflect.DeepEqual(got, frus) {
+		t.Errorf("by FRU:\n%s\nwant\n%s", jsonOf(t, got), jsonOf(t, frus))
+	}
+
+	// And the export imports as the same Devices again
+	data, err = json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locations, err = ParseDump(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again, _ := DeviceSpecs(locations)
+	if !reflect.DeepEqual(normalize(t, again), normalize(t, specs)) {
+		t.Errorf("re-imported as\n%s\nwant\n%s", jsonOf(t, again), jsonOf(t, specs))
+	}
+}
+
+// TestDerivedXNames exports collected Devices, only some of which have an
+// xname, and imports the export back with the same parents.
+func TestDerivedXNames(t *testing.T) {
+	spec := func(deviceType, serial, parent, properties string) device.DeviceSpec {
+		s := device.DeviceSpec{DeviceType: deviceType, Manufacturer: "Acme", SerialNumber: serial, ParentSerialNumber: parent}
+		if properties != "" {
+			if err := json.Unmarshal([]byte(properties), &s.Properties); err != nil {
+				t.Fatal(err)
+			}
+		}
+		return s
+	}
+	devices := devicesOf(t, []device.DeviceSpec{
+		spec("Node", "N1", "", `{"xname":"x1c0s0b0n0"}`),
+		spec("CPU", "C2", "N1", `{"redfish_uri":"/redfish/v1/Systems/Node0/Processors/CPU2"}`),
+		spec("CPU", "C1", "N1", `{"redfish_uri":"/redfish/v1/Systems/Node0/Processors/CPU1"}`),
+		spec("CPU", "C0", "N1", `{"xname":"x1c0s0b0n0p0"}`),
+		spec("DIMM", "D1", "N1", ""),
+		spec("Disk", "G1", "N1", ""),
+		spec("Fan", "F1", "N1", ""),            // no SMD type
+		spec("DIMM", "D9", "", ""),             // no xname and no parent with one
+		spec("Node", "N2", "", `{"xname":""}`), // an empty xname is none
+	})
+
+	xnames := XNames(devices)
+	bySerial := make(map[string]string)
+	for _, dev := range devices {
+		if x, ok := xnames[dev.GetUID()]; ok {
+			bySerial[dev.Spec.SerialNumber] = x
+		}
+	}
+	want := map[string]string{
+		"N1": "x1c0s0b0n0",
+		"C0": "x1c0s0b0n0p0",
+		"C1": "x1c0s0b0n0p1",
+		"C2": "x1c0s0b0n0p2",
+		"D1": "x1c0s0b0n0d0",
+		"G1": "x1c0s0b0n0g0k0",
+	}
+	if !reflect.DeepEqual(bySerial, want) {
+		t.Errorf("xnames %v, want %v", bySerial, want)
+	}
+
+	exported := ByLocation(devices, xnames)
+	ids := make([]string, len(exported))
+	for i, loc := range exported {
+		ids[i] = loc.ID
+	}
+	if got := strings.Join(ids, " "); got != "x1c0s0b0n0 x1c0s0b0n0d0 x1c0s0b0n0g0k0 x1c0s0b0n0p0 x1c0s0b0n0p1 x1c0s0b0n0p2" {
+		t.Errorf("exported locations %s", got)
+	}
+	if cpu := exported[4]; cpu.Type != "Processor" || cpu.Ordinal != 1 || cpu.Info["Id"] != "CPU1" ||
+		cpu.PopulatedFRU.FRUID != "Processor.Acme..C1" || cpu.PopulatedFRU.Info["SerialNumber"] != "C1" {
+		t.Errorf("CPU1 exported as %+v, FRU %+v", cpu, cpu.PopulatedFRU)
+	}
+
+	data, err := json.Marshal(exported)
+	if err != nil {
+		t.Fatal(err)
+	}
+	locations, err := ParseDump(data)
+	if err != nil {
+		t.Fatal(err)
+	}
+	specs, warnings := DeviceSpecs(locations)
+	if len(warnings) != 0 {
+		t.Errorf("warnings: %q", warnings)
+	}
+	parents := make(map[string]string)
+	for _, s := range specs {
+		parents[s.SerialNumber] = s.ParentSerialNumber
+	}
+	wantParents := map[string]string{"N1": "", "C0": "N1", "C1": "N1", "C2": "N1", "D1": "N1", "G1": "N1"}
+	if !reflect.DeepEqual(parents, wantParents) {
+		t.Errorf("re-imported parents %v, want %v", parents, wantParents)
+	}
+}
+
+func jsonOf(t *testing.T, v any) string {
+	t.Helper()
+	data, err := json.MarshalIndent(v, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}